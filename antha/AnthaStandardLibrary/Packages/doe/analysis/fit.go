@@ -0,0 +1,561 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+// Package analysis fits response surface models to the results of designed
+// experiments and uses them to predict and optimise responses
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/doe"
+	"gonum.org/v1/gonum/mat"
+)
+
+// A Factor is a factor of a fitted model
+type Factor struct {
+	Name    string
+	Numeric bool
+	// Range of a numeric factor in actual units. Coded units map Low to -1
+	// and High to +1.
+	Low, High float64
+	// Levels of a categorical factor. Categorical factors are effect coded:
+	// each level but the last has a column which is 1 for that level, 0 for
+	// other levels but the last and -1 for the last level.
+	Levels []string
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// columns returns the model columns of a factor for a setpoint
+func (a Factor) columns(v interface{}, coded bool) ([]float64, error) {
+	if a.Numeric {
+		x, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("factor %s: non-numeric value %v", a.Name, v)
+		}
+		if coded {
+			x = (2*x - a.Low - a.High) / (a.High - a.Low)
+		}
+		return []float64{x}, nil
+	}
+
+	key := fmt.Sprint(v)
+	cols := make([]float64, len(a.Levels)-1)
+	for i, l := range a.Levels {
+		if l != key {
+			continue
+		}
+		if i < len(cols) {
+			cols[i] = 1
+		} else {
+			for j := range cols {
+				cols[j] = -1
+			}
+		}
+		return cols, nil
+	}
+	return nil, fmt.Errorf("factor %s: unknown level %v", a.Name, v)
+}
+
+// A Coefficient is an estimated coefficient of a model term
+type Coefficient struct {
+	Term     string
+	Estimate float64
+	StdError float64
+	T        float64
+	P        float64
+}
+
+// An ANOVARow is a row of an analysis of variance table. Rows for model
+// effects use partial sums of squares: the increase in the residual sum of
+// squares when the effect is removed from the model.
+type ANOVARow struct {
+	Source string
+	DF     int
+	SS     float64
+	MS     float64
+	F      float64 // NaN if not applicable
+	P      float64 // NaN if not applicable
+}
+
+// A mainColumn is a model column of a single factor
+type mainColumn struct {
+	Factor int
+	Index  int // Index of the column among the columns of the factor
+	Name   string
+}
+
+// An effect is a group of model terms tested together in the ANOVA
+type effect struct {
+	Name  string
+	Terms []int
+}
+
+// A Fit is a least squares fit of a model to a response
+type Fit struct {
+	Response string
+	Model    doe.Model
+	Coded    bool // Whether coefficients are in coded units
+	Factors  []Factor
+
+	Coefficients []Coefficient
+	ANOVA        []ANOVARow
+	RSquared     float64
+	AdjRSquared  float64 // NaN if there are no residual degrees of freedom
+	PredRSquared float64 // R² of predictions with each run left out
+	PRESS        float64 // Predicted residual sum of squares
+	RMSE         float64 // Root mean square error of residuals
+
+	mains []mainColumn
+	terms [][]int // Main columns multiplied together for each term
+	coefs []float64
+}
+
+// FitOpt are options to FitModel
+type FitOpt struct {
+	Runs     []doe.Run
+	Response string
+	// Factors to include in the model. Defaults to all factors of the first
+	// run that vary.
+	Factors []string
+	Model   doe.Model
+	// Fit coefficients in actual rather than coded units
+	Actual bool
+}
+
+func factorOf(runs []doe.Run, name string) (Factor, []interface{}, error) {
+	f := Factor{Name: name, Numeric: true}
+	values := make([]interface{}, len(runs))
+	for i, run := range runs {
+		v, err := run.GetFactorValue(name)
+		if err != nil {
+			return f, nil, err
+		}
+		values[i] = v
+
+		x, ok := toFloat(v)
+		switch {
+		case !ok:
+			f.Numeric = false
+		case i == 0:
+			f.Low, f.High = x, x
+		default:
+			f.Low, f.High = math.Min(f.Low, x), math.Max(f.High, x)
+		}
+	}
+
+	if !f.Numeric {
+		seen := make(map[string]bool)
+		for _, v := range values {
+			if key := fmt.Sprint(v); !seen[key] {
+				seen[key] = true
+				f.Levels = append(f.Levels, key)
+			}
+		}
+		sort.Strings(f.Levels)
+	}
+	return f, values, nil
+}
+
+// varies returns true if a factor takes more than one value
+func (a Factor) varies() bool {
+	if a.Numeric {
+		return a.High > a.Low
+	}
+	return len(a.Levels) > 1
+}
+
+// structure chooses the terms of the model and groups them into effects
+func (a *Fit) structure() []effect {
+	byFactor := make([][]int, len(a.Factors))
+	for i, f := range a.Factors {
+		n := 1
+		if !f.Numeric {
+			n = len(f.Levels) - 1
+		}
+		for j := 0; j < n; j++ {
+			name := f.Name
+			if !f.Numeric {
+				name = fmt.Sprintf("%s[%s]", f.Name, f.Levels[j])
+			}
+			byFactor[i] = append(byFactor[i], len(a.mains))
+			a.mains = append(a.mains, mainColumn{Factor: i, Index: j, Name: name})
+		}
+	}
+
+	var effects []effect
+	add := func(name string, terms [][]int) {
+		e := effect{Name: name}
+		for _, t := range terms {
+			e.Terms = append(e.Terms, len(a.terms))
+			a.terms = append(a.terms, t)
+		}
+		effects = append(effects, e)
+	}
+
+	add("Intercept", [][]int{nil})
+	for i, f := range a.Factors {
+		var terms [][]int
+		for _, c := range byFactor[i] {
+			terms = append(terms, []int{c})
+		}
+		add(f.Name, terms)
+	}
+	if a.Model >= doe.InteractionModel {
+		for i := range a.Factors {
+			for j := i + 1; j < len(a.Factors); j++ {
+				var terms [][]int
+				for _, ci := range byFactor[i] {
+					for _, cj := range byFactor[j] {
+						terms = append(terms, []int{ci, cj})
+					}
+				}
+				add(a.Factors[i].Name+"*"+a.Factors[j].Name, terms)
+			}
+		}
+	}
+	if a.Model >= doe.QuadraticModel {
+		for i, f := range a.Factors {
+			if f.Numeric {
+				c := byFactor[i][0]
+				add(f.Name+"^2", [][]int{{c, c}})
+			}
+		}
+	}
+	return effects
+}
+
+func (a *Fit) termName(t []int) string {
+	if len(t) == 0 {
+		return "Intercept"
+	}
+	if len(t) == 2 && t[0] == t[1] {
+		return a.mains[t[0]].Name + "^2"
+	}
+	var names []string
+	for _, c := range t {
+		names = append(names, a.mains[c].Name)
+	}
+	return strings.Join(names, "*")
+}
+
+// row returns the model matrix row for setpoints of each factor
+func (a *Fit) row(values []interface{}) ([]float64, error) {
+	var mains []float64
+	for i, f := range a.Factors {
+		cols, err := f.columns(values[i], a.Coded)
+		if err != nil {
+			return nil, err
+		}
+		mains = append(mains, cols...)
+	}
+
+	row := make([]float64, len(a.terms))
+	for i, t := range a.terms {
+		x := 1.0
+		for _, c := range t {
+			x *= mains[c]
+		}
+		row[i] = x
+	}
+	return row, nil
+}
+
+// residualSS returns the residual sum of squares of a least squares fit
+func residualSS(x [][]float64, y []float64) (float64, error) {
+	b, _, err := leastSquares(x, y)
+	if err != nil {
+		return 0, err
+	}
+	var ss float64
+	for i, row := range x {
+		e := y[i] - dot(row, b)
+		ss += e * e
+	}
+	return ss, nil
+}
+
+// without returns the model matrix without some columns
+func without(x [][]float64, cols []int) [][]float64 {
+	drop := make(map[int]bool)
+	for _, c := range cols {
+		drop[c] = true
+	}
+	ret := make([][]float64, len(x))
+	for i, row := range x {
+		for j, v := range row {
+			if !drop[j] {
+				ret[i] = append(ret[i], v)
+			}
+		}
+	}
+	return ret
+}
+
+func anovaRow(source string, df int, ss float64, errMS float64, errDF int) ANOVARow {
+	r := ANOVARow{Source: source, DF: df, SS: ss, MS: math.NaN(), F: math.NaN(), P: math.NaN()}
+	if df > 0 {
+		r.MS = ss / float64(df)
+	}
+	if df > 0 && errDF > 0 && errMS > 0 {
+		r.F = r.MS / errMS
+		r.P = fPValue(r.F, df, errDF)
+	}
+	return r
+}
+
+// FitModel fits a model to a response of runs by least squares
+func FitModel(opt FitOpt) (*Fit, error) {
+	if len(opt.Runs) == 0 {
+		return nil, fmt.Errorf("no runs")
+	}
+
+	names := opt.Factors
+	if len(names) == 0 {
+		names = opt.Runs[0].Factordescriptors
+	}
+
+	fit := &Fit{
+		Response: opt.Response,
+		Model:    opt.Model,
+		Coded:    !opt.Actual,
+	}
+
+	n := len(opt.Runs)
+	var settings [][]interface{}
+	for _, name := range names {
+		f, values, err := factorOf(opt.Runs, name)
+		if err != nil {
+			return nil, err
+		}
+		if !f.varies() {
+			if len(opt.Factors) != 0 {
+				return nil, fmt.Errorf("factor %s does not vary", name)
+			}
+			continue
+		}
+		fit.Factors = append(fit.Factors, f)
+		settings = append(settings, values)
+	}
+
+	y := make([]float64, n)
+	for i, run := range opt.Runs {
+		v, err := run.GetResponseValue(opt.Response)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("run %d: non-numeric response %s: %v", run.RunNumber, opt.Response, v)
+		}
+		y[i] = f
+	}
+
+	effects := fit.structure()
+
+	x := make([][]float64, n)
+	points := make(map[string][]int) // Runs at each design point
+	for i := range opt.Runs {
+		values := make([]interface{}, len(fit.Factors))
+		for j := range fit.Factors {
+			values[j] = settings[j][i]
+		}
+		row, err := fit.row(values)
+		if err != nil {
+			return nil, err
+		}
+		x[i] = row
+		key := fmt.Sprint(values)
+		points[key] = append(points[key], i)
+	}
+
+	p := len(fit.terms)
+	if n < p {
+		return nil, fmt.Errorf("model has %d terms but there are only %d runs", p, n)
+	}
+
+	b, inv, err := leastSquares(x, y)
+	if err != nil {
+		return nil, fmt.Errorf("cannot estimate every model term from these runs: %s", err)
+	}
+	fit.coefs = b
+
+	var mean float64
+	for _, v := range y {
+		mean += v
+	}
+	mean /= float64(n)
+
+	var sse, sst float64
+	for i, row := range x {
+		e := y[i] - dot(row, b)
+		sse += e * e
+		sst += (y[i] - mean) * (y[i] - mean)
+
+		v := mat.NewVecDense(len(row), row)
+		h := mat.Inner(v, inv, v)
+		if h < 1-1e-10 {
+			fit.PRESS += (e / (1 - h)) * (e / (1 - h))
+		} else {
+			fit.PRESS = math.Inf(1)
+		}
+	}
+
+	dfe := n - p
+	mse := math.NaN()
+	if dfe > 0 {
+		mse = sse / float64(dfe)
+	}
+	fit.RMSE = math.Sqrt(mse)
+
+	for i, t := range fit.terms {
+		c := Coefficient{
+			Term:     fit.termName(t),
+			Estimate: b[i],
+			StdError: math.Sqrt(mse * inv.At(i, i)),
+		}
+		c.T = c.Estimate / c.StdError
+		c.P = tPValue(c.T, dfe)
+		fit.Coefficients = append(fit.Coefficients, c)
+	}
+
+	if sst > 0 {
+		fit.RSquared = 1 - sse/sst
+		fit.AdjRSquared = math.NaN()
+		if dfe > 0 {
+			fit.AdjRSquared = 1 - (sse/float64(dfe))/(sst/float64(n-1))
+		}
+		fit.PredRSquared = 1 - fit.PRESS/sst
+	}
+
+	fit.ANOVA = append(fit.ANOVA, anovaRow("Model", p-1, sst-sse, mse, dfe))
+	for _, e := range effects[1:] {
+		reduced, err := residualSS(without(x, e.Terms), y)
+		if err != nil {
+			return nil, err
+		}
+		fit.ANOVA = append(fit.ANOVA, anovaRow(e.Name, len(e.Terms), reduced-sse, mse, dfe))
+	}
+	fit.ANOVA = append(fit.ANOVA, anovaRow("Residual", dfe, sse, math.NaN(), 0))
+
+	var sspe float64
+	dfpe := 0
+	for _, idxs := range points {
+		var m float64
+		for _, i := range idxs {
+			m += y[i]
+		}
+		m /= float64(len(idxs))
+		for _, i := range idxs {
+			sspe += (y[i] - m) * (y[i] - m)
+		}
+		dfpe += len(idxs) - 1
+	}
+	if dflof := dfe - dfpe; dfpe > 0 && dflof > 0 {
+		fit.ANOVA = append(fit.ANOVA,
+			anovaRow("Lack of fit", dflof, sse-sspe, sspe/float64(dfpe), dfpe),
+			anovaRow("Pure error", dfpe, sspe, math.NaN(), 0),
+		)
+	}
+	fit.ANOVA = append(fit.ANOVA, anovaRow("Total", n-1, sst, math.NaN(), 0))
+
+	return fit, nil
+}
+
+// LackOfFit returns the lack of fit test of the ANOVA if there are
+// replicated design points to estimate pure error
+func (a *Fit) LackOfFit() (ANOVARow, bool) {
+	for _, r := range a.ANOVA {
+		if r.Source == "Lack of fit" {
+			return r, true
+		}
+	}
+	return ANOVARow{}, false
+}
+
+// Predict returns the predicted response at setpoints in actual units
+func (a *Fit) Predict(setpoints map[string]interface{}) (float64, error) {
+	values := make([]interface{}, len(a.Factors))
+	for i, f := range a.Factors {
+		v, ok := setpoints[f.Name]
+		if !ok {
+			return 0, fmt.Errorf("missing setpoint for factor %s", f.Name)
+		}
+		values[i] = v
+	}
+	row, err := a.row(values)
+	if err != nil {
+		return 0, err
+	}
+	return dot(row, a.coefs), nil
+}
+
+// PredictRun returns the predicted response for the setpoints of a run
+func (a *Fit) PredictRun(run doe.Run) (float64, error) {
+	setpoints := make(map[string]interface{})
+	for _, f := range a.Factors {
+		v, err := run.GetFactorValue(f.Name)
+		if err != nil {
+			return 0, err
+		}
+		setpoints[f.Name] = v
+	}
+	return a.Predict(setpoints)
+}
+
+func (a *Fit) String() string {
+	var lines []string
+	units := "coded"
+	if !a.Coded {
+		units = "actual"
+	}
+	lines = append(lines, fmt.Sprintf("Response: %s (coefficients in %s units)", a.Response, units))
+	lines = append(lines, fmt.Sprintf("%-20s %12s %12s %8s %8s", "Term", "Estimate", "Std Error", "t", "p"))
+	for _, c := range a.Coefficients {
+		lines = append(lines, fmt.Sprintf("%-20s %12.6g %12.6g %8.3f %8.4f", c.Term, c.Estimate, c.StdError, c.T, c.P))
+	}
+	lines = append(lines, "")
+	lines = append(lines, fmt.Sprintf("%-20s %4s %12s %12s %8s %8s", "Source", "DF", "SS", "MS", "F", "p"))
+	for _, r := range a.ANOVA {
+		lines = append(lines, fmt.Sprintf("%-20s %4d %12.6g %12.6g %8.3f %8.4f", r.Source, r.DF, r.SS, r.MS, r.F, r.P))
+	}
+	lines = append(lines, "")
+	lines = append(lines, fmt.Sprintf("R² = %.4f, adjusted R² = %.4f, predicted R² = %.4f, PRESS = %.6g, RMSE = %.6g",
+		a.RSquared, a.AdjRSquared, a.PredRSquared, a.PRESS, a.RMSE))
+	return strings.Join(lines, "\n")
+}
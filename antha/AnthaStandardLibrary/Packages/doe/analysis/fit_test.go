@@ -0,0 +1,235 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/doe"
+)
+
+func near(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+func TestPValues(t *testing.T) {
+	if p := tPValue(2, 10); !near(p, 0.073388, 1e-5) {
+		t.Errorf("t = 2, df = 10: expecting p 0.073388 but got %v", p)
+	}
+	if p := fPValue(4, 1, 10); !near(p, 0.073388, 1e-5) {
+		t.Errorf("F(1, 10) = 4: expecting p 0.073388 but got %v", p)
+	}
+	if p := fPValue(0, 3, 12); p != 1 {
+		t.Errorf("F = 0: expecting p 1 but got %v", p)
+	}
+}
+
+// respond adds a response to each run
+func respond(t *testing.T, runs []doe.Run, name string, f func(a, b float64) float64) []doe.Run {
+	var ret []doe.Run
+	for _, run := range runs {
+		a, err := run.GetFactorValue("A")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := run.GetFactorValue("B")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret = append(ret, doe.AddNewResponseFieldandValue(run, name, f(a.(float64), b.(float64))))
+	}
+	return ret
+}
+
+func designRuns(t *testing.T) []doe.Run {
+	factors := []doe.DOEPair{
+		doe.Pair("A", []interface{}{10.0, 30.0}),
+		doe.Pair("B", []interface{}{0.0, 1.0}),
+	}
+	runs, err := doe.CentralComposite(factors, 1, doe.DesignOpt{CentrePoints: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runs
+}
+
+func TestFitQuadratic(t *testing.T) {
+	// y = 5 + 2A - 3B + AB - 4A^2 in coded units
+	coded := func(a, b float64) float64 {
+		a, b = (a-20)/10, 2*b-1
+		return 5 + 2*a - 3*b + a*b - 4*a*a
+	}
+	runs := respond(t, designRuns(t), "Y", coded)
+
+	fit, err := FitModel(FitOpt{Runs: runs, Response: "Y", Model: doe.QuadraticModel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]float64{"Intercept": 5, "A": 2, "B": -3, "A*B": 1, "A^2": -4, "B^2": 0}
+	if len(fit.Coefficients) != len(expected) {
+		t.Fatalf("expecting %d coefficients but got %v", len(expected), fit.Coefficients)
+	}
+	for _, c := range fit.Coefficients {
+		if e, ok := expected[c.Term]; !ok || !near(c.Estimate, e, 1e-8) {
+			t.Errorf("%s: expecting %v but got %v", c.Term, e, c.Estimate)
+		}
+	}
+	if !near(fit.RSquared, 1, 1e-10) {
+		t.Errorf("expecting exact fit but got R² %v", fit.RSquared)
+	}
+
+	y, err := fit.Predict(map[string]interface{}{"A": 25.0, "B": 0.25})
+	if err != nil {
+		t.Fatal(err)
+	} else if e := coded(25, 0.25); !near(y, e, 1e-8) {
+		t.Errorf("expecting prediction %v but got %v", e, y)
+	}
+
+	actual, err := FitModel(FitOpt{Runs: runs, Response: "Y", Model: doe.QuadraticModel, Actual: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y2, err := actual.Predict(map[string]interface{}{"A": 25.0, "B": 0.25}); err != nil {
+		t.Fatal(err)
+	} else if !near(y, y2, 1e-6) {
+		t.Errorf("expecting coded and actual fits to agree but got %v and %v", y, y2)
+	}
+
+	if _, err := FitModel(FitOpt{Runs: runs[:4], Response: "Y", Model: doe.QuadraticModel}); err == nil {
+		t.Errorf("expecting error for too few runs")
+	}
+}
+
+func TestFitSaturated(t *testing.T) {
+	runs := respond(t, designRuns(t)[:3], "Y", func(a, b float64) float64 {
+		return 1 + a - b
+	})
+	fit, err := FitModel(FitOpt{Runs: runs, Response: "Y", Model: doe.LinearModel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(fit.RSquared, 1, 1e-10) || !math.IsNaN(fit.AdjRSquared) || !math.IsNaN(fit.RMSE) {
+		t.Errorf("expecting exact fit without residual degrees of freedom but got R² %v, adjusted R² %v, RMSE %v", fit.RSquared, fit.AdjRSquared, fit.RMSE)
+	}
+
+	// the corner and centre points cannot separate the squared terms
+	var aliased []doe.Run
+	for _, run := range respond(t, designRuns(t), "Y", func(a, b float64) float64 { return a * b }) {
+		a, err := run.GetFactorValue("A")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := run.GetFactorValue("B")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if va, vb := a.(float64), b.(float64); (va == 20) == (vb == 0.5) {
+			aliased = append(aliased, run)
+		}
+	}
+	if _, err := FitModel(FitOpt{Runs: aliased, Response: "Y", Model: doe.QuadraticModel}); err == nil {
+		t.Errorf("expecting error for aliased terms")
+	}
+}
+
+func TestLackOfFit(t *testing.T) {
+	// Curvature that a linear model misses plus noise at the replicated
+	// centre points
+	noise := []float64{0.1, -0.1, 0}
+	i := 0
+	runs := respond(t, designRuns(t), "Y", func(a, b float64) float64 {
+		a, b = (a-20)/10, 2*b-1
+		y := 5 + 2*a + 3*a*a
+		if a == 0 && b == 0 {
+			y += noise[i%len(noise)]
+			i++
+		}
+		return y
+	})
+
+	fit, err := FitModel(FitOpt{Runs: runs, Response: "Y", Model: doe.LinearModel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lof, ok := fit.LackOfFit()
+	if !ok {
+		t.Fatalf("expecting lack of fit test\n%s", fit)
+	}
+	if lof.DF != 6 || lof.P > 0.01 {
+		t.Errorf("expecting significant lack of fit with 6 df but got %+v", lof)
+	}
+
+	var total int
+	for _, r := range fit.ANOVA {
+		if r.Source == "Total" {
+			total = r.DF
+		}
+	}
+	if total != len(runs)-1 {
+		t.Errorf("expecting %d total df but got %d", len(runs)-1, total)
+	}
+}
+
+func TestCategoricalFit(t *testing.T) {
+	var runs []doe.Run
+	effects := map[string]float64{"x": 1, "y": 2, "z": 6}
+	for _, l := range []string{"x", "y", "z"} {
+		for _, temp := range []float64{20, 40} {
+			run := doe.Run{}
+			run = doe.AddNewFactorFieldandValue(run, "Enzyme", l)
+			run = doe.AddNewFactorFieldandValue(run, "Temp", temp)
+			run = doe.AddNewResponseFieldandValue(run, "Y", effects[l]+temp/10)
+			runs = append(runs, run)
+		}
+	}
+
+	fit, err := FitModel(FitOpt{Runs: runs, Response: "Y", Model: doe.LinearModel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fit.Coefficients) != 4 {
+		t.Errorf("expecting 4 coefficients but got %v", fit.Coefficients)
+	}
+	for _, r := range fit.ANOVA {
+		if r.Source == "Enzyme" && r.DF != 2 {
+			t.Errorf("expecting 2 df for categorical factor but got %d", r.DF)
+		}
+	}
+	if y, err := fit.Predict(map[string]interface{}{"Enzyme": "z", "Temp": 30.0}); err != nil {
+		t.Fatal(err)
+	} else if !near(y, 9, 1e-8) {
+		t.Errorf("expecting 9 but got %v", y)
+	}
+}
+
+func TestOptimise(t *testing.T) {
+	// Maximum of 5 + 2A - 4A^2 - B^2 at A = 0.25, B = 0 in coded units
+	runs := respond(t, designRuns(t), "Y", func(a, b float64) float64 {
+		a, b = (a-20)/10, 2*b-1
+		return 5 + 2*a - 4*a*a - b*b
+	})
+	fit, err := FitModel(FitOpt{Runs: runs, Response: "Y", Model: doe.QuadraticModel})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := Optimise(OptimiseOpt{Goals: []Goal{{Fit: fit, Kind: Maximise, Low: 0, High: 6}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := o.Setpoints["A"].(float64); !near(a, 22.5, 0.01) {
+		t.Errorf("expecting optimum at A = 22.5 but got %v", a)
+	}
+	if b := o.Setpoints["B"].(float64); !near(b, 0.5, 0.01) {
+		t.Errorf("expecting optimum at B = 0.5 but got %v", b)
+	}
+	if !near(o.Predictions["Y"], 5.25, 1e-4) {
+		t.Errorf("expecting prediction 5.25 but got %v", o.Predictions["Y"])
+	}
+
+	target := Goal{Fit: fit, Kind: Target, Low: 0, Target: 3, High: 6}
+	if o, err := Optimise(OptimiseOpt{Goals: []Goal{target}, Seed: 1}); err != nil {
+		t.Fatal(err)
+	} else if !near(o.Predictions["Y"], 3, 1e-3) {
+		t.Errorf("expecting prediction on target but got %v", o.Predictions["Y"])
+	}
+}
@@ -0,0 +1,280 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// A GoalKind is the kind of goal for a response
+type GoalKind int
+
+// Kinds of goals
+const (
+	Maximise GoalKind = iota
+	Minimise
+	Target
+)
+
+func (a GoalKind) String() string {
+	switch a {
+	case Maximise:
+		return "maximise"
+	case Minimise:
+		return "minimise"
+	case Target:
+		return "target"
+	}
+	return fmt.Sprintf("GoalKind(%d)", int(a))
+}
+
+// A Goal is a goal for a fitted response expressed as a Derringer-Suich
+// desirability function. Responses are wholly undesirable outside [Low,
+// High] and wholly desirable at High when maximising, at Low when
+// minimising and at Target when targeting.
+type Goal struct {
+	Fit    *Fit
+	Kind   GoalKind
+	Low    float64
+	High   float64
+	Target float64
+	// Shape of the desirability function; values above 1 favour responses
+	// close to the most desirable value. Defaults to 1.
+	Weight float64
+	// Relative importance of the goal in the overall desirability. Defaults
+	// to 1.
+	Importance float64
+}
+
+func orOne(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	return v
+}
+
+// Desirability returns the desirability of a response value between 0 and 1
+func (a Goal) Desirability(y float64) float64 {
+	w := orOne(a.Weight)
+	switch a.Kind {
+	case Maximise:
+		switch {
+		case y <= a.Low:
+			return 0
+		case y >= a.High:
+			return 1
+		}
+		return math.Pow((y-a.Low)/(a.High-a.Low), w)
+	case Minimise:
+		switch {
+		case y <= a.Low:
+			return 1
+		case y >= a.High:
+			return 0
+		}
+		return math.Pow((a.High-y)/(a.High-a.Low), w)
+	case Target:
+		switch {
+		case y <= a.Low || y >= a.High:
+			return 0
+		case y <= a.Target:
+			return math.Pow((y-a.Low)/(a.Target-a.Low), w)
+		}
+		return math.Pow((a.High-y)/(a.High-a.Target), w)
+	}
+	return 0
+}
+
+func (a Goal) check() error {
+	if a.Fit == nil {
+		return fmt.Errorf("goal has no fitted model")
+	}
+	if a.High <= a.Low {
+		return fmt.Errorf("goal for %s: high %v must be above low %v", a.Fit.Response, a.High, a.Low)
+	}
+	if a.Kind == Target && (a.Target <= a.Low || a.Target >= a.High) {
+		return fmt.Errorf("goal for %s: target %v must be between low %v and high %v", a.Fit.Response, a.Target, a.Low, a.High)
+	}
+	return nil
+}
+
+// OptimiseOpt are options to Optimise
+type OptimiseOpt struct {
+	Goals []Goal
+	// Number of random starting points of the search for each combination
+	// of categorical levels. Defaults to 20.
+	Starts int
+	Seed   int64
+}
+
+// An Optimum is the result of Optimise
+type Optimum struct {
+	Setpoints      map[string]interface{}
+	Predictions    map[string]float64 // Predicted value by response
+	Desirabilities map[string]float64 // Desirability by response
+	Desirability   float64            // Overall desirability
+}
+
+// Minimum step of the pattern search in coded units
+const minStep = 1e-4
+
+// Optimise finds the setpoints within the ranges of the fitted factors that
+// maximise the overall desirability of goals: the weighted geometric mean
+// of the desirability of each goal. Numeric factors are searched by pattern
+// search from random starting points and every combination of categorical
+// levels is tried.
+func Optimise(opt OptimiseOpt) (*Optimum, error) {
+	if len(opt.Goals) == 0 {
+		return nil, fmt.Errorf("no goals")
+	}
+
+	var factors []Factor
+	seen := make(map[string]bool)
+	for _, g := range opt.Goals {
+		if err := g.check(); err != nil {
+			return nil, err
+		}
+		for _, f := range g.Fit.Factors {
+			if !seen[f.Name] {
+				seen[f.Name] = true
+				factors = append(factors, f)
+			}
+		}
+	}
+	sort.Slice(factors, func(i, j int) bool {
+		return factors[i].Name < factors[j].Name
+	})
+
+	var numeric, categorical []Factor
+	for _, f := range factors {
+		if f.Numeric {
+			numeric = append(numeric, f)
+		} else {
+			categorical = append(categorical, f)
+		}
+	}
+
+	var best *Optimum
+	evaluate := func(levels []string, coded []float64) (*Optimum, error) {
+		o := &Optimum{
+			Setpoints:      make(map[string]interface{}),
+			Predictions:    make(map[string]float64),
+			Desirabilities: make(map[string]float64),
+		}
+		for i, f := range categorical {
+			o.Setpoints[f.Name] = levels[i]
+		}
+		for i, f := range numeric {
+			o.Setpoints[f.Name] = f.Low + (coded[i]+1)/2*(f.High-f.Low)
+		}
+
+		var logD, total float64
+		for _, g := range opt.Goals {
+			y, err := g.Fit.Predict(o.Setpoints)
+			if err != nil {
+				return nil, err
+			}
+			d := g.Desirability(y)
+			r := orOne(g.Importance)
+			o.Predictions[g.Fit.Response] = y
+			o.Desirabilities[g.Fit.Response] = d
+			logD += r * math.Log(d)
+			total += r
+		}
+		o.Desirability = math.Exp(logD / total)
+		return o, nil
+	}
+
+	starts := opt.Starts
+	if starts <= 0 {
+		starts = 20
+	}
+	r := rand.New(rand.NewSource(opt.Seed))
+
+	levels := make([]string, len(categorical))
+	var search func(int) error
+	search = func(c int) error {
+		if c < len(categorical) {
+			for _, l := range categorical[c].Levels {
+				levels[c] = l
+				if err := search(c + 1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		for s := 0; s < starts; s++ {
+			x := make([]float64, len(numeric))
+			for i := range x {
+				if s > 0 {
+					x[i] = 2*r.Float64() - 1
+				}
+			}
+			cur, err := evaluate(levels, x)
+			if err != nil {
+				return err
+			}
+			for step := 0.5; step >= minStep && len(x) > 0; {
+				improved := false
+				for i := range x {
+					for _, dir := range []float64{-1, 1} {
+						old := x[i]
+						x[i] = math.Max(-1, math.Min(1, old+dir*step))
+						if x[i] == old {
+							continue
+						}
+						o, err := evaluate(levels, x)
+						if err != nil {
+							return err
+						}
+						if o.Desirability > cur.Desirability {
+							cur = o
+							improved = true
+						} else {
+							x[i] = old
+						}
+					}
+				}
+				if !improved {
+					step /= 2
+				}
+			}
+			if best == nil || cur.Desirability > best.Desirability {
+				best = cur
+			}
+			if len(x) == 0 {
+				break
+			}
+		}
+		return nil
+	}
+
+	if err := search(0); err != nil {
+		return nil, err
+	}
+	return best, nil
+}
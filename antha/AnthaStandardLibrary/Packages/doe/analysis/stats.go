@@ -0,0 +1,94 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package analysis
+
+import (
+	"errors"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+var errSingular = errors.New("singular matrix")
+
+// maxCond is the largest condition number of X'X for which least squares
+// estimates are computed; beyond it terms of the model are aliased
+const maxCond = 1e12
+
+// tPValue returns the two-sided p-value of a t statistic
+func tPValue(t float64, df int) float64 {
+	if df <= 0 || math.IsNaN(t) {
+		return math.NaN()
+	}
+	dist := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: float64(df)}
+	return 2 * dist.Survival(math.Abs(t))
+}
+
+// fPValue returns the upper tail probability of an F statistic
+func fPValue(f float64, df1, df2 int) float64 {
+	if df1 <= 0 || df2 <= 0 || math.IsNaN(f) {
+		return math.NaN()
+	}
+	if f <= 0 {
+		return 1
+	}
+	dist := distuv.F{D1: float64(df1), D2: float64(df2)}
+	return dist.Survival(f)
+}
+
+// leastSquares fits y = X b by solving the normal equations and returns b
+// and (X'X)^-1
+func leastSquares(x [][]float64, y []float64) ([]float64, *mat.SymDense, error) {
+	n, p := len(x), len(x[0])
+	xm := mat.NewDense(n, p, nil)
+	for i, row := range x {
+		xm.SetRow(i, row)
+	}
+	xtx := mat.NewSymDense(p, nil)
+	xtx.SymOuterK(1, xm.T())
+
+	var chol mat.Cholesky
+	if ok := chol.Factorize(xtx); !ok || chol.Cond() > maxCond {
+		return nil, nil, errSingular
+	}
+
+	var xty, b mat.VecDense
+	xty.MulVec(xm.T(), mat.NewVecDense(n, y))
+	if err := chol.SolveVecTo(&b, &xty); err != nil {
+		return nil, nil, err
+	}
+	var inv mat.SymDense
+	if err := chol.InverseTo(&inv); err != nil {
+		return nil, nil, err
+	}
+	return mat.Col(nil, 0, &b), &inv, nil
+}
+
+func dot(a, b []float64) float64 {
+	var s float64
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
@@ -0,0 +1,239 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package doe
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// BlockHeader is the additional header and subheader under which the block
+// of each generated run is recorded
+const BlockHeader = "Block"
+
+// DesignOpt are options common to the design generators
+type DesignOpt struct {
+	// Number of blocks. Zero or one means the design is not blocked.
+	Blocks int
+	// Number of centre points added to each block. Centre points require
+	// numeric factors.
+	CentrePoints int
+	// Number of copies of the design. Zero means one.
+	Replicates int
+	// Randomise the order of runs within each block of each replicate
+	Randomise bool
+	// Seed for randomising run order and for randomised designs
+	Seed int64
+}
+
+func (opt DesignOpt) numBlocks() int {
+	if opt.Blocks < 1 {
+		return 1
+	}
+	return opt.Blocks
+}
+
+func (opt DesignOpt) rand() *rand.Rand {
+	return rand.New(rand.NewSource(opt.Seed))
+}
+
+// A design is a set of factor settings in standard order
+type design struct {
+	Factors []DOEPair
+	Rows    [][]interface{} // Setpoint of each factor for each run
+	Blocks  []int           // Zero-based block of each run or nil if unblocked
+}
+
+// toFloat returns the value of numeric levels
+func toFloat(level interface{}) (float64, bool) {
+	switch l := level.(type) {
+	case float64:
+		return l, true
+	case float32:
+		return float64(l), true
+	case int:
+		return float64(l), true
+	case int64:
+		return float64(l), true
+	}
+	return 0, false
+}
+
+// numericRange returns the lowest and highest levels of a numeric factor
+func numericRange(pair DOEPair) (lo, hi float64, err error) {
+	if len(pair.Levels) == 0 {
+		return 0, 0, fmt.Errorf("factor %s has no levels", pair.Factor)
+	}
+	for i, level := range pair.Levels {
+		f, ok := toFloat(level)
+		if !ok {
+			return 0, 0, fmt.Errorf("factor %s is not numeric: level %v", pair.Factor, level)
+		}
+		if i == 0 || f < lo {
+			lo = f
+		}
+		if i == 0 || f > hi {
+			hi = f
+		}
+	}
+	return lo, hi, nil
+}
+
+// decode returns the setpoint of a factor for a coded value, where -1 and +1
+// are the lowest and highest levels. The extremes of non-numeric factors are
+// their first and last levels; other coded values require numeric factors.
+func decode(pair DOEPair, coded float64) (interface{}, error) {
+	if len(pair.Levels) == 0 {
+		return nil, fmt.Errorf("factor %s has no levels", pair.Factor)
+	}
+	lo, hi, err := numericRange(pair)
+	if err != nil {
+		switch coded {
+		case -1:
+			return pair.Levels[0], nil
+		case 1:
+			return pair.Levels[len(pair.Levels)-1], nil
+		}
+		return nil, err
+	}
+	return round((lo+hi)/2 + coded*(hi-lo)/2)
+}
+
+// decodeRows converts coded rows into setpoints
+func decodeRows(factors []DOEPair, coded [][]float64) ([][]interface{}, error) {
+	rows := make([][]interface{}, len(coded))
+	for i, c := range coded {
+		rows[i] = make([]interface{}, len(factors))
+		for j, pair := range factors {
+			v, err := decode(pair, c[j])
+			if err != nil {
+				return nil, err
+			}
+			rows[i][j] = v
+		}
+	}
+	return rows, nil
+}
+
+// roundRobinBlocks assigns runs in standard order to blocks in turn
+func roundRobinBlocks(numRuns, numBlocks int) []int {
+	if numBlocks <= 1 {
+		return nil
+	}
+	blocks := make([]int, numRuns)
+	for i := range blocks {
+		blocks[i] = i % numBlocks
+	}
+	return blocks
+}
+
+// runs adds centre points, replicates and randomises a design according to
+// opt and returns the resulting runs
+func (a *design) runs(opt DesignOpt) ([]Run, error) {
+	numBlocks := opt.numBlocks()
+	if a.Blocks == nil && numBlocks > 1 {
+		a.Blocks = roundRobinBlocks(len(a.Rows), numBlocks)
+	}
+	blockOf := func(i int) int {
+		if a.Blocks == nil {
+			return 0
+		}
+		return a.Blocks[i]
+	}
+
+	type point struct {
+		Setpoints []interface{}
+		Block     int
+	}
+
+	var points []point
+	for i, row := range a.Rows {
+		points = append(points, point{Setpoints: row, Block: blockOf(i)})
+	}
+
+	if opt.CentrePoints > 0 {
+		coded := make([]float64, len(a.Factors))
+		centre, err := decodeRows(a.Factors, [][]float64{coded})
+		if err != nil {
+			return nil, fmt.Errorf("cannot add centre points: %s", err)
+		}
+		for b := 0; b < numBlocks; b++ {
+			for i := 0; i < opt.CentrePoints; i++ {
+				points = append(points, point{Setpoints: centre[0], Block: b})
+			}
+		}
+	}
+
+	// Standard order is by block
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Block < points[j].Block
+	})
+
+	replicates := opt.Replicates
+	if replicates < 1 {
+		replicates = 1
+	}
+
+	r := opt.rand()
+	var runs []Run
+	for rep := 0; rep < replicates; rep++ {
+		for start := 0; start < len(points); {
+			end := start
+			for end < len(points) && points[end].Block == points[start].Block {
+				end++
+			}
+
+			order := make([]int, end-start)
+			for i := range order {
+				order[i] = start + i
+			}
+			if opt.Randomise {
+				r.Shuffle(len(order), func(i, j int) {
+					order[i], order[j] = order[j], order[i]
+				})
+			}
+
+			for _, idx := range order {
+				p := points[idx]
+				run := Run{
+					RunNumber:         len(runs) + 1,
+					StdNumber:         rep*len(points) + idx + 1,
+					Factordescriptors: make([]string, len(a.Factors)),
+					Setpoints:         append([]interface{}(nil), p.Setpoints...),
+				}
+				for i, pair := range a.Factors {
+					run.Factordescriptors[i] = pair.Factor
+				}
+				if numBlocks > 1 {
+					run = AddAdditionalHeaderandValue(run, BlockHeader, BlockHeader, p.Block+1)
+				}
+				runs = append(runs, run)
+			}
+
+			start = end
+		}
+	}
+
+	return runs, nil
+}
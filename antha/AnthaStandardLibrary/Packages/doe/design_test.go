@@ -0,0 +1,312 @@
+package doe
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func twoLevelFactors(k int) []DOEPair {
+	var factors []DOEPair
+	for i := 0; i < k; i++ {
+		factors = append(factors, Pair(string('A'+rune(i)), []interface{}{0.0, 10.0}))
+	}
+	return factors
+}
+
+// codedRuns returns the setpoints of runs coded to -1, 0 and +1
+func codedRuns(t *testing.T, runs []Run) [][]float64 {
+	var rows [][]float64
+	for _, run := range runs {
+		var row []float64
+		for _, v := range run.Setpoints {
+			f, ok := toFloat(v)
+			if !ok {
+				t.Fatalf("non-numeric setpoint %v", v)
+			}
+			row = append(row, (f-5)/5)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func blockOf(t *testing.T, run Run) int {
+	b, err := run.GetAdditionalInfo(BlockHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.(int)
+}
+
+func TestFullFactorialBlocks(t *testing.T) {
+	opt := DesignOpt{
+		Blocks:       2,
+		CentrePoints: 1,
+		Replicates:   2,
+		Randomise:    true,
+		Seed:         1,
+	}
+	runs, err := FullFactorial(twoLevelFactors(3), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 20 {
+		t.Fatalf("expecting 20 runs but got %d", len(runs))
+	}
+
+	// Blocks confound the three-factor interaction
+	signs := make(map[int]float64)
+	for i, row := range codedRuns(t, runs) {
+		if runs[i].RunNumber != i+1 {
+			t.Errorf("run %d has run number %d", i+1, runs[i].RunNumber)
+		}
+		abc := row[0] * row[1] * row[2]
+		if abc == 0 {
+			continue
+		}
+		b := blockOf(t, runs[i])
+		if s, seen := signs[b]; seen && s != abc {
+			t.Errorf("block %d contains runs with ABC = %v and %v", b, s, abc)
+		}
+		signs[b] = abc
+	}
+	if len(signs) != 2 {
+		t.Errorf("expecting 2 blocks but found %v", signs)
+	}
+
+	again, err := FullFactorial(twoLevelFactors(3), opt)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(runs, again) {
+		t.Errorf("expecting the same seed to give the same design")
+	}
+}
+
+func TestFullFactorialMixedLevels(t *testing.T) {
+	factors := []DOEPair{
+		Pair("Enzyme", []interface{}{"a", "b", "c"}),
+		Pair("Temp", []interface{}{25.0, 37.0}),
+	}
+	runs, err := FullFactorial(factors, DesignOpt{})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(runs) != 6 {
+		t.Errorf("expecting 6 runs but got %d", len(runs))
+	}
+
+	if _, err := FullFactorial(factors, DesignOpt{CentrePoints: 1}); err == nil {
+		t.Errorf("expecting error adding centre points to categorical factor")
+	}
+}
+
+func TestFractionalFactorial(t *testing.T) {
+	type testCase struct {
+		K, Fraction int
+		Resolution  int
+		Defining    []string
+	}
+	for _, tc := range []testCase{
+		{K: 5, Fraction: 1, Resolution: 5, Defining: []string{"ABCDE"}},
+		{K: 6, Fraction: 2, Resolution: 4},
+		{K: 7, Fraction: 4, Resolution: 3},
+	} {
+		runs, report, err := FractionalFactorial(twoLevelFactors(tc.K), tc.Fraction, DesignOpt{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := 1 << uint(tc.K-tc.Fraction); len(runs) != e {
+			t.Errorf("2^(%d-%d): expecting %d runs but got %d", tc.K, tc.Fraction, e, len(runs))
+		}
+		if report.Resolution != tc.Resolution {
+			t.Errorf("2^(%d-%d): expecting resolution %d but got %d\n%s", tc.K, tc.Fraction, tc.Resolution, report.Resolution, report)
+		}
+		if tc.Defining != nil && !reflect.DeepEqual(report.DefiningRelation, tc.Defining) {
+			t.Errorf("2^(%d-%d): expecting defining relation %v but got %v", tc.K, tc.Fraction, tc.Defining, report.DefiningRelation)
+		}
+
+		// Main effects are balanced and orthogonal
+		rows := codedRuns(t, runs)
+		for i := 0; i < tc.K; i++ {
+			for j := i; j < tc.K; j++ {
+				var sum float64
+				for _, row := range rows {
+					if i == j {
+						sum += row[i]
+					} else {
+						sum += row[i] * row[j]
+					}
+				}
+				if sum != 0 {
+					t.Errorf("2^(%d-%d): columns %d and %d not orthogonal", tc.K, tc.Fraction, i, j)
+				}
+			}
+		}
+	}
+
+	if _, _, err := FractionalFactorial(twoLevelFactors(3), 3, DesignOpt{}); err == nil {
+		t.Errorf("expecting error for too small a fraction")
+	}
+}
+
+func TestPlackettBurman(t *testing.T) {
+	for k, n := range map[int]int{3: 4, 7: 8, 11: 12, 15: 16, 19: 20, 23: 24, 27: 32} {
+		factors := make([]DOEPair, k)
+		for i := range factors {
+			factors[i] = Pair(string(rune('A'+i)), []interface{}{0.0, 10.0})
+		}
+		runs, err := PlackettBurman(factors, DesignOpt{})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(runs) != n {
+			t.Errorf("%d factors: expecting %d runs but got %d", k, n, len(runs))
+			continue
+		}
+
+		rows := codedRuns(t, runs)
+		for i := 0; i < k; i++ {
+			for j := i; j < k; j++ {
+				var sum float64
+				for _, row := range rows {
+					if i == j {
+						sum += row[i]
+					} else {
+						sum += row[i] * row[j]
+					}
+				}
+				if sum != 0 {
+					t.Errorf("%d factors: columns %d and %d not orthogonal", k, i, j)
+				}
+			}
+		}
+	}
+}
+
+func TestCentralComposite(t *testing.T) {
+	runs, err := CentralComposite(twoLevelFactors(2), 0, DesignOpt{CentrePoints: 3})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(runs) != 11 {
+		t.Fatalf("expecting 11 runs but got %d", len(runs))
+	}
+
+	axial := 0
+	for _, row := range codedRuns(t, runs) {
+		if math.Abs(math.Abs(row[0]+row[1])-math.Sqrt2) < 1e-5 && row[0]*row[1] == 0 {
+			axial++
+		}
+	}
+	if axial != 4 {
+		t.Errorf("expecting 4 axial runs at rotatable distance but got %d", axial)
+	}
+
+	if _, err := CentralComposite([]DOEPair{Pair("A", []interface{}{"x", "y"}), Pair("B", []interface{}{1.0, 2.0})}, 0, DesignOpt{}); err == nil {
+		t.Errorf("expecting error for categorical factor")
+	}
+}
+
+func TestBoxBehnken(t *testing.T) {
+	runs, err := BoxBehnken(twoLevelFactors(3), DesignOpt{CentrePoints: 3})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(runs) != 15 {
+		t.Fatalf("expecting 15 runs but got %d", len(runs))
+	}
+	for _, row := range codedRuns(t, runs) {
+		zeros := 0
+		for _, x := range row {
+			if x == 0 {
+				zeros++
+			}
+		}
+		if zeros != 1 && zeros != 3 {
+			t.Errorf("unexpected run %v", row)
+		}
+	}
+}
+
+func TestLatinHypercube(t *testing.T) {
+	for _, gen := range []func([]DOEPair, int, DesignOpt) ([]Run, error){LatinHypercube, SpaceFilling} {
+		n := 10
+		runs, err := gen(twoLevelFactors(3), n, DesignOpt{Seed: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		strata := make([]map[int]bool, 3)
+		for i := range strata {
+			strata[i] = make(map[int]bool)
+		}
+		for _, run := range runs {
+			for i, v := range run.Setpoints {
+				f, _ := toFloat(v)
+				strata[i][int(f/10*float64(n))] = true
+			}
+		}
+		for i, s := range strata {
+			if len(s) != n {
+				t.Errorf("factor %d: expecting a value in each of %d intervals but got %v", i, n, s)
+			}
+		}
+	}
+}
+
+func TestDOptimal(t *testing.T) {
+	candidates, err := FullFactorial(twoLevelFactors(3), DesignOpt{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The best four runs for a linear model are a half fraction
+	runs, err := DOptimal(candidates, LinearModel, 4, DesignOpt{Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := codedRuns(t, runs)
+	var abc []float64
+	for _, row := range rows {
+		abc = append(abc, row[0]*row[1]*row[2])
+	}
+	for _, s := range abc[1:] {
+		if s != abc[0] {
+			t.Errorf("expecting half fraction but got %v", rows)
+			break
+		}
+	}
+
+	three := []DOEPair{
+		Pair("A", []interface{}{0.0, 5.0, 10.0}),
+		Pair("B", []interface{}{0.0, 5.0, 10.0}),
+	}
+	candidates, err = FullFactorial(three, DesignOpt{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs, err := DOptimal(candidates, QuadraticModel, 6, DesignOpt{}); err != nil {
+		t.Error(err)
+	} else if len(runs) != 6 {
+		t.Errorf("expecting 6 runs but got %d", len(runs))
+	}
+	if _, err := DOptimal(candidates, QuadraticModel, 5, DesignOpt{}); err == nil {
+		t.Errorf("expecting error for too few runs")
+	}
+}
+
+func TestDOptimalSeeds(t *testing.T) {
+	var factors []DOEPair
+	for _, name := range []string{"A", "B", "C", "D"} {
+		factors = append(factors, Pair(name, []interface{}{0.0, 5.0, 10.0}))
+	}
+	candidates, err := FullFactorial(factors, DesignOpt{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every start must be nonsingular, whatever the seed
+	for seed := int64(0); seed < 30; seed++ {
+		if runs, err := DOptimal(candidates, QuadraticModel, 15, DesignOpt{Seed: seed}); err != nil {
+			t.Errorf("seed %d: %s", seed, err)
+		} else if len(runs) != 15 {
+			t.Errorf("seed %d: expecting 15 runs but got %d", seed, len(runs))
+		}
+	}
+}
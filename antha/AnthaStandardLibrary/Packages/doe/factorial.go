@@ -0,0 +1,574 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package doe
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// factorLetters name factors in alias reports. I is reserved for the
+// identity.
+const factorLetters = "ABCDEFGHJKLMNOPQRSTUVWXYZ"
+
+// maxWordSearch is the largest number of combinations of words searched
+// exhaustively when choosing generators; larger searches are greedy
+const maxWordSearch = 20000
+
+// A word is a product of factors, e.g., ABD, with bit i set if the ith
+// factor is present
+type word uint32
+
+func (w word) Len() int {
+	return bits.OnesCount32(uint32(w))
+}
+
+func (w word) String() string {
+	if w == 0 {
+		return "I"
+	}
+	var s []byte
+	for i := 0; i < len(factorLetters); i++ {
+		if w&(1<<uint(i)) != 0 {
+			s = append(s, factorLetters[i])
+		}
+	}
+	return string(s)
+}
+
+// wordLess orders words by length and then alphabetically
+func wordLess(a, b word) bool {
+	if a.Len() != b.Len() {
+		return a.Len() < b.Len()
+	}
+	return a.String() < b.String()
+}
+
+// products returns every non-empty product of words
+func products(ws []word) []word {
+	var ret []word
+	for s := 1; s < 1<<uint(len(ws)); s++ {
+		var p word
+		for i, w := range ws {
+			if s&(1<<uint(i)) != 0 {
+				p ^= w
+			}
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+// A wordScore measures the quality of a set of words. Designs with longer
+// shortest words and then fewer short words are better.
+type wordScore struct {
+	MinLen  int
+	Pattern []int // Number of words of each length
+}
+
+func (a wordScore) better(b wordScore) bool {
+	if a.MinLen != b.MinLen {
+		return a.MinLen > b.MinLen
+	}
+	for i := range a.Pattern {
+		if i >= len(b.Pattern) {
+			return false
+		}
+		if a.Pattern[i] != b.Pattern[i] {
+			return a.Pattern[i] < b.Pattern[i]
+		}
+	}
+	return false
+}
+
+func scoreLengths(lengths []int, maxLen int) wordScore {
+	s := wordScore{MinLen: maxLen + 1, Pattern: make([]int, maxLen+1)}
+	for _, l := range lengths {
+		if l < s.MinLen {
+			s.MinLen = l
+		}
+		s.Pattern[l]++
+	}
+	return s
+}
+
+// chooseWords chooses count distinct candidates with the best score.
+// Combinations are searched exhaustively when there are few enough of them
+// and otherwise greedily.
+func chooseWords(candidates []word, count int, score func([]word) wordScore) ([]word, bool) {
+	if count == 0 {
+		return nil, true
+	}
+	if count > len(candidates) {
+		return nil, false
+	}
+
+	combos := 1.0
+	for i := 0; i < count; i++ {
+		combos *= float64(len(candidates)-i) / float64(i+1)
+	}
+
+	var best []word
+	var bestScore wordScore
+	consider := func(ws []word) {
+		s := score(ws)
+		if best == nil || s.better(bestScore) {
+			best = append([]word(nil), ws...)
+			bestScore = s
+		}
+	}
+
+	if combos <= maxWordSearch {
+		var rec func(start int, chosen []word)
+		rec = func(start int, chosen []word) {
+			if len(chosen) == count {
+				consider(chosen)
+				return
+			}
+			for i := start; i < len(candidates); i++ {
+				rec(i+1, append(chosen, candidates[i]))
+			}
+		}
+		rec(0, nil)
+		return best, true
+	}
+
+	var chosen []word
+	used := make(map[word]bool)
+	for len(chosen) < count {
+		best = nil
+		for _, c := range candidates {
+			if !used[c] {
+				consider(append(chosen, c))
+			}
+		}
+		chosen = best
+		used[chosen[len(chosen)-1]] = true
+	}
+	return chosen, true
+}
+
+// An AliasReport describes the confounding in a two-level factorial design.
+// Factors are named by letter in the order given: A, B, C, ... skipping I.
+type AliasReport struct {
+	Factors          []string // Factor named by each letter
+	Generators       []string // Columns of added factors, e.g., "E = ABD"
+	DefiningRelation []string // Words equal to the identity, e.g., "ABDE"
+	// Length of the shortest word in the defining relation or 0 if the
+	// design is a full factorial
+	Resolution      int
+	Aliases         []string // Main effects and two-factor interactions with their aliases up to three-factor interactions
+	BlockGenerators []string // Effects confounded with blocks
+}
+
+func (a *AliasReport) String() string {
+	var lines []string
+	for i, f := range a.Factors {
+		lines = append(lines, fmt.Sprintf("%c: %s", factorLetters[i], f))
+	}
+	if a.Resolution > 0 {
+		lines = append(lines, fmt.Sprintf("Resolution: %d", a.Resolution))
+	} else {
+		lines = append(lines, "Resolution: full")
+	}
+	if len(a.Generators) != 0 {
+		lines = append(lines, "Generators: "+strings.Join(a.Generators, ", "))
+	}
+	if len(a.DefiningRelation) != 0 {
+		lines = append(lines, "Defining relation: I = "+strings.Join(a.DefiningRelation, " = "))
+	}
+	if len(a.BlockGenerators) != 0 {
+		lines = append(lines, "Block generators: "+strings.Join(a.BlockGenerators, ", "))
+	}
+	if len(a.Aliases) != 0 {
+		lines = append(lines, "Aliases:")
+		for _, al := range a.Aliases {
+			lines = append(lines, "  "+al)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// twoLevel is a two-level factorial design. Basic factors vary as a full
+// factorial; the column of each factor is a product of basic factors.
+type twoLevel struct {
+	K        int    // Number of factors
+	Basic    int    // Number of basic factors
+	Columns  []word // Column of each factor in terms of basic factors
+	Defining []word // Words of the defining relation in terms of factors
+}
+
+func newTwoLevel(k, fraction int) (*twoLevel, error) {
+	if k > len(factorLetters) {
+		return nil, fmt.Errorf("at most %d factors supported but got %d", len(factorLetters), k)
+	}
+	basic := k - fraction
+	if fraction < 0 || basic < 1 {
+		return nil, fmt.Errorf("invalid fraction 1/2^%d of %d factors", fraction, k)
+	}
+
+	d := &twoLevel{K: k, Basic: basic}
+	for i := 0; i < basic; i++ {
+		d.Columns = append(d.Columns, 1<<uint(i))
+	}
+
+	// Generators are interactions of at least two basic factors
+	var candidates []word
+	for w := word(1); w < 1<<uint(basic); w++ {
+		if w.Len() >= 2 {
+			candidates = append(candidates, w)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		// Prefer the longest interactions
+		return wordLess(candidates[j], candidates[i])
+	})
+
+	definingWords := func(gens []word) []word {
+		var ws []word
+		for i, g := range gens {
+			ws = append(ws, g|1<<uint(basic+i))
+		}
+		return ws
+	}
+
+	gens, ok := chooseWords(candidates, fraction, func(gens []word) wordScore {
+		var lengths []int
+		for _, w := range products(definingWords(gens)) {
+			lengths = append(lengths, w.Len())
+		}
+		return scoreLengths(lengths, k)
+	})
+	if !ok {
+		return nil, fmt.Errorf("cannot generate a 1/2^%d fraction of %d factors: too few runs", fraction, k)
+	}
+
+	d.Columns = append(d.Columns, gens...)
+	d.Defining = products(definingWords(gens))
+	sort.Slice(d.Defining, func(i, j int) bool {
+		return wordLess(d.Defining[i], d.Defining[j])
+	})
+	return d, nil
+}
+
+// aliases returns the effects confounded with an effect
+func (a *twoLevel) aliases(effect word) []word {
+	var ret []word
+	for _, w := range a.Defining {
+		ret = append(ret, effect^w)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return wordLess(ret[i], ret[j])
+	})
+	return ret
+}
+
+// chooseBlocks returns words of basic factors whose signs define blocks
+// while confounding the shortest effects possible
+func (a *twoLevel) chooseBlocks(numBlocks int) ([]word, error) {
+	if numBlocks <= 1 {
+		return nil, nil
+	}
+	count := bits.TrailingZeros(uint(numBlocks))
+	if numBlocks != 1<<uint(count) {
+		return nil, fmt.Errorf("number of blocks of a two-level design must be a power of two but got %d", numBlocks)
+	}
+
+	// Basic factors are the first factors so words of basic factors are
+	// also effects
+	var candidates []word
+	for w := word(1); w < 1<<uint(a.Basic); w++ {
+		candidates = append(candidates, w)
+	}
+
+	minLen := func(effect word) int {
+		l := effect.Len()
+		for _, al := range a.aliases(effect) {
+			if al.Len() < l {
+				l = al.Len()
+			}
+		}
+		return l
+	}
+
+	ws, ok := chooseWords(candidates, count, func(ws []word) wordScore {
+		var lengths []int
+		for _, w := range products(ws) {
+			lengths = append(lengths, minLen(w))
+		}
+		return scoreLengths(lengths, a.K)
+	})
+	if !ok {
+		return nil, fmt.Errorf("cannot divide %d runs into %d blocks", 1<<uint(a.Basic), numBlocks)
+	}
+	for _, w := range products(ws) {
+		if minLen(w) <= 1 {
+			return nil, fmt.Errorf("cannot divide %d runs into %d blocks without confounding main effects", 1<<uint(a.Basic), numBlocks)
+		}
+	}
+	return ws, nil
+}
+
+// coded returns the design in standard order with -1 and +1 for the low and
+// high levels of each factor and the block of each run
+func (a *twoLevel) coded(blockWords []word) ([][]float64, []int) {
+	n := 1 << uint(a.Basic)
+	rows := make([][]float64, n)
+	var blocks []int
+	if len(blockWords) != 0 {
+		blocks = make([]int, n)
+	}
+
+	// sign of a word of basic factors in run r. The first factor alternates
+	// fastest.
+	sign := func(r int, w word) float64 {
+		s := 1.0
+		for i := 0; i < a.Basic; i++ {
+			if w&(1<<uint(i)) != 0 && r&(1<<uint(i)) == 0 {
+				s = -s
+			}
+		}
+		return s
+	}
+
+	for r := 0; r < n; r++ {
+		rows[r] = make([]float64, a.K)
+		for j, col := range a.Columns {
+			rows[r][j] = sign(r, col)
+		}
+		for b, w := range blockWords {
+			if sign(r, a.basicOf(w)) < 0 {
+				blocks[r] |= 1 << uint(b)
+			}
+		}
+	}
+	return rows, blocks
+}
+
+// basicOf expresses an effect in terms of basic factors
+func (a *twoLevel) basicOf(effect word) word {
+	var w word
+	for i, col := range a.Columns {
+		if effect&(1<<uint(i)) != 0 {
+			w ^= col
+		}
+	}
+	return w
+}
+
+func (a *twoLevel) report(factors []DOEPair, blockWords []word) *AliasReport {
+	r := &AliasReport{}
+	for _, f := range factors {
+		r.Factors = append(r.Factors, f.Factor)
+	}
+	for i := a.Basic; i < a.K; i++ {
+		r.Generators = append(r.Generators, fmt.Sprintf("%c = %s", factorLetters[i], a.Columns[i]))
+	}
+	for _, w := range a.Defining {
+		r.DefiningRelation = append(r.DefiningRelation, w.String())
+	}
+	if len(a.Defining) != 0 {
+		r.Resolution = a.Defining[0].Len()
+	}
+	for _, w := range blockWords {
+		r.BlockGenerators = append(r.BlockGenerators, w.String())
+	}
+
+	if len(a.Defining) == 0 {
+		return r
+	}
+
+	seen := make(map[word]bool)
+	var effects []word
+	for i := 0; i < a.K; i++ {
+		effects = append(effects, 1<<uint(i))
+	}
+	for i := 0; i < a.K; i++ {
+		for j := i + 1; j < a.K; j++ {
+			effects = append(effects, 1<<uint(i)|1<<uint(j))
+		}
+	}
+	for _, e := range effects {
+		if seen[e] {
+			continue
+		}
+		chain := []string{e.String()}
+		for _, al := range a.aliases(e) {
+			seen[al] = true
+			if al.Len() <= 3 {
+				chain = append(chain, al.String())
+			}
+		}
+		r.Aliases = append(r.Aliases, strings.Join(chain, " = "))
+	}
+
+	return r
+}
+
+// twoLevelRuns generates a blocked two-level factorial design
+func twoLevelRuns(factors []DOEPair, fraction int, opt DesignOpt) ([]Run, *AliasReport, error) {
+	d, err := newTwoLevel(len(factors), fraction)
+	if err != nil {
+		return nil, nil, err
+	}
+	blockWords, err := d.chooseBlocks(opt.numBlocks())
+	if err != nil {
+		return nil, nil, err
+	}
+	coded, blocks := d.coded(blockWords)
+	rows, err := decodeRows(factors, coded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	des := &design{Factors: factors, Rows: rows, Blocks: blocks}
+	runs, err := des.runs(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return runs, d.report(factors, blockWords), nil
+}
+
+// FullFactorial generates every combination of factor levels. If every
+// factor has two levels, blocks confound the highest order interactions
+// possible and the number of blocks must be a power of two; otherwise runs
+// are assigned to blocks in turn.
+func FullFactorial(factors []DOEPair, opt DesignOpt) ([]Run, error) {
+	if len(factors) == 0 {
+		return nil, fmt.Errorf("no factors")
+	}
+
+	twoLevels := len(factors) <= len(factorLetters)
+	for _, f := range factors {
+		if f.LevelCount() == 0 {
+			return nil, fmt.Errorf("factor %s has no levels", f.Factor)
+		}
+		twoLevels = twoLevels && f.LevelCount() == 2
+	}
+
+	if twoLevels {
+		runs, _, err := twoLevelRuns(factors, 0, opt)
+		return runs, err
+	}
+
+	var rows [][]interface{}
+	for _, run := range AllCombinations(factors) {
+		rows = append(rows, run.Setpoints)
+	}
+	des := &design{Factors: factors, Rows: rows}
+	return des.runs(opt)
+}
+
+// FractionalFactorial generates a 1/2^fraction fraction of a two-level full
+// factorial design of the lowest and highest levels of each factor.
+// Generators for the added factors are chosen to maximise resolution with
+// minimum aberration. The report describes the resulting confounding.
+func FractionalFactorial(factors []DOEPair, fraction int, opt DesignOpt) ([]Run, *AliasReport, error) {
+	if len(factors) == 0 {
+		return nil, nil, fmt.Errorf("no factors")
+	}
+	return twoLevelRuns(factors, fraction, opt)
+}
+
+// Generating rows of the cyclic Plackett-Burman designs
+var plackettBurmanRows = map[int]string{
+	12: "++-+++---+-",
+	20: "++--++++-+-+----++-",
+	24: "+++++-+-++--++--+-+----",
+}
+
+// hadamard returns a Hadamard-like matrix of n runs whose first column is
+// all ones, if one is known
+func hadamard(n int) [][]float64 {
+	if n == 1 {
+		return [][]float64{{1}}
+	}
+	if gen, ok := plackettBurmanRows[n]; ok {
+		m := make([][]float64, n)
+		for i := range m {
+			m[i] = make([]float64, n)
+			m[i][0] = 1
+			for j := 1; j < n; j++ {
+				if i == n-1 || gen[(j-1+i)%(n-1)] == '-' {
+					m[i][j] = -1
+				} else {
+					m[i][j] = 1
+				}
+			}
+		}
+		return m
+	}
+	if n%2 != 0 {
+		return nil
+	}
+	h := hadamard(n / 2)
+	if h == nil {
+		return nil
+	}
+	half := n / 2
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		for j := range m[i] {
+			v := h[i%half][j%half]
+			if i >= half && j >= half {
+				v = -v
+			}
+			m[i][j] = v
+		}
+	}
+	return m
+}
+
+// PlackettBurman generates a two-level screening design of the lowest and
+// highest levels of each factor in the fewest runs, a multiple of four, for
+// which a design is known. Main effects are orthogonal but partially
+// aliased with two-factor interactions.
+func PlackettBurman(factors []DOEPair, opt DesignOpt) ([]Run, error) {
+	k := len(factors)
+	if k == 0 {
+		return nil, fmt.Errorf("no factors")
+	}
+
+	var h [][]float64
+	for n := 4 * (k/4 + 1); h == nil; n += 4 {
+		if n > 8*(k+1) {
+			return nil, fmt.Errorf("no Plackett-Burman design known for %d factors", k)
+		}
+		h = hadamard(n)
+	}
+
+	coded := make([][]float64, len(h))
+	for i, row := range h {
+		coded[i] = row[1 : k+1]
+	}
+	rows, err := decodeRows(factors, coded)
+	if err != nil {
+		return nil, err
+	}
+	des := &design{Factors: factors, Rows: rows}
+	return des.runs(opt)
+}
@@ -0,0 +1,327 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package doe
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// A Model is the form of model that a design should estimate
+type Model int
+
+// Models for optimal designs
+const (
+	// Intercept and main effects
+	LinearModel Model = iota
+	// LinearModel plus two-factor interactions
+	InteractionModel
+	// InteractionModel plus squares of numeric factors
+	QuadraticModel
+)
+
+// maxExchangePasses limits the number of passes of the exchange algorithm
+const maxExchangePasses = 100
+
+// maxCond is the largest condition number of an information matrix which
+// is treated as nonsingular
+const maxCond = 1e12
+
+// exchangeStarts is the number of starting designs tried by DOptimal
+const exchangeStarts = 10
+
+// modelMatrix returns the rows of the model matrix of each candidate run.
+// Numeric factors are coded to [-1, 1] and categorical factors use effect
+// coding.
+func modelMatrix(candidates []Run, factors []string, model Model) ([][]float64, error) {
+	n := len(candidates)
+	type column struct {
+		Values  [][]float64 // Values of each column for each candidate
+		Numeric bool
+	}
+
+	var mains []column
+	for _, f := range factors {
+		values := make([]interface{}, n)
+		for i, run := range candidates {
+			v, err := run.GetFactorValue(f)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+
+		lo, hi, err := numericRange(Pair(f, values))
+		if err == nil {
+			if lo == hi {
+				continue
+			}
+			c := column{Values: make([][]float64, n), Numeric: true}
+			for i, v := range values {
+				x, _ := toFloat(v)
+				c.Values[i] = []float64{(2*x - lo - hi) / (hi - lo)}
+			}
+			mains = append(mains, c)
+			continue
+		}
+
+		var levels []string
+		index := make(map[string]int)
+		for _, v := range values {
+			key := fmt.Sprint(v)
+			if _, seen := index[key]; !seen {
+				index[key] = len(levels)
+				levels = append(levels, key)
+			}
+		}
+		if len(levels) < 2 {
+			continue
+		}
+		c := column{Values: make([][]float64, n)}
+		for i, v := range values {
+			row := make([]float64, len(levels)-1)
+			if l := index[fmt.Sprint(v)]; l < len(row) {
+				row[l] = 1
+			} else {
+				for j := range row {
+					row[j] = -1
+				}
+			}
+			c.Values[i] = row
+		}
+		mains = append(mains, c)
+	}
+
+	rows := make([][]float64, n)
+	for i := range rows {
+		row := []float64{1}
+		for _, c := range mains {
+			row = append(row, c.Values[i]...)
+		}
+		if model >= InteractionModel {
+			for a := range mains {
+				for b := a + 1; b < len(mains); b++ {
+					for _, x := range mains[a].Values[i] {
+						for _, y := range mains[b].Values[i] {
+							row = append(row, x*y)
+						}
+					}
+				}
+			}
+		}
+		if model >= QuadraticModel {
+			for _, c := range mains {
+				if c.Numeric {
+					row = append(row, c.Values[i][0]*c.Values[i][0])
+				}
+			}
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// information returns X'X for the given rows of the model matrix
+func information(x [][]float64, rows []int) *mat.SymDense {
+	p := len(x[0])
+	m := mat.NewSymDense(p, nil)
+	for _, d := range rows {
+		m.SymRankOne(m, 1, mat.NewVecDense(p, x[d]))
+	}
+	return m
+}
+
+// factorize returns the Cholesky factorization of an information matrix,
+// or false if it is singular
+func factorize(info *mat.SymDense) (*mat.Cholesky, bool) {
+	var chol mat.Cholesky
+	if ok := chol.Factorize(info); !ok || chol.Cond() > maxCond {
+		return nil, false
+	}
+	return &chol, true
+}
+
+// nonsingularStart returns n candidate rows whose information matrix is
+// nonsingular: candidates, in random order, that increase the rank of the
+// chosen rows until it is full, then random candidates.
+func nonsingularStart(x [][]float64, n int, r *rand.Rand) ([]int, error) {
+	p := len(x[0])
+	var chosen []int
+	var basis [][]float64
+	for _, c := range r.Perm(len(x)) {
+		if len(basis) == p {
+			break
+		}
+		// Gram-Schmidt residual of the candidate against the chosen rows
+		v := append([]float64(nil), x[c]...)
+		var norm float64
+		for _, xi := range x[c] {
+			norm += xi * xi
+		}
+		for _, b := range basis {
+			var dot float64
+			for i := range v {
+				dot += v[i] * b[i]
+			}
+			for i := range v {
+				v[i] -= dot * b[i]
+			}
+		}
+		var res float64
+		for _, vi := range v {
+			res += vi * vi
+		}
+		if res <= 1e-10*norm || res == 0 {
+			continue
+		}
+		res = math.Sqrt(res)
+		for i := range v {
+			v[i] /= res
+		}
+		basis = append(basis, v)
+		chosen = append(chosen, c)
+	}
+	if len(basis) < p {
+		return nil, fmt.Errorf("candidate runs cannot estimate model: only %d of %d terms are estimable", len(basis), p)
+	}
+	for len(chosen) < n {
+		chosen = append(chosen, r.Intn(len(x)))
+	}
+	return chosen, nil
+}
+
+// exchange improves the chosen rows in place by Fedorov's exchange algorithm
+// and returns the log determinant of their information matrix
+func exchange(x [][]float64, chosen []int) float64 {
+	rows := make([]*mat.VecDense, len(x))
+	for i, row := range x {
+		rows[i] = mat.NewVecDense(len(row), row)
+	}
+
+	chol, ok := factorize(information(x, chosen))
+	if !ok {
+		return math.Inf(-1)
+	}
+	for pass := 0; pass < maxExchangePasses; pass++ {
+		improved := false
+		for i := range chosen {
+			var inv mat.SymDense
+			if err := chol.InverseTo(&inv); err != nil {
+				return math.Inf(-1)
+			}
+			// Exchanging row xi for xc scales the determinant by
+			// (1 + d(xc, xc))(1 - d(xi, xi)) + d(xi, xc)^2 where
+			// d(a, b) = a' inv b
+			old := chosen[i]
+			dii := mat.Inner(rows[old], &inv, rows[old])
+			bestCandidate, bestRatio := old, 1.0
+			for c := range x {
+				if c == old {
+					continue
+				}
+				dic := mat.Inner(rows[old], &inv, rows[c])
+				ratio := (1+mat.Inner(rows[c], &inv, rows[c]))*(1-dii) + dic*dic
+				if ratio > bestRatio*(1+1e-9) {
+					bestCandidate, bestRatio = c, ratio
+				}
+			}
+			if bestCandidate != old {
+				chosen[i] = bestCandidate
+				if chol, ok = factorize(information(x, chosen)); !ok {
+					return math.Inf(-1)
+				}
+				improved = true
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	return chol.LogDet()
+}
+
+// DOptimal selects n runs, possibly repeated, from a set of candidate runs
+// that maximise the determinant of the information matrix of model. It uses
+// Fedorov's exchange algorithm from several random nonsingular starts given
+// by opt.Seed, keeping the best.
+// Candidates are typically generated by FullFactorial or AllCombinations.
+func DOptimal(candidates []Run, model Model, n int, opt DesignOpt) ([]Run, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no candidate runs")
+	}
+	factors := candidates[0].Factordescriptors
+
+	x, err := modelMatrix(candidates, factors, model)
+	if err != nil {
+		return nil, err
+	}
+	if p := len(x[0]); n < p {
+		return nil, fmt.Errorf("model has %d terms so needs at least %d runs but got %d", p, p, n)
+	}
+
+	r := opt.rand()
+	var chosen []int
+	best := math.Inf(-1)
+	for start := 0; start < exchangeStarts; start++ {
+		rows, err := nonsingularStart(x, n, r)
+		if err != nil {
+			return nil, err
+		}
+		if ld := exchange(x, rows); ld > best {
+			best, chosen = ld, rows
+		}
+	}
+
+	if math.IsInf(best, -1) {
+		return nil, fmt.Errorf("candidate runs cannot estimate model with %d runs", n)
+	}
+
+	var pairs []DOEPair
+	for _, f := range factors {
+		pair, err := MakePair(candidates, f)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+
+	var rows [][]interface{}
+	for _, d := range chosen {
+		row := make([]interface{}, len(factors))
+		for j, f := range factors {
+			v, err := candidates[d].GetFactorValue(f)
+			if err != nil {
+				return nil, err
+			}
+			row[j] = v
+		}
+		rows = append(rows, row)
+	}
+
+	des := &design{Factors: pairs, Rows: rows}
+	return des.runs(opt)
+}
@@ -0,0 +1,120 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package doe
+
+import (
+	"fmt"
+	"math"
+)
+
+// CentralComposite generates a central composite design: a two-level full
+// factorial of the lowest and highest levels of each numeric factor plus
+// two axial runs per factor at alpha times the half range from the centre.
+// If alpha is zero, the rotatable value (2^k)^(1/4) is used; an alpha of
+// one gives a face-centred design. Blocked designs have the factorial runs
+// in the first block and the axial runs in the second; centre points are
+// added to each block.
+func CentralComposite(factors []DOEPair, alpha float64, opt DesignOpt) ([]Run, error) {
+	k := len(factors)
+	if k < 2 {
+		return nil, fmt.Errorf("central composite designs need at least 2 factors but got %d", k)
+	}
+	if opt.numBlocks() > 2 {
+		return nil, fmt.Errorf("central composite designs have at most 2 blocks but got %d", opt.Blocks)
+	}
+	for _, f := range factors {
+		if _, _, err := numericRange(f); err != nil {
+			return nil, err
+		}
+	}
+
+	if alpha == 0 {
+		alpha = math.Pow(math.Pow(2, float64(k)), 0.25)
+	}
+
+	d, err := newTwoLevel(k, 0)
+	if err != nil {
+		return nil, err
+	}
+	coded, _ := d.coded(nil)
+	var blocks []int
+	for range coded {
+		blocks = append(blocks, 0)
+	}
+
+	for i := 0; i < k; i++ {
+		for _, s := range []float64{-alpha, alpha} {
+			row := make([]float64, k)
+			row[i] = s
+			coded = append(coded, row)
+			blocks = append(blocks, 1)
+		}
+	}
+
+	rows, err := decodeRows(factors, coded)
+	if err != nil {
+		return nil, err
+	}
+	des := &design{Factors: factors, Rows: rows}
+	if opt.numBlocks() == 2 {
+		des.Blocks = blocks
+	}
+	return des.runs(opt)
+}
+
+// BoxBehnken generates a Box-Behnken design for three or more numeric
+// factors: for every pair of factors, the four combinations of their lowest
+// and highest levels with the remaining factors at their centres. Designs
+// should include centre points.
+func BoxBehnken(factors []DOEPair, opt DesignOpt) ([]Run, error) {
+	k := len(factors)
+	if k < 3 {
+		return nil, fmt.Errorf("Box-Behnken designs need at least 3 factors but got %d", k)
+	}
+	for _, f := range factors {
+		if _, _, err := numericRange(f); err != nil {
+			return nil, err
+		}
+	}
+
+	var coded [][]float64
+	for i := 0; i < k; i++ {
+		for j := i + 1; j < k; j++ {
+			for _, si := range []float64{-1, 1} {
+				for _, sj := range []float64{-1, 1} {
+					row := make([]float64, k)
+					row[i] = si
+					row[j] = sj
+					coded = append(coded, row)
+				}
+			}
+		}
+	}
+
+	rows, err := decodeRows(factors, coded)
+	if err != nil {
+		return nil, err
+	}
+	des := &design{Factors: factors, Rows: rows}
+	return des.runs(opt)
+}
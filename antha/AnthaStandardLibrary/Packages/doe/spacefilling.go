@@ -0,0 +1,129 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package doe
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Exponent of the Morris-Mitchell criterion; large values approximate the
+// maximin distance criterion
+const maximinExponent = 15
+
+func latinHypercube(r *rand.Rand, n, k int) [][]float64 {
+	coded := make([][]float64, n)
+	for i := range coded {
+		coded[i] = make([]float64, k)
+	}
+	for j := 0; j < k; j++ {
+		for i, stratum := range r.Perm(n) {
+			coded[i][j] = -1 + 2*(float64(stratum)+r.Float64())/float64(n)
+		}
+	}
+	return coded
+}
+
+// morrisMitchell returns the Morris-Mitchell space-filling criterion of
+// points. Smaller values spread points further apart.
+func morrisMitchell(coded [][]float64) float64 {
+	var sum float64
+	for i := range coded {
+		for j := i + 1; j < len(coded); j++ {
+			var d2 float64
+			for f := range coded[i] {
+				d := coded[i][f] - coded[j][f]
+				d2 += d * d
+			}
+			sum += math.Pow(d2, -maximinExponent/2.0)
+		}
+	}
+	return math.Pow(sum, 1.0/maximinExponent)
+}
+
+func checkSpaceFilling(factors []DOEPair, n int) error {
+	if len(factors) == 0 {
+		return fmt.Errorf("no factors")
+	}
+	if n < 2 {
+		return fmt.Errorf("need at least 2 runs but got %d", n)
+	}
+	for _, f := range factors {
+		if _, _, err := numericRange(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LatinHypercube generates n runs of numeric factors such that each factor
+// takes exactly one value in each of n equal intervals of its range.
+// Designs are randomised by opt.Seed.
+func LatinHypercube(factors []DOEPair, n int, opt DesignOpt) ([]Run, error) {
+	if err := checkSpaceFilling(factors, n); err != nil {
+		return nil, err
+	}
+
+	coded := latinHypercube(opt.rand(), n, len(factors))
+	rows, err := decodeRows(factors, coded)
+	if err != nil {
+		return nil, err
+	}
+	des := &design{Factors: factors, Rows: rows}
+	return des.runs(opt)
+}
+
+// SpaceFilling generates a Latin hypercube of n runs of numeric factors
+// whose points are spread as far apart as possible. The design is improved
+// from a random Latin hypercube by exchanging values of a factor between
+// runs when that improves the Morris-Mitchell criterion.
+func SpaceFilling(factors []DOEPair, n int, opt DesignOpt) ([]Run, error) {
+	if err := checkSpaceFilling(factors, n); err != nil {
+		return nil, err
+	}
+
+	k := len(factors)
+	r := opt.rand()
+	coded := latinHypercube(r, n, k)
+	score := morrisMitchell(coded)
+	for iter := 0; iter < 20*n*k; iter++ {
+		f, i, j := r.Intn(k), r.Intn(n), r.Intn(n)
+		if i == j {
+			continue
+		}
+		coded[i][f], coded[j][f] = coded[j][f], coded[i][f]
+		if s := morrisMitchell(coded); s < score {
+			score = s
+		} else {
+			coded[i][f], coded[j][f] = coded[j][f], coded[i][f]
+		}
+	}
+
+	rows, err := decodeRows(factors, coded)
+	if err != nil {
+		return nil, err
+	}
+	des := &design{Factors: factors, Rows: rows}
+	return des.runs(opt)
+}
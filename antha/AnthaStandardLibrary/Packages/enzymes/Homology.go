@@ -0,0 +1,541 @@
+// antha/AnthaStandardLibrary/Packages/enzymes/Homology.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package enzymes
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/oligos"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+)
+
+// Homology based assembly methods. Each joins fragments whose ends share
+// a homologous overlap.
+const (
+	Gibson = "Gibson"
+	HiFi   = "HiFi"
+	SLIC   = "SLIC"
+)
+
+// Kinds of AssemblyRisk
+const (
+	RepeatedOverlap    = "repeated overlap"
+	SecondaryStructure = "secondary structure"
+	OffTargetHomology  = "off-target homology"
+)
+
+// HomologyConstraints are the constraints on the design of a homology based
+// assembly
+type HomologyConstraints struct {
+	// Lengths in bp of the overlaps between adjacent fragments
+	MinOverlap int
+	MaxOverlap int
+	// If set, the length of each overlap is chosen to bring its melting
+	// temperature closest to this; otherwise overlaps are MinOverlap long
+	TargetMeltingTemp wunit.Temperature
+	// Conditions under which the melting temperatures and structures of
+	// overlaps are calculated; DefaultConditions if nil
+	Conditions *oligos.Conditions
+
+	// Constraints on the part of each primer which anneals to its part
+	Primer oligos.PrimerConstraints
+
+	// If false, the vector is assumed to be linearised by digestion and
+	// overlaps with the vector are added entirely to the adjacent parts.
+	// Otherwise the vector is amplified like any other part.
+	AmplifyVector bool
+
+	// Overlaps forming hairpins with a free energy in kcal/mol below this
+	// are flagged as a risk. Zero disables the check.
+	MinHairpinDeltaG float64
+	// Exact matches of at least this many bp between an overlap and
+	// anywhere other than its intended position, or between two overlaps,
+	// are flagged as a risk. Zero disables the check.
+	MaxHomology int
+}
+
+var homologyOverlaps = map[string][2]int{
+	Gibson: {20, 40},
+	HiFi:   {15, 30},
+	SLIC:   {25, 40},
+}
+
+// DefaultHomologyConstraints returns the recommended constraints for a
+// homology based assembly method
+func DefaultHomologyConstraints(method string) (HomologyConstraints, error) {
+	overlap, ok := homologyOverlaps[method]
+	if !ok {
+		return HomologyConstraints{}, fmt.Errorf("unknown homology assembly method %q: expecting one of %s, %s or %s", method, Gibson, HiFi, SLIC)
+	}
+
+	return HomologyConstraints{
+		MinOverlap:        overlap[0],
+		MaxOverlap:        overlap[1],
+		TargetMeltingTemp: wunit.NewTemperature(55, "℃"),
+		Primer: oligos.PrimerConstraints{
+			MaxGCContent:      0.7,
+			MinLength:         18,
+			MaxLength:         30,
+			MinMeltingTemp:    wunit.NewTemperature(52, "℃"),
+			MaxMeltingTemp:    wunit.NewTemperature(68, "℃"),
+			TargetMeltingTemp: wunit.NewTemperature(60, "℃"),
+			Anchored:          true,
+		},
+		MinHairpinDeltaG: -3,
+		MaxHomology:      12,
+	}, nil
+}
+
+func (c HomologyConstraints) conditions() oligos.Conditions {
+	if c.Conditions != nil {
+		return *c.Conditions
+	}
+	return oligos.DefaultConditions()
+}
+
+// An Overlap is the homologous sequence joining two adjacent fragments of an
+// assembly
+type Overlap struct {
+	Upstream    string // Name of the part the overlap follows
+	Downstream  string // Name of the part the overlap precedes
+	Seq         string
+	MeltingTemp wunit.Temperature
+	GCContent   float64
+}
+
+func (o Overlap) String() string {
+	return fmt.Sprintf("%s to %s: %s (%d bp, %s)", o.Upstream, o.Downstream, o.Seq, len(o.Seq), o.MeltingTemp)
+}
+
+// A HomologyPrimer is a primer which amplifies a part and adds part of an
+// overlap to its end
+type HomologyPrimer struct {
+	oligos.Primer        // Region annealing to the part
+	Tail          string // 5' extension adding the overlap
+}
+
+// FullSequence returns the sequence of the primer including its tail
+func (p HomologyPrimer) FullSequence() string {
+	return p.Tail + p.Primer.Seq
+}
+
+// An AssemblyRisk is a feature of a design which may lead to mis-assembly
+type AssemblyRisk struct {
+	Kind    string
+	Overlap int // Index of the overlap concerned
+	Message string
+}
+
+func (r AssemblyRisk) String() string {
+	return fmt.Sprintf("%s: %s", r.Kind, r.Message)
+}
+
+// A HomologyAssembly is the design of a homology based assembly
+type HomologyAssembly struct {
+	Vector wtype.DNASequence
+	Parts  []wtype.DNASequence
+	// Overlaps[i] joins Fragments[i] to the following fragment
+	Overlaps []Overlap
+	// Forward and reverse primers for each fragment, or zero values for a
+	// vector which is not amplified
+	Primers [][2]HomologyPrimer
+	// Fragments to assemble, each ending in the overlaps with its
+	// neighbours. The vector, if any, is first.
+	Fragments []wtype.DNASequence
+	Product   wtype.DNASequence
+	Risks     []AssemblyRisk
+}
+
+// DesignHomologyAssembly designs a Gibson, HiFi or SLIC assembly of parts
+// in order into vector. The vector should be linearised: the parts are
+// inserted between its end and its start. If the vector is empty, parts are
+// assembled into a linear product.
+//
+// Overlaps are centred on the junction between parts and added by the
+// primers amplifying each part. Errors are returned if no suitable primer
+// can be found; features of the design that risk mis-assembly are returned
+// in Risks.
+func DesignHomologyAssembly(vector wtype.DNASequence, parts []wtype.DNASequence, c HomologyConstraints) (design HomologyAssembly, err error) {
+	if len(parts) == 0 {
+		return design, fmt.Errorf("no parts to assemble")
+	}
+	if c.MinOverlap <= 0 || c.MaxOverlap < c.MinOverlap {
+		return design, fmt.Errorf("invalid overlap lengths %d to %d bp", c.MinOverlap, c.MaxOverlap)
+	}
+
+	design.Vector = vector
+	design.Parts = parts
+
+	// The fragments in order, before overlaps are added
+	var templates []wtype.DNASequence
+	var amplify []bool
+	hasVector := len(vector.Seq) != 0
+	if hasVector {
+		v := vector.Dup()
+		v.Plasmid = false
+		templates = append(templates, v)
+		amplify = append(amplify, c.AmplifyVector)
+	}
+	for _, part := range parts {
+		templates = append(templates, part)
+		amplify = append(amplify, true)
+	}
+
+	// Sequence added to the 5' and 3' ends of each fragment
+	heads := make([]string, len(templates))
+	tails := make([]string, len(templates))
+
+	junctions := len(templates) - 1
+	if hasVector {
+		junctions = len(templates)
+	}
+	for i := 0; i < junctions; i++ {
+		up, down := i, (i+1)%len(templates)
+		upSeq := strings.ToUpper(templates[up].Seq)
+		downSeq := strings.ToUpper(templates[down].Seq)
+
+		overlap, split, err := chooseOverlap(upSeq, downSeq, amplify[up], amplify[down], c)
+		if err != nil {
+			return design, fmt.Errorf("cannot design overlap between %s and %s: %s", templates[up].Nm, templates[down].Nm, err)
+		}
+		overlap.Upstream = templates[up].Nm
+		overlap.Downstream = templates[down].Nm
+		design.Overlaps = append(design.Overlaps, overlap)
+
+		// the upstream fragment gains the downstream part of the overlap
+		// and vice versa
+		tails[up] = overlap.Seq[split:]
+		heads[down] = overlap.Seq[:split]
+	}
+
+	for i, template := range templates {
+		if !amplify[i] {
+			design.Primers = append(design.Primers, [2]HomologyPrimer{})
+			design.Fragments = append(design.Fragments, template)
+			continue
+		}
+
+		fwd, err := oligos.DesignFWDPrimer(template, c.Primer)
+		if err != nil {
+			return design, fmt.Errorf("cannot design forward primer for %s: %s", template.Nm, err)
+		}
+		fwd.Nm = template.Nm + "_fwd"
+		rev, err := oligos.DesignREVPrimer(template, c.Primer)
+		if err != nil {
+			return design, fmt.Errorf("cannot design reverse primer for %s: %s", template.Nm, err)
+		}
+		rev.Nm = template.Nm + "_rev"
+		rev.Reverse = true
+
+		design.Primers = append(design.Primers, [2]HomologyPrimer{
+			{Primer: fwd, Tail: heads[i]},
+			{Primer: rev, Tail: wtype.RevComp(tails[i])},
+		})
+
+		fragment := wtype.MakeLinearDNASequence(template.Nm, heads[i]+strings.ToUpper(template.Seq)+tails[i])
+		fragment.Features = shiftFeatures(template.Features, len(heads[i]), 0)
+		design.Fragments = append(design.Fragments, fragment)
+	}
+
+	design.Product, err = SimulateHomologyAssembly(design.Fragments, c.MinOverlap)
+	if err != nil {
+		return design, err
+	}
+	if hasVector && !design.Product.Plasmid {
+		return design, fmt.Errorf("assembly of %s into %s does not circularise", strings.Join(names(parts), ", "), vector.Nm)
+	}
+	if hasVector {
+		design.Product.Nm = vector.Nm + "_" + strings.Join(names(parts), "_")
+	} else {
+		design.Product.Nm = strings.Join(names(parts), "_")
+	}
+
+	// annotate the product with the position of each part
+	offset := 0
+	for i, fragment := range design.Fragments {
+		if i > 0 {
+			offset += len(design.Fragments[i-1].Seq) - len(design.Overlaps[i-1].Seq)
+		}
+		if hasVector && i == 0 {
+			continue
+		}
+		start := offset + len(heads[i]) + 1
+		end := start + len(templates[i].Seq) - 1
+		design.Product.Features = append(design.Product.Features, wtype.Feature{
+			Name:          fragment.Nm,
+			Class:         wtype.MISC_FEATURE,
+			StartPosition: start,
+			EndPosition:   end,
+			DNASeq:        strings.ToUpper(templates[i].Seq),
+		})
+	}
+
+	design.Risks, err = HomologyAssemblyRisks(design.Fragments, design.Overlaps, c)
+	return design, err
+}
+
+// chooseOverlap chooses the overlap between the end of up and the start of
+// down, returning the overlap and the number of its bases taken from up. An
+// overlap can only be added to fragments which are amplified.
+func chooseOverlap(up, down string, amplifyUp, amplifyDown bool, c HomologyConstraints) (best Overlap, split int, err error) {
+	if !amplifyUp && !amplifyDown {
+		return best, 0, fmt.Errorf("neither fragment is amplified")
+	}
+	cond := c.conditions()
+
+	found := false
+	bestDiff := math.Inf(1)
+	for length := c.MinOverlap; length <= c.MaxOverlap; length++ {
+		s := length / 2
+		switch {
+		case !amplifyUp:
+			s = length
+		case !amplifyDown:
+			s = 0
+		}
+		if s > len(up) || length-s > len(down) {
+			break
+		}
+
+		seq := up[len(up)-s:] + down[:length-s]
+		tm, err := oligos.NearestNeighbourMeltingTemp(wtype.MakeSingleStrandedDNASequence("overlap", seq), cond)
+		if err != nil {
+			return best, 0, err
+		}
+
+		diff := 0.0
+		if c.TargetMeltingTemp.ConcreteMeasurement != nil {
+			diff = math.Abs(tm.SIValue() - c.TargetMeltingTemp.SIValue())
+		}
+		if !found || diff < bestDiff {
+			best = Overlap{
+				Seq:         seq,
+				MeltingTemp: tm,
+				GCContent:   sequences.GCcontent(seq),
+			}
+			split, bestDiff, found = s, diff, true
+		}
+		if c.TargetMeltingTemp.ConcreteMeasurement == nil {
+			break
+		}
+	}
+
+	if !found {
+		return best, 0, fmt.Errorf("fragments are shorter than the minimum overlap of %d bp", c.MinOverlap)
+	}
+	return best, split, nil
+}
+
+// shiftFeatures returns copies of features moved by offset bases, wrapping
+// positions beyond length if length is positive
+func shiftFeatures(features []wtype.Feature, offset, length int) []wtype.Feature {
+	var ret []wtype.Feature
+	wrap := func(pos int) int {
+		pos += offset
+		if length > 0 && pos > length {
+			pos -= length
+		}
+		return pos
+	}
+	for _, f := range features {
+		f.StartPosition = wrap(f.StartPosition)
+		f.EndPosition = wrap(f.EndPosition)
+		ret = append(ret, f)
+	}
+	return ret
+}
+
+// endOverlap returns the length of the longest suffix of up which is a
+// prefix of down and at least min bp long, or zero
+func endOverlap(up, down string, min, max int) int {
+	if max > len(up) {
+		max = len(up)
+	}
+	if max > len(down) {
+		max = len(down)
+	}
+	for n := max; n >= min && n > 0; n-- {
+		if up[len(up)-n:] == down[:n] {
+			return n
+		}
+	}
+	return 0
+}
+
+// SimulateHomologyAssembly joins linear fragments in order where the end
+// of each shares an overlap of at least minOverlap bp with the start of
+// the next. If the end of the last fragment overlaps the start of the
+// first, the product is a plasmid. Features of the fragments are carried
+// over to the product.
+func SimulateHomologyAssembly(fragments []wtype.DNASequence, minOverlap int) (product wtype.DNASequence, err error) {
+	if len(fragments) == 0 {
+		return product, fmt.Errorf("no fragments to assemble")
+	}
+
+	seq := strings.ToUpper(fragments[0].Seq)
+	var features []wtype.Feature
+	features = append(features, shiftFeatures(fragments[0].Features, 0, 0)...)
+	for i, fragment := range fragments[1:] {
+		next := strings.ToUpper(fragment.Seq)
+		n := endOverlap(seq, next, minOverlap, len(next))
+		if n == 0 {
+			return product, fmt.Errorf("no overlap of at least %d bp between %s and %s", minOverlap, fragments[i].Nm, fragment.Nm)
+		}
+		features = append(features, shiftFeatures(fragment.Features, len(seq)-n, 0)...)
+		seq += next[n:]
+	}
+
+	// close the plasmid if the last fragment overlaps the first; its end
+	// is then a repeat of the start of the first fragment
+	first, last := fragments[0], fragments[len(fragments)-1]
+	circular := false
+	if len(fragments) > 1 {
+		max := len(first.Seq)
+		if len(last.Seq) < max {
+			max = len(last.Seq)
+		}
+		if n := endOverlap(seq, seq, minOverlap, max); n != 0 && n < len(seq) {
+			seq = seq[:len(seq)-n]
+			circular = true
+		}
+	}
+
+	product = wtype.MakeLinearDNASequence(strings.Join(names(fragments), "_"), seq)
+	product.Plasmid = circular
+
+	length := 0
+	if circular {
+		length = len(seq)
+	}
+	seen := make(map[wtype.Feature]bool)
+	for _, f := range shiftFeatures(features, 0, length) {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		product.Features = append(product.Features, f)
+	}
+	return product, nil
+}
+
+// HomologyAssemblyRisks returns the features of an assembly of fragments
+// joined by overlaps which risk mis-assembly: overlaps sharing homology
+// with each other or with fragments away from their intended position, and
+// overlaps forming stable hairpins which prevent annealing.
+func HomologyAssemblyRisks(fragments []wtype.DNASequence, overlaps []Overlap, c HomologyConstraints) (risks []AssemblyRisk, err error) {
+	cond := c.conditions()
+
+	for i, o := range overlaps {
+		if c.MinHairpinDeltaG != 0 {
+			hairpin, err := oligos.Hairpin(o.Seq, cond)
+			if err != nil {
+				return nil, err
+			}
+			if hairpin.DeltaG < c.MinHairpinDeltaG {
+				risks = append(risks, AssemblyRisk{
+					Kind:    SecondaryStructure,
+					Overlap: i,
+					Message: fmt.Sprintf("overlap %s forms a hairpin with ΔG %.2f kcal/mol (minimum %.2f)", o, hairpin.DeltaG, c.MinHairpinDeltaG),
+				})
+			}
+		}
+
+		if c.MaxHomology <= 0 {
+			continue
+		}
+
+		for j := i + 1; j < len(overlaps); j++ {
+			other := overlaps[j].Seq
+			for _, s := range []string{other, wtype.RevComp(other)} {
+				if _, n, shared := oligos.OverlapCheck(o.Seq, s); n >= c.MaxHomology {
+					risks = append(risks, AssemblyRisk{
+						Kind:    RepeatedOverlap,
+						Overlap: i,
+						Message: fmt.Sprintf("overlap %s shares %d bp (%s) with overlap %s", o, n, shared, overlaps[j]),
+					})
+					break
+				}
+			}
+		}
+
+		for k, fragment := range fragments {
+			if pos, n := offTargetHomology(o.Seq, fragment.Seq, k == i, k == (i+1)%len(fragments), c.MaxHomology); n != 0 {
+				risks = append(risks, AssemblyRisk{
+					Kind:    OffTargetHomology,
+					Overlap: i,
+					Message: fmt.Sprintf("overlap %s matches %d bp of %s at position %d", o, n, fragment.Nm, pos),
+				})
+			}
+		}
+	}
+	return risks, nil
+}
+
+// offTargetHomology returns the position, in human friendly format, and
+// length of the first exact match of at least minLength bp between overlap
+// and either strand of fragment outside the intended position of the
+// overlap at the end of upstream fragments or the start of downstream
+// fragments
+func offTargetHomology(overlap, fragment string, upstream, downstream bool, minLength int) (pos, length int) {
+	overlap = strings.ToUpper(overlap)
+	fragment = strings.ToUpper(fragment)
+	if len(overlap) < minLength {
+		return 0, 0
+	}
+
+	intended := func(start, end int) bool {
+		return (upstream && start >= len(fragment)-len(overlap)) || (downstream && end <= len(overlap))
+	}
+
+	for _, strand := range []string{overlap, wtype.RevComp(overlap)} {
+		forward := strand == overlap
+		for i := 0; i+minLength <= len(strand); i++ {
+			match := strand[i : i+minLength]
+			for start := strings.Index(fragment, match); start >= 0; start = nextIndex(fragment, match, start) {
+				if forward && intended(start, start+minLength) {
+					continue
+				}
+				// extend the match as far as it goes
+				n := minLength
+				for i+n < len(strand) && start+n < len(fragment) && strand[i+n] == fragment[start+n] {
+					n++
+				}
+				return start + 1, n
+			}
+		}
+	}
+	return 0, 0
+}
+
+// nextIndex returns the index of the next, possibly overlapping,
+// occurrence of sub in s after the one at last, or -1
+func nextIndex(s, sub string, last int) int {
+	if i := strings.Index(s[last+1:], sub); i >= 0 {
+		return last + 1 + i
+	}
+	return -1
+}
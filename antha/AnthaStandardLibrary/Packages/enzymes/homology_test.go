@@ -0,0 +1,164 @@
+package enzymes
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+func randomSequence(r *rand.Rand, n int) string {
+	bases := make([]byte, n)
+	for i := range bases {
+		bases[i] = "ACGT"[r.Intn(4)]
+	}
+	return string(bases)
+}
+
+func homologyTestParts() (vector wtype.DNASequence, parts []wtype.DNASequence) {
+	r := rand.New(rand.NewSource(1))
+	vector = wtype.MakePlasmidDNASequence("vector", randomSequence(r, 400))
+	for _, name := range []string{"promoter", "cds", "terminator"} {
+		part := wtype.MakeLinearDNASequence(name, randomSequence(r, 300))
+		part.Features = []wtype.Feature{
+			{
+				Name:          name + "_feature",
+				Class:         wtype.MISC_FEATURE,
+				StartPosition: 101,
+				EndPosition:   150,
+				DNASeq:        part.Seq[100:150],
+			},
+		}
+		parts = append(parts, part)
+	}
+	return
+}
+
+func TestDesignHomologyAssembly(t *testing.T) {
+	vector, parts := homologyTestParts()
+	c, err := DefaultHomologyConstraints(Gibson)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	design, err := DesignHomologyAssembly(vector, parts, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := vector.Seq
+	for _, part := range parts {
+		expected += part.Seq
+	}
+	if !design.Product.Plasmid {
+		t.Error("expected a plasmid")
+	}
+	if design.Product.Seq != expected {
+		t.Errorf("expected product %s, got %s", expected, design.Product.Seq)
+	}
+	if e, g := "vector_promoter_cds_terminator", design.Product.Nm; e != g {
+		t.Errorf("expected product name %q, got %q", e, g)
+	}
+
+	if e, g := 4, len(design.Overlaps); e != g {
+		t.Fatalf("expected %d overlaps, got %d", e, g)
+	}
+	for _, o := range design.Overlaps {
+		if len(o.Seq) < c.MinOverlap || len(o.Seq) > c.MaxOverlap {
+			t.Errorf("overlap %s outside %d to %d bp", o, c.MinOverlap, c.MaxOverlap)
+		}
+	}
+	// the vector is not amplified so the overlap with its end is taken
+	// entirely from the vector
+	if e, g := vector.Seq[len(vector.Seq)-len(design.Overlaps[0].Seq):], design.Overlaps[0].Seq; e != g {
+		t.Errorf("expected first overlap %s, got %s", e, g)
+	}
+
+	if e, g := vector.Seq, design.Fragments[0].Seq; e != g {
+		t.Errorf("expected vector fragment %s, got %s", e, g)
+	}
+	for i, fragment := range design.Fragments[1:] {
+		fwd, rev := design.Primers[i+1][0], design.Primers[i+1][1]
+		if !strings.HasPrefix(fragment.Seq, fwd.FullSequence()) {
+			t.Errorf("fragment %s does not start with forward primer %s", fragment.Nm, fwd.FullSequence())
+		}
+		if !strings.HasSuffix(fragment.Seq, wtype.RevComp(rev.FullSequence())) {
+			t.Errorf("fragment %s does not end with reverse primer %s", fragment.Nm, rev.FullSequence())
+		}
+		if !strings.HasPrefix(fragment.Seq, design.Overlaps[i].Seq) {
+			t.Errorf("fragment %s does not start with overlap %s", fragment.Nm, design.Overlaps[i])
+		}
+		if !strings.HasSuffix(fragment.Seq, design.Overlaps[i+1].Seq) {
+			t.Errorf("fragment %s does not end with overlap %s", fragment.Nm, design.Overlaps[i+1])
+		}
+	}
+
+	// features are carried over and parts are annotated
+	for _, part := range parts {
+		for _, name := range []string{part.Nm, part.Nm + "_feature"} {
+			features := design.Product.GetFeatureByName(name)
+			if len(features) == 0 {
+				t.Errorf("feature %s not found in product", name)
+				continue
+			}
+			f := features[0]
+			if g := design.Product.Seq[f.StartPosition-1 : f.EndPosition]; g != f.DNASeq {
+				t.Errorf("expected feature %s at %d to %d to be %s, got %s", name, f.StartPosition, f.EndPosition, f.DNASeq, g)
+			}
+		}
+	}
+
+	if len(design.Risks) != 0 {
+		t.Errorf("expected no risks, got %v", design.Risks)
+	}
+}
+
+func TestHomologyAssemblyRisks(t *testing.T) {
+	vector, parts := homologyTestParts()
+	c, err := DefaultHomologyConstraints(HiFi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// repeat the end of the promoter in the middle of the terminator
+	repeat := parts[0].Seq[len(parts[0].Seq)-20:]
+	parts[2].Seq = parts[2].Seq[:150] + repeat + parts[2].Seq[150:]
+
+	design, err := DesignHomologyAssembly(vector, parts, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, r := range design.Risks {
+		if r.Kind == OffTargetHomology && r.Overlap == 1 && strings.Contains(r.Message, "terminator") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected off-target homology of overlap 1 with terminator, got %v", design.Risks)
+	}
+}
+
+func TestSimulateHomologyAssembly(t *testing.T) {
+	fragments := []wtype.DNASequence{
+		wtype.MakeLinearDNASequence("a", "AAAAAAAAAACCCCCCCCCC"),
+		wtype.MakeLinearDNASequence("b", "CCCCCCCCCCGGGGGGGGGG"),
+	}
+
+	product, err := SimulateHomologyAssembly(fragments, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, g := "AAAAAAAAAACCCCCCCCCCGGGGGGGGGG", product.Seq; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	if product.Plasmid {
+		t.Error("expected a linear product")
+	}
+
+	if _, err := SimulateHomologyAssembly(fragments, 11); err == nil {
+		t.Error("expected an error with no overlap")
+	}
+}
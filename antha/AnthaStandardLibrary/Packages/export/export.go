@@ -39,6 +39,9 @@ import (
 	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/enzymes"
 	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/enzymes/lookup"
 	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	seqtools "github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/Seqtools"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse/genbank"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse/sbol"
 	"github.com/antha-lang/antha/antha/anthalib/wtype"
 	"github.com/antha-lang/antha/antha/anthalib/wutil"
 )
@@ -305,6 +308,29 @@ func FastaSerialfromMultipleAssemblies(dirname string, multipleassemblyparameter
 	return FastaSerial(ANTHAPATH, dirname, seqs)
 }
 
+// Genbank exports an annotated sequence to a file in genbank format.
+func Genbank(seq wtype.DNASequence, filename string) (wtype.File, error) {
+	data, err := genbank.AnnotatedSeqToGenbank(seq)
+	if err != nil {
+		return wtype.File{}, err
+	}
+	return Binary(data, filename)
+}
+
+// SBOL exports annotated sequences to a file in SBOL 2 RDF/XML format.
+func SBOL(seqs []wtype.DNASequence, filename string) (wtype.File, error) {
+	data, err := sbol.DNASequencesToSBOL(seqs)
+	if err != nil {
+		return wtype.File{}, err
+	}
+	return Binary(data, filename)
+}
+
+// SequencingVerification exports a summary of the verification of constructs by sequencing to a csv file.
+func SequencingVerification(reports []seqtools.VerificationReport, filename string) (wtype.File, error) {
+	return CSV(seqtools.VerificationSummary(reports), filename)
+}
+
 // TextFile exports data in the format of a set of strings to a file.
 // Each entry in the set of strings represents a line.
 func TextFile(filename string, lines []string) (wtype.File, error) {
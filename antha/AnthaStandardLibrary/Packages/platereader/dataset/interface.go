@@ -21,6 +21,7 @@ type AbsorbanceData interface {
 
 type FluorescenceData interface {
 	Fluorescence(wellname string, excitationWavelength, emissionWavelength int, options ...interface{}) (average float64, err error)
+	AllFluorescenceData() (map[string][]wtype.Fluorescence, error)
 }
 
 type TimeCourseData interface {
@@ -130,15 +130,47 @@ func (data MarsData) AllAbsorbanceData() (readings map[string][]wtype.Absorbance
 
 	for wellName, wellData := range data.Dataforeachwell {
 
-		var wellReadings = make([]wtype.Absorbance, len(wellData.Data.Readings[0]))
+		var wellReadings = make([]wtype.Absorbance, 0, len(wellData.Data.Readings[0]))
 
-		for readingIndex, measurement := range wellData.Data.Readings[0] {
-			wellReadings[readingIndex] = wtype.Absorbance{
+		for _, measurement := range wellData.Data.Readings[0] {
+			// Excitation and emission wavelengths differ for fluorescence
+			if measurement.EWavelength != measurement.RWavelength {
+				continue
+			}
+			wellReadings = append(wellReadings, wtype.Absorbance{
 				Wavelength:   float64(measurement.RWavelength),
 				Reading:      measurement.Reading,
 				WellLocation: wtype.MakeWellCoordsA1(wellName),
 				Annotations:  []string{measurement.ReadingType},
+			})
+		}
+
+		readings[wellName] = wellReadings
+	}
+
+	return readings, nil
+}
+
+// AllFluorescenceData returns all fluorescence readings using the well location as key.
+func (data MarsData) AllFluorescenceData() (readings map[string][]wtype.Fluorescence, err error) {
+
+	readings = make(map[string][]wtype.Fluorescence, len(data.Dataforeachwell))
+
+	for wellName, wellData := range data.Dataforeachwell {
+
+		var wellReadings []wtype.Fluorescence
+
+		for _, measurement := range wellData.Data.Readings[0] {
+			if measurement.EWavelength == measurement.RWavelength {
+				continue
 			}
+			wellReadings = append(wellReadings, wtype.Fluorescence{
+				ExcitationWavelength: float64(measurement.EWavelength),
+				EmissionWavelength:   float64(measurement.RWavelength),
+				Reading:              measurement.Reading,
+				WellLocation:         wtype.MakeWellCoordsA1(wellName),
+				Annotations:          []string{measurement.ReadingType},
+			})
 		}
 
 		readings[wellName] = wellReadings
@@ -147,6 +179,37 @@ func (data MarsData) AllAbsorbanceData() (readings map[string][]wtype.Absorbance
 	return readings, nil
 }
 
+// Fluorescence returns the average of all readings at the specified excitation and emission wavelengths.
+// If a value for options is declared, only readings whose header contains it are averaged.
+func (data MarsData) Fluorescence(well string, excitationWavelength, emissionWavelength int, options ...interface{}) (average float64, err error) {
+
+	if len(options) > 1 {
+		return 0.0, errors.Errorf("Only one option is permitted as an argument to the Fluorescence method for MarsData")
+	}
+
+	wellData, ok := data.Dataforeachwell[strings.TrimSpace(well)]
+	if !ok {
+		return 0.0, fmt.Errorf("no data for well, %s", well)
+	}
+
+	var readings []float64
+	for _, measurement := range wellData.Data.Readings[0] {
+		if measurement.EWavelength != excitationWavelength || measurement.RWavelength != emissionWavelength {
+			continue
+		}
+		if len(options) == 1 && !strings.Contains(measurement.ReadingType, fmt.Sprint(options[0])) {
+			continue
+		}
+		readings = append(readings, measurement.Reading)
+	}
+
+	if len(readings) == 0 {
+		return 0.0, fmt.Errorf("No values found for excitation wavelength %d and emission wavelength %d. Available Values found: %v", excitationWavelength, emissionWavelength, data.AvailableReadings(well))
+	}
+
+	return stats.Mean(readings)
+}
+
 // AbsScanData returns all wavelengths and readings for a specified well.
 func (data MarsData) AbsScanData(well string) (wavelengths []int, Readings []float64) {
 	wavelengths = make([]int, 0)
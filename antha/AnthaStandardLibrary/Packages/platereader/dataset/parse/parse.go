@@ -0,0 +1,59 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package parse
+
+import (
+	"fmt"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/platereader/dataset"
+)
+
+// Formats of plate reader result files
+const (
+	MarsFormat       = "mars"       // BMG MARS excel export
+	SpectraMaxFormat = "spectramax" // SpectraMax XML export
+)
+
+// ParseData parses the contents of a plate reader result file in the given
+// format. The returned data may also implement dataset.FluorescenceData if
+// the format supports fluorescence readings.
+func ParseData(format string, contents []byte) (dataset.AbsorbanceData, error) {
+	switch format {
+	case MarsFormat:
+		return ParseMarsXLSXBinary(contents, 0)
+	case SpectraMaxFormat:
+		data, err := ParseSpectraMaxData(contents)
+		if err != nil {
+			return nil, err
+		}
+		if len(data.Experiment) == 0 || len(data.Experiment[0].PlateSections) == 0 {
+			return nil, fmt.Errorf("no plate sections found in SpectraMax data")
+		}
+		if ws := data.Experiment[0].PlateSections[0].Wavelengths; len(ws) == 0 || len(ws[0].Wavelength.Wells) == 0 {
+			return nil, fmt.Errorf("no wells found in SpectraMax data")
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unknown plate reader data format %q", format)
+	}
+}
@@ -37,17 +37,13 @@ func ParseSpectraMaxData(xmlFileContents []byte) (dataOutput dataset.SpectraMaxD
 
 	s, err := decodeUTF16(xmlFileContents)
 	if err != nil {
-		panic(err)
+		return
 	}
 
 	utf8XMLContents := []byte(s)
 
 	err = xml.Unmarshal(utf8XMLContents, &dataOutput)
 
-	if err != nil {
-		fmt.Println("error:", err)
-	}
-
 	return
 }
 
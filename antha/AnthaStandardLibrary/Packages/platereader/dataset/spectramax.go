@@ -137,7 +137,7 @@ func (s SpectraMaxData) AllAbsorbanceData() (readings map[string][]wtype.Absorba
 
 	for _, w := range wells {
 
-		var wellReadings = make([]wtype.Absorbance, len(strings.Fields(w.WaveData)))
+		var wellReadings = make([]wtype.Absorbance, 0, len(strings.Fields(w.WaveData)))
 
 		if w.IsScanData() {
 			dataStrings := strings.Fields(w.RawData)
@@ -0,0 +1,411 @@
+// antha/AnthaStandardLibrary/Packages/sequences/Seqtools/verify.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package seqtools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/align"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse/trace"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// DiscrepancyType classifies differences between sequencing reads and the
+// sequence they are expected to have
+type DiscrepancyType string
+
+const (
+	Mismatch  DiscrepancyType = "mismatch"
+	Insertion DiscrepancyType = "insertion"
+	Deletion  DiscrepancyType = "deletion"
+	// Ambiguous positions are covered only by ambiguous base calls
+	Ambiguous DiscrepancyType = "ambiguous"
+)
+
+// A Discrepancy is a difference between the reads of a construct and its
+// expected sequence
+type Discrepancy struct {
+	// Position in the expected sequence in human friendly format. Insertions
+	// follow this position.
+	Position int
+	Type     DiscrepancyType
+	// Expected base, or - for insertions
+	Expected string
+	// Observed bases, or - for deletions
+	Observed string
+}
+
+func (d Discrepancy) String() string {
+	return fmt.Sprintf("%d%s>%s", d.Position, d.Expected, d.Observed)
+}
+
+// VerificationCriteria determine whether a construct passes verification
+type VerificationCriteria struct {
+	// Algorithm with which to align reads to the expected sequence
+	Algorithm align.ScoringMatrix
+	// Phred quality below which the ends of traces are trimmed
+	MinQuality int
+	// Fraction of each checked region which reads must cover
+	MinCoverage float64
+	// Largest number of discrepancies of each kind allowed in each checked
+	// region. Indels count both insertions and deletions.
+	MaxMismatches int
+	MaxIndels     int
+	MaxAmbiguous  int
+	// Names of the features to check. If empty, all features are checked,
+	// or the whole construct if it has no features.
+	Features []string
+}
+
+// DefaultVerificationCriteria requires every feature to be completely covered
+// by reads of quality 20 or above without discrepancies
+func DefaultVerificationCriteria() VerificationCriteria {
+	return VerificationCriteria{
+		Algorithm:   align.Fitted,
+		MinQuality:  20,
+		MinCoverage: 1.0,
+	}
+}
+
+// RegionReport describes the agreement of reads with a region of the
+// expected sequence
+type RegionReport struct {
+	Name string
+	// Number of positions in the region and the number covered by reads
+	Length  int
+	Covered int
+	// Discrepancies in order of position
+	Discrepancies []Discrepancy
+	Pass          bool
+}
+
+// Coverage returns the fraction of the region covered by reads
+func (r RegionReport) Coverage() float64 {
+	if r.Length == 0 {
+		return 0.0
+	}
+	return float64(r.Covered) / float64(r.Length)
+}
+
+// Count returns the number of discrepancies of type t
+func (r RegionReport) Count(t DiscrepancyType) int {
+	var n int
+	for _, d := range r.Discrepancies {
+		if d.Type == t {
+			n++
+		}
+	}
+	return n
+}
+
+func (r RegionReport) passes(criteria VerificationCriteria) bool {
+	return r.Coverage() >= criteria.MinCoverage &&
+		r.Count(Mismatch) <= criteria.MaxMismatches &&
+		r.Count(Insertion)+r.Count(Deletion) <= criteria.MaxIndels &&
+		r.Count(Ambiguous) <= criteria.MaxAmbiguous
+}
+
+// ReadAlignment is the alignment of a read to the expected sequence of a
+// construct
+type ReadAlignment struct {
+	Read wtype.DNASequence
+	// Whether the reverse complement of the read was aligned
+	Reverse   bool
+	Alignment align.Result
+}
+
+// VerificationReport describes the verification of a construct by
+// sequencing
+type VerificationReport struct {
+	Expected wtype.DNASequence
+	Reads    []ReadAlignment
+	// Agreement over the whole construct and over each feature
+	Construct RegionReport
+	Features  []RegionReport
+	Pass      bool
+}
+
+// evidence accumulates the calls of reads at a position of the expected
+// sequence
+type evidence struct {
+	covered bool
+	matched bool
+	// first discrepancy found by any read, preferring unambiguous ones
+	discrepancy *Discrepancy
+	// insertion after this position, and whether any read runs on to the
+	// next position without one
+	insertion *Discrepancy
+	spanned   bool
+}
+
+func (e *evidence) call(d Discrepancy) {
+	e.covered = true
+	if e.discrepancy == nil || (e.discrepancy.Type == Ambiguous && d.Type != Ambiguous) {
+		e.discrepancy = &d
+	}
+}
+
+// isBase returns whether b is an unambiguous base
+func isBase(b byte) bool {
+	return strings.IndexByte("ACGT", b) >= 0
+}
+
+// orientRead aligns the read and its reverse complement to the expected
+// sequence, returning the better alignment and the read as aligned.
+// Ambiguous base calls are aligned as A, costing at most one mismatch; the
+// positions of the original calls are returned so that they are reported as
+// ambiguous.
+func orientRead(expected, read wtype.DNASequence, algorithm align.ScoringMatrix) (ReadAlignment, string, error) {
+	var best ReadAlignment
+	var bestCalls string
+	for _, reverse := range []bool{false, true} {
+		calls := strings.ToUpper(read.Seq)
+		if reverse {
+			calls = wtype.RevComp(calls)
+		}
+		query := read
+		query.Seq = strings.Map(func(r rune) rune {
+			if r > 0xff || !isBase(byte(r)) {
+				return 'A'
+			}
+			return r
+		}, calls)
+
+		result, err := align.ForwardDNA(expected, query, algorithm)
+		if err != nil {
+			return best, "", fmt.Errorf("aligning read %s: %s", read.Nm, err)
+		}
+		if !reverse || result.Matches() > best.Alignment.Matches() {
+			best = ReadAlignment{Read: read, Reverse: reverse, Alignment: result}
+			bestCalls = calls
+		}
+	}
+	return best, bestCalls, nil
+}
+
+// collectEvidence walks the columns of an alignment, recording the call of
+// the read at each position of the expected sequence. Insertions before
+// the first or after the last aligned position are overhangs of the read
+// and are not recorded.
+func collectEvidence(ev []evidence, ra ReadAlignment, calls string) {
+	aln := ra.Alignment.Alignment
+	var last, q int
+	if len(aln.Raw) > 0 {
+		// local alignments may not start at the beginning of the read
+		q = aln.Raw[0].QueryAlignment.Start
+	}
+	var inserted []byte
+	for i := range aln.TemplateResult {
+		t, r := aln.TemplateResult[i], aln.QueryResult[i]
+		if rune(t) == align.GAP {
+			inserted = append(inserted, calls[q])
+			q++
+			continue
+		}
+
+		p := aln.TemplatePositions[i]
+		if last > 0 {
+			if len(inserted) > 0 && ev[last].insertion == nil {
+				ev[last].insertion = &Discrepancy{Position: last, Type: Insertion, Expected: string(align.GAP), Observed: string(inserted)}
+			} else if len(inserted) == 0 {
+				ev[last].spanned = true
+			}
+		}
+		inserted = inserted[:0]
+
+		expected := strings.ToUpper(string(t))
+		switch {
+		case rune(r) == align.GAP:
+			ev[p].call(Discrepancy{Position: p, Type: Deletion, Expected: expected, Observed: string(align.GAP)})
+		case !isBase(calls[q]):
+			ev[p].call(Discrepancy{Position: p, Type: Ambiguous, Expected: expected, Observed: string(calls[q])})
+		case calls[q] == expected[0]:
+			ev[p].covered, ev[p].matched = true, true
+		default:
+			ev[p].call(Discrepancy{Position: p, Type: Mismatch, Expected: expected, Observed: string(calls[q])})
+		}
+		if rune(r) != align.GAP {
+			q++
+		}
+		last = p
+	}
+}
+
+// featurePositions returns the positions of the expected sequence, in
+// human friendly format, spanned by a feature
+func featurePositions(f wtype.Feature, length int) []int {
+	start, end := f.StartPosition, f.EndPosition
+	if f.Reverse {
+		start, end = end, start
+	}
+	if start < 1 || end < 1 || start > length || end > length {
+		return nil
+	}
+	var positions []int
+	for p := start; ; p = p%length + 1 {
+		positions = append(positions, p)
+		if p == end {
+			return positions
+		}
+	}
+}
+
+// regionReport reports the evidence over positions of the expected sequence,
+// including insertions after the last position if the region is closed
+func regionReport(name string, positions []int, ev []evidence, closed bool) RegionReport {
+	r := RegionReport{Name: name, Length: len(positions)}
+	for i, p := range positions {
+		e := ev[p]
+		if e.covered {
+			r.Covered++
+		}
+		if !e.matched && e.discrepancy != nil {
+			r.Discrepancies = append(r.Discrepancies, *e.discrepancy)
+		}
+		if (closed || i+1 < len(positions)) && !e.spanned && e.insertion != nil {
+			r.Discrepancies = append(r.Discrepancies, *e.insertion)
+		}
+	}
+	return r
+}
+
+// Verify aligns sequencing reads against the expected sequence of a
+// construct in whichever orientation aligns better, and reports their
+// agreement over the whole construct and over each of its features. A
+// position agrees if any read matches it; otherwise the discrepancy
+// reported by the reads is preferred to an ambiguous call. Reads without
+// bases, such as those trimmed away completely, are ignored.
+func Verify(expected wtype.DNASequence, reads []wtype.DNASequence, criteria VerificationCriteria) (VerificationReport, error) {
+	report := VerificationReport{Expected: expected}
+	expected.Seq = strings.ToUpper(expected.Seq)
+	for i := range expected.Seq {
+		if !isBase(expected.Seq[i]) {
+			return report, fmt.Errorf("expected sequence %s has ambiguous base %c at position %d", expected.Nm, expected.Seq[i], i+1)
+		}
+	}
+	if criteria.Algorithm == nil {
+		criteria.Algorithm = align.Fitted
+	}
+
+	length := len(expected.Seq)
+	ev := make([]evidence, length+1)
+	for _, read := range reads {
+		if len(read.Seq) == 0 {
+			continue
+		}
+		ra, calls, err := orientRead(expected, read, criteria.Algorithm)
+		if err != nil {
+			return report, err
+		}
+		collectEvidence(ev, ra, calls)
+		report.Reads = append(report.Reads, ra)
+	}
+
+	whole := make([]int, length)
+	for i := range whole {
+		whole[i] = i + 1
+	}
+	// the end of a plasmid is joined to its start
+	report.Construct = regionReport(expected.Nm, whole, ev, expected.Plasmid)
+	report.Construct.Pass = report.Construct.passes(criteria)
+
+	checkAll := len(criteria.Features) == 0
+	checked := make(map[string]bool, len(criteria.Features))
+	for _, name := range criteria.Features {
+		checked[name] = false
+	}
+	report.Pass = true
+	for _, f := range expected.Features {
+		positions := featurePositions(f, length)
+		if positions == nil {
+			return report, fmt.Errorf("feature %s at %d..%d lies outside sequence %s", f.Name, f.StartPosition, f.EndPosition, expected.Nm)
+		}
+		r := regionReport(f.Name, positions, ev, false)
+		r.Pass = r.passes(criteria)
+		report.Features = append(report.Features, r)
+
+		if _, ok := checked[f.Name]; ok || checkAll {
+			checked[f.Name] = true
+			report.Pass = report.Pass && r.Pass
+		}
+	}
+	for name, found := range checked {
+		if !found {
+			return report, fmt.Errorf("feature %s not found in sequence %s", name, expected.Nm)
+		}
+	}
+	if len(checked) == 0 {
+		report.Pass = report.Construct.Pass
+	}
+
+	return report, nil
+}
+
+// VerifyTraces verifies a construct by Sanger sequencing, trimming the
+// traces to the minimum quality of the criteria before calling Verify
+func VerifyTraces(expected wtype.DNASequence, traces []trace.Trace, criteria VerificationCriteria) (VerificationReport, error) {
+	reads := make([]wtype.DNASequence, len(traces))
+	for i, t := range traces {
+		reads[i] = t.Trim(criteria.MinQuality).DNASequence()
+	}
+	return Verify(expected, reads, criteria)
+}
+
+// VerificationSummary tabulates verification reports with a row for each
+// construct followed by a row for each of its features, suitable for
+// export.CSV
+func VerificationSummary(reports []VerificationReport) [][]string {
+	records := [][]string{{"Construct", "Region", "Length", "Covered", "Coverage (%)", "Mismatches", "Insertions", "Deletions", "Ambiguous", "Pass", "Discrepancies"}}
+	row := func(construct string, r RegionReport) []string {
+		discrepancies := make([]string, len(r.Discrepancies))
+		for i, d := range r.Discrepancies {
+			discrepancies[i] = d.String()
+		}
+		return []string{
+			construct,
+			r.Name,
+			strconv.Itoa(r.Length),
+			strconv.Itoa(r.Covered),
+			strconv.FormatFloat(100.0*r.Coverage(), 'f', 1, 64),
+			strconv.Itoa(r.Count(Mismatch)),
+			strconv.Itoa(r.Count(Insertion)),
+			strconv.Itoa(r.Count(Deletion)),
+			strconv.Itoa(r.Count(Ambiguous)),
+			strconv.FormatBool(r.Pass),
+			strings.Join(discrepancies, " "),
+		}
+	}
+
+	for _, report := range reports {
+		construct := row(report.Expected.Nm, report.Construct)
+		// the construct row gives the verdict for the construct
+		construct[1], construct[9] = "", strconv.FormatBool(report.Pass)
+		records = append(records, construct)
+		for _, f := range report.Features {
+			records = append(records, row(report.Expected.Nm, f))
+		}
+	}
+	return records
+}
@@ -0,0 +1,224 @@
+package seqtools
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/align"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+func randomSeqForTest(n int, seed int64) string {
+	rng := rand.New(rand.NewSource(seed))
+	seq := make([]byte, n)
+	for i := range seq {
+		seq[i] = "ACGT"[rng.Intn(4)]
+	}
+	return string(seq)
+}
+
+// makeConstructForTest makes a 240 bp construct with a forward feature at
+// 21..80 and a reverse one at 101..160
+func makeConstructForTest() wtype.DNASequence {
+	construct := wtype.MakeLinearDNASequence("construct", randomSeqForTest(240, 1))
+	construct.Features = []wtype.Feature{
+		{Name: "promoter", Class: wtype.PROMOTER, StartPosition: 21, EndPosition: 80, DNASeq: construct.Seq[20:80]},
+		{Name: "cds", Class: "CDS", Reverse: true, StartPosition: 160, EndPosition: 101, DNASeq: wtype.RevComp(construct.Seq[100:160])},
+	}
+	return construct
+}
+
+// substitute replaces the base at a human friendly position
+func substitute(seq string, position int, base string) string {
+	return seq[:position-1] + base + seq[position:]
+}
+
+// makeAlignmentForTest makes the alignment of a read to the expected
+// sequence, given as aligned strings starting from a human friendly position
+func makeAlignmentForTest(template, query string, start int) ReadAlignment {
+	var positions []int
+	p := start
+	for _, t := range template {
+		positions = append(positions, p)
+		if t != align.GAP {
+			p++
+		}
+	}
+	return ReadAlignment{Alignment: align.Result{Alignment: align.Alignment{
+		TemplateResult:    template,
+		QueryResult:       query,
+		TemplatePositions: positions,
+	}}}
+}
+
+func TestCollectEvidence(t *testing.T) {
+	construct := makeConstructForTest()
+	seq := construct.Seq
+	ev := make([]evidence, len(seq)+1)
+
+	// a mismatch at 50 and an ambiguous call at 120, which the second read
+	// confirms, with an overhang which is not an insertion
+	forward := substitute(substitute(seq, 50, wtype.Comp(seq[49:50])), 120, "N")[:130]
+	calls := "TTT" + forward
+	collectEvidence(ev, makeAlignmentForTest("---"+seq[:130], "TTT"+strings.Replace(forward, "N", "A", 1), 1), calls)
+
+	// an ambiguous call at 140, position 150 deleted and a base inserted
+	// after 200
+	calls = substitute(seq, 140, "N")
+	calls = calls[90:149] + calls[150:200] + wtype.Comp(seq[199:200]) + calls[200:]
+	template := seq[90:200] + "-" + seq[200:]
+	query := strings.Replace(calls[:59]+"-"+calls[59:], "N", "A", 1)
+	collectEvidence(ev, makeAlignmentForTest(template, query, 91), calls)
+
+	discrepancies := []Discrepancy{
+		{Position: 50, Type: Mismatch, Expected: seq[49:50], Observed: wtype.Comp(seq[49:50])},
+		{Position: 140, Type: Ambiguous, Expected: seq[139:140], Observed: "N"},
+		{Position: 150, Type: Deletion, Expected: seq[149:150], Observed: "-"},
+		{Position: 200, Type: Insertion, Expected: "-", Observed: wtype.Comp(seq[199:200])},
+	}
+	whole := regionReport("construct", featurePositions(wtype.Feature{StartPosition: 1, EndPosition: len(seq)}, len(seq)), ev, false)
+	if e, g := discrepancies, whole.Discrepancies; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected discrepancies %v, got %v", e, g)
+	}
+	if e, g := 240, whole.Covered; e != g {
+		t.Errorf("expected %d positions covered, got %d", e, g)
+	}
+
+	criteria := DefaultVerificationCriteria()
+	promoter := regionReport("promoter", featurePositions(construct.Features[0], len(seq)), ev, false)
+	if promoter.Length != 60 || promoter.Count(Mismatch) != 1 || len(promoter.Discrepancies) != 1 || promoter.passes(criteria) {
+		t.Errorf("unexpected report of promoter: %+v", promoter)
+	}
+	cds := regionReport("cds", featurePositions(construct.Features[1], len(seq)), ev, false)
+	if cds.Length != 60 || cds.Count(Ambiguous) != 1 || cds.Count(Deletion) != 1 || cds.passes(criteria) {
+		t.Errorf("unexpected report of cds: %+v", cds)
+	}
+
+	criteria.MaxMismatches, criteria.MaxIndels, criteria.MaxAmbiguous = 1, 1, 1
+	if !promoter.passes(criteria) || !cds.passes(criteria) {
+		t.Error("expected features to pass lenient criteria")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	construct := makeConstructForTest()
+
+	// a mismatch at 50 in the promoter read forwards, and an ambiguous call
+	// at 140 in the cds and an insertion after 200 read in reverse
+	mismatch := "A"
+	if construct.Seq[49] == 'A' {
+		mismatch = "C"
+	}
+	forward := substitute(construct.Seq, 50, mismatch)[:130]
+	ambiguous := substitute(construct.Seq, 140, "N")
+	reverse := wtype.RevComp(ambiguous[110:200] + "GGG" + ambiguous[200:])
+	reads := []wtype.DNASequence{
+		wtype.MakeLinearDNASequence("forward", forward),
+		wtype.MakeLinearDNASequence("reverse", reverse),
+	}
+
+	report, err := Verify(construct, reads, DefaultVerificationCriteria())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, g := 2, len(report.Reads); e != g {
+		t.Fatalf("expected %d reads, got %d", e, g)
+	}
+	if report.Reads[0].Reverse || !report.Reads[1].Reverse {
+		t.Errorf("expected forward and reverse reads, got reverse %t and %t", report.Reads[0].Reverse, report.Reads[1].Reverse)
+	}
+	if e, g := 240, report.Construct.Covered; e != g {
+		t.Errorf("expected %d positions covered, got %d", e, g)
+	}
+	expected := []Discrepancy{
+		{Position: 50, Type: Mismatch, Expected: construct.Seq[49:50], Observed: mismatch},
+		{Position: 140, Type: Ambiguous, Expected: construct.Seq[139:140], Observed: "N"},
+		{Position: 200, Type: Insertion, Expected: "-", Observed: "GGG"},
+	}
+	if g := report.Construct.Discrepancies; !reflect.DeepEqual(expected, g) {
+		t.Errorf("expected discrepancies %v, got %v", expected, g)
+	}
+	if report.Construct.Pass || report.Pass {
+		t.Error("expected construct with discrepancies to fail")
+	}
+
+	if e, g := 2, len(report.Features); e != g {
+		t.Fatalf("expected %d features, got %d", e, g)
+	}
+	promoter, cds := report.Features[0], report.Features[1]
+	if promoter.Name != "promoter" || promoter.Length != 60 || promoter.Covered != 60 || promoter.Count(Mismatch) != 1 || len(promoter.Discrepancies) != 1 || promoter.Pass {
+		t.Errorf("expected promoter with one mismatch, got %+v", promoter)
+	}
+	if cds.Name != "cds" || cds.Length != 60 || cds.Covered != 60 || cds.Count(Ambiguous) != 1 || len(cds.Discrepancies) != 1 || cds.Pass {
+		t.Errorf("expected cds with one ambiguous call, got %+v", cds)
+	}
+
+	// the insertion lies outside the features, so checking a feature read
+	// without discrepancies passes
+	reads[0].Seq = construct.Seq[:130]
+	criteria := DefaultVerificationCriteria()
+	criteria.Features = []string{"promoter"}
+	if report, err := Verify(construct, reads, criteria); err != nil {
+		t.Error(err)
+	} else if !report.Pass || !report.Features[0].Pass || report.Features[1].Pass {
+		t.Errorf("expected only the promoter to pass, got %+v", report.Features)
+	}
+
+	failed := []wtype.DNASequence{wtype.MakeLinearDNASequence("failed", "")}
+	report, err = Verify(construct, failed, DefaultVerificationCriteria())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Reads) != 0 || report.Construct.Covered != 0 || len(report.Features) != 2 || report.Pass {
+		t.Errorf("expected construct without reads to fail, got %+v", report)
+	}
+
+	criteria.Features = []string{"missing"}
+	if _, err := Verify(construct, failed, criteria); err == nil {
+		t.Error("expected an error checking a missing feature")
+	}
+
+	construct.Seq = substitute(construct.Seq, 10, "N")
+	if _, err := Verify(construct, failed, DefaultVerificationCriteria()); err == nil {
+		t.Error("expected an error verifying an ambiguous sequence")
+	}
+}
+
+func TestVerificationSummary(t *testing.T) {
+	report := VerificationReport{
+		Expected:  wtype.MakeLinearDNASequence("construct", "ACGT"),
+		Construct: RegionReport{Length: 4, Covered: 3, Discrepancies: []Discrepancy{{Position: 2, Type: Mismatch, Expected: "C", Observed: "T"}}},
+		Features:  []RegionReport{{Name: "promoter", Length: 2, Covered: 2, Pass: true}},
+		Pass:      true,
+	}
+
+	summary := VerificationSummary([]VerificationReport{report})
+	if e, g := 3, len(summary); e != g {
+		t.Fatalf("expected %d rows, got %d", e, g)
+	}
+	if e, g := []string{"construct", "", "4", "3", "75.0", "1", "0", "0", "0", "true", "2C>T"}, summary[1]; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected summary %v, got %v", e, g)
+	}
+	if e, g := []string{"construct", "promoter", "2", "2", "100.0", "0", "0", "0", "0", "true", ""}, summary[2]; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected summary %v, got %v", e, g)
+	}
+}
+
+func TestFeaturePositions(t *testing.T) {
+	for _, test := range []struct {
+		Feature  wtype.Feature
+		Expected []int
+	}{
+		{wtype.Feature{StartPosition: 2, EndPosition: 4}, []int{2, 3, 4}},
+		{wtype.Feature{StartPosition: 4, EndPosition: 2, Reverse: true}, []int{2, 3, 4}},
+		{wtype.Feature{StartPosition: 9, EndPosition: 2}, []int{9, 10, 1, 2}},
+		{wtype.Feature{StartPosition: 2, EndPosition: 9, Reverse: true}, []int{9, 10, 1, 2}},
+		{wtype.Feature{StartPosition: 2, EndPosition: 11}, nil},
+	} {
+		if g := featurePositions(test.Feature, 10); !reflect.DeepEqual(test.Expected, g) {
+			t.Errorf("expected %v for feature %d..%d, got %v", test.Expected, test.Feature.StartPosition, test.Feature.EndPosition, g)
+		}
+	}
+}
@@ -1,5 +1,8 @@
 // package align allows aligning Antha sequences using the biogo implementation of the
-// Needleman-Wunsch and Smith-Waterman alignment algorithms
+// Needleman-Wunsch and Smith-Waterman alignment algorithms.
+// Protein and affine gap global, local and semi-global alignment against
+// substitution matrices such as BLOSUM62 is provided by Pairwise, and
+// progressive multiple sequence alignment by Multiple.
 package align
 
 import (
@@ -361,6 +364,12 @@ func DNA(seq1, seq2 wtype.DNASequence, alignmentMatrix ScoringMatrix) (alignment
 	return fwdResult, err
 }
 
+// ForwardDNA aligns the query sequence as given, without its reverse complement, against the template using a specified scoring algorithm.
+// Template positions of the resulting alignment are in the same order as its columns.
+func ForwardDNA(template, query wtype.DNASequence, alignmentMatrix ScoringMatrix) (Result, error) {
+	return dnaFWDAlignment(template, query, alignmentMatrix)
+}
+
 func dnaFWDAlignment(template, query wtype.DNASequence, alignmentMatrix ScoringMatrix) (alignment Result, err error) {
 
 	if containsN(template) {
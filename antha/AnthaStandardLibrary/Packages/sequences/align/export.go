@@ -0,0 +1,160 @@
+// antha/AnthaStandardLibrary/Packages/sequences/align/export.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package align
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// lineLength is the number of alignment columns written per line on export.
+const lineLength = 60
+
+// FASTA writes the aligned subjects of aln in FASTA format, gaps included.
+func FASTA(w io.Writer, aln wtype.SimpleAlignment) error {
+	for _, row := range aln {
+		if _, err := fmt.Fprintf(w, ">%s\n", row.Name); err != nil {
+			return err
+		}
+		for start := 0; start < len(row.Subject); start += lineLength {
+			end := start + lineLength
+			if end > len(row.Subject) {
+				end = len(row.Subject)
+			}
+			if _, err := fmt.Fprintln(w, row.Subject[start:end]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Clustal writes the aligned subjects of aln in Clustal format. Each block is
+// followed by a conservation line marking fully conserved columns with "*" and,
+// for proteins, columns conserved within a strong or weak group of residues
+// with ":" and "." respectively.
+func Clustal(w io.Writer, aln wtype.SimpleAlignment) error {
+	if _, err := fmt.Fprint(w, "CLUSTAL W multiple sequence alignment\n\n"); err != nil {
+		return err
+	}
+	if len(aln) == 0 {
+		return nil
+	}
+
+	length := len(aln[0].Subject)
+	width := 0
+	names := make([]string, len(aln))
+	for i, row := range aln {
+		if len(row.Subject) != length {
+			return fmt.Errorf("aligned sequence %s has length %d, expected %d", row.Name, len(row.Subject), length)
+		}
+		names[i] = strings.Replace(row.Name, " ", "_", -1)
+		if len(names[i]) > width {
+			width = len(names[i])
+		}
+	}
+	width += 6
+
+	protein := !isNucleotideAlignment(aln)
+	residues := make([]int, len(aln))
+
+	for start := 0; start < length; start += lineLength {
+		end := start + lineLength
+		if end > length {
+			end = length
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+		for i, row := range aln {
+			block := row.Subject[start:end]
+			residues[i] += len(block) - strings.Count(block, string(GAP))
+			if _, err := fmt.Fprintf(w, "%-*s%s %d\n", width, names[i], block, residues[i]); err != nil {
+				return err
+			}
+		}
+		marks := make([]byte, 0, end-start)
+		for c := start; c < end; c++ {
+			marks = append(marks, conservationMark(aln, c, protein))
+		}
+		if _, err := fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", width), strings.TrimRight(string(marks), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the Clustal strong and weak conservation groups
+var (
+	strongGroups = []string{"STA", "NEQK", "NHQK", "NDEQ", "QHRK", "MILV", "MILF", "HY", "FYW"}
+	weakGroups   = []string{"CSA", "ATV", "SAG", "STNK", "STPA", "SGND", "SNDEQK", "NDEQHK", "NEQHRK", "FVLIM", "HFY"}
+)
+
+func conservationMark(aln wtype.SimpleAlignment, column int, protein bool) byte {
+	residues := columnResidues(aln, column)
+	if residues == "" {
+		return ' '
+	}
+	if strings.Count(residues, residues[:1]) == len(residues) {
+		return '*'
+	}
+	if !protein {
+		return ' '
+	}
+	for _, g := range [...]struct {
+		groups []string
+		mark   byte
+	}{{strongGroups, ':'}, {weakGroups, '.'}} {
+		for _, group := range g.groups {
+			if strings.Trim(residues, group) == "" {
+				return g.mark
+			}
+		}
+	}
+	return ' '
+}
+
+// columnResidues returns the upper case residues of a column, or nothing if it contains a gap.
+func columnResidues(aln wtype.SimpleAlignment, column int) string {
+	residues := make([]byte, 0, len(aln))
+	for _, row := range aln {
+		r := row.Subject[column]
+		if isGap(rune(r)) {
+			return ""
+		}
+		residues = append(residues, r)
+	}
+	return strings.ToUpper(string(residues))
+}
+
+func isNucleotideAlignment(aln wtype.SimpleAlignment) bool {
+	for _, row := range aln {
+		if strings.Trim(strings.ToUpper(row.Subject), "ACGTUN-") != "" {
+			return false
+		}
+	}
+	return true
+}
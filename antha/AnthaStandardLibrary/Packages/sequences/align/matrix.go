@@ -0,0 +1,257 @@
+// antha/AnthaStandardLibrary/Packages/sequences/align/matrix.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package align
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SubstitutionMatrix scores the alignment of one residue against another.
+// Letters lists the residues in the order of the rows and columns of Scores.
+// Residues not in Letters are scored as X where the matrix defines it.
+type SubstitutionMatrix struct {
+	Name    string
+	Letters string
+	Scores  [][]int
+	index   [256]int
+	unknown int
+}
+
+// NewSubstitutionMatrix validates a square scoring matrix over letters.
+// Letters are case insensitive.
+func NewSubstitutionMatrix(name, letters string, scores [][]int) (*SubstitutionMatrix, error) {
+	letters = strings.ToUpper(letters)
+	if len(letters) == 0 {
+		return nil, fmt.Errorf("substitution matrix %s has no letters", name)
+	}
+	if len(scores) != len(letters) {
+		return nil, fmt.Errorf("substitution matrix %s has %d rows for %d letters", name, len(scores), len(letters))
+	}
+	m := &SubstitutionMatrix{Name: name, Letters: letters, Scores: scores, unknown: -1}
+	for i := range m.index {
+		m.index[i] = -1
+	}
+	for i := 0; i < len(letters); i++ {
+		if len(scores[i]) != len(letters) {
+			return nil, fmt.Errorf("substitution matrix %s row %c has %d columns for %d letters", name, letters[i], len(scores[i]), len(letters))
+		}
+		if m.index[letters[i]] != -1 {
+			return nil, fmt.Errorf("substitution matrix %s has letter %c more than once", name, letters[i])
+		}
+		m.index[letters[i]] = i
+		m.index[strings.ToLower(letters[i : i+1])[0]] = i
+	}
+	m.unknown = m.index['X']
+	return m, nil
+}
+
+// ParseSubstitutionMatrix reads a substitution matrix in the NCBI format,
+// a header line of letters followed by one row per letter with the row
+// letter in the first column. Lines starting with # are comments.
+func ParseSubstitutionMatrix(name string, r io.Reader) (*SubstitutionMatrix, error) {
+	var letters string
+	var scores [][]int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if letters == "" {
+			letters = strings.Join(fields, "")
+			if len(letters) != len(fields) {
+				return nil, fmt.Errorf("substitution matrix %s: header must list single letters", name)
+			}
+			continue
+		}
+		if len(fields[0]) != 1 || len(scores) >= len(letters) || fields[0][0] != letters[len(scores)] {
+			return nil, fmt.Errorf("substitution matrix %s: unexpected row %q", name, fields[0])
+		}
+		row := make([]int, 0, len(fields)-1)
+		for _, f := range fields[1:] {
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("substitution matrix %s row %s: %s", name, fields[0], err)
+			}
+			row = append(row, v)
+		}
+		scores = append(scores, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewSubstitutionMatrix(name, letters, scores)
+}
+
+// Score returns the score for aligning residue a against residue b.
+// Residues the matrix cannot score are given its lowest score.
+func (m *SubstitutionMatrix) Score(a, b byte) int {
+	i, j := m.lookup(a), m.lookup(b)
+	if i < 0 || j < 0 {
+		return m.min()
+	}
+	return m.Scores[i][j]
+}
+
+// Covers reports whether the matrix can score residue a, either directly
+// or as an unknown residue.
+func (m *SubstitutionMatrix) Covers(a byte) bool {
+	return m.lookup(a) >= 0
+}
+
+func (m *SubstitutionMatrix) lookup(a byte) int {
+	if i := m.index[a]; i >= 0 {
+		return i
+	}
+	return m.unknown
+}
+
+func (m *SubstitutionMatrix) min() int {
+	min := m.Scores[0][0]
+	for _, row := range m.Scores {
+		for _, v := range row {
+			if v < min {
+				min = v
+			}
+		}
+	}
+	return min
+}
+
+func mustParseMatrix(name, table string) *SubstitutionMatrix {
+	m, err := ParseSubstitutionMatrix(name, strings.NewReader(table))
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+var (
+	// BLOSUM62 is the BLOSUM62 protein substitution matrix (Henikoff & Henikoff 1992),
+	// the default matrix of protein BLAST.
+	BLOSUM62 = mustParseMatrix("BLOSUM62", blosum62)
+
+	// PAM250 is the Dayhoff PAM250 protein substitution matrix, suited to
+	// distantly related proteins.
+	PAM250 = mustParseMatrix("PAM250", pam250)
+
+	// DNAFull is a nucleotide substitution matrix scoring matches +5,
+	// mismatches -4 and any alignment with N -2.
+	DNAFull = mustParseMatrix("DNAFull", dnaFull)
+)
+
+// SubstitutionMatrices provides a map to lookup substitution matrices based on names.
+var SubstitutionMatrices = map[string]*SubstitutionMatrix{
+	"BLOSUM62": BLOSUM62,
+	"PAM250":   PAM250,
+	"DNAFull":  DNAFull,
+}
+
+// LookupSubstitutionMatrix returns the built in substitution matrix with the given name.
+func LookupSubstitutionMatrix(name string) (*SubstitutionMatrix, error) {
+	for key, m := range SubstitutionMatrices {
+		if strings.EqualFold(key, name) {
+			return m, nil
+		}
+	}
+	var valid []string
+	for key := range SubstitutionMatrices {
+		valid = append(valid, key)
+	}
+	sort.Strings(valid)
+	return nil, fmt.Errorf("no substitution matrix %q found: valid options are %s", name, strings.Join(valid, ", "))
+}
+
+const blosum62 = `
+#  Matrix made by matblas from blosum62.iij
+   A  R  N  D  C  Q  E  G  H  I  L  K  M  F  P  S  T  W  Y  V  B  Z  X  *
+A  4 -1 -2 -2  0 -1 -1  0 -2 -1 -1 -1 -1 -2 -1  1  0 -3 -2  0 -2 -1  0 -4
+R -1  5  0 -2 -3  1  0 -2  0 -3 -2  2 -1 -3 -2 -1 -1 -3 -2 -3 -1  0 -1 -4
+N -2  0  6  1 -3  0  0  0  1 -3 -3  0 -2 -3 -2  1  0 -4 -2 -3  3  0 -1 -4
+D -2 -2  1  6 -3  0  2 -1 -1 -3 -4 -1 -3 -3 -1  0 -1 -4 -3 -3  4  1 -1 -4
+C  0 -3 -3 -3  9 -3 -4 -3 -3 -1 -1 -3 -1 -2 -3 -1 -1 -2 -2 -1 -3 -3 -2 -4
+Q -1  1  0  0 -3  5  2 -2  0 -3 -2  1  0 -3 -1  0 -1 -2 -1 -2  0  3 -1 -4
+E -1  0  0  2 -4  2  5 -2  0 -3 -3  1 -2 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+G  0 -2  0 -1 -3 -2 -2  6 -2 -4 -4 -2 -3 -3 -2  0 -2 -2 -3 -3 -1 -2 -1 -4
+H -2  0  1 -1 -3  0  0 -2  8 -3 -3 -1 -2 -1 -2 -1 -2 -2  2 -3  0  0 -1 -4
+I -1 -3 -3 -3 -1 -3 -3 -4 -3  4  2 -3  1  0 -3 -2 -1 -3 -1  3 -3 -3 -1 -4
+L -1 -2 -3 -4 -1 -2 -3 -4 -3  2  4 -2  2  0 -3 -2 -1 -2 -1  1 -4 -3 -1 -4
+K -1  2  0 -1 -3  1  1 -2 -1 -3 -2  5 -1 -3 -1  0 -1 -3 -2 -2  0  1 -1 -4
+M -1 -1 -2 -3 -1  0 -2 -3 -2  1  2 -1  5  0 -2 -1 -1 -1 -1  1 -3 -1 -1 -4
+F -2 -3 -3 -3 -2 -3 -3 -3 -1  0  0 -3  0  6 -4 -2 -2  1  3 -1 -3 -3 -1 -4
+P -1 -2 -2 -1 -3 -1 -1 -2 -2 -3 -3 -1 -2 -4  7 -1 -1 -4 -3 -2 -2 -1 -2 -4
+S  1 -1  1  0 -1  0  0  0 -1 -2 -2  0 -1 -2 -1  4  1 -3 -2 -2  0  0  0 -4
+T  0 -1  0 -1 -1 -1 -1 -2 -2 -1 -1 -1 -1 -2 -1  1  5 -2 -2  0 -1 -1  0 -4
+W -3 -3 -4 -4 -2 -2 -3 -2 -2 -3 -2 -3 -1  1 -4 -3 -2 11  2 -3 -4 -3 -2 -4
+Y -2 -2 -2 -3 -2 -1 -2 -3  2 -1 -1 -2 -1  3 -3 -2 -2  2  7 -1 -3 -2 -1 -4
+V  0 -3 -3 -3 -1 -2 -2 -3 -3  3  1 -2  1 -1 -2 -2  0 -3 -1  4 -3 -2 -1 -4
+B -2 -1  3  4 -3  0  1 -1  0 -3 -4  0 -3 -3 -2  0 -1 -4 -3 -3  4  1 -1 -4
+Z -1  0  0  1 -3  3  4 -2  0 -3 -3  1 -1 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+X  0 -1 -1 -1 -2 -1 -1 -1 -1 -1 -1 -1 -1 -1 -2  0  0 -2 -1 -1 -1 -1 -1 -4
+* -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4  1
+`
+
+const pam250 = `
+   A  R  N  D  C  Q  E  G  H  I  L  K  M  F  P  S  T  W  Y  V  B  Z  X  *
+A  2 -2  0  0 -2  0  0  1 -1 -1 -2 -1 -1 -3  1  1  1 -6 -3  0  0  0  0 -8
+R -2  6  0 -1 -4  1 -1 -3  2 -2 -3  3  0 -4  0  0 -1  2 -4 -2 -1  0 -1 -8
+N  0  0  2  2 -4  1  1  0  2 -2 -3  1 -2 -3  0  1  0 -4 -2 -2  2  1  0 -8
+D  0 -1  2  4 -5  2  3  1  1 -2 -4  0 -3 -6 -1  0  0 -7 -4 -2  3  3 -1 -8
+C -2 -4 -4 -5 12 -5 -5 -3 -3 -2 -6 -5 -5 -4 -3  0 -2 -8  0 -2 -4 -5 -3 -8
+Q  0  1  1  2 -5  4  2 -1  3 -2 -2  1 -1 -5  0 -1 -1 -5 -4 -2  1  3 -1 -8
+E  0 -1  1  3 -5  2  4  0  1 -2 -3  0 -2 -5 -1  0  0 -7 -4 -2  3  3 -1 -8
+G  1 -3  0  1 -3 -1  0  5 -2 -3 -4 -2 -3 -5  0  1  0 -7 -5 -1  0  0 -1 -8
+H -1  2  2  1 -3  3  1 -2  6 -2 -2  0 -2 -2  0 -1 -1 -3  0 -2  1  2 -1 -8
+I -1 -2 -2 -2 -2 -2 -2 -3 -2  5  2 -2  2  1 -2 -1  0 -5 -1  4 -2 -2 -1 -8
+L -2 -3 -3 -4 -6 -2 -3 -4 -2  2  6 -3  4  2 -3 -3 -2 -2 -1  2 -3 -3 -1 -8
+K -1  3  1  0 -5  1  0 -2  0 -2 -3  5  0 -5 -1  0  0 -3 -4 -2  1  0 -1 -8
+M -1  0 -2 -3 -5 -1 -2 -3 -2  2  4  0  6  0 -2 -2 -1 -4 -2  2 -2 -2 -1 -8
+F -3 -4 -3 -6 -4 -5 -5 -5 -2  1  2 -5  0  9 -5 -3 -3  0  7 -1 -4 -5 -2 -8
+P  1  0  0 -1 -3  0 -1  0  0 -2 -3 -1 -2 -5  6  1  0 -6 -5 -1 -1  0 -1 -8
+S  1  0  1  0  0 -1  0  1 -1 -1 -3  0 -2 -3  1  2  1 -2 -3 -1  0  0  0 -8
+T  1 -1  0  0 -2 -1  0  0 -1  0 -2  0 -1 -3  0  1  3 -5 -3  0  0 -1  0 -8
+W -6  2 -4 -7 -8 -5 -7 -7 -3 -5 -2 -3 -4  0 -6 -2 -5 17  0 -6 -5 -6 -4 -8
+Y -3 -4 -2 -4  0 -4 -4 -5  0 -1 -1 -4 -2  7 -5 -3 -3  0 10 -2 -3 -4 -2 -8
+V  0 -2 -2 -2 -2 -2 -2 -1 -2  4  2 -2  2 -1 -1 -1  0 -6 -2  4 -2 -2 -1 -8
+B  0 -1  2  3 -4  1  3  0  1 -2 -3  1 -2 -4 -1  0  0 -5 -3 -2  3  2 -1 -8
+Z  0  0  1  3 -5  3  3  0  2 -2 -3  0 -2 -5  0  0 -1 -6 -4 -2  2  3 -1 -8
+X  0 -1  0 -1 -3 -1 -1 -1 -1 -1 -1 -1 -1 -2 -1  0  0 -4 -2 -1 -1 -1 -1 -8
+* -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8  1
+`
+
+const dnaFull = `
+   A  C  G  T  U  N
+A  5 -4 -4 -4 -4 -2
+C -4  5 -4 -4 -4 -2
+G -4 -4  5 -4 -4 -2
+T -4 -4 -4  5  5 -2
+U -4 -4 -4  5  5 -2
+N -2 -2 -2 -2 -2 -1
+`
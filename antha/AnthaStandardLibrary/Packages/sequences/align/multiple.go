@@ -0,0 +1,305 @@
+// antha/AnthaStandardLibrary/Packages/sequences/align/multiple.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package align
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// KmerDistance returns the fractional common k-mer distance between a and b:
+// one minus the number of k-mers shared between the two sequences divided by
+// the number of k-mers in the shorter sequence.
+// A value between 0 (identical k-mer content) and 1 (no k-mers in common) is returned.
+func KmerDistance(a, b string, k int) float64 {
+	a, b = strings.ToUpper(a), strings.ToUpper(b)
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	if k > len(a) {
+		k = len(a)
+	}
+	if k < 1 {
+		return 1
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i+k <= len(a); i++ {
+		counts[a[i:i+k]]++
+	}
+	var shared int
+	for i := 0; i+k <= len(b); i++ {
+		if counts[b[i:i+k]] > 0 {
+			counts[b[i:i+k]]--
+			shared++
+		}
+	}
+	return 1 - float64(shared)/float64(len(a)-k+1)
+}
+
+// GuideTree clusters the sequences by UPGMA on their pairwise k-mer distances.
+// The leaves of the returned tree are named after the sequences and have the
+// index of the sequence in seqs as their UID; Depth is the distance in nodes from the root.
+func GuideTree(seqs []Sequence, k int) (*wtype.TOL, error) {
+	if len(seqs) == 0 {
+		return nil, fmt.Errorf("no sequences to build a guide tree from")
+	}
+
+	type cluster struct {
+		node *wtype.TOL
+		size int
+	}
+
+	clusters := make([]cluster, len(seqs))
+	dist := make([][]float64, len(seqs))
+	for i, seq := range seqs {
+		clusters[i] = cluster{node: &wtype.TOL{UID: strconv.Itoa(i), Name: seq.Name()}, size: 1}
+		dist[i] = make([]float64, len(seqs))
+		for j := 0; j < i; j++ {
+			dist[i][j] = KmerDistance(seq.Sequence(), seqs[j].Sequence(), k)
+			dist[j][i] = dist[i][j]
+		}
+	}
+
+	// active lists the clusters still to be joined; joined clusters replace the first of the pair
+	active := make([]int, len(seqs))
+	for i := range active {
+		active[i] = i
+	}
+
+	for len(active) > 1 {
+		bi, bj := 0, 1
+		for i := 0; i < len(active); i++ {
+			for j := i + 1; j < len(active); j++ {
+				if dist[active[i]][active[j]] < dist[active[bi]][active[bj]] {
+					bi, bj = i, j
+				}
+			}
+		}
+		a, b := active[bi], active[bj]
+
+		parent := &wtype.TOL{UID: fmt.Sprintf("node%d", len(seqs)-len(active)), Children: []*wtype.TOL{clusters[a].node, clusters[b].node}}
+		clusters[a].node.Parent = parent
+		clusters[b].node.Parent = parent
+
+		for _, c := range active {
+			if c == a || c == b {
+				continue
+			}
+			d := (dist[a][c]*float64(clusters[a].size) + dist[b][c]*float64(clusters[b].size)) / float64(clusters[a].size+clusters[b].size)
+			dist[a][c], dist[c][a] = d, d
+		}
+		clusters[a] = cluster{node: parent, size: clusters[a].size + clusters[b].size}
+		active = append(active[:bj], active[bj+1:]...)
+	}
+
+	root := clusters[active[0]].node
+	setDepth(root, 0)
+	return root, nil
+}
+
+func setDepth(node *wtype.TOL, depth int) {
+	node.Depth = depth
+	for _, child := range node.Children {
+		setDepth(child, depth+1)
+	}
+}
+
+// kmerSize returns the k-mer length used to build guide trees with the given matrix.
+func kmerSize(m *SubstitutionMatrix) int {
+	if len(m.Letters) > 10 {
+		return 3
+	}
+	return 6
+}
+
+// Multiple progressively aligns the sequences: a guide tree is built from
+// k-mer distances by GuideTree and the sequences are then joined by affine gap
+// global profile alignment, from the leaves of the tree to its root.
+// Profiles are scored by the average substitution score of all pairs of residues in two columns,
+// with pairs involving a gap scoring zero.
+//
+// The alignment is returned in the order of seqs. Each row has the sequence
+// name in Name, the aligned sequence in Subject and, as Score, the fraction of
+// the alignment's columns in which the sequence matches the consensus.
+func Multiple(seqs []Sequence, scoring Scoring) (wtype.SimpleAlignment, error) {
+	if err := scoring.validate(); err != nil {
+		return nil, err
+	}
+	if len(seqs) == 0 {
+		return nil, fmt.Errorf("no sequences to align")
+	}
+
+	residues := make([]string, len(seqs))
+	for i, seq := range seqs {
+		residues[i] = strings.ToUpper(seq.Sequence())
+		if err := scoring.checkResidues(seq.Name(), residues[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	tree, err := GuideTree(seqs, kmerSize(scoring.Matrix))
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := alignNode(tree, residues, scoring)
+	if err != nil {
+		return nil, err
+	}
+
+	aln := make(wtype.SimpleAlignment, len(seqs))
+	for row, index := range root.members {
+		aln[index] = wtype.AlignedBioSequence{Name: seqs[index].Name(), Subject: root.rows[row]}
+	}
+
+	consensus := aln.Consensus()
+	for i := range aln {
+		var matches int
+		for c := 0; c < len(consensus); c++ {
+			if aln[i].Subject[c] == consensus[c] && !isGap(rune(consensus[c])) {
+				matches++
+			}
+		}
+		aln[i].Score = float64(matches) / float64(len(consensus))
+	}
+
+	return aln, nil
+}
+
+// MultipleProtein progressively aligns protein sequences; see Multiple.
+func MultipleProtein(seqs []wtype.ProteinSequence, scoring Scoring) (wtype.SimpleAlignment, error) {
+	var s []Sequence
+	for i := range seqs {
+		s = append(s, &seqs[i])
+	}
+	return Multiple(s, scoring)
+}
+
+// profile is a set of aligned sequences: members are the indices of the
+// sequences and rows the aligned sequences.
+type profile struct {
+	members []int
+	rows    []string
+}
+
+func (p profile) length() int {
+	return len(p.rows[0])
+}
+
+// frequencies returns, for each column, the number of each residue of the matrix alphabet.
+func (p profile) frequencies(m *SubstitutionMatrix) [][]float64 {
+	freqs := make([][]float64, p.length())
+	for c := range freqs {
+		freqs[c] = make([]float64, len(m.Letters))
+		for _, row := range p.rows {
+			if !isGap(rune(row[c])) {
+				freqs[c][m.lookup(row[c])]++
+			}
+		}
+	}
+	return freqs
+}
+
+func alignNode(node *wtype.TOL, residues []string, scoring Scoring) (profile, error) {
+	if len(node.Children) == 0 {
+		index, err := strconv.Atoi(node.UID)
+		if err != nil || index < 0 || index >= len(residues) {
+			return profile{}, fmt.Errorf("guide tree leaf %s does not refer to a sequence", node.UID)
+		}
+		return profile{members: []int{index}, rows: []string{residues[index]}}, nil
+	}
+
+	merged, err := alignNode(node.Children[0], residues, scoring)
+	if err != nil {
+		return profile{}, err
+	}
+	for _, child := range node.Children[1:] {
+		next, err := alignNode(child, residues, scoring)
+		if err != nil {
+			return profile{}, err
+		}
+		merged = alignProfiles(merged, next, scoring)
+	}
+	return merged, nil
+}
+
+// alignProfiles aligns profile b against profile a, keeping the columns of each intact.
+func alignProfiles(a, b profile, scoring Scoring) profile {
+	m := scoring.Matrix
+	fa, fb := a.frequencies(m), b.frequencies(m)
+	pairs := float64(len(a.rows) * len(b.rows))
+
+	// weighted[c][l] is the summed score of residue l against column c of a
+	weighted := make([][]float64, len(fa))
+	for c, counts := range fa {
+		weighted[c] = make([]float64, len(m.Letters))
+		for l := range m.Letters {
+			for r, n := range counts {
+				weighted[c][l] += n * float64(m.Scores[r][l])
+			}
+		}
+	}
+
+	p := affine(a.length(), b.length(), func(i, j int) float64 {
+		var score float64
+		for l, n := range fb[j] {
+			score += n * weighted[i][l]
+		}
+		return score / pairs
+	}, scoring.Gap, Global)
+
+	rows := make([]strings.Builder, len(a.rows)+len(b.rows))
+	i, j := 0, 0
+	for _, op := range p.ops {
+		for r, row := range a.rows {
+			if op == opInsert {
+				rows[r].WriteRune(GAP)
+			} else {
+				rows[r].WriteByte(row[i])
+			}
+		}
+		for r, row := range b.rows {
+			if op == opDelete {
+				rows[len(a.rows)+r].WriteRune(GAP)
+			} else {
+				rows[len(a.rows)+r].WriteByte(row[j])
+			}
+		}
+		if op != opInsert {
+			i++
+		}
+		if op != opDelete {
+			j++
+		}
+	}
+
+	merged := profile{members: append(append([]int{}, a.members...), b.members...)}
+	for r := range rows {
+		merged.rows = append(merged.rows, rows[r].String())
+	}
+	return merged
+}
@@ -0,0 +1,127 @@
+package align
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+var globins = []wtype.ProteinSequence{
+	{Nm: "human", Seq: "MVLSPADKTNVKAAWGKVGAHAGEYGAEALERMFLSFPTTKTYFPHF"},
+	{Nm: "horse", Seq: "MVLSGEDKSNVKAAWGKVGGHAAEYGAEALERMFLGFPTTKTYFPHF"},
+	{Nm: "whale", Seq: "MVLSEGEWQLVLHVWAKVEADVAGHGQDILIRLFKSHPETLEKF"},
+	{Nm: "lamprey", Seq: "MPIVDTGSVAPLSAAEKTKIRSAWAPVYSTYETSGVDILVKFFTSTPAAQEFFPKF"},
+}
+
+func TestGuideTree(t *testing.T) {
+	var seqs []Sequence
+	for i := range globins {
+		seqs = append(seqs, &globins[i])
+	}
+	tree, err := GuideTree(seqs, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var newick func(n *wtype.TOL) string
+	newick = func(n *wtype.TOL) string {
+		if len(n.Children) == 0 {
+			return n.Name
+		}
+		var children []string
+		for _, c := range n.Children {
+			if c.Parent != n || c.Depth != n.Depth+1 {
+				t.Errorf("node %s: inconsistent parent or depth", c.UID)
+			}
+			children = append(children, newick(c))
+		}
+		return "(" + strings.Join(children, ",") + ")"
+	}
+
+	if expected := "(((human,horse),whale),lamprey)"; newick(tree) != expected {
+		t.Errorf("expected guide tree %s, got %s", expected, newick(tree))
+	}
+
+	if d := KmerDistance("ACGTACGT", "acgtacgt", 3); d != 0 {
+		t.Errorf("expected identical sequences to have distance 0, got %v", d)
+	}
+	if d := KmerDistance("AAAAAA", "CCCCCC", 3); d != 1 {
+		t.Errorf("expected unrelated sequences to have distance 1, got %v", d)
+	}
+}
+
+func TestMultiple(t *testing.T) {
+	aln, err := MultipleProtein(globins, DefaultProteinScoring())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aln) != len(globins) {
+		t.Fatalf("expected %d rows, got %d", len(globins), len(aln))
+	}
+	for i, row := range aln {
+		if row.Name != globins[i].Nm {
+			t.Errorf("row %d: expected %s, got %s", i, globins[i].Nm, row.Name)
+		}
+		if len(row.Subject) != len(aln[0].Subject) {
+			t.Errorf("%s: aligned length %d differs from %d", row.Name, len(row.Subject), len(aln[0].Subject))
+		}
+		if ungapped := strings.Replace(row.Subject, "-", "", -1); ungapped != globins[i].Seq {
+			t.Errorf("%s: aligned sequence %s does not match input", row.Name, ungapped)
+		}
+	}
+	if expected := "M---------VLSPADKTNVKAAWGKVGAHAGEYGAEALERMFLSFPTTKTYFPHF"; aln[0].Subject != expected {
+		t.Errorf("expected human row %s, got %s", expected, aln[0].Subject)
+	}
+
+	if _, err := Multiple(nil, DefaultProteinScoring()); err == nil {
+		t.Error("expected error aligning no sequences")
+	}
+}
+
+var testAlignment = wtype.SimpleAlignment{
+	{Name: "seq 1", Subject: "MKV-LA"},
+	{Name: "seq2", Subject: "MRV-LS"},
+	{Name: "seq3", Subject: "MKIGL-"},
+}
+
+func TestConsensus(t *testing.T) {
+	if c := testAlignment.Consensus(); c != "MKV-LA" {
+		t.Errorf("expected consensus MKV-LA, got %s", c)
+	}
+	conservation := testAlignment.Conservation()
+	expected := []float64{1, 2.0 / 3.0, 2.0 / 3.0, 1.0 / 3.0, 1, 1.0 / 3.0}
+	for i := range expected {
+		if conservation[i] != expected[i] {
+			t.Errorf("column %d: expected conservation %v, got %v", i, expected[i], conservation[i])
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	var clustal bytes.Buffer
+	if err := Clustal(&clustal, testAlignment); err != nil {
+		t.Fatal(err)
+	}
+	expected := `CLUSTAL W multiple sequence alignment
+
+
+seq_1      MKV-LA 5
+seq2       MRV-LS 5
+seq3       MKIGL- 5
+           *:: *
+`
+	if clustal.String() != expected {
+		t.Errorf("expected Clustal\n%s got\n%s", expected, clustal.String())
+	}
+
+	var fasta bytes.Buffer
+	if err := FASTA(&fasta, testAlignment); err != nil {
+		t.Fatal(err)
+	}
+	expected = ">seq 1\nMKV-LA\n>seq2\nMRV-LS\n>seq3\nMKIGL-\n"
+	if fasta.String() != expected {
+		t.Errorf("expected FASTA\n%s got\n%s", expected, fasta.String())
+	}
+}
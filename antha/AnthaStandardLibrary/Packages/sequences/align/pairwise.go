@@ -0,0 +1,440 @@
+// antha/AnthaStandardLibrary/Packages/sequences/align/pairwise.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package align
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// Mode selects which parts of the two sequences must be aligned.
+type Mode int
+
+const (
+	// Global aligns both sequences end to end (Needleman-Wunsch).
+	Global Mode = iota
+	// Local aligns the highest scoring pair of subsequences (Smith-Waterman).
+	Local
+	// SemiGlobal aligns both sequences end to end but does not penalise
+	// gaps at either end, so one sequence may overhang the other.
+	SemiGlobal
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Global:
+		return "Global"
+	case Local:
+		return "Local"
+	case SemiGlobal:
+		return "SemiGlobal"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// GapPenalty is an affine gap penalty: a gap of length n costs Open + n*Extend.
+// Both values are given as positive costs.
+type GapPenalty struct {
+	Open   int
+	Extend int
+}
+
+func (g GapPenalty) cost(n int) float64 {
+	return float64(g.Open + n*g.Extend)
+}
+
+// Scoring combines a substitution matrix with an affine gap penalty.
+type Scoring struct {
+	Matrix *SubstitutionMatrix
+	Gap    GapPenalty
+}
+
+// DefaultProteinScoring is BLOSUM62 with a gap open cost of 11 and extension cost of 1,
+// as used by protein BLAST.
+func DefaultProteinScoring() Scoring {
+	return Scoring{Matrix: BLOSUM62, Gap: GapPenalty{Open: 11, Extend: 1}}
+}
+
+// DefaultDNAScoring is DNAFull with a gap open cost of 10 and extension cost of 1.
+func DefaultDNAScoring() Scoring {
+	return Scoring{Matrix: DNAFull, Gap: GapPenalty{Open: 10, Extend: 1}}
+}
+
+func (s Scoring) validate() error {
+	if s.Matrix == nil {
+		return fmt.Errorf("no substitution matrix specified")
+	}
+	if s.Gap.Open < 0 || s.Gap.Extend < 0 {
+		return fmt.Errorf("gap penalties must not be negative: open %d, extend %d", s.Gap.Open, s.Gap.Extend)
+	}
+	return nil
+}
+
+// checkResidues returns an error if seq contains residues the matrix cannot score.
+func (s Scoring) checkResidues(name, seq string) error {
+	if len(seq) == 0 {
+		return fmt.Errorf("sequence %s is empty", name)
+	}
+	for i := 0; i < len(seq); i++ {
+		if !s.Matrix.Covers(seq[i]) {
+			return fmt.Errorf("residue %q at position %d of %s cannot be scored by %s", seq[i], i+1, name, s.Matrix.Name)
+		}
+	}
+	return nil
+}
+
+// Sequence is a named sequence of residues. It is satisfied by wtype.BioSequence
+// implementations and by *wtype.ProteinSequence.
+type Sequence interface {
+	Name() string
+	Sequence() string
+}
+
+// PairwiseResult stores the alignment of a query against a template
+// produced by Pairwise or Protein.
+type PairwiseResult struct {
+	Template  string
+	Query     string
+	Mode      Mode
+	Scoring   Scoring
+	Score     float64
+	Alignment Alignment
+}
+
+// String prints alignment result in form of two aligned sequence strings printed on parallel lines.
+func (r PairwiseResult) String() string {
+	return fmt.Sprintf("%s\n%s\n", r.Alignment.TemplateResult, r.Alignment.QueryResult)
+}
+
+// Identity returns the fraction of alignment columns in which template and query residues are identical.
+// A value between 0 and 1 is returned.
+func (r PairwiseResult) Identity() float64 {
+	return r.fraction(func(a, b byte) bool {
+		return strings.EqualFold(string(a), string(b))
+	})
+}
+
+// Similarity returns the fraction of alignment columns in which the template and query residues
+// have a positive substitution score.
+// A value between 0 and 1 is returned.
+func (r PairwiseResult) Similarity() float64 {
+	return r.fraction(func(a, b byte) bool {
+		return r.Scoring.Matrix.Score(a, b) > 0
+	})
+}
+
+func (r PairwiseResult) fraction(match func(a, b byte) bool) float64 {
+	t, q := r.Alignment.TemplateResult, r.Alignment.QueryResult
+	if len(t) == 0 {
+		return 0
+	}
+	var count int
+	for i := 0; i < len(t); i++ {
+		if !isGap(rune(t[i])) && !isGap(rune(q[i])) && match(t[i], q[i]) {
+			count++
+		}
+	}
+	return float64(count) / float64(len(t))
+}
+
+// Protein aligns two protein sequences using an affine gap aligner.
+// DefaultProteinScoring, or Scoring using BLOSUM62 or PAM250 with a chosen gap penalty, are appropriate.
+// In the resulting alignment, mismatches are represented by lower case letters, gaps represented by the GAP character "-".
+func Protein(template, query wtype.ProteinSequence, scoring Scoring, mode Mode) (PairwiseResult, error) {
+	return Pairwise(&template, &query, scoring, mode)
+}
+
+// Pairwise aligns query against template in the given mode using an affine gap aligner,
+// so that a gap costs Open + length*Extend.
+// Unlike DNA, which uses the biogo aligners, any alphabet covered by the substitution matrix may be aligned
+// and the reverse complement of the query is not considered.
+// In the resulting alignment, mismatches are represented by lower case letters, gaps represented by the GAP character "-".
+// An error is returned if a sequence is empty, contains residues the matrix cannot score or,
+// in Local mode, if no pair of residues scores above zero.
+func Pairwise(template, query Sequence, scoring Scoring, mode Mode) (PairwiseResult, error) {
+	if err := scoring.validate(); err != nil {
+		return PairwiseResult{}, err
+	}
+	t := strings.ToUpper(template.Sequence())
+	q := strings.ToUpper(query.Sequence())
+	if err := scoring.checkResidues(template.Name(), t); err != nil {
+		return PairwiseResult{}, err
+	}
+	if err := scoring.checkResidues(query.Name(), q); err != nil {
+		return PairwiseResult{}, err
+	}
+
+	p := affine(len(t), len(q), func(i, j int) float64 {
+		return float64(scoring.Matrix.Score(t[i], q[j]))
+	}, scoring.Gap, mode)
+
+	if len(p.ops) == 0 {
+		return PairwiseResult{}, fmt.Errorf("no local alignment found between %s and %s", template.Name(), query.Name())
+	}
+
+	return PairwiseResult{
+		Template:  template.Name(),
+		Query:     query.Name(),
+		Mode:      mode,
+		Scoring:   scoring,
+		Score:     p.score,
+		Alignment: formatMisMatches(p.alignment(t, q)),
+	}, nil
+}
+
+// alignment operations: a template residue against a query residue,
+// a template residue against a gap and a query residue against a gap.
+const (
+	opMatch byte = iota
+	opDelete
+	opInsert
+	opStart
+)
+
+// path is the result of an affine alignment: the operations aligning
+// template[tStart:] against query[qStart:], and its score.
+type path struct {
+	ops            []byte
+	tStart, qStart int
+	score          float64
+}
+
+// affine aligns a template of length n against a query of length m using
+// Gotoh's algorithm, where score(i, j) scores template residue i against query residue j.
+func affine(n, m int, score func(i, j int) float64, gap GapPenalty, mode Mode) path {
+	neg := math.Inf(-1)
+	width := m + 1
+	size := (n + 1) * width
+
+	// the best scores of alignments of template[:i] and query[:j] ending in
+	// a match (mat), a template residue against a gap (del) or a query residue against a gap (ins),
+	// and the state each was reached from
+	mat, del, ins := make([]float64, size), make([]float64, size), make([]float64, size)
+	matFrom, delFrom, insFrom := make([]byte, size), make([]byte, size), make([]byte, size)
+
+	for k := range mat {
+		mat[k], del[k], ins[k] = neg, neg, neg
+	}
+	if mode != Local {
+		mat[0] = 0
+		for i := 1; i <= n; i++ {
+			if mode == Global {
+				del[i*width] = -gap.cost(i)
+			} else {
+				del[i*width] = 0
+			}
+		}
+		for j := 1; j <= m; j++ {
+			if mode == Global {
+				ins[j] = -gap.cost(j)
+			} else {
+				ins[j] = 0
+			}
+		}
+	}
+
+	open, extend := gap.cost(1), float64(gap.Extend)
+
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			k := i*width + j
+
+			diag := k - width - 1
+			best, from := mat[diag], opMatch
+			if del[diag] > best {
+				best, from = del[diag], opDelete
+			}
+			if ins[diag] > best {
+				best, from = ins[diag], opInsert
+			}
+			if mode == Local && best <= 0 {
+				best, from = 0, opStart
+			}
+			mat[k], matFrom[k] = best+score(i-1, j-1), from
+
+			up := k - width
+			best, from = mat[up]-open, opMatch
+			if del[up]-extend > best {
+				best, from = del[up]-extend, opDelete
+			}
+			if ins[up]-open > best {
+				best, from = ins[up]-open, opInsert
+			}
+			del[k], delFrom[k] = best, from
+
+			left := k - 1
+			best, from = mat[left]-open, opMatch
+			if del[left]-open > best {
+				best, from = del[left]-open, opDelete
+			}
+			if ins[left]-extend > best {
+				best, from = ins[left]-extend, opInsert
+			}
+			ins[k], insFrom[k] = best, from
+		}
+	}
+
+	// find where the alignment ends
+	endI, endJ, state, best := n, m, opMatch, neg
+	consider := func(i, j int) {
+		k := i*width + j
+		for s, v := range [3]float64{mat[k], del[k], ins[k]} {
+			if v > best {
+				endI, endJ, state, best = i, j, byte(s), v
+			}
+		}
+	}
+	switch mode {
+	case Global:
+		consider(n, m)
+	case SemiGlobal:
+		for j := 0; j <= m; j++ {
+			consider(n, j)
+		}
+		for i := 0; i <= n; i++ {
+			consider(i, m)
+		}
+	case Local:
+		for k, v := range mat {
+			if v > best {
+				endI, endJ, state, best = k/width, k%width, opMatch, v
+			}
+		}
+		if best <= 0 {
+			return path{}
+		}
+	}
+
+	var ops []byte
+	if mode == SemiGlobal {
+		for j := m; j > endJ; j-- {
+			ops = append(ops, opInsert)
+		}
+		for i := n; i > endI; i-- {
+			ops = append(ops, opDelete)
+		}
+	}
+
+	i, j := endI, endJ
+	for i > 0 || j > 0 {
+		if mode != Local && i == 0 {
+			for ; j > 0; j-- {
+				ops = append(ops, opInsert)
+			}
+			break
+		}
+		if mode != Local && j == 0 {
+			for ; i > 0; i-- {
+				ops = append(ops, opDelete)
+			}
+			break
+		}
+		k := i*width + j
+		ops = append(ops, state)
+		switch state {
+		case opMatch:
+			state = matFrom[k]
+			i--
+			j--
+		case opDelete:
+			state = delFrom[k]
+			i--
+		case opInsert:
+			state = insFrom[k]
+			j--
+		}
+		if state == opStart {
+			break
+		}
+	}
+
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+
+	return path{ops: ops, tStart: i, qStart: j, score: best}
+}
+
+// alignment formats the path as an Alignment of template against query,
+// with positions recorded as by format.
+func (p path) alignment(template, query string) Alignment {
+	var t, q strings.Builder
+	var aln Alignment
+
+	ti, qi := p.tStart, p.qStart
+	for start := 0; start < len(p.ops); {
+		op := p.ops[start]
+		end := start
+		for end < len(p.ops) && p.ops[end] == op {
+			end++
+		}
+		length := end - start
+
+		raw := RawAlignment{
+			TemplateAlignment: Position{Start: ti, End: ti},
+			QueryAlignment:    Position{Start: qi, End: qi},
+		}
+		if op != opInsert {
+			raw.TemplateAlignment = Position{Start: ti, End: ti + length, Length: length}
+		}
+		if op != opDelete {
+			raw.QueryAlignment = Position{Start: qi, End: qi + length, Length: length}
+		}
+		aln.Raw = append(aln.Raw, raw)
+
+		for c := 0; c < length; c++ {
+			switch op {
+			case opMatch:
+				t.WriteByte(template[ti+c])
+				q.WriteByte(query[qi+c])
+				aln.TemplatePositions = append(aln.TemplatePositions, ti+c+1)
+				aln.QueryPositions = append(aln.QueryPositions, qi+c+1)
+			case opDelete:
+				t.WriteByte(template[ti+c])
+				q.WriteRune(GAP)
+				aln.TemplatePositions = append(aln.TemplatePositions, ti+c+1)
+				aln.QueryPositions = append(aln.QueryPositions, ti+1)
+			case opInsert:
+				t.WriteRune(GAP)
+				q.WriteByte(query[qi+c])
+				aln.TemplatePositions = append(aln.TemplatePositions, qi+1)
+				aln.QueryPositions = append(aln.QueryPositions, qi+c+1)
+			}
+		}
+
+		if op != opInsert {
+			ti += length
+		}
+		if op != opDelete {
+			qi += length
+		}
+		start = end
+	}
+
+	aln.TemplateResult = t.String()
+	aln.QueryResult = q.String()
+	return aln
+}
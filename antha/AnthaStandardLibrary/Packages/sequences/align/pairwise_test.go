@@ -0,0 +1,141 @@
+package align
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+func TestSubstitutionMatrices(t *testing.T) {
+	for name, m := range SubstitutionMatrices {
+		for i := range m.Letters {
+			for j := range m.Letters {
+				if m.Scores[i][j] != m.Scores[j][i] {
+					t.Errorf("%s not symmetric at %c%c", name, m.Letters[i], m.Letters[j])
+				}
+			}
+		}
+	}
+
+	if s := BLOSUM62.Score('W', 'w'); s != 11 {
+		t.Errorf("BLOSUM62 W/W: expected 11, got %d", s)
+	}
+	if s := PAM250.Score('C', 'C'); s != 12 {
+		t.Errorf("PAM250 C/C: expected 12, got %d", s)
+	}
+	if s := BLOSUM62.Score('J', 'A'); s != BLOSUM62.Score('X', 'A') {
+		t.Errorf("BLOSUM62 J/A: expected unknown residue to score as X, got %d", s)
+	}
+
+	if m, err := LookupSubstitutionMatrix("blosum62"); err != nil || m != BLOSUM62 {
+		t.Errorf("LookupSubstitutionMatrix(blosum62): got %v, %v", m, err)
+	}
+	if _, err := LookupSubstitutionMatrix("BLOSUM999"); err == nil {
+		t.Error("LookupSubstitutionMatrix(BLOSUM999): expected error")
+	}
+
+	if _, err := ParseSubstitutionMatrix("bad", strings.NewReader("  A C\nA 1 0\nG 0 1\n")); err == nil {
+		t.Error("ParseSubstitutionMatrix: expected error for mislabelled row")
+	}
+}
+
+type pairwiseTest struct {
+	Mode      Mode
+	Alignment string
+	Score     float64
+	Raw       []RawAlignment
+}
+
+func TestProtein(t *testing.T) {
+	template := wtype.ProteinSequence{Nm: "template", Seq: "HEAGAWGHEE"}
+	query := wtype.ProteinSequence{Nm: "query", Seq: "PAWHEAE"}
+
+	tests := []pairwiseTest{
+		{
+			Mode:      Global,
+			Alignment: "HEAgAWgheE\n---pAWheaE\n",
+			Score:     1,
+			Raw: []RawAlignment{
+				{TemplateAlignment: Position{0, 3, 3}, QueryAlignment: Position{0, 0, 0}},
+				{TemplateAlignment: Position{3, 10, 7}, QueryAlignment: Position{0, 7, 7}},
+			},
+		},
+		{
+			Mode:      Local,
+			Alignment: "HEA\nHEA\n",
+			Score:     17,
+			Raw: []RawAlignment{
+				{TemplateAlignment: Position{0, 3, 3}, QueryAlignment: Position{3, 6, 3}},
+			},
+		},
+		{
+			Mode:      SemiGlobal,
+			Alignment: "HEAgAWgheE\n---pAWheaE\n",
+			Score:     15,
+		},
+	}
+
+	for _, test := range tests {
+		r, err := Protein(template, query, DefaultProteinScoring(), test.Mode)
+		if err != nil {
+			t.Errorf("%s: %s", test.Mode, err)
+			continue
+		}
+		if r.String() != test.Alignment {
+			t.Errorf("%s: expected alignment\n%s got\n%s", test.Mode, test.Alignment, r.String())
+		}
+		if r.Score != test.Score {
+			t.Errorf("%s: expected score %v, got %v", test.Mode, test.Score, r.Score)
+		}
+		if test.Raw != nil && !reflect.DeepEqual(r.Alignment.Raw, test.Raw) {
+			t.Errorf("%s: expected raw alignment %v, got %v", test.Mode, test.Raw, r.Alignment.Raw)
+		}
+	}
+}
+
+func TestPairwiseSemiGlobal(t *testing.T) {
+	scoring := Scoring{Matrix: PAM250, Gap: GapPenalty{Open: 10, Extend: 1}}
+	r, err := Pairwise(&wtype.ProteinSequence{Nm: "full", Seq: "MKTAYIAKQRQISFVKSHFSRQ"}, &wtype.ProteinSequence{Nm: "fragment", Seq: "AYIAKQRQ"}, scoring, SemiGlobal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "MKTAYIAKQRQISFVKSHFSRQ\n---AYIAKQRQ-----------\n"; r.String() != expected {
+		t.Errorf("expected alignment\n%s got\n%s", expected, r.String())
+	}
+	if r.Identity() != 8.0/22.0 {
+		t.Errorf("expected identity %v, got %v", 8.0/22.0, r.Identity())
+	}
+}
+
+func TestPairwiseDNA(t *testing.T) {
+	r, err := Pairwise(&wtype.DNASequence{Nm: "template", Seq: "GTTGACAGACTAGATTCACG"}, &wtype.DNASequence{Nm: "query", Seq: "gacagacga"}, DefaultDNAScoring(), Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "GACAGACtA\nGACAGACgA\n"; r.String() != expected {
+		t.Errorf("expected alignment\n%s got\n%s", expected, r.String())
+	}
+	if expected := []int{4, 5, 6, 7, 8, 9, 10, 11, 12}; !reflect.DeepEqual(r.Alignment.TemplatePositions, expected) {
+		t.Errorf("expected template positions %v, got %v", expected, r.Alignment.TemplatePositions)
+	}
+}
+
+func TestPairwiseErrors(t *testing.T) {
+	protein := &wtype.ProteinSequence{Nm: "protein", Seq: "MKTAYIAKQRQ"}
+	dna := &wtype.DNASequence{Nm: "dna", Seq: "ACGT"}
+
+	if _, err := Pairwise(protein, dna, Scoring{}, Global); err == nil {
+		t.Error("expected error with no substitution matrix")
+	}
+	if _, err := Pairwise(protein, &wtype.ProteinSequence{Nm: "empty"}, DefaultProteinScoring(), Global); err == nil {
+		t.Error("expected error aligning empty sequence")
+	}
+	if _, err := Pairwise(protein, dna, DefaultDNAScoring(), Global); err == nil {
+		t.Error("expected error aligning protein with DNA matrix")
+	}
+	if _, err := Pairwise(&wtype.DNASequence{Nm: "a", Seq: "AAAA"}, &wtype.DNASequence{Nm: "c", Seq: "CCCC"}, DefaultDNAScoring(), Local); err == nil {
+		t.Error("expected error with no local alignment")
+	}
+}
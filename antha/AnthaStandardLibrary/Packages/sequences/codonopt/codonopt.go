@@ -0,0 +1,277 @@
+// antha/AnthaStandardLibrary/Packages/sequences/codonopt/codonopt.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+// Package codonopt designs coding sequences for proteins which are well
+// adapted to the codon usage of a host while satisfying constraints on
+// their synthesis, assembly and expression.
+//
+// Each amino acid is first encoded by the codon of highest weight in the
+// host. Violations of the constraints are then repaired by synonymous codon
+// changes, choosing at each step the change which most reduces the
+// violations, and of those the one of highest weight, until no single
+// change improves the sequence.
+package codonopt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/internal/seqrules"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// An Objective is the index of codon adaptation which is maximised
+type Objective string
+
+// Objectives of optimisation
+const (
+	// Codon adaptation index (Sharp and Li, 1987)
+	CAI Objective = "CAI"
+	// tRNA adaptation index (dos Reis et al., 2004)
+	TAI Objective = "tAI"
+)
+
+// Options of an optimisation
+type Options struct {
+	// Index maximised; CAI by default
+	Objective Objective
+	// Weights of codons for the tRNA adaptation index of the host, as
+	// returned by TAIWeights; required to maximise tAI
+	TRNAWeights map[string]float64
+	Constraints Constraints
+}
+
+// A ConstraintStatus reports whether an optimised sequence satisfies a
+// constraint
+type ConstraintStatus struct {
+	Constraint string
+	Satisfied  bool
+	Violations []Violation
+}
+
+// A Report is the result of an optimisation
+type Report struct {
+	Sequence wtype.DNASequence
+	CAI      float64
+	// tRNA adaptation index, if TRNAWeights were given
+	TAI         float64
+	Constraints []ConstraintStatus
+	// Whether every constraint is satisfied
+	Satisfied bool
+}
+
+// Violations returns the violations of every constraint
+func (r Report) Violations() (vs []Violation) {
+	for _, c := range r.Constraints {
+		vs = append(vs, c.Violations...)
+	}
+	return
+}
+
+// optimiser holds the state of an optimisation
+type optimiser struct {
+	aas     string
+	table   wtype.CodonTable
+	weights map[string]float64
+	// synonymous codons of each amino acid in order of decreasing weight
+	options map[string][]string
+	c       Constraints
+	ms      []seqrules.Motif
+}
+
+// Optimise designs a coding sequence for a protein in a host with the given
+// codon table, maximising the CAI or tAI subject to constraints. An error
+// is returned if the protein cannot be encoded by the table; constraints
+// which cannot be satisfied are reported as such.
+func Optimise(protein wtype.ProteinSequence, table wtype.CodonTable, opts Options) (report Report, err error) {
+	aas := strings.ToUpper(protein.Sequence())
+	if err = wtype.ValidAA(aas); err != nil {
+		return
+	}
+
+	caiWeights := CAIWeights(table)
+	o := optimiser{aas: aas, table: table, weights: caiWeights, c: opts.Constraints}
+	switch opts.Objective {
+	case CAI, "":
+	case TAI:
+		if len(opts.TRNAWeights) == 0 {
+			return report, fmt.Errorf("tRNA weights are required to optimise tAI")
+		}
+		o.weights = normaliseWeights(opts.TRNAWeights)
+	default:
+		return report, fmt.Errorf("unknown objective %s: options are %s and %s", opts.Objective, CAI, TAI)
+	}
+
+	if o.options, err = o.synonyms(); err != nil {
+		return
+	}
+	if o.ms, err = o.c.motifs(); err != nil {
+		return
+	}
+
+	codons := make([]string, len(aas))
+	for i := range aas {
+		codons[i] = o.options[aas[i:i+1]][0]
+	}
+	if codons, err = o.repair(codons); err != nil {
+		return
+	}
+
+	seq := strings.Join(codons, "")
+	vs, err := o.c.check(seq, o.ms)
+	if err != nil {
+		return
+	}
+
+	report.Sequence = wtype.MakeLinearDNASequence(protein.Name(), seq)
+	report.CAI = indexOf(codons, aas, table, caiWeights)
+	if len(opts.TRNAWeights) != 0 {
+		report.TAI = indexOf(codons, aas, table, normaliseWeights(opts.TRNAWeights))
+	}
+	report.Satisfied = len(vs) == 0
+	for _, name := range o.c.active(o.ms) {
+		status := ConstraintStatus{Constraint: name, Satisfied: true}
+		for _, v := range vs {
+			if v.Rule == name {
+				status.Satisfied = false
+				status.Violations = append(status.Violations, publicViolation(v))
+			}
+		}
+		report.Constraints = append(report.Constraints, status)
+	}
+	return
+}
+
+func normaliseWeights(weights map[string]float64) map[string]float64 {
+	n := make(map[string]float64, len(weights))
+	for codon, w := range weights {
+		n[normaliseCodon(codon)] = w
+	}
+	return n
+}
+
+// synonyms returns the codons of the table for each amino acid of the
+// protein in order of decreasing weight
+func (o optimiser) synonyms() (map[string][]string, error) {
+	options := make(map[string][]string)
+	for _, r := range o.aas {
+		aa := string(r)
+		if _, done := options[aa]; done {
+			continue
+		}
+		var codons []string
+		for codon := range o.table.CodonByAA[aa] {
+			codons = append(codons, normaliseCodon(codon))
+		}
+		if len(codons) == 0 {
+			return nil, fmt.Errorf("no codons for %s in codon table %s", aa, o.table.TaxID)
+		}
+		sort.Slice(codons, func(i, j int) bool {
+			if wi, wj := o.weights[codons[i]], o.weights[codons[j]]; wi != wj {
+				return wi > wj
+			}
+			return codons[i] < codons[j]
+		})
+		options[aa] = codons
+	}
+	return options, nil
+}
+
+// repair makes synonymous codon changes to reduce the violations of the
+// constraints, trying the violations in order of position and accepting
+// the first change which reduces the total cost
+func (o optimiser) repair(codons []string) ([]string, error) {
+	vs, err := o.c.check(strings.Join(codons, ""), o.ms)
+	if err != nil {
+		return nil, err
+	}
+	cost := totalCost(vs)
+
+	// every accepted change reduces the cost, but bound the number of
+	// changes in case of rounding
+	for n := 0; n < 10*len(codons) && cost > 0; n++ {
+		improved := false
+		for _, v := range vs {
+			i, codon, err := o.bestChange(codons, v, cost)
+			if err != nil {
+				return nil, err
+			}
+			if codon != "" {
+				codons[i] = codon
+				if vs, err = o.c.check(strings.Join(codons, ""), o.ms); err != nil {
+					return nil, err
+				}
+				cost, improved = totalCost(vs), true
+				break
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return codons, nil
+}
+
+// bestChange returns the synonymous change of a codon overlapping a
+// violation which most reduces the cost below the current cost, and of
+// those the one which least reduces the index, or an empty codon if there
+// is none. Only the violations near each changed codon are rechecked.
+func (o optimiser) bestChange(codons []string, v seqrules.Violation, cost float64) (best int, bestCodon string, err error) {
+	const tolerance = 1e-9
+	bestCost := cost
+	var bestLoss float64
+
+	seq := strings.Join(codons, "")
+	repeats := o.c.repeatWords(seq)
+	structure, err := o.c.structureCost(seq)
+	if err != nil {
+		return 0, "", err
+	}
+
+	first, last := (v.Start-1)/3, (v.End-1)/3
+	for i := first; i <= last && i < len(codons); i++ {
+		current := codons[i]
+		start, end := 3*i, 3*i+3
+		local := o.c.localCost(seq, start, end, o.ms)
+		for _, option := range o.options[o.aas[i:i+1]] {
+			if option == current {
+				continue
+			}
+			changed := seq[:start] + option + seq[end:]
+			c := cost + o.c.localCost(changed, start, end, o.ms) - local + repeats.delta(seq, changed, start, end)
+			if start < o.c.FivePrimeWindow {
+				s, e := o.c.structureCost(changed)
+				if e != nil {
+					return 0, "", e
+				}
+				c += s - structure
+			}
+			loss := math.Log(math.Max(o.weights[current], minWeight)) - math.Log(math.Max(o.weights[option], minWeight))
+			if c < bestCost-tolerance || (bestCodon != "" && c < bestCost+tolerance && loss < bestLoss) {
+				best, bestCodon, bestCost, bestLoss = i, option, c, loss
+			}
+		}
+	}
+	return
+}
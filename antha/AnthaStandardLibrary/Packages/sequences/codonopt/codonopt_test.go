@@ -0,0 +1,271 @@
+package codonopt
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/enzymes"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+var eColi = wtype.CodonTable(sequences.EColiTable)
+
+func translateForTest(seq string) string {
+	var aas []string
+	for i := 0; i+3 <= len(seq); i += 3 {
+		aas = append(aas, sequences.Codontable[seq[i:i+3]])
+	}
+	return strings.Join(aas, "")
+}
+
+func optimiseForTest(t *testing.T, protein string, opts Options) Report {
+	report, err := Optimise(wtype.ProteinSequence{Nm: "test", Seq: protein}, eColi, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := translateForTest(report.Sequence.Seq); g != protein {
+		t.Errorf("expected %s to encode %s, got %s", report.Sequence.Seq, protein, g)
+	}
+	return report
+}
+
+func TestOptimiseUnconstrained(t *testing.T) {
+	report := optimiseForTest(t, "MKLAG*", Options{})
+	if e, g := "ATGAAACTGGCGGGCTAA", report.Sequence.Seq; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	if report.CAI != 1.0 || !report.Satisfied || len(report.Constraints) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestOptimiseConstrained(t *testing.T) {
+	// KKK is AAAAAAAAA, and A is GCG, in the unconstrained sequence
+	protein := "MKKKAAAAAAAAAAGL"
+	constraints := Constraints{
+		AvoidSites:     []wtype.RestrictionEnzyme{{Enzyme: wtype.Enzyme{Nm: "BsrDI"}, RecognitionSequence: "GCAATG"}},
+		AvoidSequences: []string{"CTGGCG"},
+		MinGC:          0.3,
+		MaxGC:          0.7,
+		GCWindow:       30,
+		MaxHomopolymer: 5,
+	}
+	report := optimiseForTest(t, protein, Options{Constraints: constraints})
+
+	if !report.Satisfied {
+		t.Errorf("expected constraints to be satisfied, got %v", report.Violations())
+	}
+	var names []string
+	for _, c := range report.Constraints {
+		names = append(names, c.Constraint)
+	}
+	if e, g := []string{RestrictionSites, ForbiddenSequences, GCContent, Homopolymers}, names; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected constraints %v, got %v", e, g)
+	}
+	if report.CAI >= 1.0 || report.CAI <= 0.0 {
+		t.Errorf("expected CAI less than 1.0, got %f", report.CAI)
+	}
+
+	vs, err := constraints.check(report.Sequence.Seq, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != 0 {
+		t.Errorf("expected no violations, got %v", vs)
+	}
+}
+
+func TestOptimiseAssemblyStandard(t *testing.T) {
+	standard, err := enzymes.LookupAssemblyStandard("MoClo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	constraints := DefaultConstraints()
+	constraints.AssemblyStandard = &standard
+	constraints.AssemblyLevel = "Level0"
+
+	// green fluorescent protein
+	gfp := "MSKGEELFTGVVPILVELDGDVNGHKFSVSGEGEGDATYGKLTLKFICTTGKLPVPWPTLVTTFSYGVQCFSRYPDHMKQHDFFKSAMPEGYVQERTIFFKDDGNYKTRAEVKFEGDTLVNRIELKGIDFKEDGNILGHKLEYNYNSHNVYIMADKQKNGIKVNFKIRHNIEDGSVQLADHYQQNTPIGDGPVLLPDNHYLSTQSALSKDPNEKRDHMVLLEFVTAAGITHGMDELYK*"
+	report := optimiseForTest(t, gfp, Options{Constraints: constraints})
+
+	for _, c := range report.Constraints {
+		if c.Constraint == RestrictionSites && !c.Satisfied {
+			t.Errorf("expected BsaI sites to be removed, got %v", c.Violations)
+		}
+	}
+	if report.CAI < 0.8 {
+		t.Errorf("expected CAI of at least 0.8, got %f", report.CAI)
+	}
+}
+
+func TestOptimiseUnsatisfiable(t *testing.T) {
+	report := optimiseForTest(t, "MMWM", Options{Constraints: Constraints{AvoidSequences: []string{"ATGTGG"}}})
+	e := []ConstraintStatus{{
+		Constraint: ForbiddenSequences,
+		Violations: []Violation{{Constraint: ForbiddenSequences, Start: 4, End: 9, Message: "forbidden sequence ATGTGG found on + strand"}},
+	}}
+	if g := report.Constraints; !reflect.DeepEqual(e, g) || report.Satisfied {
+		t.Errorf("expected constraints %+v, got %+v", e, g)
+	}
+}
+
+func TestOptimiseErrors(t *testing.T) {
+	protein := wtype.ProteinSequence{Nm: "test", Seq: "MKL"}
+	if _, err := Optimise(protein, eColi, Options{Objective: TAI}); err == nil {
+		t.Error("expected an error optimising tAI without weights")
+	}
+	if _, err := Optimise(protein, wtype.NewCodonTable(), Options{}); err == nil {
+		t.Error("expected an error optimising with an empty table")
+	}
+	if _, err := Optimise(wtype.ProteinSequence{Seq: "MK1"}, eColi, Options{}); err == nil {
+		t.Error("expected an error optimising an invalid protein")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := Constraints{
+		AvoidSites:         []wtype.RestrictionEnzyme{{Enzyme: wtype.Enzyme{Nm: "BglI"}, RecognitionSequence: "GCCNNNNNGGC"}},
+		MaxHomopolymer:     4,
+		MaxRepeat:          5,
+		FivePrimeWindow:    30,
+		MinFivePrimeDeltaG: -5.0,
+	}
+	ms, err := c.motifs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := "GGGGCCCCGATCGGGGCCCCATGCCAATTTGGCTTTTTACGTACGTAC"
+	vs, err := c.check(seq, ms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g []string
+	for _, v := range vs {
+		g = append(g, v.String())
+	}
+	e := []string{
+		"5' structure at 1..30: hairpin with ΔG -7.31 kcal/mol (minimum -5.00)",
+		"repeats at 13..20: 8 bp repeat of 1..8 longer than 5 bp",
+		"restriction sites at 23..33: BglI site GCCNNNNNGGC found on + strand",
+		"homopolymers at 34..38: 5 bp run of T longer than 4 bp",
+		"repeats at 42..48: 7 bp repeat of 38..44 longer than 5 bp",
+	}
+	if !reflect.DeepEqual(e, g) {
+		t.Errorf("expected violations %q, got %q", e, g)
+	}
+}
+
+func TestCheckAssemblyOverhangs(t *testing.T) {
+	standard, err := enzymes.LookupAssemblyStandard("MoClo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Constraints{AssemblyStandard: &standard, AssemblyLevel: "Level0"}
+	ms, err := c.motifs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the AATG overhang is too far from the BsaI site to be cut out
+	vs, err := c.check("AATGACACACACACACACACACGGTCTCAGGAGT", ms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g []string
+	for _, v := range vs {
+		g = append(g, v.String())
+	}
+	e := []string{
+		"restriction sites at 23..28: BsaI site GGTCTC found on + strand",
+		"assembly overhangs at 30..33: MoClo Level0 overhang GGAG found on + strand within 9 bp of GGTCTC at 23",
+	}
+	if !reflect.DeepEqual(e, g) {
+		t.Errorf("expected violations %q, got %q", e, g)
+	}
+}
+
+func TestLocalCost(t *testing.T) {
+	standard, err := enzymes.LookupAssemblyStandard("MoClo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := DefaultConstraints()
+	c.AssemblyStandard = &standard
+	c.MaxRepeat = 6
+	c.MaxHomopolymer = 3
+	ms, err := c.motifs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	random := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "ACGT"[r.Intn(4)]
+		}
+		return string(b)
+	}
+
+	seq := random(300)
+	for n := 0; n < 200; n++ {
+		start := 3 * r.Intn(len(seq)/3)
+		changed := seq[:start] + random(3) + seq[start+3:]
+
+		before, err := c.check(seq, ms)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after, err := c.check(changed, ms)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sBefore, err := c.structureCost(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sAfter, err := c.structureCost(changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		delta := c.localCost(changed, start, start+3, ms) - c.localCost(seq, start, start+3, ms)
+		delta += c.repeatWords(seq).delta(seq, changed, start, start+3) + sAfter - sBefore
+		if e, g := totalCost(after), totalCost(before)+delta; math.Abs(e-g) > 1e-6 {
+			t.Fatalf("changing codon at %d: expected cost %f, got %f", start+1, e, g)
+		}
+		seq = changed
+	}
+}
+
+func TestTAIWeights(t *testing.T) {
+	// GCC is decoded by tRNA GGC, and GCT by wobble pairing
+	weights := TAIWeights(map[string]int{"GGC": 2})
+	if w := weights["GCC"]; w != 1.0 {
+		t.Errorf("expected weight %f for GCC, got %f", 1.0, w)
+	}
+	wobble := 1 - sGU
+	if w := weights["GCT"]; math.Abs(w-wobble) > 1e-9 {
+		t.Errorf("expected weight %f for GCT, got %f", wobble, w)
+	}
+	if w := weights["AAA"]; math.Abs(w-math.Sqrt(wobble)) > 1e-9 {
+		t.Errorf("expected undecoded codon to have mean weight %f, got %f", math.Sqrt(wobble), w)
+	}
+	if _, found := weights["ATG"]; found {
+		t.Error("expected ATG not to be weighted")
+	}
+}
+
+func TestCAIWeights(t *testing.T) {
+	weights := CAIWeights(eColi)
+	if e, g := 0.14/0.47, weights["TTA"]; math.Abs(e-g) > 1e-9 {
+		t.Errorf("expected weight %f for TTA, got %f", e, g)
+	}
+	if e, g := 1.0, weights["CTG"]; e != g {
+		t.Errorf("expected weight %f for CTG, got %f", e, g)
+	}
+}
@@ -0,0 +1,385 @@
+// antha/AnthaStandardLibrary/Packages/sequences/codonopt/constraints.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package codonopt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/enzymes"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/internal/seqrules"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/oligos"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// Names of the constraints of an optimisation
+const (
+	RestrictionSites   = "restriction sites"
+	AssemblyOverhangs  = "assembly overhangs"
+	ForbiddenSequences = "forbidden sequences"
+	GCContent          = "GC content"
+	Homopolymers       = "homopolymers"
+	Repeats            = "repeats"
+	FivePrimeStructure = "5' structure"
+)
+
+// Constraints are the constraints on the sequence of an optimised gene.
+// Zero valued constraints are not applied.
+type Constraints struct {
+	// Restriction enzymes whose sites must not occur on either strand
+	AvoidSites []wtype.RestrictionEnzyme
+	// Assembly standard whose enzyme sites must not occur on either strand,
+	// for the level named by AssemblyLevel or every level if it is empty.
+	// Its overhangs are only forbidden where a site of the level's enzyme
+	// would cut them out, since elsewhere they are never exposed; most
+	// overhangs would otherwise occur in any but the shortest genes.
+	AssemblyStandard *enzymes.AssemblyStandard
+	AssemblyLevel    string
+	// Other sequences which must not occur on either strand
+	AvoidSequences []string
+	// Range of the GC content of every window of GCWindow bp, as fractions;
+	// the GC content of the whole sequence is constrained if GCWindow is
+	// zero or greater than its length
+	GCWindow int
+	MinGC    float64
+	MaxGC    float64
+	// Longest run of a single base allowed
+	MaxHomopolymer int
+	// Longest sequence allowed to occur more than once on the same strand
+	MaxRepeat int
+	// Minimum free energy in kcal/mol of hairpins in the first
+	// FivePrimeWindow bases of the mRNA, which is preceded by
+	// FivePrimeContext, such as the ribosome binding site, if given. Free
+	// energies are estimated using DNA parameters under Conditions, or
+	// oligos.DefaultConditions if nil.
+	FivePrimeWindow    int
+	FivePrimeContext   string
+	MinFivePrimeDeltaG float64
+	Conditions         *oligos.Conditions
+}
+
+// DefaultConstraints are typical constraints on the synthesis and
+// expression of genes
+func DefaultConstraints() Constraints {
+	return Constraints{
+		GCWindow:           50,
+		MinGC:              0.25,
+		MaxGC:              0.75,
+		MaxHomopolymer:     8,
+		MaxRepeat:          20,
+		FivePrimeWindow:    48,
+		MinFivePrimeDeltaG: -8.0,
+	}
+}
+
+func (c Constraints) conditions() oligos.Conditions {
+	if c.Conditions != nil {
+		return *c.Conditions
+	}
+	return oligos.DefaultConditions()
+}
+
+// A Violation is a region of a sequence which fails a constraint
+type Violation struct {
+	Constraint string
+	// Position of the region in human friendly format
+	Start, End int
+	Message    string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s at %d..%d: %s", v.Constraint, v.Start, v.End, v.Message)
+}
+
+func publicViolation(v seqrules.Violation) Violation {
+	return Violation{Constraint: v.Rule, Start: v.Start, End: v.End, Message: v.Message}
+}
+
+// constraintWeights are multiplied by the cost of violations so that
+// repair trades softer constraints for harder ones
+var constraintWeights = map[string]float64{
+	RestrictionSites:   10.0,
+	AssemblyOverhangs:  1.0,
+	ForbiddenSequences: 10.0,
+	GCContent:          1.0,
+	Homopolymers:       1.0,
+	Repeats:            1.0,
+	FivePrimeStructure: 1.0,
+}
+
+// motifs returns the sequences which the constraints forbid
+func (c Constraints) motifs() ([]seqrules.Motif, error) {
+	var ms []seqrules.Motif
+	for _, enz := range c.AvoidSites {
+		ms = append(ms, seqrules.Motif{Rule: RestrictionSites, Name: enz.Name() + " site", Seq: enz.RecognitionSequence})
+	}
+
+	if c.AssemblyStandard != nil {
+		levels := c.AssemblyStandard.LevelNames()
+		if c.AssemblyLevel != "" {
+			levels = []string{c.AssemblyLevel}
+		}
+		for _, name := range levels {
+			level, err := c.AssemblyStandard.GetLevel(name)
+			if err != nil {
+				return nil, err
+			}
+			enz := level.GetEnzyme()
+			ms = append(ms, seqrules.Motif{Rule: RestrictionSites, Name: enz.Name() + " site", Seq: enz.RecognitionSequence})
+
+			// the ends cut by the enzyme lie within this distance of its site
+			within := enz.EndLength + maxInt(absInt(enz.Topstrand3primedistancefromend), absInt(enz.Bottomstrand5primedistancefromend))
+
+			overhangs := []enzymes.StandardOverhangs{level.GetVectorEnds()}
+			for _, class := range level.AnnotationOptions() {
+				overhangs = append(overhangs, level.PartOverhangs[class])
+			}
+			for _, o := range overhangs {
+				for _, s := range []string{o.Upstream, o.Downstream} {
+					if s != "" {
+						ms = append(ms, seqrules.Motif{Rule: AssemblyOverhangs, Name: c.AssemblyStandard.Name + " " + name + " overhang", Seq: s, Near: strings.ToUpper(enz.RecognitionSequence), Within: within})
+					}
+				}
+			}
+		}
+	}
+
+	for _, s := range c.AvoidSequences {
+		ms = append(ms, seqrules.Motif{Rule: ForbiddenSequences, Name: "forbidden sequence", Seq: s})
+	}
+
+	// each motif is searched for on both strands, so those which are the
+	// reverse complement of another are redundant
+	var unique []seqrules.Motif
+	seen := make(map[string]bool)
+	for _, m := range ms {
+		m.Seq = strings.ToUpper(m.Seq)
+		if m.Seq == "" || seen[m.Seq+" "+m.Near] || seen[wtype.RevComp(m.Seq)+" "+m.Near] {
+			continue
+		}
+		seen[m.Seq+" "+m.Near] = true
+		unique = append(unique, m)
+	}
+	return unique, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// gcWindow returns the length of the windows whose GC content is
+// constrained in a sequence of length n, or zero if it is not constrained
+func (c Constraints) gcWindow(n int) int {
+	if c.MinGC <= 0 && c.MaxGC <= 0 {
+		return 0
+	}
+	if c.GCWindow <= 0 || c.GCWindow > n {
+		return n
+	}
+	return c.GCWindow
+}
+
+// checkGC returns the regions of seq whose windows lie outside the GC
+// content range
+func (c Constraints) checkGC(seq string) []seqrules.Violation {
+	return seqrules.GCWindows(GCContent, seq, c.gcWindow(len(seq)), c.MinGC, c.MaxGC)
+}
+
+// checkStructure returns the 5' region of seq if, preceded by the 5'
+// context, it forms a hairpin more stable than allowed
+func (c Constraints) checkStructure(seq string) ([]seqrules.Violation, error) {
+	if c.FivePrimeWindow <= 0 || c.MinFivePrimeDeltaG >= 0 {
+		return nil, nil
+	}
+	end := c.FivePrimeWindow
+	if end > len(seq) {
+		end = len(seq)
+	}
+	hairpin, err := oligos.Hairpin(strings.ToUpper(c.FivePrimeContext)+seq[:end], c.conditions())
+	if err != nil {
+		return nil, err
+	}
+	if hairpin.DeltaG >= c.MinFivePrimeDeltaG {
+		return nil, nil
+	}
+	return []seqrules.Violation{{
+		Rule:    FivePrimeStructure,
+		Start:   1,
+		End:     end,
+		Message: fmt.Sprintf("hairpin with ΔG %.2f kcal/mol (minimum %.2f)", hairpin.DeltaG, c.MinFivePrimeDeltaG),
+		Cost:    c.MinFivePrimeDeltaG - hairpin.DeltaG,
+	}}, nil
+}
+
+// structureCost returns the weighted cost of the 5' structure of seq
+func (c Constraints) structureCost(seq string) (float64, error) {
+	vs, err := c.checkStructure(seq)
+	if err != nil {
+		return 0, err
+	}
+	return totalCost(vs), nil
+}
+
+// localCost returns the weighted cost of the violations of seq, other than
+// repeats and 5' structure, near the bases from start to end: all those
+// which changing these bases could make or break. Sequences differing only
+// in these bases differ in cost by the difference in their local costs.
+func (c Constraints) localCost(seq string, start, end int, ms []seqrules.Motif) float64 {
+	n := len(seq)
+	region := func(lo, hi int) string {
+		return seq[maxInt(lo, 0):minInt(hi, n)]
+	}
+
+	var reach int
+	for _, m := range ms {
+		reach = maxInt(reach, m.Reach())
+	}
+	vs := seqrules.Motifs(region(start-reach, end+reach), ms)
+
+	if window := c.gcWindow(n); window > 0 {
+		vs = append(vs, seqrules.GCWindows(GCContent, region(start-window+1, end+window-1), window, c.MinGC, c.MaxGC)...)
+	}
+
+	// extend the region to the ends of the runs of bases either side
+	lo, hi := maxInt(start-1, 0), minInt(end+1, n)
+	for lo > 0 && seq[lo-1] == seq[lo] {
+		lo--
+	}
+	for hi < n && seq[hi] == seq[hi-1] {
+		hi++
+	}
+	vs = append(vs, seqrules.Homopolymers(Homopolymers, seq[lo:hi], c.MaxHomopolymer)...)
+
+	return totalCost(vs)
+}
+
+// repeatWords counts the words of MaxRepeat+1 bases of a sequence. The cost
+// of its repeats is the number of words less the number of distinct words.
+type repeatWords struct {
+	k      int
+	counts map[string]int
+}
+
+// repeatWords returns the words of seq, or nil if repeats are not
+// constrained
+func (c Constraints) repeatWords(seq string) *repeatWords {
+	if c.MaxRepeat <= 0 {
+		return nil
+	}
+	r := &repeatWords{k: c.MaxRepeat + 1, counts: make(map[string]int)}
+	for i := 0; i+r.k <= len(seq); i++ {
+		r.counts[seq[i:i+r.k]]++
+	}
+	return r
+}
+
+// delta returns the change in the weighted cost of repeats when the bases
+// of the sequence from start to end are changed to give seq
+func (r *repeatWords) delta(old, seq string, start, end int) float64 {
+	if r == nil {
+		return 0
+	}
+	lo, hi := maxInt(start-r.k+1, 0), minInt(end, len(seq)-r.k+1)
+	var distinct int
+	for i := lo; i < hi; i++ {
+		w := old[i : i+r.k]
+		if r.counts[w]--; r.counts[w] == 0 {
+			distinct--
+		}
+	}
+	for i := lo; i < hi; i++ {
+		w := seq[i : i+r.k]
+		if r.counts[w]++; r.counts[w] == 1 {
+			distinct++
+		}
+	}
+	for i := lo; i < hi; i++ {
+		r.counts[seq[i:i+r.k]]--
+		r.counts[old[i:i+r.k]]++
+	}
+	return -float64(distinct) * constraintWeights[Repeats]
+}
+
+// check returns the violations of the constraints by seq, in order of
+// position
+func (c Constraints) check(seq string, ms []seqrules.Motif) ([]seqrules.Violation, error) {
+	vs := seqrules.Motifs(seq, ms)
+	vs = append(vs, c.checkGC(seq)...)
+	vs = append(vs, seqrules.Homopolymers(Homopolymers, seq, c.MaxHomopolymer)...)
+	vs = append(vs, seqrules.Repeats(Repeats, seq, c.MaxRepeat, false)...)
+	structure, err := c.checkStructure(seq)
+	if err != nil {
+		return nil, err
+	}
+	vs = append(vs, structure...)
+	seqrules.Sort(vs)
+	return vs, nil
+}
+
+// totalCost is the weighted cost of a set of violations
+func totalCost(vs []seqrules.Violation) float64 {
+	return seqrules.TotalCost(vs, constraintWeights)
+}
+
+// active returns the names of the constraints which are applied
+func (c Constraints) active(ms []seqrules.Motif) []string {
+	var names []string
+	for _, name := range []string{RestrictionSites, AssemblyOverhangs, ForbiddenSequences} {
+		for _, m := range ms {
+			if m.Rule == name {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	if c.MinGC > 0 || c.MaxGC > 0 {
+		names = append(names, GCContent)
+	}
+	if c.MaxHomopolymer > 0 {
+		names = append(names, Homopolymers)
+	}
+	if c.MaxRepeat > 0 {
+		names = append(names, Repeats)
+	}
+	if c.FivePrimeWindow > 0 && c.MinFivePrimeDeltaG < 0 {
+		names = append(names, FivePrimeStructure)
+	}
+	return names
+}
@@ -0,0 +1,159 @@
+// antha/AnthaStandardLibrary/Packages/sequences/codonopt/weights.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package codonopt
+
+import (
+	"math"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// minWeight is the weight of codons which are never used by the host, so
+// that the geometric mean of a sequence containing them is not zero
+const minWeight = 0.01
+
+// normaliseCodon converts an RNA or lower case codon to upper case DNA
+func normaliseCodon(codon string) string {
+	return strings.Replace(strings.ToUpper(codon), "U", "T", -1)
+}
+
+// CAIWeights returns the relative adaptiveness of each codon of a host
+// codon table: its frequency divided by that of the most frequent
+// synonymous codon (Sharp and Li, 1987)
+func CAIWeights(table wtype.CodonTable) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, set := range table.CodonByAA {
+		var max float64
+		for _, f := range set {
+			max = math.Max(max, f)
+		}
+		for codon, f := range set {
+			if max > 0 {
+				weights[normaliseCodon(codon)] = f / max
+			} else {
+				weights[normaliseCodon(codon)] = 0
+			}
+		}
+	}
+	return weights
+}
+
+// Wobble penalties of dos Reis et al. (2004): the loss of efficiency of
+// decoding a codon by an anticodon which does not pair with its third base
+// by Watson-Crick pairing. Adenosine at position 34 of the anticodon is
+// assumed to be modified to inosine.
+const (
+	sGU = 0.41
+	sIC = 0.28
+	sIA = 0.9999
+	sUG = 0.68
+)
+
+// anticodon returns the anticodon, 5' to 3', whose first base is b34 and
+// which pairs with the first two bases of codon
+func anticodon(b34 string, codon string) string {
+	return b34 + wtype.Comp(codon[1:2]) + wtype.Comp(codon[0:1])
+}
+
+// TAIWeights returns the weights of codons in the tRNA adaptation index
+// (dos Reis et al., 2004) given the gene copy number of each tRNA of the
+// host, keyed by anticodon written 5' to 3' in DNA. Codons which no tRNA
+// decodes are given the geometric mean weight of the other codons. Stop
+// codons and ATG are not weighted.
+func TAIWeights(tRNAGeneCopies map[string]int) map[string]float64 {
+	copies := make(map[string]float64, len(tRNAGeneCopies))
+	for a, n := range tRNAGeneCopies {
+		copies[normaliseCodon(a)] = float64(n)
+	}
+	tgcn := func(b34, codon string) float64 {
+		return copies[anticodon(b34, codon)]
+	}
+
+	raw := make(map[string]float64)
+	var max float64
+	for _, a := range "ACGT" {
+		for _, b := range "ACGT" {
+			for _, c := range "ACGT" {
+				codon := string([]rune{a, b, c})
+				switch codon {
+				case "ATG", "TAA", "TAG", "TGA":
+					continue
+				}
+				var w float64
+				switch c {
+				case 'T':
+					w = tgcn("A", codon) + (1-sGU)*tgcn("G", codon)
+				case 'C':
+					w = tgcn("G", codon) + (1-sIC)*tgcn("A", codon)
+				case 'A':
+					w = tgcn("T", codon) + (1-sIA)*tgcn("A", codon)
+				case 'G':
+					w = tgcn("C", codon) + (1-sUG)*tgcn("T", codon)
+				}
+				raw[codon] = w
+				max = math.Max(max, w)
+			}
+		}
+	}
+
+	weights := make(map[string]float64, len(raw))
+	if max == 0 {
+		return weights
+	}
+	var logSum float64
+	var n int
+	for codon, w := range raw {
+		weights[codon] = w / max
+		if w > 0 {
+			logSum += math.Log(w / max)
+			n++
+		}
+	}
+	mean := math.Exp(logSum / float64(n))
+	for codon, w := range weights {
+		if w == 0 {
+			weights[codon] = mean
+		}
+	}
+	return weights
+}
+
+// indexOf returns the geometric mean of the weights of the codons of a
+// sequence, ignoring stop codons and amino acids with a single codon
+func indexOf(codons []string, aas string, table wtype.CodonTable, weights map[string]float64) float64 {
+	var logSum float64
+	var n int
+	for i, codon := range codons {
+		aa := aas[i : i+1]
+		if aa == "*" || len(table.CodonByAA[aa]) < 2 {
+			continue
+		}
+		logSum += math.Log(math.Max(weights[codon], minWeight))
+		n++
+	}
+	if n == 0 {
+		return 1.0
+	}
+	return math.Exp(logSum / float64(n))
+}
@@ -0,0 +1,58 @@
+// antha/AnthaStandardLibrary/Packages/sequences/internal/featrange/featrange.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+// Package featrange finds the positions of the sequence covered by a
+// feature. It is shared by the writers of sequence files.
+package featrange
+
+import (
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// Ranges returns the ranges of positions, in human friendly format, of a
+// sequence of the given length covered by a feature. Features spanning the
+// origin of a plasmid, whose end precedes their start, cover two ranges.
+//
+// Reverse features may be given with start before end, as by the genbank
+// parser and SBOL importer, or with start after end, as by searching for
+// them, so their orientation cannot tell whether they span the origin.
+// Instead, a reverse feature spans the origin if its sequence is as long as
+// the positions from the greater of its start and end to the origin and on
+// to the lesser.
+func Ranges(f wtype.Feature, length int) [][2]int {
+	start, end := f.StartPosition, f.EndPosition
+	if f.Reverse {
+		lo, hi := start, end
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		start, end = lo, hi
+		if n := len(f.DNASeq); n != hi-lo+1 && n == length-hi+1+lo {
+			start, end = hi, lo
+		}
+	}
+
+	if start <= end {
+		return [][2]int{{start, end}}
+	}
+	return [][2]int{{start, length}, {1, end}}
+}
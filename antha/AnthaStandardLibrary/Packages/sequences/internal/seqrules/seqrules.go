@@ -0,0 +1,279 @@
+// antha/AnthaStandardLibrary/Packages/sequences/internal/seqrules/seqrules.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+// Package seqrules checks DNA sequences against rules on their GC content,
+// homopolymers, repeats and motifs, returning the regions which break them
+// with the amount by which they do. It is shared by the checks of
+// sequences for synthesis and by codon optimisation.
+package seqrules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// A Violation is a region of a sequence which breaks a rule
+type Violation struct {
+	Rule string
+	// Position of the region in human friendly format
+	Start, End int
+	Message    string
+	// Amount by which the rule is broken, which repairs reduce
+	Cost float64
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s at %d..%d: %s", v.Rule, v.Start, v.End, v.Message)
+}
+
+// TotalCost is the cost of a set of violations, each multiplied by the
+// weight of its rule
+func TotalCost(vs []Violation, weights map[string]float64) float64 {
+	var cost float64
+	for _, v := range vs {
+		cost += weights[v.Rule] * v.Cost
+	}
+	return cost
+}
+
+// Sort sorts violations by position, keeping the order of those at the
+// same position
+func Sort(vs []Violation) {
+	sort.SliceStable(vs, func(i, j int) bool {
+		return vs[i].Start < vs[j].Start
+	})
+}
+
+// GCCount returns the number of G and C bases of an upper case sequence
+func GCCount(seq string) int {
+	return strings.Count(seq, "G") + strings.Count(seq, "C")
+}
+
+// GCExcess returns the number of bases by which the GC count of a region
+// of n bases lies outside a range of fractions, where a maximum of zero
+// is no maximum, and a description of the GC content
+func GCExcess(gc, n int, min, max float64) (float64, string) {
+	f := float64(gc) / float64(n)
+	if below := min*float64(n) - float64(gc); below > 0 {
+		return below, fmt.Sprintf("GC content %.1f%% below %.1f%%", 100*f, 100*min)
+	}
+	if above := float64(gc) - max*float64(n); max > 0 && above > 0 {
+		return above, fmt.Sprintf("GC content %.1f%% above %.1f%%", 100*f, 100*max)
+	}
+	return 0, ""
+}
+
+// GCWindows returns the regions of seq whose windows lie outside a range
+// of GC content, where a maximum of zero is no maximum. The cost of each is
+// the total number of bases by which its windows exceed the range, in units
+// of the window length.
+func GCWindows(rule, seq string, window int, min, max float64) (vs []Violation) {
+	if window <= 0 || window > len(seq) || (min <= 0 && max <= 0) {
+		return
+	}
+	isGC := func(b byte) int {
+		if b == 'G' || b == 'C' {
+			return 1
+		}
+		return 0
+	}
+	gc := GCCount(seq[:window])
+
+	var current *Violation
+	var worst float64
+	for i := 0; i+window <= len(seq); i++ {
+		if i > 0 {
+			gc += isGC(seq[i+window-1]) - isGC(seq[i-1])
+		}
+		excess, message := GCExcess(gc, window, min, max)
+		if excess <= 0 {
+			current = nil
+			continue
+		}
+		if current == nil {
+			vs = append(vs, Violation{Rule: rule, Start: i + 1})
+			worst = 0
+			current = &vs[len(vs)-1]
+		}
+		current.End = i + window
+		current.Cost += excess / float64(window)
+		if excess > worst {
+			worst = excess
+			current.Message = fmt.Sprintf("%s in %d bp window", message, window)
+		}
+	}
+	return
+}
+
+// Homopolymers returns the runs of a single base in seq longer than max.
+// The cost of each is the number of bases by which it is.
+func Homopolymers(rule, seq string, max int) (vs []Violation) {
+	if max <= 0 {
+		return
+	}
+	for i := 0; i < len(seq); {
+		j := i + 1
+		for j < len(seq) && seq[j] == seq[i] {
+			j++
+		}
+		if n := j - i; n > max {
+			vs = append(vs, Violation{
+				Rule:    rule,
+				Start:   i + 1,
+				End:     j,
+				Message: fmt.Sprintf("%d bp run of %c longer than %d bp", n, seq[i], max),
+				Cost:    float64(n - max),
+			})
+		}
+		i = j
+	}
+	return
+}
+
+// Repeats returns the later copies of sequences longer than max which occur
+// earlier in seq, on the same strand or, if inverted, the opposite one.
+// Overlapping copies are reported as a single repeat whose cost is the
+// number of repeated words of max+1 bases it contains.
+func Repeats(rule, seq string, max int, inverted bool) (vs []Violation) {
+	if max <= 0 {
+		return
+	}
+	k := max + 1
+
+	// positions of the earlier copies of the first and last words of each
+	// repeat
+	var firsts, lasts []int
+	seen := make(map[string]int)
+	var current *Violation
+	for i := 0; i+k <= len(seq); i++ {
+		word := seq[i : i+k]
+		partner := word
+		if inverted {
+			partner = wtype.RevComp(word)
+		}
+		j, found := seen[partner]
+		if _, ok := seen[word]; !ok {
+			seen[word] = i
+		}
+		if !found {
+			current = nil
+			continue
+		}
+		if current == nil {
+			vs = append(vs, Violation{Rule: rule, Start: i + 1})
+			firsts = append(firsts, j)
+			lasts = append(lasts, j)
+			current = &vs[len(vs)-1]
+		}
+		current.End = i + k
+		current.Cost++
+		lasts[len(lasts)-1] = j
+	}
+
+	for i := range vs {
+		n := vs[i].End - vs[i].Start + 1
+		kind, start := "repeat", firsts[i]
+		if inverted {
+			kind, start = "inverted repeat", lasts[i]
+		}
+		vs[i].Message = fmt.Sprintf("%d bp %s of %d..%d longer than %d bp", n, kind, start+1, start+n, max)
+	}
+	return
+}
+
+// A Motif is a sequence, possibly containing IUPAC ambiguity codes, which
+// must not occur, or if Near is given must not occur within Within bp of
+// Near, on either strand
+type Motif struct {
+	Rule string
+	// Description of the motif, if any, for messages
+	Name   string
+	Seq    string
+	Near   string
+	Within int
+}
+
+// Reach returns the furthest a change to a sequence can be from an
+// occurrence of the motif whose violation it makes or breaks
+func (m Motif) Reach() int {
+	return len(m.Seq) + len(m.Near) + m.Within
+}
+
+// Occurrences returns the positions at which s occurs on either strand of
+// seq and on which strand
+func Occurrences(seq, s string) (starts []int, strands []string) {
+	rc := wtype.RevComp(s)
+	for i := 0; i+len(s) <= len(seq); i++ {
+		if sequences.MatchesWobbleAt(seq, s, i) {
+			starts, strands = append(starts, i), append(strands, "+")
+		} else if rc != s && sequences.MatchesWobbleAt(seq, rc, i) {
+			starts, strands = append(starts, i), append(strands, "-")
+		}
+	}
+	return
+}
+
+// Motifs returns the occurrences of motifs on either strand of seq, each
+// at a cost of one
+func Motifs(seq string, ms []Motif) (vs []Violation) {
+	for _, m := range ms {
+		var near []int
+		if m.Near != "" {
+			if near, _ = Occurrences(seq, m.Near); len(near) == 0 {
+				continue
+			}
+		}
+		name := m.Seq
+		if m.Name != "" {
+			name = m.Name + " " + m.Seq
+		}
+		starts, strands := Occurrences(seq, m.Seq)
+		for j, i := range starts {
+			message := fmt.Sprintf("%s found on %s strand", name, strands[j])
+			if m.Near != "" {
+				site := -1
+				for _, n := range near {
+					if n-(i+len(m.Seq)) <= m.Within && i-(n+len(m.Near)) <= m.Within {
+						site = n
+						break
+					}
+				}
+				if site < 0 {
+					continue
+				}
+				message += fmt.Sprintf(" within %d bp of %s at %d", m.Within, m.Near, site+1)
+			}
+			vs = append(vs, Violation{
+				Rule:    m.Rule,
+				Start:   i + 1,
+				End:     i + len(m.Seq),
+				Message: message,
+				Cost:    1.0,
+			})
+		}
+	}
+	return
+}
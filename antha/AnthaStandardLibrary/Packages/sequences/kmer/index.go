@@ -0,0 +1,84 @@
+// antha/AnthaStandardLibrary/Packages/sequences/kmer/index.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package kmer
+
+import (
+	"strings"
+)
+
+// A Location is the position of a k-mer in an indexed sequence
+type Location struct {
+	// Index of the sequence
+	Seq int
+	// Zero-based offset of the k-mer in the sequence
+	Offset int
+}
+
+// A Seed is a k-mer shared by a query and an indexed sequence
+type Seed struct {
+	Location
+	// Zero-based offset of the k-mer in the query
+	QueryOffset int
+}
+
+// Diagonal is the offset of the query in the indexed sequence implied by
+// the seed
+func (s Seed) Diagonal() int {
+	return s.Offset - s.QueryOffset
+}
+
+// An Index locates every k-mer of a set of sequences. Unlike HashDB, which
+// compares k-mer counts, an Index keeps positions so that matches can be
+// extended into alignments.
+type Index struct {
+	K         int
+	Locations map[string][]Location
+}
+
+// NewIndex makes an empty index of k-mers of length k
+func NewIndex(k int) *Index {
+	return &Index{K: k, Locations: make(map[string][]Location)}
+}
+
+// Add indexes the k-mers of sequence s, which is referred to as seq in
+// locations. K-mers are indexed case-insensitively.
+func (idx *Index) Add(seq int, s string) {
+	s = strings.ToUpper(s)
+	for i := 0; i+idx.K <= len(s); i++ {
+		w := s[i : i+idx.K]
+		idx.Locations[w] = append(idx.Locations[w], Location{Seq: seq, Offset: i})
+	}
+}
+
+// Seeds returns every k-mer of the query found in the index, grouped by
+// indexed sequence
+func (idx *Index) Seeds(query string) map[int][]Seed {
+	query = strings.ToUpper(query)
+	seeds := make(map[int][]Seed)
+	for i := 0; i+idx.K <= len(query); i++ {
+		for _, l := range idx.Locations[query[i:i+idx.K]] {
+			seeds[l.Seq] = append(seeds[l.Seq], Seed{Location: l, QueryOffset: i})
+		}
+	}
+	return seeds
+}
@@ -1,6 +1,7 @@
 package kmer
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/antha-lang/antha/antha/anthalib/wtype"
@@ -37,3 +38,29 @@ func Test1(t *testing.T) {
 		t.Errorf("Expected first hit to be sequence \"There\", instead got \"%s\"", sr[0].Name)
 	}
 }
+
+func TestIndex(t *testing.T) {
+	idx := NewIndex(3)
+	idx.Add(0, "acgtacg")
+	idx.Add(1, "TTTACG")
+
+	if e, g := []Location{{0, 0}, {0, 4}, {1, 3}}, idx.Locations["ACG"]; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected ACG at %v, got %v", e, g)
+	}
+
+	seeds := idx.Seeds("GTACGT")
+	if e, g := 2, len(seeds); e != g {
+		t.Fatalf("expected seeds in %d sequences, got %d", e, g)
+	}
+	diagonals := make(map[int]int)
+	for _, s := range seeds[0] {
+		diagonals[s.Diagonal()]++
+	}
+	// GTACG at offset 2, and ACGT at offset 0
+	if e := map[int]int{2: 3, -2: 2}; !reflect.DeepEqual(e, diagonals) {
+		t.Errorf("expected diagonals %v, got %v", e, diagonals)
+	}
+	if e, g := []Seed{{Location{1, 2}, 1}, {Location{1, 3}, 2}}, seeds[1]; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected seeds %v, got %v", e, g)
+	}
+}
@@ -109,6 +109,28 @@ func Wobble(seq string) (alloptions []string) {
 	return
 }
 
+// MatchesWobbleAt returns whether the pattern, which may contain IUPAC
+// ambiguity codes, matches seq starting at the zero-based position i.
+// Bases of seq are matched case-sensitively.
+func MatchesWobbleAt(seq, pattern string, i int) bool {
+	if i < 0 || i+len(pattern) > len(seq) {
+		return false
+	}
+	for j := 0; j < len(pattern); j++ {
+		if pattern[j] == seq[i+j] {
+			continue
+		}
+		found := false
+		for _, b := range WobbleMap[pattern[j:j+1]] {
+			found = found || b[0] == seq[i+j]
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 func allCombinations(arr [][]string) []string {
 	if len(arr) == 1 {
 		return arr[0]
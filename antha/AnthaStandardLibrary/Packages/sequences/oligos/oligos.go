@@ -25,6 +25,7 @@ package oligos
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -40,6 +41,10 @@ type Primer struct {
 	GCContent   float64
 	Reverse     bool
 	MeltingTemp wunit.Temperature
+
+	HairpinDeltaG   float64 // kcal/mol; set when hairpins are checked
+	SelfDimerDeltaG float64 // kcal/mol; set when self-dimers are checked
+	Score           float64 // Lower is better; set when scoring primers
 }
 
 // checks for overlap between sequences (not including mismatches)
@@ -162,153 +167,219 @@ func DNAregion(sequence wtype.DNASequence, startposition int, endposition int) (
 
 }
 
-// Takes defined region and makes an oligosequence between a defined minimum and maximum length
-// with a melting temperature between a defined minimum and maximum and a maximum GC content ( between 0 and 1).
-// function finds oligo by starting at position 0 and making sequence of the minimum length, calculating parameters
-// and if they do not match then adds one basepair to end of sequence until the maximum length is reached.
-// if still unsuccessful, the function begins again at position 1 and cycles through until a matching oligo sequence is found.
-// overlapthresholdwithseqstoavoid allows maximum permissable partial overlap to be specified by the user, if set to -1 any overlap is tolerated
-func FWDOligoSeq(seq wtype.DNASequence, maxGCcontent float64, minlength int, maxlength int, minmeltingtemp wunit.Temperature, maxmeltingtemp wunit.Temperature, seqstoavoid []string, overlapthresholdwithseqstoavoid int) (oligoseq Primer, err error) {
-
-	if maxlength > len(seq.Sequence()) {
-		return oligoseq, fmt.Errorf("Sequence %s %s too small to design primer for or max length of primer %d too long", seq.Nm, seq.Seq, maxlength)
-	}
+// PrimerConstraints are the constraints on designed primers
+type PrimerConstraints struct {
+	MaxGCContent   float64 // Between 0 and 1
+	MinLength      int
+	MaxLength      int
+	MinMeltingTemp wunit.Temperature
+	MaxMeltingTemp wunit.Temperature
+	SeqsToAvoid    []string
+	// Maximum permissible partial overlap in bp with SeqsToAvoid; ignored
+	// unless positive
+	OverlapThreshold int
+
+	// If set, melting temperatures are calculated by
+	// NearestNeighbourMeltingTemp under these conditions rather than by
+	// BasicMeltingTemp. Secondary structures are calculated under these
+	// conditions or else DefaultConditions.
+	Conditions *Conditions
+	// Primers forming hairpins, self-dimers or dimers with any of
+	// CrossDimerWith with a free energy in kcal/mol below these values are
+	// rejected. Zero disables a check.
+	MinHairpinDeltaG    float64
+	MinSelfDimerDeltaG  float64
+	MinCrossDimerDeltaG float64
+	CrossDimerWith      []string
+
+	// If set, all candidate primers are scored and the one with the lowest
+	// score is returned rather than the first satisfying the constraints.
+	// The score is the distance in ℃ of the melting temperature from
+	// TargetMeltingTemp plus the magnitudes of the hairpin and self-dimer
+	// free energies in kcal/mol.
+	TargetMeltingTemp wunit.Temperature
+
+	// If set, primers start at the end of the sequence rather than within
+	// MaxLength bp of it, as when a whole part is amplified
+	Anchored bool
+}
 
-	region := strings.ToUpper(seq.Sequence())
+func (c PrimerConstraints) scoring() bool {
+	return c.TargetMeltingTemp.ConcreteMeasurement != nil
+}
 
-	for start := 0; start < maxlength; start++ {
+// evaluate checks a candidate primer against the constraints. The error
+// describes the first constraint that the candidate fails.
+func (c PrimerConstraints) evaluate(seq wtype.DNASequence, candidate, primerType string) (oligoseq Primer, err error) {
+	seqName := seq.Name()
+	ssoligo := wtype.MakeSingleStrandedDNASequence("oligo", candidate)
 
-		for end := minlength + start; end <= start+maxlength; end++ {
+	cond := DefaultConditions()
+	if c.Conditions != nil {
+		cond = *c.Conditions
+	}
 
-			var overlapthresholdfail bool
+	gc := sequences.GCcontent(candidate)
+	if gc > c.MaxGCContent {
+		return oligoseq, fmt.Errorf("%s", gcContentErrorString(primerType, seqName, gc, c.MaxGCContent))
+	}
 
-			tempoligoseq := region[start:end]
+	meltingtemp := BasicMeltingTemp(ssoligo)
+	if c.Conditions != nil {
+		if meltingtemp, err = NearestNeighbourMeltingTemp(ssoligo, cond); err != nil {
+			return
+		}
+	}
+	if c.MinMeltingTemp.SIValue() >= meltingtemp.SIValue() {
+		return oligoseq, fmt.Errorf("%s", meltingTempErrorString(primerType, seqName, "minimum", meltingtemp.SIValue(), c.MinMeltingTemp.SIValue()))
+	}
+	if c.MaxMeltingTemp.SIValue() <= meltingtemp.SIValue() {
+		return oligoseq, fmt.Errorf("%s", meltingTempErrorString(primerType, seqName, "maximum", meltingtemp.SIValue(), c.MaxMeltingTemp.SIValue()))
+	}
 
-			ssoligo := wtype.MakeSingleStrandedDNASequence("oligo", tempoligoseq)
+	if bindingsites := CheckNonSpecificBinding(seq, ssoligo); bindingsites != 1 {
+		return oligoseq, fmt.Errorf("%s", bindingSiteErrorString(primerType, seqName, bindingsites))
+	}
 
-			temppercentage := sequences.GCcontent(tempoligoseq)
+	if search.PartialInStrings(c.SeqsToAvoid, candidate, search.IgnoreCase) {
+		return oligoseq, fmt.Errorf("%s", primerErrorString(primerType, seqName, " that contain the specified sequences to avoid.", " removing these from the parameters."))
+	}
 
-			meltingtemp := BasicMeltingTemp(ssoligo)
+	if c.OverlapThreshold > 0 {
+		for _, avoid := range c.SeqsToAvoid {
+			if _, overlap, _ := OverlapCheck(candidate, avoid); overlap > c.OverlapThreshold {
+				return oligoseq, fmt.Errorf("%s", primerErrorString(primerType, seqName, " that violate the overlap threshold.", " adjusting this parameter."))
+			}
+		}
+	}
 
-			bindingsites := CheckNonSpecificBinding(seq, ssoligo)
+	oligoseq.DNASequence = wtype.MakeSingleStrandedDNASequence("Primer", candidate)
+	oligoseq.GCContent = gc
+	oligoseq.Length = len(candidate)
+	oligoseq.MeltingTemp = meltingtemp
 
-			if len(seqstoavoid) > 0 && overlapthresholdwithseqstoavoid > 0 {
-				for _, seq := range seqstoavoid {
-					_, overlap, _ := OverlapCheck(tempoligoseq, seq)
+	if c.MinHairpinDeltaG != 0 || c.scoring() {
+		hairpin, err := Hairpin(candidate, cond)
+		if err != nil {
+			return oligoseq, err
+		} else if c.MinHairpinDeltaG != 0 && hairpin.DeltaG < c.MinHairpinDeltaG {
+			return oligoseq, fmt.Errorf("%s", structureErrorString(primerType, seqName, "a hairpin", hairpin.DeltaG, c.MinHairpinDeltaG))
+		}
+		oligoseq.HairpinDeltaG = hairpin.DeltaG
+	}
 
-					if overlap > overlapthresholdwithseqstoavoid {
-						overlapthresholdfail = true
-					}
-				}
-			}
+	if c.MinSelfDimerDeltaG != 0 || c.scoring() {
+		dimer, err := SelfDimer(candidate, cond)
+		if err != nil {
+			return oligoseq, err
+		} else if c.MinSelfDimerDeltaG != 0 && dimer.DeltaG < c.MinSelfDimerDeltaG {
+			return oligoseq, fmt.Errorf("%s", structureErrorString(primerType, seqName, "a self-dimer", dimer.DeltaG, c.MinSelfDimerDeltaG))
+		}
+		oligoseq.SelfDimerDeltaG = dimer.DeltaG
+	}
 
-			if temppercentage <= maxGCcontent && minmeltingtemp.SIValue() < meltingtemp.SIValue() && maxmeltingtemp.SIValue() > meltingtemp.SIValue() && bindingsites == 1 && !search.PartialInStrings(seqstoavoid, tempoligoseq, search.IgnoreCase) && !overlapthresholdfail {
-				oligoseq.DNASequence = wtype.MakeSingleStrandedDNASequence("Primer", tempoligoseq)
-				oligoseq.GCContent = temppercentage
-				oligoseq.Length = len(tempoligoseq)
-				oligoseq.MeltingTemp = meltingtemp
-				err = nil
-				return
-
-			} else {
-				var combinedErrors []string
-				var i bool = true
-				var primerType, seqName string = "FORWARD", seq.Name()
-				switch i {
-				case temppercentage >= maxGCcontent:
-					combinedErrors = append(combinedErrors, gcContentErrorString(primerType, seqName, temppercentage, maxGCcontent))
-				case minmeltingtemp.SIValue() > meltingtemp.SIValue():
-					combinedErrors = append(combinedErrors, meltingTempErrorString(primerType, seqName, "minimum", meltingtemp.SIValue(), minmeltingtemp.SIValue()))
-				case maxmeltingtemp.SIValue() < meltingtemp.SIValue():
-					combinedErrors = append(combinedErrors, meltingTempErrorString(primerType, seqName, "maximum", meltingtemp.SIValue(), maxmeltingtemp.SIValue()))
-				case bindingsites > 1:
-					combinedErrors = append(combinedErrors, bindingSiteErrorString(primerType, seqName, bindingsites))
-				case search.PartialInStrings(seqstoavoid, tempoligoseq, search.IgnoreCase):
-					combinedErrors = append(combinedErrors, primerErrorString(primerType, seqName, " that contain the specified sequences to avoid.", " removing these from the parameters."))
-				case overlapthresholdfail:
-					combinedErrors = append(combinedErrors, primerErrorString(primerType, seqName, " that violate the overlap threshold.", " adjusting this parameter."))
-				}
-				err = fmt.Errorf(strings.Join(combinedErrors, "\n"))
+	if c.MinCrossDimerDeltaG != 0 {
+		for _, other := range c.CrossDimerWith {
+			dimer, err := CrossDimer(candidate, other, cond)
+			if err != nil {
+				return oligoseq, err
+			} else if dimer.DeltaG < c.MinCrossDimerDeltaG {
+				return oligoseq, fmt.Errorf("%s", structureErrorString(primerType, seqName, "a dimer with "+other, dimer.DeltaG, c.MinCrossDimerDeltaG))
 			}
 		}
 	}
+
+	if c.scoring() {
+		oligoseq.Score = math.Abs(meltingtemp.SIValue()-c.TargetMeltingTemp.SIValue()) - oligoseq.HairpinDeltaG - oligoseq.SelfDimerDeltaG
+	}
 	return
 }
 
-func REVOligoSeq(seq wtype.DNASequence, maxGCcontent float64, minlength int, maxlength int, minmeltingtemp wunit.Temperature, maxmeltingtemp wunit.Temperature, seqstoavoid []string, overlapthresholdwithseqstoavoid int) (oligoseq Primer, err error) {
-
-	//var start int
-	//var end int
-
-	// get the reverse complement of the region
-
-	if maxlength > len(seq.Sequence()) {
-		return oligoseq, fmt.Errorf("Sequence %s %s too small to design primer for or max length of primer %d too long", seq.Nm, seq.Seq, maxlength)
+// designPrimer finds a primer from the 5' end of region, which is a strand
+// of seq. Without a target melting temperature, the first primer
+// satisfying the constraints is returned; otherwise the best scoring.
+func designPrimer(seq wtype.DNASequence, region, primerType string, c PrimerConstraints) (oligoseq Primer, err error) {
+	if c.MinLength < 1 || c.MinLength > c.MaxLength {
+		return oligoseq, fmt.Errorf("invalid primer length range %d to %d for %s", c.MinLength, c.MaxLength, seq.Nm)
+	}
+	if c.MaxLength > len(seq.Sequence()) || c.MinLength > len(region) {
+		return oligoseq, fmt.Errorf("Sequence %s %s too small to design primer for or max length of primer %d too long", seq.Nm, seq.Seq, c.MaxLength)
 	}
 
-	region := seq.Sequence()
-	revregion := sequences.RevComp(region)
-
-	for start := 0; start < maxlength; start++ {
-
-		for end := minlength + start; end <= start+maxlength; end++ {
-
-			var overlapthresholdfail bool
-
-			tempoligoseq := revregion[start:end]
-
-			ssoligo := wtype.MakeSingleStrandedDNASequence("oligo", tempoligoseq)
-
-			temppercentage := sequences.GCcontent(tempoligoseq)
-
-			meltingtemp := BasicMeltingTemp(ssoligo)
-
-			bindingsites := CheckNonSpecificBinding(seq, ssoligo)
-
-			if len(seqstoavoid) > 0 && overlapthresholdwithseqstoavoid > 0 {
-				for _, seq := range seqstoavoid {
-					_, overlap, _ := OverlapCheck(tempoligoseq, seq)
+	starts := c.MaxLength
+	if c.Anchored {
+		starts = 1
+	}
 
-					if overlap > overlapthresholdwithseqstoavoid {
-						overlapthresholdfail = true
-					}
+	found := false
+	for start := 0; start < starts; start++ {
+		for end := c.MinLength + start; end <= start+c.MaxLength && end <= len(region); end++ {
+			candidate, cerr := c.evaluate(seq, region[start:end], primerType)
+			if cerr != nil {
+				if !found {
+					err = cerr
 				}
+				continue
 			}
-
-			if temppercentage <= maxGCcontent && minmeltingtemp.SIValue() < meltingtemp.SIValue() && maxmeltingtemp.SIValue() > meltingtemp.SIValue() && bindingsites == 1 && !search.PartialInStrings(seqstoavoid, tempoligoseq, search.IgnoreCase) && !overlapthresholdfail {
-				oligoseq.DNASequence = wtype.MakeSingleStrandedDNASequence("Primer", tempoligoseq)
-				oligoseq.GCContent = temppercentage
-				oligoseq.Length = len(tempoligoseq)
-				oligoseq.MeltingTemp = meltingtemp
-				err = nil
-				return
-
-			} else {
-				var combinedErrors []string
-				var i bool = true
-				var primerType, seqName string = "REVERSE", seq.Name()
-				switch i {
-				case temppercentage >= maxGCcontent:
-					combinedErrors = append(combinedErrors, gcContentErrorString(primerType, seqName, temppercentage, maxGCcontent))
-				case minmeltingtemp.SIValue() > meltingtemp.SIValue():
-					combinedErrors = append(combinedErrors, meltingTempErrorString(primerType, seqName, "minimum", meltingtemp.SIValue(), minmeltingtemp.SIValue()))
-				case maxmeltingtemp.SIValue() < meltingtemp.SIValue():
-					combinedErrors = append(combinedErrors, meltingTempErrorString(primerType, seqName, "maximum", meltingtemp.SIValue(), maxmeltingtemp.SIValue()))
-				case bindingsites > 1:
-					combinedErrors = append(combinedErrors, bindingSiteErrorString(primerType, seqName, bindingsites))
-				case search.PartialInStrings(seqstoavoid, tempoligoseq, search.IgnoreCase):
-					combinedErrors = append(combinedErrors, primerErrorString(primerType, seqName, " that contain the specified sequences to avoid.", " removing these from the parameters."))
-				case overlapthresholdfail:
-					combinedErrors = append(combinedErrors, primerErrorString(primerType, seqName, " that violate the overlap threshold.", " adjusting this parameter."))
-				}
-				err = fmt.Errorf(strings.Join(combinedErrors, "\n"))
+			if !c.scoring() {
+				return candidate, nil
 			}
+			if !found || candidate.Score < oligoseq.Score {
+				oligoseq = candidate
+			}
+			found = true
+			err = nil
 		}
 	}
-
+	if !found && err == nil {
+		err = fmt.Errorf("no %s primer candidates of %d to %d bp in %s", strings.ToLower(primerType), c.MinLength, c.MaxLength, seq.Nm)
+	}
 	return
 }
 
+// Takes defined region and makes an oligosequence between a defined minimum and maximum length
+// with a melting temperature between a defined minimum and maximum and a maximum GC content ( between 0 and 1).
+// function finds oligo by starting at position 0 and making sequence of the minimum length, calculating parameters
+// and if they do not match then adds one basepair to end of sequence until the maximum length is reached.
+// if still unsuccessful, the function begins again at position 1 and cycles through until a matching oligo sequence is found.
+// overlapthresholdwithseqstoavoid allows maximum permissable partial overlap to be specified by the user, if set to -1 any overlap is tolerated
+func FWDOligoSeq(seq wtype.DNASequence, maxGCcontent float64, minlength int, maxlength int, minmeltingtemp wunit.Temperature, maxmeltingtemp wunit.Temperature, seqstoavoid []string, overlapthresholdwithseqstoavoid int) (oligoseq Primer, err error) {
+	return DesignFWDPrimer(seq, PrimerConstraints{
+		MaxGCContent:     maxGCcontent,
+		MinLength:        minlength,
+		MaxLength:        maxlength,
+		MinMeltingTemp:   minmeltingtemp,
+		MaxMeltingTemp:   maxmeltingtemp,
+		SeqsToAvoid:      seqstoavoid,
+		OverlapThreshold: overlapthresholdwithseqstoavoid,
+	})
+}
+
+// As FWDOligoSeq but reverse complementing the region
+func REVOligoSeq(seq wtype.DNASequence, maxGCcontent float64, minlength int, maxlength int, minmeltingtemp wunit.Temperature, maxmeltingtemp wunit.Temperature, seqstoavoid []string, overlapthresholdwithseqstoavoid int) (oligoseq Primer, err error) {
+	return DesignREVPrimer(seq, PrimerConstraints{
+		MaxGCContent:     maxGCcontent,
+		MinLength:        minlength,
+		MaxLength:        maxlength,
+		MinMeltingTemp:   minmeltingtemp,
+		MaxMeltingTemp:   maxmeltingtemp,
+		SeqsToAvoid:      seqstoavoid,
+		OverlapThreshold: overlapthresholdwithseqstoavoid,
+	})
+}
+
+// DesignFWDPrimer designs a forward primer from the start of seq
+// satisfying constraints, searching as FWDOligoSeq.
+func DesignFWDPrimer(seq wtype.DNASequence, constraints PrimerConstraints) (Primer, error) {
+	return designPrimer(seq, strings.ToUpper(seq.Sequence()), "FORWARD", constraints)
+}
+
+// DesignREVPrimer designs a reverse primer from the end of seq satisfying
+// constraints.
+func DesignREVPrimer(seq wtype.DNASequence, constraints PrimerConstraints) (Primer, error) {
+	return designPrimer(seq, sequences.RevComp(seq.Sequence()), "REVERSE", constraints)
+}
+
 // primerErrorString formats a textual message in the form of an erorr related to primerdesign.
 // primerorientation and sequence name are inputted, along with the error message i.e. "X not found in Y".
 // and a proposed resolution i.e. "Please put X in Y".
@@ -340,6 +411,11 @@ func bindingSiteErrorString(primerOrientation, sequenceName string, bindingSites
 	return primerErrorString(primerOrientation, sequenceName, fmt.Sprintf(" with more than one (%d) binding sites.", bindingSites), " selecting another region.")
 }
 
+// structureErrorString formats a textual message if a primer forms a secondary structure more stable than specified.
+func structureErrorString(primerOrientation, sequenceName, structure string, deltaG, minDeltaG float64) string {
+	return primerErrorString(primerOrientation, sequenceName, fmt.Sprintf(" forming %s with free energy (%f kcal/mol) lower than the minimum specified (%f kcal/mol).", structure, deltaG, minDeltaG), " selecting another region or lowering this parameter.")
+}
+
 func DesignFWDPRimerstoCoverFullSequence(seq wtype.DNASequence, sequenceinterval int, maxGCcontent float64, minlength int, maxlength int, minmeltingtemp wunit.Temperature, maxmeltingtemp wunit.Temperature, seqstoavoid []string, overlapthresholdwithseqstoavoid int) (primers []Primer) {
 	primers = make([]Primer, 0)
 	avoidthese := make([]string, 0)
@@ -393,44 +469,33 @@ func DesignFWDPRimerstoCoverRegion(seq wtype.DNASequence, regionstart, regionend
 	return
 }
 
-/*
-func DesignPrimerstoFlankRegion(seq wtype.DNASequence, regionstart, regionend int, maxGCcontent float64, minlength int, maxlength int, minmeltingtemp wunit.Temperature, maxmeltingtemp wunit.Temperature, seqstoavoid []string, overlapthresholdwithseqstoavoid int) (primers [2]Primer) {
-
-	primers = make([]Primer, 2)
-
-	avoidthese := make([]string, 0)
-
-	if len(seqstoavoid) != 0 {
-		for _, seq := range seqstoavoid {
-			avoidthese = append(avoidthese, seq)
-		}
+// DesignPrimerstoFlankRegion designs a forward primer within flank bp
+// upstream of a region of seq and a reverse primer within flank bp
+// downstream. Positions are in user format (i.e. first position is 1 and
+// not 0). If MinCrossDimerDeltaG is set, the reverse primer must also not
+// dimerise with the forward primer.
+func DesignPrimerstoFlankRegion(seq wtype.DNASequence, regionstart, regionend, flank int, constraints PrimerConstraints) (primers [2]Primer, err error) {
+	if regionstart < 2 || regionend >= len(seq.Sequence()) || regionend < regionstart {
+		return primers, fmt.Errorf("no room to design primers flanking region %d:%d of %s", regionstart, regionend, seq.Nm)
 	}
 
-	if regionstart-100 > 0 {
-		regionstart = regionstart - 100
-	} else {
-		regionstart = 0
+	upstream := DNAregion(seq, int(math.Max(1, float64(regionstart-flank))), regionstart-1)
+	primers[0], err = DesignFWDPrimer(upstream, constraints)
+	if err != nil {
+		return
 	}
+	primers[0].Nm = "primer_" + seq.Nm + "_FWD"
 
-	for i := regionstart; i < regionend; i = i + sequenceinterval {
-
-		region := DNAregion(seq, i, len(seq.Sequence()))
-
-		primer, err := FWDOligoSeq(region, maxGCcontent, minlength, maxlength, minmeltingtemp, maxmeltingtemp, avoidthese, overlapthresholdwithseqstoavoid)
-
-		if err != nil {
-			panic(err.Error() + " for " + region.Nm)
-		}
-
-		primer.Nm = "primer_" + seq.Nm + "_" + strconv.Itoa(i) + ":" + strconv.Itoa(i-1+sequenceinterval)
-
-		primers = append(primers, primer)
-
-		avoidthese = append(avoidthese, primer.Sequence())
+	downstream := DNAregion(seq, regionend+1, int(math.Min(float64(len(seq.Sequence())), float64(regionend+flank))))
+	constraints.CrossDimerWith = append(append([]string(nil), constraints.CrossDimerWith...), primers[0].Sequence())
+	primers[1], err = DesignREVPrimer(downstream, constraints)
+	if err != nil {
+		return
 	}
+	primers[1].Nm = "primer_" + seq.Nm + "_REV"
+	primers[1].Reverse = true
 	return
 }
-*/
 
 func DesignFWDPRimerstoCoverSequence(seq wtype.DNASequence, targetseq string, sequenceinterval int, maxGCcontent float64, minlength int, maxlength int, minmeltingtemp wunit.Temperature, maxmeltingtemp wunit.Temperature, seqstoavoid []string, overlapthresholdwithseqstoavoid int) (primers []Primer) {
 	primers = make([]Primer, 0)
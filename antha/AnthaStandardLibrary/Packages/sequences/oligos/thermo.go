@@ -0,0 +1,383 @@
+// Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package oligos
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+)
+
+// Gas constant in cal/(K mol)
+const gasConstant = 1.9872
+
+const kelvin = 273.15
+
+// nnParam is an enthalpy in kcal/mol and an entropy in cal/(K mol)
+type nnParam struct {
+	H, S float64
+}
+
+// SantaLucia (1998) unified nearest-neighbour parameters for Watson-Crick
+// stacks. Keys are the top strand 5' to 3'; the remaining stacks are found
+// by reverse complementing.
+var nnStacks = map[string]nnParam{
+	"AA": {-7.9, -22.2},
+	"AT": {-7.2, -20.4},
+	"TA": {-7.2, -21.3},
+	"CA": {-8.5, -22.7},
+	"GT": {-8.4, -22.4},
+	"CT": {-7.8, -21.0},
+	"GA": {-8.2, -22.2},
+	"CG": {-10.6, -27.2},
+	"GC": {-9.8, -24.4},
+	"GG": {-8.0, -19.9},
+}
+
+var (
+	// Duplex initiation with a terminal G·C or A·T pair
+	initGC = nnParam{0.1, -2.8}
+	initAT = nnParam{2.3, 4.1}
+	// Symmetry correction for self-complementary duplexes
+	symmetry = nnParam{0, -1.4}
+)
+
+// Free energies of hairpin loops at 37℃ in kcal/mol by loop length
+// (SantaLucia and Hicks, 2004)
+var hairpinLoops = map[int]float64{
+	3: 3.5, 4: 3.5, 5: 3.3, 6: 4.0, 7: 4.2, 8: 4.3, 9: 4.5, 10: 4.6,
+	12: 5.0, 14: 5.1, 16: 5.3, 18: 5.5, 20: 5.7, 25: 6.1, 30: 6.3,
+}
+
+const minHairpinLoop = 3
+
+func complement(b byte) byte {
+	switch b {
+	case 'A':
+		return 'T'
+	case 'T':
+		return 'A'
+	case 'C':
+		return 'G'
+	case 'G':
+		return 'C'
+	}
+	return 0
+}
+
+func pairs(a, b byte) bool {
+	return complement(a) == b
+}
+
+func stack(a, b byte) nnParam {
+	if p, ok := nnStacks[string([]byte{a, b})]; ok {
+		return p
+	}
+	return nnStacks[string([]byte{complement(b), complement(a)})]
+}
+
+func terminal(b byte) nnParam {
+	if b == 'A' || b == 'T' {
+		return initAT
+	}
+	return initGC
+}
+
+func cleanSeq(seq string) (string, error) {
+	seq = strings.ToUpper(seq)
+	for i := 0; i < len(seq); i++ {
+		if complement(seq[i]) == 0 {
+			return "", fmt.Errorf("unsupported base %q at position %d of %s", seq[i], i+1, seq)
+		}
+	}
+	return seq, nil
+}
+
+// Conditions are the conditions under which oligos hybridise
+type Conditions struct {
+	Na    wunit.Concentration // Monovalent cations
+	Mg    wunit.Concentration // Divalent cations
+	DNTP  wunit.Concentration // dNTPs, which chelate divalent cations
+	Oligo wunit.Concentration // Total concentration of oligo strands
+	// Temperature at which free energies of secondary structures are
+	// calculated
+	Temperature wunit.Temperature
+}
+
+// DefaultConditions are 50 mM Na+, 50 nM oligo and no Mg2+ or dNTPs;
+// free energies are calculated at 37℃.
+func DefaultConditions() Conditions {
+	return Conditions{
+		Na:          wunit.NewConcentration(50, "mM"),
+		Oligo:       wunit.NewConcentration(50, "nM"),
+		Temperature: wunit.NewTemperature(37, "℃"),
+	}
+}
+
+func molar(c wunit.Concentration) (float64, error) {
+	if c.ConcreteMeasurement == nil {
+		return 0, nil
+	}
+	m, err := c.InStringUnit("M")
+	if err != nil {
+		return 0, err
+	}
+	return m.RawValue(), nil
+}
+
+// saltCorrection returns the entropy correction per nearest-neighbour
+// stack. Divalent cations are converted to an equivalent sodium
+// concentration following von Ahsen et al. (2001).
+func (c Conditions) saltCorrection() (float64, error) {
+	var conc [3]float64
+	for i, m := range []wunit.Concentration{c.Na, c.Mg, c.DNTP} {
+		v, err := molar(m)
+		if err != nil {
+			return 0, err
+		}
+		conc[i] = v
+	}
+	na, mg, dntp := conc[0], conc[1], conc[2]
+
+	// Na+ + 120 sqrt(Mg2+ - dNTP) with concentrations in mM
+	naEq := na + 120*math.Sqrt(math.Max(mg-dntp, 0)*1000)/1000
+	if naEq <= 0 {
+		return 0, fmt.Errorf("no cations to stabilise duplex")
+	}
+	return 0.368 * math.Log(naEq), nil
+}
+
+func (c Conditions) kelvin() float64 {
+	if c.Temperature.ConcreteMeasurement == nil {
+		return 37 + kelvin
+	}
+	return c.Temperature.ConvertToString("℃") + kelvin
+}
+
+// duplex returns the enthalpy and entropy of a perfectly matched duplex of
+// seq with its complement
+func duplex(seq string) nnParam {
+	p := terminal(seq[0])
+	end := terminal(seq[len(seq)-1])
+	p.H += end.H
+	p.S += end.S
+	for i := 0; i+1 < len(seq); i++ {
+		s := stack(seq[i], seq[i+1])
+		p.H += s.H
+		p.S += s.S
+	}
+	return p
+}
+
+func selfComplementary(seq string) bool {
+	for i := 0; i < len(seq); i++ {
+		if !pairs(seq[i], seq[len(seq)-1-i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// NearestNeighbourMeltingTemp calculates the melting temperature of a
+// DNASequence hybridised to its complement using the unified
+// nearest-neighbour parameters of SantaLucia (1998):
+//
+//	Tm = ΔH / (ΔS + R ln(Ct/x))
+//
+// where Ct is the total oligo concentration and x is 4, or 1 for
+// self-complementary sequences. The entropy is corrected for salt by
+// 0.368 (N-1) ln [Na+] with divalent cations converted to an equivalent
+// Na+ concentration by [Na+] + 120 sqrt([Mg2+] - [dNTP]) in mM (von Ahsen
+// et al., 2001).
+func NearestNeighbourMeltingTemp(primersequence wtype.DNASequence, c Conditions) (meltingtemp wunit.Temperature, err error) {
+	seq, err := cleanSeq(primersequence.Sequence())
+	if err != nil {
+		return
+	}
+	if len(seq) < 2 {
+		return meltingtemp, fmt.Errorf("sequence %q too short to calculate melting temperature", seq)
+	}
+
+	ct, err := molar(c.Oligo)
+	if err != nil {
+		return
+	} else if ct <= 0 {
+		return meltingtemp, fmt.Errorf("oligo concentration must be positive")
+	}
+	salt, err := c.saltCorrection()
+	if err != nil {
+		return
+	}
+
+	p := duplex(seq)
+	x := 4.0
+	if selfComplementary(seq) {
+		p.S += symmetry.S
+		x = 1
+	}
+	p.S += salt * float64(len(seq)-1)
+
+	tm := p.H*1000/(p.S+gasConstant*math.Log(ct/x)) - kelvin
+	return wunit.NewTemperature(tm, "℃"), nil
+}
+
+// A Structure is a secondary structure formed by one or two oligos
+type Structure struct {
+	DeltaG float64 // Free energy in kcal/mol; zero if there is no structure
+	// Paired bases as zero-based positions in the first and second
+	// sequences. For hairpins both positions are in the same sequence.
+	Pairs [][2]int
+}
+
+// helix returns the free energy in kcal/mol of a helix of consecutive
+// stacked pairs (top[i], bottom[i]) where top runs 5' to 3'
+func helix(top, bottom []byte, c Conditions, salt float64) float64 {
+	t := c.kelvin()
+	var p nnParam
+	for i := 0; i+1 < len(top); i++ {
+		s := stack(top[i], top[i+1])
+		p.H += s.H
+		p.S += s.S + salt
+	}
+	for _, b := range []byte{top[0], top[len(top)-1]} {
+		if b == 'A' || b == 'T' {
+			e := terminal(b)
+			g := terminal('G')
+			p.H += e.H - g.H
+			p.S += e.S - g.S
+		}
+	}
+	return p.H - t*p.S/1000
+}
+
+// hairpinLoop returns the free energy of a hairpin loop of n bases,
+// extrapolating between tabulated lengths with the Jacobson-Stockmayer
+// formula
+func hairpinLoop(n int, c Conditions) float64 {
+	if g, ok := hairpinLoops[n]; ok {
+		return g * c.kelvin() / (37 + kelvin)
+	}
+	known := n - 1
+	for ; known > minHairpinLoop; known-- {
+		if _, ok := hairpinLoops[known]; ok {
+			break
+		}
+	}
+	g := hairpinLoops[known] + 2.44*gasConstant*(37+kelvin)/1000*math.Log(float64(n)/float64(known))
+	// Loop free energies are entropic so scale with temperature
+	return g * c.kelvin() / (37 + kelvin)
+}
+
+// Hairpin returns the most stable hairpin formed by an oligo: a stem of
+// consecutive Watson-Crick pairs closing a loop of at least three bases.
+// Mismatches and bulges within stems are not considered.
+func Hairpin(seq string, c Conditions) (best Structure, err error) {
+	seq, err = cleanSeq(seq)
+	if err != nil {
+		return
+	}
+	salt, err := c.saltCorrection()
+	if err != nil {
+		return
+	}
+
+	for i := 0; i < len(seq); i++ {
+		for j := i + minHairpinLoop + 1; j < len(seq); j++ {
+			loop := hairpinLoop(j-i-1, c)
+			var top, bottom []byte
+			var ps [][2]int
+			for m := 0; i-m >= 0 && j+m < len(seq) && pairs(seq[i-m], seq[j+m]); m++ {
+				// Build the stem 5' to 3' along the first arm
+				top = append([]byte{seq[i-m]}, top...)
+				bottom = append([]byte{seq[j+m]}, bottom...)
+				ps = append([][2]int{{i - m, j + m}}, ps...)
+				if len(top) < 2 {
+					continue
+				}
+				if g := helix(top, bottom, c, salt) + loop; g < best.DeltaG {
+					best = Structure{DeltaG: g, Pairs: append([][2]int(nil), ps...)}
+				}
+			}
+		}
+	}
+	return
+}
+
+// CrossDimer returns the most stable duplex of consecutive Watson-Crick
+// pairs formed between two oligos. Mismatches and bulges are not
+// considered.
+func CrossDimer(seq1, seq2 string, c Conditions) (best Structure, err error) {
+	a, err := cleanSeq(seq1)
+	if err != nil {
+		return
+	}
+	b, err := cleanSeq(seq2)
+	if err != nil {
+		return
+	}
+	salt, err := c.saltCorrection()
+	if err != nil {
+		return
+	}
+
+	// Initiation of a bimolecular duplex (SantaLucia and Hicks, 2004)
+	init := nnParam{0.2, -5.7}
+	initG := init.H - c.kelvin()*init.S/1000
+
+	// a[i] pairs with b[k-i] for each antiparallel alignment k
+	for k := 0; k < len(a)+len(b)-1; k++ {
+		var top, bottom []byte
+		var ps [][2]int
+		flush := func() {
+			for start := 0; start+1 < len(top); start++ {
+				for end := start + 2; end <= len(top); end++ {
+					if g := helix(top[start:end], bottom[start:end], c, salt) + initG; g < best.DeltaG {
+						best = Structure{DeltaG: g, Pairs: append([][2]int(nil), ps[start:end]...)}
+					}
+				}
+			}
+			top, bottom, ps = nil, nil, nil
+		}
+		for i := 0; i < len(a); i++ {
+			j := k - i
+			if j < 0 || j >= len(b) || !pairs(a[i], b[j]) {
+				flush()
+				continue
+			}
+			top = append(top, a[i])
+			bottom = append(bottom, b[j])
+			ps = append(ps, [2]int{i, j})
+		}
+		flush()
+	}
+	return
+}
+
+// SelfDimer returns the most stable duplex formed by two copies of an
+// oligo
+func SelfDimer(seq string, c Conditions) (Structure, error) {
+	return CrossDimer(seq, seq, c)
+}
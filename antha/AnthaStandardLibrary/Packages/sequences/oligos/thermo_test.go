@@ -0,0 +1,221 @@
+package oligos
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+)
+
+func TestDuplexFreeEnergy(t *testing.T) {
+	// Worked example of SantaLucia (1998): ΔG37 of CGTTGA is -5.35 kcal/mol
+	p := duplex("CGTTGA")
+	if g := p.H - (37+kelvin)*p.S/1000; math.Abs(g+5.35) > 0.1 {
+		t.Errorf("expecting ΔG37 -5.35 but got %f", g)
+	}
+}
+
+func TestNearestNeighbourMeltingTemp(t *testing.T) {
+	tm := func(seq string, c Conditions) float64 {
+		mt, err := NearestNeighbourMeltingTemp(wtype.MakeSingleStrandedDNASequence("oligo", seq), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mt.SIValue()
+	}
+
+	c := DefaultConditions()
+	primer := "GTAAAACGACGGCCAGT"
+	base := tm(primer, c)
+	if base < 45 || base > 60 {
+		t.Errorf("expecting melting temperature of %s between 45 and 60℃ but got %f", primer, base)
+	}
+	if at := tm("ATTATAATTAATATATT", c); at >= base {
+		t.Errorf("expecting AT rich oligo to melt below %f but got %f", base, at)
+	}
+
+	mg := c
+	mg.Mg = wunit.NewConcentration(1.5, "mM")
+	withMg := tm(primer, mg)
+	if withMg <= base {
+		t.Errorf("expecting Mg2+ to raise melting temperature above %f but got %f", base, withMg)
+	}
+	mg.DNTP = wunit.NewConcentration(1.5, "mM")
+	if chelated := tm(primer, mg); math.Abs(chelated-base) > 1e-9 {
+		t.Errorf("expecting dNTPs to chelate Mg2+ but got %f rather than %f", chelated, base)
+	}
+
+	conc := c
+	conc.Oligo = wunit.NewConcentration(1, "uM")
+	if higher := tm(primer, conc); higher <= base {
+		t.Errorf("expecting higher oligo concentration to raise melting temperature above %f but got %f", base, higher)
+	}
+
+	none := c
+	none.Na = wunit.Concentration{}
+	if _, err := NearestNeighbourMeltingTemp(wtype.MakeSingleStrandedDNASequence("oligo", primer), none); err == nil {
+		t.Errorf("expecting error without cations")
+	}
+	if _, err := NearestNeighbourMeltingTemp(wtype.MakeSingleStrandedDNASequence("oligo", "ACGTN"), c); err == nil {
+		t.Errorf("expecting error for ambiguous base")
+	}
+}
+
+func TestHairpin(t *testing.T) {
+	c := DefaultConditions()
+
+	s, err := Hairpin("GCGCGCAAAAGCGCGC", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DeltaG > -5 {
+		t.Errorf("expecting stable hairpin but got %+v", s)
+	}
+	if len(s.Pairs) != 6 || s.Pairs[0] != [2]int{0, 15} {
+		t.Errorf("expecting stem of 6 pairs from the ends but got %v", s.Pairs)
+	}
+
+	if s, err := Hairpin("AAAAAAAAAAAAAAAA", c); err != nil {
+		t.Fatal(err)
+	} else if s.DeltaG != 0 || len(s.Pairs) != 0 {
+		t.Errorf("expecting no hairpin but got %+v", s)
+	}
+
+	// Loops shorter than three bases cannot form
+	if s, err := Hairpin("GCGCAAGCGC", c); err != nil {
+		t.Fatal(err)
+	} else {
+		for _, p := range s.Pairs {
+			if p[1]-p[0] <= minHairpinLoop {
+				t.Errorf("unexpected pair %v closing a loop of %d bases", p, p[1]-p[0]-1)
+			}
+		}
+	}
+}
+
+func TestDimers(t *testing.T) {
+	c := DefaultConditions()
+	seq := "ATGAGCAAAGGAGAAGAAC"
+
+	full, err := CrossDimer(seq, sequences.RevComp(seq), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(full.Pairs) != len(seq) {
+		t.Errorf("expecting complementary oligos to pair fully but got %v", full.Pairs)
+	}
+
+	partial, err := CrossDimer(seq, sequences.RevComp(seq[:8]), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partial.DeltaG <= full.DeltaG || partial.DeltaG >= 0 {
+		t.Errorf("expecting partial dimer (%f) less stable than full duplex (%f)", partial.DeltaG, full.DeltaG)
+	}
+
+	if s, err := SelfDimer("GAATTCGAATTC", c); err != nil {
+		t.Fatal(err)
+	} else if len(s.Pairs) != 12 {
+		t.Errorf("expecting palindrome to pair fully but got %v", s.Pairs)
+	}
+	if s, err := SelfDimer("AAAAAAAAAA", c); err != nil {
+		t.Fatal(err)
+	} else if s.DeltaG != 0 {
+		t.Errorf("expecting no self-dimer but got %+v", s)
+	}
+}
+
+func TestDesignPrimerConstraints(t *testing.T) {
+	// A hairpin-forming stretch precedes an unstructured one
+	hairpin := "GCGCGCGAAAAGCGCGCGC"
+	seq := wtype.MakeLinearDNASequence("template", hairpin+"ATGAGCAAAGGAGAAGAACTTTTCACTGGAGTTGTCCCAATTCTTGTTGAATTAGATGG")
+	c := DefaultConditions()
+	constraints := PrimerConstraints{
+		MaxGCContent:   0.8,
+		MinLength:      18,
+		MaxLength:      25,
+		MinMeltingTemp: wunit.NewTemperature(40, "C"),
+		MaxMeltingTemp: wunit.NewTemperature(80, "C"),
+		Conditions:     &c,
+	}
+
+	primer, err := DesignFWDPrimer(seq, constraints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(primer.Sequence(), hairpin[:10]) {
+		t.Errorf("expecting first candidate without structure checks but got %s", primer.Sequence())
+	}
+
+	constraints.MinHairpinDeltaG = -3
+	primer, err = DesignFWDPrimer(seq, constraints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, err := Hairpin(primer.Sequence(), c); err != nil {
+		t.Fatal(err)
+	} else if h.DeltaG < -3 || primer.HairpinDeltaG != h.DeltaG {
+		t.Errorf("expecting primer without hairpin but got %s with ΔG %f", primer.Sequence(), h.DeltaG)
+	}
+
+	constraints.TargetMeltingTemp = wunit.NewTemperature(55, "C")
+	scored, err := DesignFWDPrimer(seq, constraints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scored.Score > primer.Score && primer.Score != 0 {
+		t.Errorf("expecting best scoring primer but got score %f", scored.Score)
+	}
+
+	constraints.MinHairpinDeltaG = -100
+	constraints.MinMeltingTemp = wunit.NewTemperature(90, "C")
+	constraints.MaxMeltingTemp = wunit.NewTemperature(95, "C")
+	if _, err := DesignFWDPrimer(seq, constraints); err == nil {
+		t.Errorf("expecting error for unattainable melting temperature")
+	}
+
+	constraints.MinMeltingTemp = wunit.NewTemperature(40, "C")
+	constraints.MaxMeltingTemp = wunit.NewTemperature(80, "C")
+	constraints.MinLength, constraints.MaxLength = 25, 18
+	if _, err := DesignFWDPrimer(seq, constraints); err == nil {
+		t.Errorf("expecting error for minimum length above maximum length")
+	}
+	constraints.MinLength, constraints.MaxLength = 0, 18
+	if _, err := DesignREVPrimer(seq, constraints); err == nil {
+		t.Errorf("expecting error for zero minimum length")
+	}
+}
+
+func TestDesignPrimerstoFlankRegion(t *testing.T) {
+	seq := wtype.MakeLinearDNASequence("sfGFP", oligotests[0].sequence.Sequence())
+	c := DefaultConditions()
+	constraints := PrimerConstraints{
+		MaxGCContent:        0.6,
+		MinLength:           18,
+		MaxLength:           25,
+		MinMeltingTemp:      wunit.NewTemperature(50, "C"),
+		MaxMeltingTemp:      wunit.NewTemperature(65, "C"),
+		Conditions:          &c,
+		MinSelfDimerDeltaG:  -12,
+		MinCrossDimerDeltaG: -12,
+	}
+	primers, err := DesignPrimerstoFlankRegion(seq, 300, 400, 100, constraints)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fwd := strings.Index(seq.Sequence(), primers[0].Sequence())
+	if fwd < 199 || fwd+primers[0].Length > 299 {
+		t.Errorf("expecting forward primer upstream of region but found at %d", fwd)
+	}
+	rev := strings.Index(seq.Sequence(), sequences.RevComp(primers[1].Sequence()))
+	if rev < 400 || rev+primers[1].Length > 500 {
+		t.Errorf("expecting reverse primer downstream of region but found at %d", rev)
+	}
+	if !primers[1].Reverse {
+		t.Errorf("expecting reverse primer to be marked as such")
+	}
+}
@@ -30,10 +30,13 @@ import (
 	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse/fasta"
 	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse/gdx"
 	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse/genbank"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse/sbol"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse/trace"
 	"github.com/antha-lang/antha/antha/anthalib/wtype"
 )
 
-// Creates a DNASequence from a sequence file of format: .gdx .fasta .gb
+// Creates a DNASequence from a sequence file of format: .gdx .fasta .gb .sbol,
+// or from the base calls of a sequencing trace of format: .ab1 .scf
 func DNAFileToDNASequence(sequenceFile wtype.File) (sequences []wtype.DNASequence, err error) {
 
 	sequences = make([]wtype.DNASequence, 0)
@@ -50,6 +53,13 @@ func DNAFileToDNASequence(sequenceFile wtype.File) (sequences []wtype.DNASequenc
 	case filepath.Ext(fn) == ".gb" || filepath.Ext(fn) == ".gbk":
 		seq, err = genbank.GenbankToFeaturelessDNASequence(sequenceFile)
 		sequences = append(sequences, seq)
+	case filepath.Ext(fn) == ".sbol":
+		seqs, err = sbol.SBOLToDNASequences(sequenceFile)
+		sequences = append(sequences, seqs...)
+	case filepath.Ext(fn) == ".ab1" || filepath.Ext(fn) == ".scf":
+		var t trace.Trace
+		t, err = trace.TraceFile(sequenceFile)
+		sequences = append(sequences, t.DNASequence())
 	default:
 		err = fmt.Errorf("non valid sequence file format: %s", filepath.Ext(fn))
 	}
@@ -225,16 +225,25 @@ func featureline1(line string) (reverse bool, class string, startposition int, e
 		}
 		var warning error
 		if strings.Contains(s, `join`) {
-			warning = fmt.Errorf("feature \"%s\" contains join location, adding as one feature only for now", s)
+			if strings.HasPrefix(s, "complement(") {
+				reverse = true
+				s = strings.TrimPrefix(s, "complement(")
+			}
 			s = strings.Replace(s, "Join(", "", -1)
+			s = strings.Replace(s, "join(", "", -1)
 			s = strings.Replace(s, ")", "", -1)
 			joinhandler := strings.Split(s, `,`)
+			// a join of two ranges where the second starts at 1 is a
+			// feature spanning the origin of a plasmid
+			if len(joinhandler) != 2 || !strings.HasPrefix(joinhandler[1], "1..") {
+				warning = fmt.Errorf("feature \"%s\" contains join location, adding as one feature only for now", s)
+			}
 			split := strings.Split(joinhandler[0], "..")
 			startposition, err = strconv.Atoi(split[0])
 			if err != nil {
 				return
 			}
-			split = strings.Split(joinhandler[1], "..")
+			split = strings.Split(joinhandler[len(joinhandler)-1], "..")
 			endposition, err = strconv.Atoi(strings.TrimRight(split[1], "\n"))
 			if err != nil {
 				return
@@ -287,6 +296,16 @@ func featureline1(line string) (reverse bool, class string, startposition int, e
 
 	return
 }
+
+// unquote returns the value of a qualifier following its opening quote,
+// where quotes within the value are escaped by doubling them
+func unquote(value string) string {
+	value = strings.TrimSuffix(strings.TrimSpace(value), `"`)
+	value = strings.Replace(value, `""`, "\x00", -1)
+	value = strings.Replace(value, `"`, "", -1)
+	return strings.TrimSpace(strings.Replace(value, "\x00", `"`, -1))
+}
+
 func featureline2(line string) (description string, found bool) {
 
 	fields := strings.Split(line, " ")
@@ -313,8 +332,7 @@ func featureline2(line string) (description string, found bool) {
 
 			parts := strings.SplitAfterN(line, `="`, 2)
 			if len(parts) == 2 {
-				parts[1] = strings.Replace(parts[1], `"`, "", -1)
-				description = strings.TrimSpace(parts[1])
+				description = unquote(parts[1])
 				found = true
 				return
 			}
@@ -325,8 +343,7 @@ func featureline2(line string) (description string, found bool) {
 			if strings.Contains(line, `="`) {
 				parts := strings.SplitAfterN(line, `="`, 2)
 				if len(parts) == 2 {
-					parts[1] = strings.Replace(parts[1], `"`, "", -1)
-					description = strings.TrimSpace(parts[1])
+					description = unquote(parts[1])
 					found = true
 					return
 				}
@@ -346,8 +363,7 @@ func featureline2(line string) (description string, found bool) {
 
 			parts := strings.SplitAfterN(line, `="`, 2)
 			if len(parts) == 2 {
-				parts[1] = strings.Replace(parts[1], `"`, "", -1)
-				description = strings.TrimSpace(parts[1])
+				description = unquote(parts[1])
 				found = true
 				return
 			}
@@ -441,7 +457,13 @@ func handleFeatures(lines []string, seq string, seqtype string) (features []wtyp
 
 			// Warning! this needs to change to handle cases where start and position assignment has failed rather than just ignoring the problem
 			if startposition != 0 && endposition != 0 {
-				feature = sequences.MakeFeature(description, seq[startposition-1:endposition], startposition, endposition, seqtype, class, rev)
+				var featureSeq string
+				if startposition > endposition {
+					featureSeq = seq[startposition-1:] + seq[:endposition]
+				} else {
+					featureSeq = seq[startposition-1 : endposition]
+				}
+				feature = sequences.MakeFeature(description, featureSeq, startposition, endposition, seqtype, class, rev)
 			}
 
 			features = append(features, feature)
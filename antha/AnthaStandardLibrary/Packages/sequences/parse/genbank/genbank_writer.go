@@ -0,0 +1,144 @@
+// antha/AnthaStandardLibrary/Packages/sequences/parse/genbank/genbank_writer.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package genbank
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/internal/featrange"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+const (
+	// Width of lines of qualifier values and of the indent before them
+	qualifierWidth  = 58
+	qualifierIndent = "                     "
+	// Bases per group and groups per line in the ORIGIN section
+	baseGroup     = 10
+	groupsPerLine = 6
+)
+
+// locusName returns name with whitespace replaced, as spaces separate
+// fields of the LOCUS line
+func locusName(name string) string {
+	if len(name) == 0 {
+		return "unnamed"
+	}
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// featureLocation returns the location of a feature in genbank format.
+// Features on a plasmid spanning the origin are written as joins.
+func featureLocation(f wtype.Feature, length int) string {
+	var parts []string
+	for _, r := range featrange.Ranges(f, length) {
+		parts = append(parts, fmt.Sprintf("%d..%d", r[0], r[1]))
+	}
+
+	loc := parts[0]
+	if len(parts) > 1 {
+		loc = fmt.Sprintf("join(%s)", strings.Join(parts, ","))
+	}
+
+	if f.Reverse {
+		return fmt.Sprintf("complement(%s)", loc)
+	}
+	return loc
+}
+
+// writeQualifier writes a quoted qualifier, wrapping values longer than a
+// line if wrap is true
+func writeQualifier(buf *bytes.Buffer, key, value string, wrap bool) {
+	line := fmt.Sprintf("/%s=\"%s\"", key, strings.Replace(value, `"`, `""`, -1))
+	if !wrap {
+		fmt.Fprintf(buf, "%s%s\n", qualifierIndent, line)
+		return
+	}
+	for len(line) > qualifierWidth {
+		fmt.Fprintf(buf, "%s%s\n", qualifierIndent, line[:qualifierWidth])
+		line = line[qualifierWidth:]
+	}
+	fmt.Fprintf(buf, "%s%s\n", qualifierIndent, line)
+}
+
+// AnnotatedSeqToGenbank returns the contents of a genbank file describing
+// an annotated DNASequence. Each feature is written with its name as its
+// label and its protein sequence, if any, as its translation.
+func AnnotatedSeqToGenbank(seq wtype.DNASequence) ([]byte, error) {
+	var buf bytes.Buffer
+
+	topology := "linear"
+	if seq.Plasmid {
+		topology = "circular"
+	}
+	name := locusName(seq.Nm)
+	date := strings.ToUpper(time.Now().Format("02-Jan-2006"))
+
+	fmt.Fprintf(&buf, "LOCUS       %-16s %11d bp    DNA     %-8s SYN %s\n", name, len(seq.Seq), topology, date)
+	fmt.Fprintf(&buf, "DEFINITION  %s.\n", seq.Nm)
+	fmt.Fprintf(&buf, "ACCESSION   .\n")
+	fmt.Fprintf(&buf, "VERSION     .\n")
+	fmt.Fprintf(&buf, "KEYWORDS    .\n")
+	fmt.Fprintf(&buf, "SOURCE      .\n")
+	fmt.Fprintf(&buf, "  ORGANISM  .\n")
+
+	fmt.Fprintf(&buf, "FEATURES             Location/Qualifiers\n")
+	for _, f := range seq.Features {
+		class := f.Class
+		if len(class) == 0 {
+			class = wtype.MISC_FEATURE
+		}
+		if strings.ContainsAny(class, " \t") {
+			return nil, fmt.Errorf("invalid class %q of feature %s", class, f.Name)
+		}
+		if f.StartPosition < 1 || f.EndPosition < 1 || f.StartPosition > len(seq.Seq) || f.EndPosition > len(seq.Seq) {
+			return nil, fmt.Errorf("feature %s at %d to %d is outside sequence %s of length %d", f.Name, f.StartPosition, f.EndPosition, seq.Nm, len(seq.Seq))
+		}
+
+		fmt.Fprintf(&buf, "     %-15s %s\n", class, featureLocation(f, len(seq.Seq)))
+		writeQualifier(&buf, "label", f.Name, false)
+		if len(f.Protseq) != 0 {
+			writeQualifier(&buf, "translation", f.Protseq, true)
+		}
+	}
+
+	fmt.Fprintf(&buf, "ORIGIN\n")
+	bases := strings.ToLower(seq.Seq)
+	for i := 0; i < len(bases); i += baseGroup * groupsPerLine {
+		fmt.Fprintf(&buf, "%9d", i+1)
+		for j := i; j < i+baseGroup*groupsPerLine && j < len(bases); j += baseGroup {
+			end := j + baseGroup
+			if end > len(bases) {
+				end = len(bases)
+			}
+			fmt.Fprintf(&buf, " %s", bases[j:end])
+		}
+		fmt.Fprintf(&buf, "\n")
+	}
+	fmt.Fprintf(&buf, "//\n")
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,117 @@
+package genbank
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+func makeTestAnnotatedSeq(t *testing.T) wtype.DNASequence {
+	promoter := "TTGACAATTAATCATCGGCTCGTATAATGTGTGG"
+	cds := "ATGAGCAAAGGAGAAGAACTTTTCACTGGAGTTGTCCCAATTCTTGTTGAATTAGATGGTGATGTTAATGGGCACAAATTTTCTGTCAGTGGAGAGGGTGAAGGTGATGCTACATAA"
+	spacer := "GCGGCCGCTACTAGTAGCGGCCGCTGCAGTCCGGCAAAAAAC"
+	origin := "CCTAGGTTAGCGATCAGTCA"
+
+	seq := origin[10:] + promoter + spacer + wtype.RevComp(cds) + spacer + origin[:10]
+
+	features := []wtype.Feature{
+		sequences.MakeFeature("promoter", promoter, 0, 0, "dna", wtype.PROMOTER, ""),
+		sequences.MakeFeature("gfp \"partial\"", wtype.RevComp(cds), 0, 0, "dna", wtype.CDS, "reverse"),
+		sequences.MakeFeature("origin", origin, 0, 0, "dna", wtype.MISC_FEATURE, ""),
+		sequences.MakeFeature("reverse origin", origin[5:15], 0, 0, "dna", wtype.MISC_FEATURE, "reverse"),
+	}
+
+	annotated, err := sequences.MakeAnnotatedSeq("test plasmid", seq, true, features)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return annotated
+}
+
+func TestGenbankRoundTrip(t *testing.T) {
+	seq := makeTestAnnotatedSeq(t)
+
+	contents, err := AnnotatedSeqToGenbank(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := GenbankContentsToAnnotatedSeq(contents)
+	if err != nil {
+		t.Fatalf("%s in:\n%s", err, contents)
+	}
+
+	if e, g := "test_plasmid", parsed.Nm; e != g {
+		t.Errorf("expected name %q, got %q", e, g)
+	}
+	if !parsed.Plasmid {
+		t.Error("expected a plasmid")
+	}
+	if !strings.EqualFold(seq.Seq, parsed.Seq) {
+		t.Errorf("expected sequence %s, got %s", seq.Seq, parsed.Seq)
+	}
+
+	if e, g := len(seq.Features), len(parsed.Features); e != g {
+		t.Fatalf("expected %d features, got %d: %v", e, g, parsed.Features)
+	}
+	for i, e := range seq.Features {
+		g := parsed.Features[i]
+		if e.Name != g.Name || e.Class != g.Class || e.Reverse != g.Reverse || e.StartPosition != g.StartPosition || e.EndPosition != g.EndPosition {
+			t.Errorf("expected feature %+v, got %+v", e, g)
+		}
+		if !strings.EqualFold(e.DNASeq, g.DNASeq) {
+			t.Errorf("expected sequence of feature %s to be %s, got %s", e.Name, e.DNASeq, g.DNASeq)
+		}
+		if e.Protseq != g.Protseq {
+			t.Errorf("expected translation of feature %s to be %s, got %s", e.Name, e.Protseq, g.Protseq)
+		}
+	}
+}
+
+func TestGenbankFormat(t *testing.T) {
+	seq := makeTestAnnotatedSeq(t)
+
+	contents, err := AnnotatedSeqToGenbank(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"     promoter        11..44",
+		"     CDS             complement(87..203)",
+		"                     /label=\"gfp \"\"partial\"\"\"",
+		"     misc_feature    join(246..255,1..10)",
+		"     misc_feature    complement(join(251..255,1..5))",
+		"        1 cgatcagtca ttgacaatta atcatcggct cgtataatgt gtgggcggcc gctactagta",
+	} {
+		if !strings.Contains(string(contents), line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, contents)
+		}
+	}
+
+	if !strings.HasPrefix(string(contents), "LOCUS       test_plasmid") {
+		t.Errorf("unexpected LOCUS line in:\n%s", contents)
+	}
+}
+
+func TestFeatureLocation(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Feature  wtype.Feature
+		Location string
+	}{
+		{"forward", wtype.Feature{StartPosition: 3, EndPosition: 8, DNASeq: "ACGTAC"}, "3..8"},
+		{"forward spanning origin", wtype.Feature{StartPosition: 18, EndPosition: 3, DNASeq: "ACGTAC"}, "join(18..20,1..3)"},
+		{"reverse searched", wtype.Feature{Reverse: true, StartPosition: 8, EndPosition: 3, DNASeq: "ACGTAC"}, "complement(3..8)"},
+		{"reverse parsed", wtype.Feature{Reverse: true, StartPosition: 3, EndPosition: 8, DNASeq: "ACGTAC"}, "complement(3..8)"},
+		{"reverse searched spanning origin", wtype.Feature{Reverse: true, StartPosition: 3, EndPosition: 18, DNASeq: "ACGTAC"}, "complement(join(18..20,1..3))"},
+		{"reverse parsed spanning origin", wtype.Feature{Reverse: true, StartPosition: 18, EndPosition: 3, DNASeq: "ACGTAC"}, "complement(join(18..20,1..3))"},
+		{"reverse without sequence", wtype.Feature{Reverse: true, StartPosition: 8, EndPosition: 3}, "complement(3..8)"},
+	} {
+		if e, g := test.Location, featureLocation(test.Feature, 20); e != g {
+			t.Errorf("%s: expected location %s, got %s", test.Name, e, g)
+		}
+	}
+}
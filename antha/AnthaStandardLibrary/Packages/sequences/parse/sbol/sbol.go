@@ -0,0 +1,472 @@
+// antha/AnthaStandardLibrary/Packages/sequences/parse/sbol/sbol.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+// Package sbol converts DNA sequences to and from SBOL 2 documents in
+// RDF/XML format.
+//
+// Each DNASequence corresponds to a ComponentDefinition of type DnaRegion
+// with a Sequence of its elements; features correspond to
+// SequenceAnnotations with Range locations and Sequence Ontology roles.
+package sbol
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/internal/featrange"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+const (
+	rdfNS     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	sbolNS    = "http://sbols.org/v2#"
+	dctermsNS = "http://purl.org/dc/terms/"
+	provNS    = "http://www.w3.org/ns/prov#"
+
+	// URIPrefix is prepended to the URIs of objects written by
+	// DNASequencesToSBOL
+	URIPrefix = "http://antha-lang.org/"
+
+	dnaRegion         = "http://www.biopax.org/release/biopax-level3.owl#DnaRegion"
+	iupacDNA          = "http://www.chem.qmul.ac.uk/iubmb/misc/naseq.html"
+	inline            = sbolNS + "inline"
+	reverseComplement = sbolNS + "reverseComplement"
+
+	soPrefix       = "http://identifiers.org/so/"
+	circularDNA    = soPrefix + "SO:0000988"
+	linearDNA      = soPrefix + "SO:0000987"
+	singleStranded = soPrefix + "SO:0000984"
+	doubleStranded = soPrefix + "SO:0000985"
+	soRegion       = soPrefix + "SO:0000001"
+)
+
+// featureRoles maps feature classes to Sequence Ontology terms
+var featureRoles = map[string]string{
+	wtype.ORF:           soPrefix + "SO:0000236",
+	wtype.CDS:           soPrefix + "SO:0000316",
+	wtype.GENE:          soPrefix + "SO:0000704",
+	wtype.MISC_FEATURE:  soRegion,
+	wtype.PROMOTER:      soPrefix + "SO:0000167",
+	wtype.TRNA:          soPrefix + "SO:0000253",
+	wtype.RRNA:          soPrefix + "SO:0000252",
+	wtype.NCRNA:         soPrefix + "SO:0000655",
+	wtype.REGULATORY:    soPrefix + "SO:0005836",
+	wtype.REPEAT_REGION: soPrefix + "SO:0000657",
+	"RBS":               soPrefix + "SO:0000139",
+	"terminator":        soPrefix + "SO:0000141",
+	"rep_origin":        soPrefix + "SO:0000296",
+	"primer_bind":       soPrefix + "SO:0005850",
+}
+
+func featureRole(class string) string {
+	for c, role := range featureRoles {
+		if strings.EqualFold(c, class) {
+			return role
+		}
+	}
+	return soRegion
+}
+
+func featureClass(roles []resource) string {
+	for _, r := range roles {
+		for c, role := range featureRoles {
+			if role == r.Resource && role != soRegion {
+				return c
+			}
+		}
+	}
+	return wtype.MISC_FEATURE
+}
+
+// displayID returns name modified to be a valid SBOL displayId, which
+// consists of letters, digits and underscores and does not start with a
+// digit
+func displayID(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, name)
+	if len(id) == 0 || unicode.IsDigit(rune(id[0])) {
+		id = "_" + id
+	}
+	return id
+}
+
+type resource struct {
+	Resource string `xml:"resource,attr"`
+}
+
+// Types read from SBOL documents. These match elements by local name only.
+
+type document struct {
+	ComponentDefinitions []componentDefinition `xml:"ComponentDefinition"`
+	Sequences            []sequence            `xml:"Sequence"`
+}
+
+type identified struct {
+	About     string `xml:"about,attr"`
+	DisplayID string `xml:"displayId"`
+	Title     string `xml:"title"`
+}
+
+func (id identified) name() string {
+	if len(id.Title) != 0 {
+		return id.Title
+	}
+	return id.DisplayID
+}
+
+type componentDefinition struct {
+	identified
+	Types       []resource           `xml:"type"`
+	Roles       []resource           `xml:"role"`
+	Sequences   []resource           `xml:"sequence"`
+	Components  []component          `xml:"component>Component"`
+	Annotations []sequenceAnnotation `xml:"sequenceAnnotation>SequenceAnnotation"`
+}
+
+func (cd componentDefinition) hasType(t string) bool {
+	for _, r := range cd.Types {
+		if r.Resource == t {
+			return true
+		}
+	}
+	return false
+}
+
+type component struct {
+	identified
+	Definition resource `xml:"definition"`
+}
+
+type sequenceAnnotation struct {
+	identified
+	Ranges    []location `xml:"location>Range"`
+	Component resource   `xml:"component"`
+	Roles     []resource `xml:"role"`
+}
+
+type location struct {
+	identified
+	Start       int      `xml:"start"`
+	End         int      `xml:"end"`
+	Orientation resource `xml:"orientation"`
+}
+
+type sequence struct {
+	identified
+	Elements string   `xml:"elements"`
+	Encoding resource `xml:"encoding"`
+}
+
+// SBOLToDNASequences parses a file in SBOL format into DNASequences
+func SBOLToDNASequences(file wtype.File) ([]wtype.DNASequence, error) {
+	data, err := file.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return SBOLContentsToDNASequences(data)
+}
+
+// SBOLContentsToDNASequences parses the contents of an SBOL document into
+// a DNASequence for each DNA ComponentDefinition with a sequence which is
+// not a subcomponent of another. Annotations located by Ranges become
+// features, named after their subcomponent if they have no name
+// themselves.
+func SBOLContentsToDNASequences(contents []byte) ([]wtype.DNASequence, error) {
+	var doc document
+	if err := xml.Unmarshal(contents, &doc); err != nil {
+		return nil, fmt.Errorf("invalid SBOL document: %s", err)
+	}
+
+	seqs := make(map[string]sequence)
+	for _, s := range doc.Sequences {
+		seqs[s.About] = s
+	}
+	defs := make(map[string]componentDefinition)
+	subcomponents := make(map[string]bool)
+	for _, cd := range doc.ComponentDefinitions {
+		defs[cd.About] = cd
+		for _, c := range cd.Components {
+			subcomponents[c.Definition.Resource] = true
+		}
+	}
+
+	var ret []wtype.DNASequence
+	for _, cd := range doc.ComponentDefinitions {
+		if subcomponents[cd.About] || !cd.hasType(dnaRegion) || len(cd.Sequences) == 0 {
+			continue
+		}
+		seq, err := makeDNASequence(cd, seqs, defs)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, seq)
+	}
+
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no DNA components with sequences found in SBOL document")
+	}
+	return ret, nil
+}
+
+func makeDNASequence(cd componentDefinition, seqs map[string]sequence, defs map[string]componentDefinition) (wtype.DNASequence, error) {
+	s, ok := seqs[cd.Sequences[0].Resource]
+	if !ok {
+		return wtype.DNASequence{}, fmt.Errorf("sequence %s of %s not found", cd.Sequences[0].Resource, cd.About)
+	}
+	if len(s.Encoding.Resource) != 0 && s.Encoding.Resource != iupacDNA {
+		return wtype.DNASequence{}, fmt.Errorf("unsupported encoding %s of sequence %s", s.Encoding.Resource, s.About)
+	}
+
+	ret := wtype.MakeLinearDNASequence(cd.name(), strings.ToUpper(strings.Join(strings.Fields(s.Elements), "")))
+	ret.Plasmid = cd.hasType(circularDNA)
+	ret.Singlestranded = cd.hasType(singleStranded)
+
+	components := make(map[string]component)
+	for _, c := range cd.Components {
+		components[c.About] = c
+	}
+
+	for _, sa := range cd.Annotations {
+		if len(sa.Ranges) == 0 {
+			continue
+		}
+
+		name := sa.name()
+		roles := sa.Roles
+		if c, ok := components[sa.Component.Resource]; ok {
+			def := defs[c.Definition.Resource]
+			if len(sa.Title) == 0 {
+				name = def.name()
+			}
+			if len(roles) == 0 {
+				roles = def.Roles
+			}
+		}
+
+		f, err := makeFeature(name, featureClass(roles), sa.Ranges, ret)
+		if err != nil {
+			return ret, fmt.Errorf("annotation %s of %s: %s", sa.About, cd.About, err)
+		}
+		ret.Features = append(ret.Features, f)
+	}
+
+	return ret, nil
+}
+
+// makeFeature makes a feature from the ranges of an annotation. On a
+// plasmid, two ranges meeting at the origin make a feature spanning it;
+// otherwise the feature spans all ranges.
+func makeFeature(name, class string, ranges []location, seq wtype.DNASequence) (wtype.Feature, error) {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start < ranges[j].Start
+	})
+	for _, r := range ranges {
+		if r.Start < 1 || r.End < r.Start || r.End > len(seq.Seq) {
+			return wtype.Feature{}, fmt.Errorf("invalid range %d to %d in sequence of length %d", r.Start, r.End, len(seq.Seq))
+		}
+	}
+
+	first, last := ranges[0], ranges[len(ranges)-1]
+	start, end := first.Start, last.End
+	bases := seq.Seq[start-1 : end]
+	if seq.Plasmid && len(ranges) == 2 && first.Start == 1 && last.End == len(seq.Seq) {
+		start, end = last.Start, first.End
+		bases = seq.Seq[start-1:] + seq.Seq[:end]
+	}
+
+	rev := ""
+	if first.Orientation.Resource == reverseComplement {
+		rev = "reverse"
+	}
+	// as in the genbank parser, the start of a feature precedes its end
+	// unless it spans the origin, whichever its orientation
+	return sequences.MakeFeature(name, bases, start, end, "dna", class, rev), nil
+}
+
+// Types written to SBOL documents. These name elements with the prefixes
+// declared on the root element.
+
+type rdfDocument struct {
+	XMLName              xml.Name                 `xml:"rdf:RDF"`
+	RDF                  string                   `xml:"xmlns:rdf,attr"`
+	SBOL                 string                   `xml:"xmlns:sbol,attr"`
+	DCTerms              string                   `xml:"xmlns:dcterms,attr"`
+	Prov                 string                   `xml:"xmlns:prov,attr"`
+	ComponentDefinitions []rdfComponentDefinition `xml:"sbol:ComponentDefinition"`
+	Sequences            []rdfSequence            `xml:"sbol:Sequence"`
+}
+
+type rdfResource struct {
+	Resource string `xml:"rdf:resource,attr"`
+}
+
+type rdfIdentified struct {
+	About        string      `xml:"rdf:about,attr"`
+	PersistentID rdfResource `xml:"sbol:persistentIdentity"`
+	DisplayID    string      `xml:"sbol:displayId"`
+	Version      string      `xml:"sbol:version,omitempty"`
+	Title        string      `xml:"dcterms:title,omitempty"`
+}
+
+func makeIdentified(parent, id string) rdfIdentified {
+	persistent := parent + "/" + id
+	return rdfIdentified{
+		About:        persistent + "/1",
+		PersistentID: rdfResource{persistent},
+		DisplayID:    id,
+		Version:      "1",
+	}
+}
+
+type rdfComponentDefinition struct {
+	rdfIdentified
+	Types       []rdfResource           `xml:"sbol:type"`
+	Roles       []rdfResource           `xml:"sbol:role"`
+	Sequence    rdfResource             `xml:"sbol:sequence"`
+	Annotations []rdfSequenceAnnotation `xml:"sbol:sequenceAnnotation>sbol:SequenceAnnotation"`
+}
+
+type rdfSequenceAnnotation struct {
+	rdfIdentified
+	Ranges []rdfRange    `xml:"sbol:location>sbol:Range"`
+	Roles  []rdfResource `xml:"sbol:role"`
+}
+
+type rdfRange struct {
+	rdfIdentified
+	Start       int         `xml:"sbol:start"`
+	End         int         `xml:"sbol:end"`
+	Orientation rdfResource `xml:"sbol:orientation"`
+}
+
+type rdfSequence struct {
+	rdfIdentified
+	Elements string      `xml:"sbol:elements"`
+	Encoding rdfResource `xml:"sbol:encoding"`
+}
+
+// featureRanges returns the ranges covered by a feature, which are two if
+// the feature spans the origin of a plasmid
+func featureRanges(f wtype.Feature, length int) [][2]int {
+	start, end := f.StartPosition, f.EndPosition
+	if f.Reverse && start > end {
+		start, end = end, start
+	}
+	if start <= end {
+		return [][2]int{{start, end}}
+	}
+	return [][2]int{{start, length}, {1, end}}
+}
+
+// DNASequencesToSBOL returns an SBOL document describing DNASequences and
+// their features. Objects are identified by URIs starting with URIPrefix.
+func DNASequencesToSBOL(seqs []wtype.DNASequence) ([]byte, error) {
+	doc := rdfDocument{
+		RDF:     rdfNS,
+		SBOL:    sbolNS,
+		DCTerms: dctermsNS,
+		Prov:    provNS,
+	}
+
+	ids := make(map[string]bool)
+	for _, seq := range seqs {
+		id := displayID(seq.Nm)
+		if ids[id] {
+			return nil, fmt.Errorf("more than one sequence with identifier %s", id)
+		}
+		ids[id] = true
+
+		s := rdfSequence{
+			rdfIdentified: makeIdentified(strings.TrimSuffix(URIPrefix, "/"), id+"_sequence"),
+			Elements:      strings.ToLower(seq.Seq),
+			Encoding:      rdfResource{iupacDNA},
+		}
+
+		topology := linearDNA
+		if seq.Plasmid {
+			topology = circularDNA
+		}
+		strands := doubleStranded
+		if seq.Singlestranded {
+			strands = singleStranded
+		}
+
+		cd := rdfComponentDefinition{
+			rdfIdentified: makeIdentified(strings.TrimSuffix(URIPrefix, "/"), id),
+			Types:         []rdfResource{{dnaRegion}, {topology}, {strands}},
+			Sequence:      rdfResource{s.About},
+		}
+		cd.Title = seq.Nm
+		if seq.Plasmid {
+			cd.Roles = append(cd.Roles, rdfResource{soPrefix + "SO:0000155"})
+		}
+
+		parent := strings.TrimSuffix(cd.About, "/1")
+		for i, f := range seq.Features {
+			if f.StartPosition < 1 || f.EndPosition < 1 || f.StartPosition > len(seq.Seq) || f.EndPosition > len(seq.Seq) {
+				return nil, fmt.Errorf("feature %s at %d to %d is outside sequence %s of length %d", f.Name, f.StartPosition, f.EndPosition, seq.Nm, len(seq.Seq))
+			}
+
+			sa := rdfSequenceAnnotation{
+				rdfIdentified: makeIdentified(parent, fmt.Sprintf("annotation%d", i)),
+				Roles:         []rdfResource{{featureRole(f.Class)}},
+			}
+			sa.Title = f.Name
+
+			orientation := inline
+			if f.Reverse {
+				orientation = reverseComplement
+			}
+			for j, r := range featrange.Ranges(f, len(seq.Seq)) {
+				sa.Ranges = append(sa.Ranges, rdfRange{
+					rdfIdentified: makeIdentified(strings.TrimSuffix(sa.About, "/1"), fmt.Sprintf("range%d", j)),
+					Start:         r[0],
+					End:           r[1],
+					Orientation:   rdfResource{orientation},
+				})
+			}
+			cd.Annotations = append(cd.Annotations, sa)
+		}
+
+		doc.ComponentDefinitions = append(doc.ComponentDefinitions, cd)
+		doc.Sequences = append(doc.Sequences, s)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,155 @@
+package sbol
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+func TestSBOLRoundTrip(t *testing.T) {
+	promoter := "TTGACAATTAATCATCGGCTCGTATAATGTGTGG"
+	terminator := "CCAGGCATCAAATAAAACGAAAGGCTCAGTCG"
+	origin := "CCTAGGTTAGCGATCAGTCA"
+	seq := origin[10:] + promoter + "GCGGCCGCTACTAGTA" + wtype.RevComp(terminator) + origin[:10]
+
+	plasmid, err := sequences.MakeAnnotatedSeq("test plasmid", seq, true, []wtype.Feature{
+		sequences.MakeFeature("promoter", promoter, 0, 0, "dna", wtype.PROMOTER, ""),
+		sequences.MakeFeature("terminator", wtype.RevComp(terminator), 0, 0, "dna", "terminator", "reverse"),
+		sequences.MakeFeature("origin", origin, 0, 0, "dna", wtype.MISC_FEATURE, ""),
+		sequences.MakeFeature("reverse origin", origin[5:15], 0, 0, "dna", wtype.MISC_FEATURE, "reverse"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oligo := wtype.MakeSingleStrandedDNASequence("oligo", "ACGTACGTACGT")
+
+	contents, err := DNASequencesToSBOL([]wtype.DNASequence{plasmid, oligo})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{
+		`<sbol:ComponentDefinition rdf:about="http://antha-lang.org/test_plasmid/1">`,
+		`<sbol:start>98</sbol:start>`,
+		`<sbol:end>102</sbol:end>`,
+		`<sbol:type rdf:resource="http://identifiers.org/so/SO:0000988"></sbol:type>`,
+		`<sbol:orientation rdf:resource="http://sbols.org/v2#reverseComplement"></sbol:orientation>`,
+	} {
+		if !strings.Contains(string(contents), s) {
+			t.Errorf("expected %s in:\n%s", s, contents)
+		}
+	}
+
+	parsed, err := SBOLContentsToDNASequences(contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, g := 2, len(parsed); e != g {
+		t.Fatalf("expected %d sequences, got %d", e, g)
+	}
+
+	// as in the genbank parser, the start of features which do not span the
+	// origin precedes their end, whichever their orientation
+	var features []wtype.Feature
+	for _, f := range plasmid.Features {
+		if f.Reverse {
+			f.StartPosition, f.EndPosition = f.EndPosition, f.StartPosition
+		}
+		features = append(features, f)
+	}
+	if e, g := (wtype.Feature{Name: "terminator", StartPosition: 61, EndPosition: 92}), features[1]; e.Name != g.Name || e.StartPosition != g.StartPosition || e.EndPosition != g.EndPosition {
+		t.Errorf("expected %s at %d..%d, got %s at %d..%d", e.Name, e.StartPosition, e.EndPosition, g.Name, g.StartPosition, g.EndPosition)
+	}
+	if e, g := (wtype.Feature{Name: "reverse origin", StartPosition: 98, EndPosition: 5}), features[3]; e.Name != g.Name || e.StartPosition != g.StartPosition || e.EndPosition != g.EndPosition {
+		t.Errorf("expected %s at %d..%d, got %s at %d..%d", e.Name, e.StartPosition, e.EndPosition, g.Name, g.StartPosition, g.EndPosition)
+	}
+	expected := plasmid
+	expected.Features = features
+
+	for i, e := range []wtype.DNASequence{expected, oligo} {
+		g := parsed[i]
+		if e.Nm != g.Nm || e.Plasmid != g.Plasmid || e.Singlestranded != g.Singlestranded || e.Seq != g.Seq {
+			t.Errorf("expected %s, got %s", e.Nm, g.Nm)
+		}
+		if !reflect.DeepEqual(e.Features, g.Features) {
+			t.Errorf("expected features %+v, got %+v", e.Features, g.Features)
+		}
+	}
+}
+
+// A document as written by other tools, where a part is a subcomponent
+var testSBOL = `<?xml version="1.0" ?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:prov="http://www.w3.org/ns/prov#" xmlns:sbol="http://sbols.org/v2#">
+  <sbol:ComponentDefinition rdf:about="http://example.com/device/1">
+    <sbol:displayId>device</sbol:displayId>
+    <sbol:type rdf:resource="http://www.biopax.org/release/biopax-level3.owl#DnaRegion"/>
+    <sbol:sequence rdf:resource="http://example.com/device_seq/1"/>
+    <sbol:component>
+      <sbol:Component rdf:about="http://example.com/device/rbs_c/1">
+        <sbol:displayId>rbs_c</sbol:displayId>
+        <sbol:definition rdf:resource="http://example.com/B0034/1"/>
+      </sbol:Component>
+    </sbol:component>
+    <sbol:sequenceAnnotation>
+      <sbol:SequenceAnnotation rdf:about="http://example.com/device/anno/1">
+        <sbol:displayId>anno</sbol:displayId>
+        <sbol:location>
+          <sbol:Range rdf:about="http://example.com/device/anno/range/1">
+            <sbol:displayId>range</sbol:displayId>
+            <sbol:start>3</sbol:start>
+            <sbol:end>14</sbol:end>
+            <sbol:orientation rdf:resource="http://sbols.org/v2#inline"/>
+          </sbol:Range>
+        </sbol:location>
+        <sbol:component rdf:resource="http://example.com/device/rbs_c/1"/>
+      </sbol:SequenceAnnotation>
+    </sbol:sequenceAnnotation>
+  </sbol:ComponentDefinition>
+  <sbol:ComponentDefinition rdf:about="http://example.com/B0034/1">
+    <sbol:displayId>B0034</sbol:displayId>
+    <dcterms:title>B0034 RBS</dcterms:title>
+    <sbol:type rdf:resource="http://www.biopax.org/release/biopax-level3.owl#DnaRegion"/>
+    <sbol:role rdf:resource="http://identifiers.org/so/SO:0000139"/>
+    <sbol:sequence rdf:resource="http://example.com/B0034_seq/1"/>
+  </sbol:ComponentDefinition>
+  <sbol:Sequence rdf:about="http://example.com/device_seq/1">
+    <sbol:displayId>device_seq</sbol:displayId>
+    <sbol:elements>ccaaagaggagaaatt</sbol:elements>
+    <sbol:encoding rdf:resource="http://www.chem.qmul.ac.uk/iubmb/misc/naseq.html"/>
+  </sbol:Sequence>
+  <sbol:Sequence rdf:about="http://example.com/B0034_seq/1">
+    <sbol:displayId>B0034_seq</sbol:displayId>
+    <sbol:elements>aaagaggagaaa</sbol:elements>
+    <sbol:encoding rdf:resource="http://www.chem.qmul.ac.uk/iubmb/misc/naseq.html"/>
+  </sbol:Sequence>
+</rdf:RDF>
+`
+
+func TestSBOLSubcomponents(t *testing.T) {
+	parsed, err := SBOLContentsToDNASequences([]byte(testSBOL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, g := 1, len(parsed); e != g {
+		t.Fatalf("expected %d sequences, got %d", e, g)
+	}
+
+	device := parsed[0]
+	if e, g := "device", device.Nm; e != g {
+		t.Errorf("expected name %q, got %q", e, g)
+	}
+	if device.Plasmid {
+		t.Error("expected a linear sequence")
+	}
+	if e, g := 1, len(device.Features); e != g {
+		t.Fatalf("expected %d features, got %d", e, g)
+	}
+
+	f := device.Features[0]
+	if f.Name != "B0034 RBS" || f.Class != "RBS" || f.Reverse || f.StartPosition != 3 || f.EndPosition != 14 || f.DNASeq != "AAAGAGGAGAAA" {
+		t.Errorf("unexpected feature %+v", f)
+	}
+}
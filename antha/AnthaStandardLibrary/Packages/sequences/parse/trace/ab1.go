@@ -0,0 +1,178 @@
+// antha/AnthaStandardLibrary/Packages/sequences/parse/trace/ab1.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package trace
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	abifMagic = "ABIF"
+	// size of the header up to and including the root directory entry
+	abifHeaderSize = 34
+	abifEntrySize  = 28
+)
+
+// abifEntry is an entry of the directory of an ABIF file
+type abifEntry struct {
+	Name        string
+	Number      int
+	ElementType int
+	ElementSize int
+	NumElements int
+	DataSize    int
+	DataOffset  int
+	// offset of the entry itself; data of four bytes or fewer is stored in
+	// place of its offset
+	offset int
+}
+
+func (e abifEntry) data(data []byte) ([]byte, error) {
+	start := e.DataOffset
+	if e.DataSize <= 4 {
+		start = e.offset + 20
+	}
+	if start < 0 || e.DataSize < 0 || start+e.DataSize > len(data) {
+		return nil, fmt.Errorf("data of tag %s%d out of range", e.Name, e.Number)
+	}
+	return data[start : start+e.DataSize], nil
+}
+
+func readABIFEntry(data []byte, offset int) (abifEntry, error) {
+	if offset < 0 || offset+abifEntrySize > len(data) {
+		return abifEntry{}, fmt.Errorf("directory entry at %d out of range", offset)
+	}
+	b := data[offset : offset+abifEntrySize]
+	return abifEntry{
+		Name:        string(b[0:4]),
+		Number:      int(int32(binary.BigEndian.Uint32(b[4:8]))),
+		ElementType: int(int16(binary.BigEndian.Uint16(b[8:10]))),
+		ElementSize: int(int16(binary.BigEndian.Uint16(b[10:12]))),
+		NumElements: int(int32(binary.BigEndian.Uint32(b[12:16]))),
+		DataSize:    int(int32(binary.BigEndian.Uint32(b[16:20]))),
+		DataOffset:  int(int32(binary.BigEndian.Uint32(b[20:24]))),
+		offset:      offset,
+	}, nil
+}
+
+// abifTags maps tag names and numbers, as in PBAS2, to directory entries
+type abifTags map[string]abifEntry
+
+func (tags abifTags) bytes(data []byte, name string, numbers ...int) ([]byte, bool, error) {
+	for _, n := range numbers {
+		if e, ok := tags[fmt.Sprintf("%s%d", name, n)]; ok {
+			b, err := e.data(data)
+			return b, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+func (tags abifTags) shorts(data []byte, name string, numbers ...int) ([]int, bool, error) {
+	b, ok, err := tags.bytes(data, name, numbers...)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	shorts := make([]int, len(b)/2)
+	for i := range shorts {
+		shorts[i] = int(int16(binary.BigEndian.Uint16(b[2*i:])))
+	}
+	return shorts, true, nil
+}
+
+// ParseAB1 parses the contents of an Applied Biosystems ABIF trace. Base
+// calls and qualities are those of the basecaller, falling back to those
+// edited by the user, and channels are the analysed data.
+func ParseAB1(data []byte) (Trace, error) {
+	var t Trace
+	if len(data) < abifHeaderSize || string(data[:4]) != abifMagic {
+		return t, fmt.Errorf("not an ABIF file")
+	}
+
+	root, err := readABIFEntry(data, 6)
+	if err != nil {
+		return t, err
+	}
+	// the directory must fit within the file before its size is trusted
+	if root.NumElements < 0 || root.DataOffset < 0 || root.DataOffset > len(data) || root.NumElements > (len(data)-root.DataOffset)/abifEntrySize {
+		return t, fmt.Errorf("directory of %d entries at %d out of range", root.NumElements, root.DataOffset)
+	}
+	tags := make(abifTags, root.NumElements)
+	for i := 0; i < root.NumElements; i++ {
+		e, err := readABIFEntry(data, root.DataOffset+i*abifEntrySize)
+		if err != nil {
+			return t, err
+		}
+		tags[fmt.Sprintf("%s%d", e.Name, e.Number)] = e
+	}
+
+	bases, ok, err := tags.bytes(data, "PBAS", 2, 1)
+	if err != nil {
+		return t, err
+	} else if !ok {
+		return t, fmt.Errorf("no base calls in trace")
+	}
+	t.Bases = strings.ToUpper(string(bases))
+
+	if quality, ok, err := tags.bytes(data, "PCON", 2, 1); err != nil {
+		return t, err
+	} else if ok {
+		t.Quality = make([]int, len(quality))
+		for i, q := range quality {
+			t.Quality[i] = int(q)
+		}
+	}
+
+	if t.PeakLocations, _, err = tags.shorts(data, "PLOC", 2, 1); err != nil {
+		return t, err
+	}
+
+	order, ok, err := tags.bytes(data, "FWO_", 1)
+	if err != nil {
+		return t, err
+	} else if !ok || len(order) != 4 {
+		order = []byte("GATC")
+	}
+	t.Channels = make(map[byte][]int, 4)
+	for i, base := range strings.ToUpper(string(order)) {
+		channel, ok, err := tags.shorts(data, "DATA", 9+i)
+		if err != nil {
+			return t, err
+		} else if ok {
+			t.Channels[byte(base)] = channel
+		}
+	}
+
+	if name, ok, err := tags.bytes(data, "SMPL", 1); err != nil {
+		return t, err
+	} else if ok && len(name) > 0 {
+		// a pString whose first byte is its length
+		if n := int(name[0]); n < len(name) {
+			t.Name = string(name[1 : n+1])
+		}
+	}
+
+	return t, nil
+}
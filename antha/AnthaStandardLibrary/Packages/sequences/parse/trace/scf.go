@@ -0,0 +1,164 @@
+// antha/AnthaStandardLibrary/Packages/sequences/parse/trace/scf.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package trace
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	scfMagic      = ".scf"
+	scfHeaderSize = 128
+	// size of a base record in versions before 3
+	scfBaseSize = 12
+)
+
+// scfHeader is the header of an SCF file
+type scfHeader struct {
+	Samples       int
+	SamplesOffset int
+	Bases         int
+	BasesOffset   int
+	Version       string
+	SampleSize    int
+}
+
+func readSCFHeader(data []byte) (scfHeader, error) {
+	if len(data) < scfHeaderSize || string(data[:4]) != scfMagic {
+		return scfHeader{}, fmt.Errorf("not an SCF file")
+	}
+	u := func(offset int) int {
+		return int(binary.BigEndian.Uint32(data[offset:]))
+	}
+	h := scfHeader{
+		Samples:       u(4),
+		SamplesOffset: u(8),
+		Bases:         u(12),
+		BasesOffset:   u(24),
+		Version:       string(data[36:40]),
+		SampleSize:    u(40),
+	}
+	if h.SampleSize != 1 && h.SampleSize != 2 {
+		return h, fmt.Errorf("unsupported sample size %d", h.SampleSize)
+	}
+	return h, nil
+}
+
+// ParseSCF parses the contents of a Standard Chromatogram Format trace of
+// version 2 or 3
+func ParseSCF(data []byte) (Trace, error) {
+	var t Trace
+	h, err := readSCFHeader(data)
+	if err != nil {
+		return t, err
+	}
+
+	v3 := h.Version >= "3"
+	samplesSize := 4 * h.Samples * h.SampleSize
+	basesSize := h.Bases * scfBaseSize
+	if h.SamplesOffset+samplesSize > len(data) {
+		return t, fmt.Errorf("samples out of range")
+	} else if h.BasesOffset+basesSize > len(data) {
+		return t, fmt.Errorf("bases out of range")
+	}
+
+	t.Channels = make(map[byte][]int, 4)
+	samples := data[h.SamplesOffset : h.SamplesOffset+samplesSize]
+	sample := func(i int) int {
+		if h.SampleSize == 1 {
+			return int(samples[i])
+		}
+		return int(binary.BigEndian.Uint16(samples[2*i:]))
+	}
+	for c := range Channels {
+		channel := make([]int, h.Samples)
+		for i := range channel {
+			if v3 {
+				// channels are stored one after another
+				channel[i] = sample(c*h.Samples + i)
+			} else {
+				// samples of all four channels are stored together
+				channel[i] = sample(4*i + c)
+			}
+		}
+		if v3 {
+			undelta(channel, h.SampleSize)
+		}
+		t.Channels[Channels[c]] = channel
+	}
+
+	bases := data[h.BasesOffset : h.BasesOffset+basesSize]
+	calls := make([]byte, h.Bases)
+	t.PeakLocations = make([]int, h.Bases)
+	t.Quality = make([]int, h.Bases)
+	for i := range calls {
+		var probs [4]byte
+		if v3 {
+			// peaks, then the probabilities of each base, then calls
+			t.PeakLocations[i] = int(binary.BigEndian.Uint32(bases[4*i:]))
+			for c := range probs {
+				probs[c] = bases[(4+c)*h.Bases+i]
+			}
+			calls[i] = bases[8*h.Bases+i]
+		} else {
+			record := bases[i*scfBaseSize : (i+1)*scfBaseSize]
+			t.PeakLocations[i] = int(binary.BigEndian.Uint32(record))
+			copy(probs[:], record[4:8])
+			calls[i] = record[8]
+		}
+		t.Quality[i] = callQuality(calls[i], probs)
+	}
+	t.Bases = strings.ToUpper(string(calls))
+
+	return t, nil
+}
+
+// undelta reverses the twofold delta encoding of SCF 3 samples, wrapping as
+// the unsigned samples did when encoded
+func undelta(samples []int, size int) {
+	mask := 1<<(8*uint(size)) - 1
+	for n := 0; n < 2; n++ {
+		var prev int
+		for i := range samples {
+			samples[i] = (samples[i] + prev) & mask
+			prev = samples[i]
+		}
+	}
+}
+
+// callQuality is the probability recorded for the called base or, if it is
+// ambiguous, the highest of those recorded for any base
+func callQuality(call byte, probs [4]byte) int {
+	if c := strings.IndexByte(Channels, call&^0x20); c >= 0 {
+		return int(probs[c])
+	}
+	var q byte
+	for _, p := range probs {
+		if p > q {
+			q = p
+		}
+	}
+	return int(q)
+}
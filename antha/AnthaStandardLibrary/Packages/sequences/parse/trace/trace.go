@@ -0,0 +1,128 @@
+// antha/AnthaStandardLibrary/Packages/sequences/parse/trace/trace.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+// Package trace parses Sanger sequencing traces in ABIF (.ab1) and SCF
+// formats into base calls, quality scores and chromatogram channels.
+package trace
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// Channels are the bases whose chromatogram intensities a Trace records, in
+// the order they are stored in SCF files
+const Channels = "ACGT"
+
+// A Trace is a Sanger sequencing read
+type Trace struct {
+	Name string
+	// Base calls
+	Bases string
+	// Phred quality of each base call
+	Quality []int
+	// Index into the channels of the peak of each base call
+	PeakLocations []int
+	// Intensity of each of the bases A, C, G and T at each sample of the
+	// chromatogram
+	Channels map[byte][]int
+}
+
+// DNASequence returns the base calls of the trace as a linear sequence
+func (t Trace) DNASequence() wtype.DNASequence {
+	return wtype.MakeLinearDNASequence(t.Name, t.Bases)
+}
+
+// TrimPositions returns the zero-based, half-open region of the base calls
+// kept by quality trimming. It uses the modified Mott algorithm: each base
+// scores the difference between the error probability at minQuality and its
+// own, and the region with the highest total score is kept. The region is
+// empty if no base has quality above minQuality.
+func (t Trace) TrimPositions(minQuality int) (start, end int) {
+	if len(t.Quality) != len(t.Bases) {
+		return 0, len(t.Bases)
+	}
+	limit := math.Pow(10.0, -float64(minQuality)/10.0)
+
+	var best, score float64
+	var from int
+	for i, q := range t.Quality {
+		score += limit - math.Pow(10.0, -float64(q)/10.0)
+		if score <= 0.0 {
+			score, from = 0.0, i+1
+		} else if score > best {
+			best, start, end = score, from, i+1
+		}
+	}
+	return start, end
+}
+
+// Trim returns the trace with low quality base calls removed from both ends,
+// as determined by TrimPositions. Channels are left untrimmed so that peak
+// locations remain valid.
+func (t Trace) Trim(minQuality int) Trace {
+	start, end := t.TrimPositions(minQuality)
+	trimmed := t
+	trimmed.Bases = t.Bases[start:end]
+	if len(t.Quality) == len(t.Bases) {
+		trimmed.Quality = t.Quality[start:end]
+	}
+	if len(t.PeakLocations) == len(t.Bases) {
+		trimmed.PeakLocations = t.PeakLocations[start:end]
+	}
+	return trimmed
+}
+
+// ParseTrace parses the contents of an AB1 or SCF trace, detected by its
+// leading magic number
+func ParseTrace(data []byte) (Trace, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte(abifMagic)):
+		return ParseAB1(data)
+	case bytes.HasPrefix(data, []byte(scfMagic)):
+		return ParseSCF(data)
+	default:
+		return Trace{}, fmt.Errorf("not an AB1 or SCF trace")
+	}
+}
+
+// TraceFile parses a .ab1 or .scf file. The trace is named after the file if
+// it does not record a sample name.
+func TraceFile(file wtype.File) (Trace, error) {
+	data, err := file.ReadAll()
+	if err != nil {
+		return Trace{}, err
+	}
+	t, err := ParseTrace(data)
+	if err != nil {
+		return t, fmt.Errorf("parsing %s: %s", file.Name, err)
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(file.Name), filepath.Ext(file.Name))
+	}
+	return t, nil
+}
@@ -0,0 +1,207 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+type testTag struct {
+	Name        string
+	Number      int
+	ElementType int
+	ElementSize int
+	Data        []byte
+}
+
+func shortsForTest(values ...int) []byte {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	return b
+}
+
+// makeAB1ForTest writes an ABIF file with the directory after the data
+func makeAB1ForTest(tags []testTag) []byte {
+	var data bytes.Buffer
+	data.WriteString(abifMagic)
+	binary.Write(&data, binary.BigEndian, uint16(101)) // nolint
+
+	entry := func(name string, number, elementType, elementSize, numElements, dataSize, dataOffset int) []byte {
+		b := make([]byte, abifEntrySize)
+		copy(b, name)
+		binary.BigEndian.PutUint32(b[4:], uint32(number))
+		binary.BigEndian.PutUint16(b[8:], uint16(elementType))
+		binary.BigEndian.PutUint16(b[10:], uint16(elementSize))
+		binary.BigEndian.PutUint32(b[12:], uint32(numElements))
+		binary.BigEndian.PutUint32(b[16:], uint32(dataSize))
+		binary.BigEndian.PutUint32(b[20:], uint32(dataOffset))
+		return b
+	}
+
+	body := make([]byte, 0)
+	var dir []byte
+	offset := abifHeaderSize + 94 // header is padded to 128 bytes
+	for _, tag := range tags {
+		e := entry(tag.Name, tag.Number, tag.ElementType, tag.ElementSize, len(tag.Data)/tag.ElementSize, len(tag.Data), offset+len(body))
+		if len(tag.Data) <= 4 {
+			copy(e[20:24], make([]byte, 4))
+			copy(e[20:], tag.Data)
+		} else {
+			body = append(body, tag.Data...)
+		}
+		dir = append(dir, e...)
+	}
+
+	data.Write(entry("tdir", 1, 1023, abifEntrySize, len(tags), len(dir), offset+len(body)))
+	data.Write(make([]byte, 94))
+	data.Write(body)
+	data.Write(dir)
+	return data.Bytes()
+}
+
+func TestParseAB1(t *testing.T) {
+	data := makeAB1ForTest([]testTag{
+		{"PBAS", 2, 2, 1, []byte("acgtn")},
+		{"PCON", 2, 2, 1, []byte{10, 20, 30, 40, 2}},
+		{"PLOC", 2, 4, 2, shortsForTest(1, 3, 5, 7, 9)},
+		{"FWO_", 1, 2, 1, []byte("GATC")},
+		{"DATA", 9, 4, 2, shortsForTest(9, 9, 9)},
+		{"DATA", 10, 4, 2, shortsForTest(10, 10, 10)},
+		{"DATA", 11, 4, 2, shortsForTest(11, 11, 11)},
+		{"DATA", 12, 4, 2, shortsForTest(12, 12, 12)},
+		{"SMPL", 1, 18, 1, append([]byte{5}, "clone"...)},
+	})
+
+	tr, err := ParseTrace(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, g := "clone", tr.Name; e != g {
+		t.Errorf("expected name %q, got %q", e, g)
+	}
+	if e, g := "ACGTN", tr.Bases; e != g {
+		t.Errorf("expected bases %s, got %s", e, g)
+	}
+	if e, g := []int{10, 20, 30, 40, 2}, tr.Quality; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected qualities %v, got %v", e, g)
+	}
+	if e, g := []int{1, 3, 5, 7, 9}, tr.PeakLocations; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected peaks %v, got %v", e, g)
+	}
+	for base, v := range map[byte]int{'G': 9, 'A': 10, 'T': 11, 'C': 12} {
+		if e, g := []int{v, v, v}, tr.Channels[base]; !reflect.DeepEqual(e, g) {
+			t.Errorf("expected channel %c %v, got %v", base, e, g)
+		}
+	}
+
+	if _, err := ParseAB1(data[:20]); err == nil {
+		t.Error("expected an error parsing a truncated file")
+	}
+	if _, err := ParseAB1(data[:len(data)-abifEntrySize/2]); err == nil {
+		t.Error("expected an error parsing a file with a truncated directory")
+	}
+
+	// the number of directory entries is in the root entry at offset 6
+	malformed := append([]byte{}, data...)
+	binary.BigEndian.PutUint32(malformed[18:], 1<<30)
+	if _, err := ParseAB1(malformed); err == nil {
+		t.Error("expected an error parsing a file with too many directory entries")
+	}
+}
+
+func makeSCFForTest(version string, samples [4][]int, bases string, peaks []int, probs [4][]byte) []byte {
+	nSamples, nBases := len(samples[0]), len(bases)
+	header := make([]byte, scfHeaderSize)
+	copy(header, scfMagic)
+	binary.BigEndian.PutUint32(header[4:], uint32(nSamples))
+	binary.BigEndian.PutUint32(header[8:], scfHeaderSize)
+	binary.BigEndian.PutUint32(header[12:], uint32(nBases))
+	binary.BigEndian.PutUint32(header[24:], uint32(scfHeaderSize+8*nSamples))
+	copy(header[36:], version)
+	binary.BigEndian.PutUint32(header[40:], 2)
+
+	data := append([]byte{}, header...)
+	if version >= "3" {
+		for _, channel := range samples {
+			// delta encode twice
+			encoded := append([]int{}, channel...)
+			for n := 0; n < 2; n++ {
+				prev := 0
+				for i, v := range encoded {
+					encoded[i], prev = v-prev, v
+				}
+			}
+			data = append(data, shortsForTest(encoded...)...)
+		}
+		for _, p := range peaks {
+			data = append(data, 0, 0, 0, byte(p))
+		}
+		for _, p := range probs {
+			data = append(data, p...)
+		}
+		data = append(data, bases...)
+		data = append(data, make([]byte, 3*nBases)...)
+	} else {
+		for i := 0; i < nSamples; i++ {
+			data = append(data, shortsForTest(samples[0][i], samples[1][i], samples[2][i], samples[3][i])...)
+		}
+		for i := range bases {
+			data = append(data, 0, 0, 0, byte(peaks[i]), probs[0][i], probs[1][i], probs[2][i], probs[3][i], bases[i], 0, 0, 0)
+		}
+	}
+	return data
+}
+
+func TestParseSCF(t *testing.T) {
+	samples := [4][]int{{0, 500, 20}, {100, 3, 4}, {7, 7, 900}, {1000, 0, 65535}}
+	peaks := []int{0, 1, 2}
+	probs := [4][]byte{{40, 1, 1}, {1, 1, 1}, {1, 30, 1}, {1, 1, 2}}
+
+	for _, version := range []string{"2.00", "3.00"} {
+		tr, err := ParseTrace(makeSCFForTest(version, samples, "agn", peaks, probs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e, g := "AGN", tr.Bases; e != g {
+			t.Errorf("version %s: expected bases %s, got %s", version, e, g)
+		}
+		if e, g := []int{40, 30, 2}, tr.Quality; !reflect.DeepEqual(e, g) {
+			t.Errorf("version %s: expected qualities %v, got %v", version, e, g)
+		}
+		if e, g := peaks, tr.PeakLocations; !reflect.DeepEqual(e, g) {
+			t.Errorf("version %s: expected peaks %v, got %v", version, e, g)
+		}
+		for c := range Channels {
+			if e, g := samples[c], tr.Channels[Channels[c]]; !reflect.DeepEqual(e, g) {
+				t.Errorf("version %s: expected channel %c %v, got %v", version, Channels[c], e, g)
+			}
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	tr := Trace{
+		Bases:         "NNACGTACGTAN",
+		Quality:       []int{2, 5, 30, 40, 40, 15, 40, 40, 30, 30, 8, 3},
+		PeakLocations: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	}
+
+	if start, end := tr.TrimPositions(20); start != 2 || end != 10 {
+		t.Errorf("expected to keep [2, 10), got [%d, %d)", start, end)
+	}
+
+	trimmed := tr.Trim(20)
+	if e, g := "ACGTACGT", trimmed.Bases; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	if e, g := []int{2, 3, 4, 5, 6, 7, 8, 9}, trimmed.PeakLocations; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected peaks %v, got %v", e, g)
+	}
+
+	if e, g := "", tr.Trim(50).Bases; e != g {
+		t.Errorf("expected everything trimmed, got %s", g)
+	}
+}
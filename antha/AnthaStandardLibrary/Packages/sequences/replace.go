@@ -395,51 +395,61 @@ func CodonOptions(codon string) (replacementoptions []string) {
 	return
 }
 
+// ReplaceCodoninORF replaces the codon at a position, in human friendly
+// format, of the ORF between the start and end positions of a sequence with
+// the first synonymous codon which does not introduce any of seqstoavoid
+// into the ORF. The ORF must start with ATG and end with a stop codon; ORFs
+// on the reverse strand have a start position greater than their end.
 func ReplaceCodoninORF(sequence wtype.DNASequence, startandendoforf StartEndPair, position int, seqstoavoid []string) (newseq wtype.DNASequence, codontochange string, option string, err error) {
 
 	sequence.Seq = strings.ToUpper(sequence.Seq)
 
-	// only handling cases where orf is not in reverse strand currently
-	if startandendoforf[0] < startandendoforf[1] {
+	if startandendoforf[0] > startandendoforf[1] {
+		// replace the codon of the ORF on the forward strand of the reverse
+		// complement
+		n := len(sequence.Seq)
+		revcomp := sequence
+		revcomp.Seq = wtype.RevComp(sequence.Seq)
+		newseq, codontochange, option, err = ReplaceCodoninORF(revcomp, MakeStartendPair(n+1-startandendoforf[0], n+1-startandendoforf[1]), n+1-position, seqstoavoid)
+		newseq.Seq = wtype.RevComp(newseq.Seq)
+		return
+	}
 
-		if position < startandendoforf[0] || position > startandendoforf[1] {
-			return sequence, codontochange, option, fmt.Errorf("position %d specified is out of range of orf start and finish specified %+v for %s", position, startandendoforf, sequence.Nm)
+	if position < startandendoforf[0] || position > startandendoforf[1] {
+		return sequence, codontochange, option, fmt.Errorf("position %d specified is out of range of orf start and finish specified %+v for %s", position, startandendoforf, sequence.Nm)
 
+	}
+	seqslice := sequence.Seq[startandendoforf[0]-1 : startandendoforf[1]]
+	orf, orftrue := FindORF(seqslice)
+	if orftrue /*&& len(orf.DNASeq) == len(seqslice)*/ {
+		codontochange, pair, err := Codonfromposition(seqslice, (position - startandendoforf[0]))
+		if err != nil {
+			return sequence, codontochange, option, err
 		}
-		seqslice := sequence.Seq[startandendoforf[0]-1 : startandendoforf[1]]
-		orf, orftrue := FindORF(seqslice)
-		if orftrue /*&& len(orf.DNASeq) == len(seqslice)*/ {
-			codontochange, pair, err := Codonfromposition(seqslice, (position - startandendoforf[0]))
-			if err != nil {
-				return sequence, codontochange, option, err
-			}
-
-			options := CodonOptions(codontochange)
 
-			for _, option := range options {
-				tempseq := ReplacePosition(seqslice, pair, option)
-				temporf, _ := FindORF(tempseq)
+		options := CodonOptions(codontochange)
 
-				sitesfound := search.FindAllStrings(tempseq, seqstoavoid)
+		for _, option := range options {
+			if option == codontochange {
+				continue
+			}
+			tempseq := ReplacePosition(seqslice, pair, option)
+			temporf, _ := FindORF(tempseq)
 
-				if temporf.ProtSeq == orf.ProtSeq && len(sitesfound) == 0 {
-					newseq := sequence
-					newseq.Seq = tempseq
-					return newseq, codontochange, option, err
-				}
+			sitesfound := search.FindAllStrings(tempseq, seqstoavoid)
 
+			if temporf.ProtSeq == orf.ProtSeq && len(sitesfound) == 0 {
+				newseq := sequence
+				newseq.Seq = sequence.Seq[:startandendoforf[0]-1] + tempseq + sequence.Seq[startandendoforf[1]:]
+				return newseq, codontochange, option, err
 			}
-			err = fmt.Errorf("No satisfactory alternative codon options found to replace codon: %+v in options %+v", codontochange, options)
-			return sequence, codontochange, option, err
-		} else {
-			err = fmt.Errorf("No orf found in sequence %s positions %d to %d", sequence.Nm, startandendoforf[0], startandendoforf[1])
-			return sequence, codontochange, option, err
+
 		}
-	} else {
-		newseq = sequence
-		err = fmt.Errorf("orf in reverse direction, fix ReplaceCodoninORF func to handle this")
+		err = fmt.Errorf("No satisfactory alternative codon options found to replace codon: %+v in options %+v", codontochange, options)
+		return sequence, codontochange, option, err
 	}
-	return
+	err = fmt.Errorf("No orf found in sequence %s positions %d to %d", sequence.Nm, startandendoforf[0], startandendoforf[1])
+	return sequence, codontochange, option, err
 }
 
 func ReplacePosition(sequence string, position StartEndPair, replacement string) (newseq string) {
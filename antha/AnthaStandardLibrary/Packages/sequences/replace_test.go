@@ -468,3 +468,29 @@ func TestRotate(t *testing.T) {
 		}
 	}
 }
+
+func TestReplaceCodoninORF(t *testing.T) {
+	seq := wtype.MakeLinearDNASequence("test", "GGATGAAAGGCTAACC")
+	expected := "GGATGAAGGGCTAACC"
+
+	newseq, codon, option, err := ReplaceCodoninORF(seq, MakeStartendPair(3, 14), 7, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newseq.Seq != expected || codon != "AAA" || option != "AAG" {
+		t.Errorf("expected %s replacing AAA with AAG, got %s replacing %s with %s", expected, newseq.Seq, codon, option)
+	}
+
+	seq.Seq = wtype.RevComp(seq.Seq)
+	newseq, _, _, err = ReplaceCodoninORF(seq, MakeStartendPair(14, 3), 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := wtype.RevComp(expected); newseq.Seq != e {
+		t.Errorf("expected %s replacing codon of reverse ORF, got %s", e, newseq.Seq)
+	}
+
+	if _, _, _, err := ReplaceCodoninORF(wtype.MakeLinearDNASequence("test", "ATGAAATAA"), MakeStartendPair(1, 9), 4, []string{"AAG"}); err == nil {
+		t.Error("expected an error when every option introduces a sequence to avoid")
+	}
+}
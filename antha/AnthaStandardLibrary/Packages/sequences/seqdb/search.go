@@ -0,0 +1,374 @@
+// antha/AnthaStandardLibrary/Packages/sequences/seqdb/search.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package seqdb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/align"
+	biogo "github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/biogo/ncbi/blast"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/blast"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/kmer"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// A Searcher finds sequences similar to a query
+type Searcher interface {
+	Search(query wtype.BioSequence) (wtype.BlastResults, error)
+}
+
+// NewSearcher returns a Searcher of the local database in the file at path,
+// or of NCBI if path is empty
+func NewSearcher(path string) (Searcher, error) {
+	if path == "" {
+		return NCBI{}, nil
+	}
+	return OpenFile(path)
+}
+
+// NCBI searches the NCBI nr database with megablast
+type NCBI struct{}
+
+// Search searches NCBI with blastn
+func (NCBI) Search(query wtype.BioSequence) (wtype.BlastResults, error) {
+	hits, err := blast.MegaBlastN(query.Sequence())
+	if err != nil {
+		return wtype.BlastResults{}, err
+	}
+
+	res := wtype.NewBlastResults()
+	res.Program, res.DBname, res.Query = "blastn", "nr", query.Sequence()
+	for _, hit := range hits {
+		h := wtype.NewBlastHit()
+		h.Name = hit.Accession
+		if h.Name == "" {
+			h.Name = hit.Id
+		}
+		for i, hsp := range hit.Hsps {
+			if i == 0 || hsp.BitScore > h.Score {
+				h.Score = hsp.BitScore
+			}
+			if i == 0 || hsp.EValue < h.Eval {
+				h.Eval = hsp.EValue
+			}
+			h.Alignments = append(h.Alignments, hspAlignment(hsp))
+		}
+		res.Hits = append(res.Hits, h)
+	}
+	return res, nil
+}
+
+func hspAlignment(hsp biogo.Hsp) wtype.AlignedSequence {
+	a := wtype.NewAlignedSequence()
+	a.Qstrand, a.Sstrand = "Plus", "Plus"
+	if hsp.HitFrom > hsp.HitTo {
+		a.Sstrand = "Minus"
+	}
+	a.Qstart, a.Qend, a.Sstart, a.Send = hsp.QueryFrom, hsp.QueryTo, hsp.HitFrom, hsp.HitTo
+	a.Qseq, a.Sseq = string(hsp.QuerySeq), string(hsp.SubjectSeq)
+	if hsp.HspIdentity != nil && len(hsp.QuerySeq) > 0 {
+		a.ID = 100.0 * float64(*hsp.HspIdentity) / float64(len(hsp.QuerySeq))
+	}
+	return a
+}
+
+// SearchParameters control searches of a DB
+type SearchParameters struct {
+	// Fewest k-mers an indexed sequence must share with the query on a
+	// strand for it to be aligned
+	MinSeeds int
+	// Number of bases either side of the seeded region of an indexed
+	// sequence to include in its alignment, and the largest difference in
+	// diagonal between seeds of the same region
+	Flank int
+	// Largest E value of hits reported
+	MaxEvalue float64
+	// Largest number of hits reported, or all if zero
+	MaxHits int
+}
+
+// DefaultSearchParameters are those of new databases
+func DefaultSearchParameters() SearchParameters {
+	return SearchParameters{
+		MinSeeds:  2,
+		Flank:     50,
+		MaxEvalue: 10.0,
+		MaxHits:   50,
+	}
+}
+
+// Alignments are scored with matches scoring 2 and mismatches and gaps -1.
+// Lambda is that of these scores for sequences of uniform composition, and
+// K a typical value for ungapped nucleotide alignments, so that E values
+// and bit scores are estimates.
+const (
+	scoreLambda = 0.2645
+	scoreK      = 0.1
+)
+
+// scoring is that of alignments, where N, to which ambiguous bases are
+// masked, mismatches every base
+var scoring = align.Scoring{
+	Matrix: mustMatrix(align.NewSubstitutionMatrix("seqdb", "ACGTN", [][]int{
+		{2, -1, -1, -1, -1},
+		{-1, 2, -1, -1, -1},
+		{-1, -1, 2, -1, -1},
+		{-1, -1, -1, 2, -1},
+		{-1, -1, -1, -1, -1},
+	})),
+	Gap: align.GapPenalty{Open: 0, Extend: 1},
+}
+
+func mustMatrix(m *align.SubstitutionMatrix, err error) *align.SubstitutionMatrix {
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Search finds the sequences of the database sharing at least MinSeeds
+// k-mers with the query on either strand, within Flank bases of the same
+// diagonal, and aligns the query to the seeded regions using the
+// Smith-Waterman algorithm. Hits are returned in order of
+// decreasing score, with one alignment for each strand on which they are
+// found. Bases other than A, C, G and T mismatch every base. Plasmids are
+// searched as circular sequences, so that hits may span the origin; the
+// start of such hits is reported after their end.
+func (db *DB) Search(query wtype.BioSequence) (wtype.BlastResults, error) {
+	res := wtype.NewBlastResults()
+	q := strings.ToUpper(query.Sequence())
+	res.Program, res.DBname, res.Query = "seqdb", db.Name, q
+	res.DBSizeSeqs = len(db.Entries)
+	res.DBSizeLetters = 0
+	for _, e := range db.Entries {
+		res.DBSizeLetters += len(e.Seq)
+	}
+
+	hits := make(map[int]*wtype.BlastHit)
+	for _, minus := range []bool{false, true} {
+		s := q
+		if minus {
+			s = wtype.RevComp(q)
+		}
+		seeds := db.index.Seeds(s)
+		seqs := make([]int, 0, len(seeds))
+		for seq := range seeds {
+			seqs = append(seqs, seq)
+		}
+		sort.Ints(seqs)
+
+		for _, seq := range seqs {
+			if entry := db.Entries[seq]; entry.Plasmid {
+				seeds[seq] = circularSeeds(seeds[seq], len(entry.Seq))
+			}
+			cluster := seedCluster(seeds[seq], db.Params.Flank)
+			if len(cluster) < db.Params.MinSeeds {
+				continue
+			}
+			a, score, ok, err := db.extend(q, seq, cluster, minus)
+			if err != nil {
+				return res, err
+			} else if !ok {
+				continue
+			}
+			eval := scoreK * float64(len(q)) * float64(res.DBSizeLetters) * math.Exp(-scoreLambda*score)
+			if eval > db.Params.MaxEvalue {
+				continue
+			}
+			bits := (scoreLambda*score - math.Log(scoreK)) / math.Ln2
+
+			h, found := hits[seq]
+			if !found {
+				nh := wtype.NewBlastHit()
+				nh.Name, nh.Score, nh.Eval = db.Entries[seq].Name, bits, eval
+				h = &nh
+				hits[seq] = h
+			}
+			h.Score, h.Eval = math.Max(h.Score, bits), math.Min(h.Eval, eval)
+			h.Alignments = append(h.Alignments, a)
+		}
+	}
+
+	for _, h := range hits {
+		res.Hits = append(res.Hits, *h)
+	}
+	sort.Slice(res.Hits, func(i, j int) bool {
+		if res.Hits[i].Score != res.Hits[j].Score {
+			return res.Hits[i].Score > res.Hits[j].Score
+		}
+		return res.Hits[i].Name < res.Hits[j].Name
+	})
+	if db.Params.MaxHits > 0 && len(res.Hits) > db.Params.MaxHits {
+		res.Hits = res.Hits[:db.Params.MaxHits]
+	}
+	return res, nil
+}
+
+// seedCluster returns the largest set of seeds whose diagonals lie within
+// width of each other, so that alignments are seeded by a single region of
+// an indexed sequence
+func seedCluster(seeds []kmer.Seed, width int) []kmer.Seed {
+	sorted := append([]kmer.Seed{}, seeds...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Diagonal() < sorted[j].Diagonal()
+	})
+	var best []kmer.Seed
+	for i, j := 0, 0; j < len(sorted); j++ {
+		for sorted[j].Diagonal()-sorted[i].Diagonal() > width {
+			i++
+		}
+		if j-i+1 > len(best) {
+			best = sorted[i : j+1]
+		}
+	}
+	return best
+}
+
+// circularSeeds places the seeds of a query in a circular sequence of the
+// given length on two copies of it laid end to end, moving seeds whose
+// diagonal starts before the origin to the second copy. Seeds of a query
+// spanning the origin then share a diagonal.
+func circularSeeds(seeds []kmer.Seed, length int) []kmer.Seed {
+	moved := make([]kmer.Seed, len(seeds))
+	for i, s := range seeds {
+		if s.Diagonal() < 0 {
+			s.Offset += length
+		}
+		moved[i] = s
+	}
+	return moved
+}
+
+// seedWindow returns the zero-based, half-open region of an indexed sequence
+// of the given length spanned by the query at the diagonals of a cluster of
+// seeds, with flanks. Windows of circular sequences are of two copies of the
+// sequence laid end to end, and are no longer than the sequence.
+func seedWindow(cluster []kmer.Seed, queryLength, length, flank int, circular bool) (int, int) {
+	lo := cluster[0].Diagonal() - flank
+	hi := cluster[len(cluster)-1].Diagonal() + queryLength + flank
+	if !circular {
+		return maxInt(lo, 0), minInt(hi, length)
+	}
+	if lo < 0 {
+		lo, hi = lo+length, hi+length
+	}
+	return lo, minInt(hi, minInt(lo+length, 2*length))
+}
+
+// maskAmbiguous replaces bases other than A, C, G and T with N, which
+// scoring mismatches with every base
+func maskAmbiguous(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("ACGT", r) {
+			return r
+		}
+		return 'N'
+	}, s)
+}
+
+// extend aligns the query to the region of an indexed sequence seeded by a
+// cluster of seeds. Hits
+// on the minus strand align the query to the reverse complement of the
+// region, so that the query is reported on the plus strand as BLAST does.
+func (db *DB) extend(query string, seq int, cluster []kmer.Seed, minus bool) (wtype.AlignedSequence, float64, bool, error) {
+	entry := db.Entries[seq]
+	target := entry.Seq
+	if entry.Plasmid {
+		target = entry.Seq + entry.Seq
+	}
+	lo, hi := seedWindow(cluster, len(query), len(entry.Seq), db.Params.Flank, entry.Plasmid)
+	window := target[lo:hi]
+	if minus {
+		window = wtype.RevComp(window)
+	}
+
+	subject := wtype.MakeLinearDNASequence(entry.Name, maskAmbiguous(window))
+	masked := wtype.MakeLinearDNASequence("query", maskAmbiguous(query))
+	result, err := align.Pairwise(&subject, &masked, scoring, align.Local)
+	if err != nil {
+		return wtype.AlignedSequence{}, 0.0, false, fmt.Errorf("aligning to %s: %s", entry.Name, err)
+	}
+	aln := result.Alignment
+	if len(aln.TemplateResult) == 0 || len(aln.Raw) == 0 {
+		return wtype.AlignedSequence{}, 0.0, false, nil
+	}
+
+	a := wtype.NewAlignedSequence()
+	a.Qstrand, a.Sstrand = "Plus", "Plus"
+	a.Qseq, a.Sseq = strings.ToUpper(aln.QueryResult), strings.ToUpper(aln.TemplateResult)
+
+	var matches int
+	first, last := -1, -1
+	for i := range aln.TemplateResult {
+		t, q := rune(a.Sseq[i]), rune(a.Qseq[i])
+		if t == q && t != align.GAP && t != 'N' {
+			matches++
+		}
+		if t != align.GAP {
+			if first < 0 {
+				first = aln.TemplatePositions[i]
+			}
+			last = aln.TemplatePositions[i]
+		}
+	}
+
+	a.Qstart, a.Qend = len(query), 0
+	for _, r := range aln.Raw {
+		if r.QueryAlignment.Length > 0 {
+			a.Qstart = minInt(a.Qstart, r.QueryAlignment.Start+1)
+			a.Qend = maxInt(a.Qend, r.QueryAlignment.End)
+		}
+	}
+	if minus {
+		// positions in the reverse complement of the window, counted back
+		// from its end
+		a.Sstrand = "Minus"
+		a.Sstart, a.Send = hi-first+1, hi-last+1
+	} else {
+		a.Sstart, a.Send = lo+first, lo+last
+	}
+	if entry.Plasmid {
+		// positions in the second copy of a plasmid are those of the first
+		a.Sstart, a.Send = (a.Sstart-1)%len(entry.Seq)+1, (a.Send-1)%len(entry.Seq)+1
+	}
+	a.ID = 100.0 * float64(matches) / float64(len(a.Qseq))
+
+	return a, result.Score, true, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,259 @@
+// antha/AnthaStandardLibrary/Packages/sequences/seqdb/seqdb.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+// Package seqdb is a local database of DNA sequences which can be searched
+// without access to NCBI.
+//
+// Sequences are imported from directories of sequence files and indexed by
+// k-mer. Searches find indexed sequences sharing k-mers with the query and
+// extend them into local alignments, reporting hits as BLAST does. Both the
+// database and NCBI are Searchers, so that elements can use either.
+package seqdb
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/kmer"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// DefaultK is the length of indexed k-mers, the word size of blastn
+const DefaultK = 11
+
+// importable are the extensions of sequence files imported from directories
+var importable = map[string]bool{
+	".fasta": true,
+	".fa":    true,
+	".gb":    true,
+	".gbk":   true,
+	".gdx":   true,
+	".sbol":  true,
+}
+
+// An Entry is a sequence of the database
+type Entry struct {
+	Name    string
+	Seq     string
+	Plasmid bool
+	// File from which the sequence was imported, if any
+	Source string
+}
+
+// indexed returns the sequence of the entry indexed with k-mers of length
+// k. Plasmids are indexed with their first k-1 bases repeated at the end, so
+// that k-mers spanning the origin are found.
+func (e Entry) indexed(k int) string {
+	if !e.Plasmid || k <= 1 {
+		return e.Seq
+	}
+	n := k - 1
+	if n > len(e.Seq) {
+		n = len(e.Seq)
+	}
+	return e.Seq + e.Seq[:n]
+}
+
+// A DB is a local database of DNA sequences
+type DB struct {
+	Name string
+	// Length of indexed k-mers
+	K int
+	// Directories from which sequences are imported
+	Dirs []string
+	// Modification times of the files imported
+	Sources map[string]time.Time
+	Entries []Entry
+	Params  SearchParameters
+
+	index *kmer.Index
+}
+
+// New makes an empty database indexing k-mers of length k
+func New(name string, k int) *DB {
+	return &DB{
+		Name:    name,
+		K:       k,
+		Sources: make(map[string]time.Time),
+		Params:  DefaultSearchParameters(),
+		index:   kmer.NewIndex(k),
+	}
+}
+
+// Add adds sequences to the database which were not imported from a file
+func (db *DB) Add(seqs ...wtype.DNASequence) {
+	db.add("", seqs)
+}
+
+func (db *DB) add(source string, seqs []wtype.DNASequence) {
+	for _, s := range seqs {
+		e := Entry{Name: s.Nm, Seq: strings.ToUpper(s.Seq), Plasmid: s.Plasmid, Source: source}
+		db.index.Add(len(db.Entries), e.indexed(db.K))
+		db.Entries = append(db.Entries, e)
+	}
+}
+
+// reindex rebuilds the index from the entries
+func (db *DB) reindex() {
+	db.index = kmer.NewIndex(db.K)
+	for i, e := range db.Entries {
+		db.index.Add(i, e.indexed(db.K))
+	}
+}
+
+// ImportDir imports the sequence files in dir and its subdirectories, and
+// records it to be rescanned by Update. It returns the number of files
+// imported.
+func (db *DB) ImportDir(dir string) (int, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+	found := false
+	for _, d := range db.Dirs {
+		found = found || d == abs
+	}
+	if !found {
+		db.Dirs = append(db.Dirs, abs)
+	}
+	return db.Update()
+}
+
+// Update rescans the directories of the database, importing new and
+// modified sequence files and removing the sequences of files which have
+// been modified or deleted. It returns the number of files changed.
+func (db *DB) Update() (int, error) {
+	current := make(map[string]time.Time)
+	for _, dir := range db.Dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && importable[strings.ToLower(filepath.Ext(path))] {
+				current[path] = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("scanning %s: %s", dir, err)
+		}
+	}
+
+	var changed int
+	stale := make(map[string]bool)
+	for source, modTime := range db.Sources {
+		if t, ok := current[source]; !ok || !t.Equal(modTime) {
+			stale[source] = true
+			delete(db.Sources, source)
+			changed++
+		}
+	}
+	if len(stale) > 0 {
+		var entries []Entry
+		for _, e := range db.Entries {
+			if !stale[e.Source] {
+				entries = append(entries, e)
+			}
+		}
+		db.Entries = entries
+		db.reindex()
+	}
+
+	var added []string
+	for source := range current {
+		if _, ok := db.Sources[source]; !ok {
+			added = append(added, source)
+		}
+	}
+	sort.Strings(added)
+	for _, source := range added {
+		data, err := ioutil.ReadFile(source)
+		if err != nil {
+			return changed, err
+		}
+		var f wtype.File
+		f.Name = source
+		if err := f.WriteAll(data); err != nil {
+			return changed, err
+		}
+		seqs, err := parse.DNAFileToDNASequence(f)
+		if err != nil {
+			return changed, fmt.Errorf("importing %s: %s", source, err)
+		}
+		db.add(source, seqs)
+		db.Sources[source] = current[source]
+		if !stale[source] {
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// Save writes the database. The index is rebuilt when it is loaded.
+func (db *DB) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(db)
+}
+
+// Load reads a database written by Save
+func Load(r io.Reader) (*DB, error) {
+	var db DB
+	if err := gob.NewDecoder(r).Decode(&db); err != nil {
+		return nil, fmt.Errorf("cannot read sequence database: %s", err)
+	}
+	if db.Sources == nil {
+		db.Sources = make(map[string]time.Time)
+	}
+	db.reindex()
+	return &db, nil
+}
+
+// SaveFile writes the database to a file
+func (db *DB) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := db.Save(f); err != nil {
+		f.Close() // nolint
+		return err
+	}
+	return f.Close()
+}
+
+// OpenFile reads a database from a file written by SaveFile
+func OpenFile(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint
+	return Load(f)
+}
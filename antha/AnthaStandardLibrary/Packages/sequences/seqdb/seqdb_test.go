@@ -0,0 +1,237 @@
+package seqdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/kmer"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+func entryNames(db *DB) []string {
+	var names []string
+	for _, e := range db.Entries {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func writeFileForTest(t *testing.T, path, contents string, modTime time.Time) {
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportAndUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seqdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint
+
+	then := time.Now().Add(-time.Hour)
+	writeFileForTest(t, filepath.Join(dir, "a.fasta"), ">a1\nACGTACGTACGTACGT\n>a2\nTTTTGGGGCCCCAAAA\n", then)
+	writeFileForTest(t, filepath.Join(dir, "b.fa"), ">b\nGATTACAGATTACA\n", then)
+	writeFileForTest(t, filepath.Join(dir, "notes.txt"), "not a sequence", then)
+
+	db := New("test", 4)
+	db.Add(wtype.MakeLinearDNASequence("added", "CCCCCCCC"))
+
+	if n, err := db.ImportDir(dir); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Errorf("expected %d files imported, got %d", 2, n)
+	}
+	if e, g := []string{"added", "a1", "a2", "b"}, entryNames(db); !reflect.DeepEqual(e, g) {
+		t.Errorf("expected entries %v, got %v", e, g)
+	}
+
+	if n, err := db.Update(); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Errorf("expected no files changed, got %d", n)
+	}
+
+	writeFileForTest(t, filepath.Join(dir, "a.fasta"), ">a3\nACGTACGTAAAA\n", time.Now())
+	if err := os.Remove(filepath.Join(dir, "b.fa")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.Update(); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Errorf("expected %d files changed, got %d", 2, n)
+	}
+	if e, g := []string{"added", "a3"}, entryNames(db); !reflect.DeepEqual(e, g) {
+		t.Errorf("expected entries %v, got %v", e, g)
+	}
+	if e, g := []kmer.Location{{Seq: 1, Offset: 0}, {Seq: 1, Offset: 4}}, db.index.Locations["ACGT"]; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected ACGT indexed at %v, got %v", e, g)
+	}
+
+	var buf bytes.Buffer
+	if err := db.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(db.Entries, loaded.Entries) || !reflect.DeepEqual(db.Dirs, loaded.Dirs) || db.Params != loaded.Params {
+		t.Errorf("expected %+v, loaded %+v", db, loaded)
+	}
+	if !reflect.DeepEqual(db.index, loaded.index) {
+		t.Error("expected index to be rebuilt when loaded")
+	}
+	if n, err := loaded.Update(); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Errorf("expected no files changed after loading, got %d", n)
+	}
+}
+
+func TestSeedCluster(t *testing.T) {
+	seed := func(offset, queryOffset int) kmer.Seed {
+		return kmer.Seed{Location: kmer.Location{Offset: offset}, QueryOffset: queryOffset}
+	}
+	seeds := []kmer.Seed{seed(1000, 0), seed(105, 5), seed(10, 0), seed(112, 10), seed(120, 20)}
+
+	cluster := seedCluster(seeds, 10)
+	if e, g := []kmer.Seed{seeds[1], seeds[4], seeds[3]}, cluster; !reflect.DeepEqual(e, g) {
+		t.Fatalf("expected cluster %v, got %v", e, g)
+	}
+
+	if lo, hi := seedWindow(cluster, 30, 1000, 10, false); lo != 90 || hi != 142 {
+		t.Errorf("expected window [90, 142), got [%d, %d)", lo, hi)
+	}
+	if lo, hi := seedWindow(cluster, 30, 120, 200, false); lo != 0 || hi != 120 {
+		t.Errorf("expected window clipped to [0, 120), got [%d, %d)", lo, hi)
+	}
+}
+
+func TestCircularSeeds(t *testing.T) {
+	db := New("test", 4)
+	plasmid := wtype.MakePlasmidDNASequence("plasmid", "GATTACACCCCCCCCCTTTT")
+	db.Add(plasmid)
+
+	// TTTTGATT spans the origin
+	seeds := db.index.Seeds("TTTTGATT")[0]
+	if e, g := 5, len(seeds); e != g {
+		t.Fatalf("expected %d seeds spanning the origin, got %d", e, g)
+	}
+
+	cluster := seedCluster(circularSeeds(seeds, 20), 0)
+	if e, g := 5, len(cluster); e != g {
+		t.Fatalf("expected all %d seeds on the same diagonal, got %d", e, g)
+	}
+	if e, g := 16, cluster[0].Diagonal(); e != g {
+		t.Errorf("expected diagonal %d, got %d", e, g)
+	}
+
+	if lo, hi := seedWindow(cluster, 8, 20, 2, true); lo != 14 || hi != 26 {
+		t.Errorf("expected window [14, 26), got [%d, %d)", lo, hi)
+	}
+	if lo, hi := seedWindow(cluster, 8, 20, 10, true); lo != 6 || hi != 26 {
+		t.Errorf("expected window no longer than the plasmid [6, 26), got [%d, %d)", lo, hi)
+	}
+	if lo, hi := seedWindow([]kmer.Seed{{QueryOffset: 2}}, 8, 20, 2, true); lo != 16 || hi != 28 {
+		t.Errorf("expected window moved past the origin [16, 28), got [%d, %d)", lo, hi)
+	}
+}
+
+func randomSeq(r *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = "ACGT"[r.Intn(4)]
+	}
+	return string(b)
+}
+
+type searchTest struct {
+	Name                       string
+	Query                      string
+	Hit                        string
+	Sstrand                    string
+	Sstart, Send, Qstart, Qend int
+}
+
+func TestSearch(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	linear := randomSeq(r, 200)
+	plasmid := randomSeq(r, 200)
+
+	db := New("test", 8)
+	db.Add(wtype.MakeLinearDNASequence("linear", linear), wtype.MakePlasmidDNASequence("plasmid", plasmid))
+
+	// one base of the query differs from the linear entry
+	mismatched := linear[50:80] + "A" + linear[81:110]
+	if linear[80] == 'A' {
+		mismatched = linear[50:80] + "C" + linear[81:110]
+	}
+	origin := plasmid[170:] + plasmid[:30]
+
+	tests := []searchTest{
+		{Name: "linear plus", Query: linear[50:110], Hit: "linear", Sstrand: "Plus", Sstart: 51, Send: 110, Qstart: 1, Qend: 60},
+		{Name: "linear minus", Query: wtype.RevComp(linear[50:110]), Hit: "linear", Sstrand: "Minus", Sstart: 110, Send: 51, Qstart: 1, Qend: 60},
+		{Name: "flanked", Query: "NNNNNNNNNN" + linear[100:160], Hit: "linear", Sstrand: "Plus", Sstart: 101, Send: 160, Qstart: 11, Qend: 70},
+		{Name: "mismatch", Query: mismatched, Hit: "linear", Sstrand: "Plus", Sstart: 51, Send: 110, Qstart: 1, Qend: 60},
+		{Name: "plasmid origin plus", Query: origin, Hit: "plasmid", Sstrand: "Plus", Sstart: 171, Send: 30, Qstart: 1, Qend: 60},
+		{Name: "plasmid origin minus", Query: wtype.RevComp(origin), Hit: "plasmid", Sstrand: "Minus", Sstart: 30, Send: 171, Qstart: 1, Qend: 60},
+	}
+
+	for _, test := range tests {
+		q := wtype.MakeLinearDNASequence(test.Name, test.Query)
+		res, err := db.Search(&q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Hits) != 1 || res.Hits[0].Name != test.Hit || len(res.Hits[0].Alignments) != 1 {
+			t.Errorf("%s: expected one alignment to %s, got %+v", test.Name, test.Hit, res.Hits)
+			continue
+		}
+		a := res.Hits[0].Alignments[0]
+		g := searchTest{Name: test.Name, Query: test.Query, Hit: res.Hits[0].Name, Sstrand: a.Sstrand, Sstart: a.Sstart, Send: a.Send, Qstart: a.Qstart, Qend: a.Qend}
+		if !reflect.DeepEqual(test, g) {
+			t.Errorf("%s: expected %+v, got %+v", test.Name, test, g)
+		}
+		if test.Name == "mismatch" {
+			if e := 100.0 * 59 / 60; a.ID != e {
+				t.Errorf("%s: expected identity %f, got %f", test.Name, e, a.ID)
+			}
+		} else if a.ID != 100 {
+			t.Errorf("%s: expected identical alignment, got %f", test.Name, a.ID)
+		}
+	}
+
+	// a query found in both entries
+	db.Add(wtype.MakeLinearDNASequence("copy", linear[40:120]))
+	q := wtype.MakeLinearDNASequence("query", linear[50:110])
+	if res, err := db.Search(&q); err != nil {
+		t.Fatal(err)
+	} else if len(res.Hits) != 2 {
+		t.Errorf("expected hits to both copies, got %+v", res.Hits)
+	}
+
+	db.Params.MaxHits = 1
+	if res, err := db.Search(&q); err != nil {
+		t.Fatal(err)
+	} else if len(res.Hits) != 1 {
+		t.Errorf("expected hits limited to %d, got %+v", 1, res.Hits)
+	}
+
+	db.Params.MaxHits = 0
+	db.Params.MaxEvalue = 1e-30
+	if res, err := db.Search(&q); err != nil {
+		t.Fatal(err)
+	} else if len(res.Hits) != 0 {
+		t.Errorf("expected no hits below E value %g, got %+v", db.Params.MaxEvalue, res.Hits)
+	}
+}
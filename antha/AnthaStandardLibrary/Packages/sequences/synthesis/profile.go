@@ -0,0 +1,147 @@
+// antha/AnthaStandardLibrary/Packages/sequences/synthesis/profile.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+// Package synthesis checks whether DNA sequences can be synthesised by a
+// vendor and repairs those which cannot.
+//
+// The rules of each vendor are data: a Profile of limits on length, GC
+// content, homopolymers, repeats, hairpins and forbidden motifs, which may
+// be read from JSON. Check returns the violations of a profile's rules by a
+// sequence, with their positions. Repair additionally removes violations by
+// synonymous codon changes within CDS features, leaving other annotated
+// features untouched.
+package synthesis
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+)
+
+// A Profile is the set of rules a vendor imposes on sequences it
+// synthesises. Zero valued rules are not applied.
+type Profile struct {
+	Name string
+	// Range of sequence lengths in bp
+	MinLength int
+	MaxLength int
+	// Range of GC content of the whole sequence, as fractions
+	MinGC float64
+	MaxGC float64
+	// Range of GC content of every window of GCWindow bp, as fractions
+	GCWindow    int
+	MinWindowGC float64
+	MaxWindowGC float64
+	// Longest run of a single base allowed
+	MaxHomopolymer int
+	// Longest sequences allowed to occur more than once on the same strand
+	// (direct repeats) or on opposite strands (inverted repeats)
+	MaxDirectRepeat   int
+	MaxInvertedRepeat int
+	// Minimum free energy in kcal/mol of hairpins within every window of
+	// HairpinWindow bp
+	HairpinWindow    int
+	MinHairpinDeltaG float64
+	// Sequences, which may contain IUPAC ambiguity codes, which must not
+	// occur on either strand
+	ForbiddenMotifs []string
+}
+
+// DefaultProfile has rules typical of vendors of gene fragments, without
+// limits on length
+func DefaultProfile() Profile {
+	return Profile{
+		Name:              "default",
+		MinGC:             0.3,
+		MaxGC:             0.7,
+		GCWindow:          100,
+		MinWindowGC:       0.25,
+		MaxWindowGC:       0.8,
+		MaxHomopolymer:    9,
+		MaxDirectRepeat:   20,
+		MaxInvertedRepeat: 20,
+		HairpinWindow:     60,
+		MinHairpinDeltaG:  -20,
+	}
+}
+
+// Profiles are those of the vendors of sequences.SynthesisStandards, with
+// their length and repeat limits and the other rules of DefaultProfile.
+// Vendors' current guidelines should be checked before ordering.
+var Profiles = vendorProfiles()
+
+func vendorProfiles() map[string]Profile {
+	profiles := make(map[string]Profile, len(sequences.SynthesisStandards))
+	for name, standard := range sequences.SynthesisStandards {
+		p := DefaultProfile()
+		p.Name = name
+		p.MinLength, _ = standard["MinLength"].(int)
+		p.MaxLength, _ = standard["MaxLength"].(int)
+		if repeatMax, ok := standard["RepeatMax"].(int); ok {
+			p.MaxDirectRepeat = repeatMax
+			p.MaxInvertedRepeat = repeatMax
+		}
+		profiles[name] = p
+	}
+	return profiles
+}
+
+func profileNames(profiles map[string]Profile) []string {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupProfile looks up the profile of a vendor by name. An error is
+// returned if there is no profile for the vendor.
+func LookupProfile(name string) (Profile, error) {
+	p, found := Profiles[name]
+	if !found {
+		return p, fmt.Errorf("no synthesis profile found for %s. Valid options are: %s", name, strings.Join(profileNames(Profiles), ", "))
+	}
+	return p, nil
+}
+
+// ParseProfiles reads a JSON array of profiles, returning them by name
+func ParseProfiles(data []byte) (map[string]Profile, error) {
+	var ps []Profile
+	if err := json.Unmarshal(data, &ps); err != nil {
+		return nil, fmt.Errorf("cannot read synthesis profiles: %s", err)
+	}
+	profiles := make(map[string]Profile, len(ps))
+	for _, p := range ps {
+		if p.Name == "" {
+			return nil, fmt.Errorf("synthesis profile without a name")
+		}
+		if _, found := profiles[p.Name]; found {
+			return nil, fmt.Errorf("duplicate synthesis profile %s", p.Name)
+		}
+		profiles[p.Name] = p
+	}
+	return profiles, nil
+}
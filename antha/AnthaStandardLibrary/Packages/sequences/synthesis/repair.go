@@ -0,0 +1,267 @@
+// antha/AnthaStandardLibrary/Packages/sequences/synthesis/repair.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package synthesis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// A CodonChange is a synonymous codon change made by Repair
+type CodonChange struct {
+	Feature string
+	// Number of the codon in the feature, counting from one
+	Codon    int
+	Old, New string
+}
+
+func (c CodonChange) String() string {
+	return fmt.Sprintf("%s codon %d %s>%s", c.Feature, c.Codon, c.Old, c.New)
+}
+
+// A Report is the result of checking, and possibly repairing, a sequence
+// against a profile
+type Report struct {
+	Profile string
+	// The sequence checked, after any repair
+	Sequence   wtype.DNASequence
+	Violations []Violation
+	Changes    []CodonChange
+	// Whether the sequence breaks no rules
+	Feasible bool
+}
+
+// CheckAll checks sequences against a profile, repairing them first if
+// repair is set
+func CheckAll(seqs []wtype.DNASequence, p Profile, repair bool) ([]Report, error) {
+	var reports []Report
+	for _, seq := range seqs {
+		var r Report
+		var err error
+		if repair {
+			r, err = Repair(seq, p)
+		} else {
+			var vs []Violation
+			vs, err = Check(seq, p)
+			r = Report{Profile: p.Name, Sequence: seq, Violations: vs, Feasible: len(vs) == 0}
+		}
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// A codon is a codon of a CDS feature which repair may change
+type codon struct {
+	Feature int
+	// Number of the codon in the feature, counting from zero
+	Index int
+	// Positions of the codon on the forward strand in human friendly
+	// format, with start before end
+	Start, End int
+}
+
+// Repair checks a sequence against a profile and removes as many
+// violations as it can by synonymous codon changes within its CDS
+// features. Codons are only changed if they overlap no other features,
+// except genes, and changes are only kept if they reduce the violations.
+// Violations of the length rule cannot be repaired.
+func Repair(seq wtype.DNASequence, p Profile) (Report, error) {
+	seq.Seq = strings.ToUpper(seq.Seq)
+	seq.Features = append([]wtype.Feature(nil), seq.Features...)
+	report := Report{Profile: p.Name}
+
+	c := newChecker(p)
+	vs, err := c.check(seq.Seq)
+	if err != nil {
+		return report, fmt.Errorf("checking %s: %s", seq.Nm, err)
+	}
+	cost := totalCost(vs)
+	codons := changeableCodons(seq)
+	avoid := c.avoid()
+
+	for n := 0; n < len(seq.Seq) && cost > 0; n++ {
+		improved := false
+		for _, v := range vs {
+			if ruleWeights[v.Rule] == 0 {
+				continue
+			}
+			for _, cd := range codons {
+				if cd.End < v.Start || cd.Start > v.End {
+					continue
+				}
+				f := seq.Features[cd.Feature]
+				orf := sequences.MakeStartendPair(featureRange(f))
+				if f.Reverse {
+					orf[0], orf[1] = orf[1], orf[0]
+				}
+				newSeq, old, option, err := sequences.ReplaceCodoninORF(seq, orf, cd.Start+1, absent(avoid, featureSeq(seq.Seq, f)))
+				if err != nil {
+					// no synonymous codon avoids the forbidden sequences
+					continue
+				}
+				newVs, err := c.check(newSeq.Seq)
+				if err != nil {
+					return report, fmt.Errorf("checking %s: %s", seq.Nm, err)
+				}
+				if newCost := totalCost(newVs); newCost < cost {
+					seq, vs, cost, improved = newSeq, newVs, newCost, true
+					report.Changes = append(report.Changes, CodonChange{Feature: f.Name, Codon: cd.Index + 1, Old: old, New: option})
+					break
+				}
+			}
+			if improved {
+				break
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	for i, f := range seq.Features {
+		if f.Class == wtype.CDS {
+			seq.Features[i].DNASeq = featureSeq(seq.Seq, f)
+		}
+	}
+	report.Sequence = seq
+	report.Violations = publicViolations(vs)
+	report.Feasible = len(vs) == 0
+	return report, nil
+}
+
+// avoid returns the sequences which synonymous changes must not introduce
+// into CDS features: forbidden motifs without ambiguity codes on either
+// strand and runs of bases longer than allowed
+func (c *checker) avoid() []string {
+	var avoid []string
+	for _, m := range c.motifs {
+		if strings.Trim(m.Seq, "ACGT") == "" {
+			avoid = append(avoid, m.Seq)
+			if rc := wtype.RevComp(m.Seq); rc != m.Seq {
+				avoid = append(avoid, rc)
+			}
+		}
+	}
+	if c.p.MaxHomopolymer > 0 {
+		for _, b := range []string{"A", "C", "G", "T"} {
+			avoid = append(avoid, strings.Repeat(b, c.p.MaxHomopolymer+1))
+		}
+	}
+	return avoid
+}
+
+// absent returns the sequences to avoid which are not already in seq, since
+// ReplaceCodoninORF rejects any change leaving one in the ORF
+func absent(avoid []string, seq string) []string {
+	var a []string
+	for _, s := range avoid {
+		if !strings.Contains(seq, s) {
+			a = append(a, s)
+		}
+	}
+	return a
+}
+
+// changeableCodons returns the codons of the CDS features of seq, other than
+// start codons, which overlap no other CDS features nor any features other
+// than genes and the source of the sequence
+func changeableCodons(seq wtype.DNASequence) (codons []codon) {
+	protected := make([]int, len(seq.Seq)+2)
+	for _, f := range seq.Features {
+		if f.Class == wtype.GENE || f.Class == "source" {
+			continue
+		}
+		lo, hi := featureRange(f)
+		if lo > hi {
+			// across the origin
+			for i := 1; i <= hi && i < len(protected); i++ {
+				protected[i]++
+			}
+			hi = len(seq.Seq)
+		}
+		for i := lo; i <= hi && i < len(protected); i++ {
+			protected[i]++
+		}
+	}
+
+	for fi, f := range seq.Features {
+		if f.Class != wtype.CDS {
+			continue
+		}
+		lo, hi := featureRange(f)
+		// features across the origin of plasmids are not repaired
+		if lo > hi || hi > len(seq.Seq) || (hi-lo+1)%3 != 0 {
+			continue
+		}
+		for k := 1; k < (hi-lo+1)/3; k++ {
+			cd := codon{Feature: fi, Index: k, Start: lo + 3*k, End: lo + 3*k + 2}
+			if f.Reverse {
+				cd.Start, cd.End = hi-3*k-2, hi-3*k
+			}
+			free := true
+			for i := cd.Start; i <= cd.End; i++ {
+				// the CDS itself covers every position once
+				free = free && protected[i] == 1
+			}
+			if free {
+				codons = append(codons, cd)
+			}
+		}
+	}
+	return
+}
+
+// featureRange returns the first and last positions of a feature on the
+// forward strand in human friendly format. The last position precedes the
+// first for features spanning the origin of a plasmid. Reverse features may
+// be given with start before end, as by the genbank parser, or with start
+// after end, as by searching for them; the length of the feature sequence
+// tells the latter from a reverse feature spanning the origin.
+func featureRange(f wtype.Feature) (int, int) {
+	if f.StartPosition > f.EndPosition && f.Reverse && (len(f.DNASeq) == 0 || len(f.DNASeq) == f.StartPosition-f.EndPosition+1) {
+		return f.EndPosition, f.StartPosition
+	}
+	return f.StartPosition, f.EndPosition
+}
+
+// featureSeq returns the sequence of a feature of seq, reading reverse
+// features on the reverse strand
+func featureSeq(seq string, f wtype.Feature) string {
+	lo, hi := featureRange(f)
+	var s string
+	if lo > hi {
+		s = seq[lo-1:] + seq[:hi]
+	} else {
+		s = seq[lo-1 : hi]
+	}
+	if f.Reverse {
+		return wtype.RevComp(s)
+	}
+	return s
+}
@@ -0,0 +1,207 @@
+// antha/AnthaStandardLibrary/Packages/sequences/synthesis/rules.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package synthesis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/internal/seqrules"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/oligos"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// Rules of a Profile
+const (
+	Length         = "length"
+	GCContent      = "GC content"
+	LocalGCContent = "local GC content"
+	Homopolymer    = "homopolymer"
+	DirectRepeat   = "direct repeat"
+	InvertedRepeat = "inverted repeat"
+	Hairpin        = "hairpin"
+	ForbiddenMotif = "forbidden motif"
+)
+
+// A Violation is a region of a sequence which breaks a rule
+type Violation struct {
+	Rule string
+	// Position of the region in human friendly format
+	Start, End int
+	Message    string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s at %d..%d: %s", v.Rule, v.Start, v.End, v.Message)
+}
+
+// ruleWeights are multiplied by the cost of violations so that repair
+// does not trade forbidden motifs for other violations
+var ruleWeights = map[string]float64{
+	Length:         0.0,
+	GCContent:      1.0,
+	LocalGCContent: 1.0,
+	Homopolymer:    1.0,
+	DirectRepeat:   1.0,
+	InvertedRepeat: 1.0,
+	Hairpin:        1.0,
+	ForbiddenMotif: 10.0,
+}
+
+// A checker checks sequences against the rules of a profile, remembering
+// the hairpins of windows, which are slow to find, between checks
+type checker struct {
+	p        Profile
+	motifs   []seqrules.Motif
+	hairpins map[string]oligos.Structure
+}
+
+func newChecker(p Profile) *checker {
+	c := &checker{p: p, hairpins: make(map[string]oligos.Structure)}
+	seen := make(map[string]bool)
+	for _, m := range p.ForbiddenMotifs {
+		m = strings.ToUpper(m)
+		if m != "" && !seen[m] && !seen[wtype.RevComp(m)] {
+			seen[m] = true
+			c.motifs = append(c.motifs, seqrules.Motif{Rule: ForbiddenMotif, Seq: m})
+		}
+	}
+	return c
+}
+
+// Check returns the violations of the rules of a profile by a sequence, in
+// order of position
+func Check(seq wtype.DNASequence, p Profile) ([]Violation, error) {
+	vs, err := newChecker(p).check(strings.ToUpper(seq.Seq))
+	if err != nil {
+		return nil, fmt.Errorf("checking %s: %s", seq.Nm, err)
+	}
+	return publicViolations(vs), nil
+}
+
+func publicViolations(vs []seqrules.Violation) []Violation {
+	var pvs []Violation
+	for _, v := range vs {
+		pvs = append(pvs, Violation{Rule: v.Rule, Start: v.Start, End: v.End, Message: v.Message})
+	}
+	return pvs
+}
+
+func (c *checker) check(seq string) ([]seqrules.Violation, error) {
+	p := c.p
+	vs := c.checkLength(seq)
+	vs = append(vs, c.checkGC(seq)...)
+	vs = append(vs, seqrules.GCWindows(LocalGCContent, seq, p.GCWindow, p.MinWindowGC, p.MaxWindowGC)...)
+	vs = append(vs, seqrules.Homopolymers(Homopolymer, seq, p.MaxHomopolymer)...)
+	vs = append(vs, seqrules.Repeats(DirectRepeat, seq, p.MaxDirectRepeat, false)...)
+	vs = append(vs, seqrules.Repeats(InvertedRepeat, seq, p.MaxInvertedRepeat, true)...)
+	vs = append(vs, seqrules.Motifs(seq, c.motifs)...)
+	hairpins, err := c.checkHairpins(seq)
+	if err != nil {
+		return nil, err
+	}
+	vs = append(vs, hairpins...)
+	seqrules.Sort(vs)
+	return vs, nil
+}
+
+// totalCost is the weighted cost of a set of violations
+func totalCost(vs []seqrules.Violation) float64 {
+	return seqrules.TotalCost(vs, ruleWeights)
+}
+
+func (c *checker) checkLength(seq string) []seqrules.Violation {
+	var message string
+	if c.p.MinLength > 0 && len(seq) < c.p.MinLength {
+		message = fmt.Sprintf("%d bp shorter than %d bp", len(seq), c.p.MinLength)
+	} else if c.p.MaxLength > 0 && len(seq) > c.p.MaxLength {
+		message = fmt.Sprintf("%d bp longer than %d bp", len(seq), c.p.MaxLength)
+	} else {
+		return nil
+	}
+	return []seqrules.Violation{{Rule: Length, Start: 1, End: len(seq), Message: message}}
+}
+
+// checkGC returns the whole sequence if its GC content is out of range, at
+// a cost of the number of bases by which it is
+func (c *checker) checkGC(seq string) []seqrules.Violation {
+	if len(seq) == 0 || (c.p.MinGC <= 0 && c.p.MaxGC <= 0) {
+		return nil
+	}
+	excess, message := seqrules.GCExcess(seqrules.GCCount(seq), len(seq), c.p.MinGC, c.p.MaxGC)
+	if excess <= 0 {
+		return nil
+	}
+	return []seqrules.Violation{{Rule: GCContent, Start: 1, End: len(seq), Message: message, Cost: excess}}
+}
+
+// checkHairpins returns the stems of hairpins more stable than allowed
+// within windows overlapping by half their length
+func (c *checker) checkHairpins(seq string) (vs []seqrules.Violation, err error) {
+	window := c.p.HairpinWindow
+	if window <= 0 || c.p.MinHairpinDeltaG >= 0 || len(seq) == 0 {
+		return
+	}
+	if window > len(seq) {
+		window = len(seq)
+	}
+	step := window / 2
+	if step == 0 {
+		step = 1
+	}
+
+	found := make(map[[2]int]bool)
+	for start := 0; ; start += step {
+		if start+window > len(seq) {
+			start = len(seq) - window
+		}
+		w := seq[start : start+window]
+		hairpin, cached := c.hairpins[w]
+		if !cached {
+			if hairpin, err = oligos.Hairpin(w, oligos.DefaultConditions()); err != nil {
+				return nil, err
+			}
+			c.hairpins[w] = hairpin
+		}
+
+		if hairpin.DeltaG < c.p.MinHairpinDeltaG && len(hairpin.Pairs) > 0 {
+			pairs := hairpin.Pairs
+			stem := [2]int{start + pairs[0][0] + 1, start + pairs[0][1] + 1}
+			if !found[stem] {
+				found[stem] = true
+				vs = append(vs, seqrules.Violation{
+					Rule:    Hairpin,
+					Start:   stem[0],
+					End:     stem[1],
+					Message: fmt.Sprintf("%d bp stem closing a %d base loop with ΔG %.2f kcal/mol below %.2f", len(pairs), pairs[len(pairs)-1][1]-pairs[len(pairs)-1][0]-1, hairpin.DeltaG, c.p.MinHairpinDeltaG),
+					Cost:    c.p.MinHairpinDeltaG - hairpin.DeltaG,
+				})
+			}
+		}
+
+		if start+window >= len(seq) {
+			break
+		}
+	}
+	return
+}
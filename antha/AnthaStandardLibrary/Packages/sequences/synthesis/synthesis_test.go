@@ -0,0 +1,191 @@
+package synthesis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/parse/genbank"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+func violationStrings(vs []Violation) []string {
+	var s []string
+	for _, v := range vs {
+		s = append(s, v.String())
+	}
+	return s
+}
+
+func TestCheck(t *testing.T) {
+	p := Profile{
+		MinLength:         100,
+		MaxGC:             0.5,
+		GCWindow:          10,
+		MinWindowGC:       0.2,
+		MaxHomopolymer:    5,
+		MaxDirectRepeat:   7,
+		MaxInvertedRepeat: 7,
+		HairpinWindow:     30,
+		MinHairpinDeltaG:  -8,
+		ForbiddenMotifs:   []string{"GGATCC", "GCNGC"},
+	}
+	seq := wtype.MakeLinearDNASequence("test", "GGATCCTTATCGCAGGCGTCGCTAAAAAAAATACGCACGCAGCGTCGATCGCGGCGCCAGGCGCCGCGTATCGCAGGCGT")
+	vs, err := Check(seq, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := []string{
+		"length at 1..80: 80 bp shorter than 100 bp",
+		"GC content at 1..80: GC content 62.5% above 50.0%",
+		"forbidden motif at 1..6: GGATCC found on + strand",
+		"local GC content at 22..34: GC content 0.0% below 20.0% in 10 bp window",
+		"homopolymer at 24..31: 8 bp run of A longer than 5 bp",
+		"forbidden motif at 39..43: GCNGC found on + strand",
+		"hairpin at 50..68: 8 bp stem closing a 3 base loop with ΔG -9.12 kcal/mol below -8.00",
+		"forbidden motif at 51..55: GCNGC found on + strand",
+		"inverted repeat at 60..68: 9 bp inverted repeat of 50..58 longer than 7 bp",
+		"forbidden motif at 63..67: GCNGC found on + strand",
+		"direct repeat at 69..80: 12 bp repeat of 8..19 longer than 7 bp",
+	}
+	if g := violationStrings(vs); !reflect.DeepEqual(e, g) {
+		t.Errorf("expected violations:\n%s\ngot:\n%s", strings.Join(e, "\n"), strings.Join(g, "\n"))
+	}
+
+	if vs, err := Check(wtype.MakeLinearDNASequence("short", "ACGT"), Profile{MaxLength: 10}); err != nil || len(vs) != 0 {
+		t.Errorf("expected no violations of empty rules, got %v, %v", vs, err)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	promoter := "CCGAATTCCG"
+	forward := "ATGGAATTCAAAAAAAAATAA"
+	reverse := wtype.RevComp("ATGCTGGAATTCTAA")
+	seq := wtype.MakeLinearDNASequence("test", promoter+forward+"GC"+reverse)
+	l1, l2 := len(promoter), len(promoter)+len(forward)+2
+	seq.Features = []wtype.Feature{
+		{Name: "promoter", Class: wtype.PROMOTER, StartPosition: 1, EndPosition: l1, DNASeq: promoter},
+		{Name: "forward", Class: wtype.CDS, StartPosition: l1 + 1, EndPosition: l1 + len(forward), DNASeq: forward},
+		{Name: "reverse", Class: wtype.CDS, Reverse: true, StartPosition: len(seq.Seq), EndPosition: l2 + 1, DNASeq: wtype.RevComp(reverse)},
+	}
+	p := Profile{Name: "test", MaxHomopolymer: 6, ForbiddenMotifs: []string{"GAATTC"}}
+
+	report, err := Repair(seq, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, g := []string{"forbidden motif at 3..8: GAATTC found on + strand"}, violationStrings(report.Violations); !reflect.DeepEqual(e, g) || report.Feasible {
+		t.Errorf("expected only the promoter to violate rules, got %v", g)
+	}
+	if e, g := []CodonChange{
+		{Feature: "forward", Codon: 2, Old: "GAA", New: "GAG"},
+		{Feature: "forward", Codon: 4, Old: "AAA", New: "AAG"},
+		{Feature: "reverse", Codon: 3, Old: "GAA", New: "GAG"},
+	}, report.Changes; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected changes %v, got %v", e, g)
+	}
+
+	repaired := report.Sequence
+	if !strings.HasPrefix(repaired.Seq, promoter) {
+		t.Errorf("expected promoter to be unchanged in %s", repaired.Seq)
+	}
+	for i, f := range repaired.Features {
+		if f.Class == wtype.CDS {
+			e, err := sequences.Translate(wtype.MakeLinearDNASequence(f.Name, seq.Features[i].DNASeq))
+			if err != nil {
+				t.Fatal(err)
+			}
+			g, err := sequences.Translate(wtype.MakeLinearDNASequence(f.Name, featureSeq(repaired.Seq, f)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Seq != g.Seq {
+				t.Errorf("expected %s to encode %s, got %s", f.Name, e.Seq, g.Seq)
+			}
+		}
+		if f.DNASeq != featureSeq(repaired.Seq, f) {
+			t.Errorf("expected sequence of %s to be updated", f.Name)
+		}
+	}
+	if seq.Features[1].DNASeq != forward {
+		t.Error("expected features of the original sequence to be unchanged")
+	}
+}
+
+func TestRepairGenbank(t *testing.T) {
+	seq, err := genbank.GenbankContentsToAnnotatedSeq([]byte(`LOCUS       test                      23 bp    DNA     linear   SYN 01-JAN-2018
+DEFINITION  test.
+FEATURES             Location/Qualifiers
+     source          1..23
+                     /label="test"
+     CDS             complement(5..19)
+                     /label="reverse"
+ORIGIN
+        1 gcatttagaa ttccagcatg cat
+//
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := seq.Features[1]; !f.Reverse || f.Start(wtype.IGNOREDIRECTION) != 5 || f.End(wtype.IGNOREDIRECTION) != 19 {
+		t.Fatalf("expected a reverse CDS at 5..19, got %+v", f)
+	}
+
+	// reverse features may also be given with start before end
+	forwardOrder := seq
+	forwardOrder.Features = append([]wtype.Feature(nil), seq.Features...)
+	forwardOrder.Features[1].StartPosition, forwardOrder.Features[1].EndPosition = 5, 19
+
+	for _, s := range []wtype.DNASequence{seq, forwardOrder} {
+		report, err := Repair(s, Profile{ForbiddenMotifs: []string{"GAATTC"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e, g := []CodonChange{{Feature: "reverse", Codon: 3, Old: "GAA", New: "GAG"}}, report.Changes; !reflect.DeepEqual(e, g) || !report.Feasible {
+			t.Errorf("expected changes %v, got %v", e, g)
+		}
+		if e, g := "ATGCTGGAGTTCTAA", report.Sequence.Features[1].DNASeq; e != g {
+			t.Errorf("expected reverse CDS %s, got %s", e, g)
+		}
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	p := Profile{Name: "test", MaxHomopolymer: 3}
+	seqs := []wtype.DNASequence{
+		wtype.MakeLinearDNASequence("good", "ACGTACGT"),
+		wtype.MakeLinearDNASequence("bad", "ACGGGGT"),
+	}
+	reports, err := CheckAll(seqs, p, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || !reports[0].Feasible || reports[1].Feasible || len(reports[1].Violations) != 1 || reports[1].Profile != "test" {
+		t.Errorf("unexpected reports %+v", reports)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	p, err := LookupProfile("GenScript")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MinLength != 400 || p.MaxLength != 8000 || p.MaxDirectRepeat != 70 || p.MaxInvertedRepeat != 70 || p.MaxHomopolymer != DefaultProfile().MaxHomopolymer {
+		t.Errorf("unexpected profile %+v", p)
+	}
+	if _, err := LookupProfile("nobody"); err == nil {
+		t.Error("expected an error looking up a missing profile")
+	}
+
+	profiles, err := ParseProfiles([]byte(`[{"Name": "vendor", "MaxLength": 3000, "ForbiddenMotifs": ["GGTCTC"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, g := (Profile{Name: "vendor", MaxLength: 3000, ForbiddenMotifs: []string{"GGTCTC"}}), profiles["vendor"]; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+	if _, err := ParseProfiles([]byte(`[{"Name": "a"}, {"Name": "a"}]`)); err == nil {
+		t.Error("expected an error parsing duplicate profiles")
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 // This simulates the sequence assembly reaction to validate if parts will synthesise with intended manufacturer.
 // Does not validate construct assembly so should be used in conjunction with enzymes.Assemblysimulator()
+// See package synthesis for configurable checks and repair.
 func ValidateSynthesis(parts []wtype.DNASequence, vector string, manufacturer string) (string, bool) {
 
 	var status string
@@ -73,3 +73,14 @@ func (sample *Absorbance) PathlengthCorrect(pathlength wunit.Length) {
 
 	sample.Reading = sample.Reading * referencepathlength.SIValue() / pathlength.SIValue()
 }
+
+// Fluorescence is a fluorescence reading of a well
+type Fluorescence struct {
+	WellLocation         WellCoords
+	Reading              float64
+	ExcitationWavelength float64
+	EmissionWavelength   float64
+	Reader               string
+	// Annotations is a field to add custom user labels
+	Annotations []string
+}
@@ -21,6 +21,12 @@ const (
 
 	// This value will be used for aspirating, dispensing and mixing.
 	DefaultPipetteSpeed string = "DEFAULTPIPETTESPEED"
+
+	// VolumeCV is the coefficient of variation of volumes transferred, in percent.
+	VolumeCV string = "VOLUMECV"
+
+	// VolumeBias is the systematic error of volumes transferred, in percent.
+	VolumeBias string = "VOLUMEBIAS"
 )
 
 func GetPolicyConsequents() AParamSet {
@@ -132,6 +138,8 @@ func MakePolicyItems() AParamSet {
 		"LLFBELOWSURFACE":             AParam{Name: "LLFBELOWSURFACE", Type: typemap[Float64], Desc: "Distance below surface for Liquid Level Following (LLF) when aspirating"},
 		"LLFABOVESURFACE":             AParam{Name: "LLFABOVESURFACE", Type: typemap[Float64], Desc: "Distance below surface for Liquid Level Following (LLF) when dispensing"},
 		PolicyNameField:               AParam{Name: PolicyNameField, Type: typemap[String], Desc: "Name of the Liquid Policy"},
+		VolumeCV:                      AParam{Name: VolumeCV, Type: typemap[Float64], Desc: "coefficient of variation of transferred volumes (%)"},
+		VolumeBias:                    AParam{Name: VolumeBias, Type: typemap[Float64], Desc: "systematic error of transferred volumes (%), positive if more is dispensed than requested"},
 		"USE_LLF":                     AParam{Name: "USE_LLF", Type: typemap[Bool], Desc: "Use Liquid-level following if plate has a model for liquid height-volume relations and the driver can use it."},
 	}
 }
@@ -25,9 +25,13 @@ package wtype
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
+// AlignedBioSequence is one row of an alignment. In multiple alignments
+// Name identifies the sequence and Subject holds the aligned sequence.
 type AlignedBioSequence struct {
+	Name    string
 	Query   string
 	Subject string
 	Score   float64
@@ -131,6 +135,59 @@ func (res BlastResults) QueryCentredAlignment() []AlignedBioSequence {
 
 type SimpleAlignment []AlignedBioSequence
 
+// Consensus returns the most common residue in each column of the aligned
+// subjects, or a gap where gaps outnumber it. Ties are broken alphabetically.
+func (aln SimpleAlignment) Consensus() string {
+	consensus, _ := aln.consensus()
+	return consensus
+}
+
+// Conservation returns the fraction of sequences carrying the most common
+// residue in each column of the aligned subjects.
+func (aln SimpleAlignment) Conservation() []float64 {
+	_, conservation := aln.consensus()
+	return conservation
+}
+
+func (aln SimpleAlignment) consensus() (string, []float64) {
+	if len(aln) == 0 {
+		return "", nil
+	}
+
+	consensus := make([]byte, len(aln[0].Subject))
+	conservation := make([]float64, len(aln[0].Subject))
+
+	for i := range consensus {
+		counts := make(map[byte]int)
+		for _, row := range aln {
+			if i < len(row.Subject) {
+				counts[strings.ToUpper(row.Subject[i : i+1])[0]]++
+			}
+		}
+
+		var best byte = '-'
+		for residue, n := range counts {
+			if residue == '-' {
+				continue
+			}
+			if best == '-' || n > counts[best] || (n == counts[best] && residue < best) {
+				best = residue
+			}
+		}
+
+		conservation[i] = float64(counts[best]) / float64(len(aln))
+		if best == '-' {
+			conservation[i] = 0
+		}
+		if counts['-'] > counts[best] {
+			best = '-'
+		}
+		consensus[i] = best
+	}
+
+	return string(consensus), conservation
+}
+
 // no guarantees... it's just some strings
 type ReallySimpleAlignment []string
 
@@ -250,6 +250,72 @@ func (lhc *Liquid) setDNASequences(seqList []DNASequence) {
 	lhc.Extra[SEQSKEY] = seqList
 }
 
+const (
+	ABSORBANCEKEY   = "Absorbance"
+	FLUORESCENCEKEY = "Fluorescence"
+)
+
+// getReadings decodes the readings stored under key into readings, which
+// should be a pointer to a slice.
+func (lhc *Liquid) getReadings(key string, readings interface{}) error {
+	v, found := lhc.Extra[key]
+	if !found {
+		return nil
+	}
+
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(bts, readings); err != nil {
+		return fmt.Errorf("Problem getting %s %s readings. Readings found: %+v; error: %s", lhc.Name(), key, v, err.Error())
+	}
+	return nil
+}
+
+// Absorbances returns the absorbance readings measured for the component,
+// e.g., by a plate reader.
+func (lhc *Liquid) Absorbances() ([]Absorbance, error) {
+	var readings []Absorbance
+	err := lhc.getReadings(ABSORBANCEKEY, &readings)
+	return readings, err
+}
+
+// AddAbsorbances appends absorbance readings to those of the component.
+func (lhc *Liquid) AddAbsorbances(readings ...Absorbance) error {
+	existing, err := lhc.Absorbances()
+	if err != nil {
+		return err
+	}
+	if lhc.Extra == nil {
+		lhc.Extra = make(map[string]interface{})
+	}
+	lhc.Extra[ABSORBANCEKEY] = append(existing, readings...)
+	return nil
+}
+
+// Fluorescences returns the fluorescence readings measured for the
+// component, e.g., by a plate reader.
+func (lhc *Liquid) Fluorescences() ([]Fluorescence, error) {
+	var readings []Fluorescence
+	err := lhc.getReadings(FLUORESCENCEKEY, &readings)
+	return readings, err
+}
+
+// AddFluorescences appends fluorescence readings to those of the component.
+func (lhc *Liquid) AddFluorescences(readings ...Fluorescence) error {
+	existing, err := lhc.Fluorescences()
+	if err != nil {
+		return err
+	}
+	if lhc.Extra == nil {
+		lhc.Extra = make(map[string]interface{})
+	}
+	lhc.Extra[FLUORESCENCEKEY] = append(existing, readings...)
+	return nil
+}
+
 // Returns the positions of any matching instances of a sequence in a slice of sequences.
 // If checkSeqs is set to false, only the name will be checked;
 // if checkSeqs is set to true, matching sequences with different names will also be checked.
@@ -697,6 +763,15 @@ func (cmp *Liquid) MixPreserveTvol(cmp2 *Liquid) {
 // add cmp2 to cmp
 func (cmp *Liquid) Mix(cmp2 *Liquid) {
 	wasEmpty := cmp.IsZero()
+
+	// propagate uncertainty only if either liquid is known to be inexact
+	var uncertainty *LiquidUncertainty
+	if cmp.HasUncertainty() || cmp2.HasUncertainty() {
+		if u, err := mixedUncertainty(cmp, cmp2); err == nil {
+			uncertainty = &u
+		}
+	}
+
 	cmp.Smax = mergeSolubilities(cmp, cmp2)
 	// determine type of final
 	cmp.Type = mergeTypes(cmp, cmp2)
@@ -735,6 +810,9 @@ func (cmp *Liquid) Mix(cmp2 *Liquid) {
 
 	cmp.SetSample(false)
 
+	if uncertainty != nil {
+		cmp.SetUncertainty(*uncertainty)
+	}
 }
 
 // @implement Liquid
@@ -223,3 +223,44 @@ func TestEqualTypeVolume(t *testing.T) {
 	}
 
 }
+
+func TestLiquidReadings(t *testing.T) {
+	c := NewLHComponent()
+	abs := Absorbance{
+		WellLocation: MakeWellCoordsA1("B3"),
+		Reading:      0.5,
+		Wavelength:   600,
+		Pathlength:   wunit.NewLength(1, "cm"),
+		Reader:       "spectramax",
+	}
+	if err := c.AddAbsorbances(abs); err != nil {
+		t.Fatal(err)
+	}
+	fl := Fluorescence{Reading: 1200, ExcitationWavelength: 485, EmissionWavelength: 520}
+	if err := c.AddFluorescences(fl); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c2 Liquid
+	if err := json.Unmarshal(bs, &c2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c2.AddAbsorbances(abs); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c2.Absorbances(); err != nil {
+		t.Error(err)
+	} else if e := []Absorbance{abs, abs}; !reflect.DeepEqual(got, e) {
+		t.Errorf("expecting %v but got %v", e, got)
+	}
+	if got, err := c2.Fluorescences(); err != nil {
+		t.Error(err)
+	} else if e := []Fluorescence{fl}; !reflect.DeepEqual(got, e) {
+		t.Errorf("expecting %v but got %v", e, got)
+	}
+}
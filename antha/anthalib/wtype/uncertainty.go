@@ -0,0 +1,331 @@
+// anthalib/wtype/uncertainty.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package wtype
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+)
+
+// UNCERTAINTYKEY is the key in Extra under which the uncertainty of a liquid
+// is stored
+const UNCERTAINTYKEY = "Uncertainty"
+
+// UncertaintyCoverage is the number of standard deviations either side of
+// the expected value which concentration ranges cover (approximately 95%)
+const UncertaintyCoverage = 2.0
+
+// Uncertainty is the relative error of a quantity
+type Uncertainty struct {
+	CV   float64 `json:"cv"`   // random error, as a fraction of the nominal value
+	Bias float64 `json:"bias"` // systematic error, as a fraction of the nominal value
+}
+
+// IsZero returns true if the quantity is exact
+func (u Uncertainty) IsZero() bool {
+	return u.CV == 0 && u.Bias == 0
+}
+
+// Range returns the range of values within UncertaintyCoverage standard
+// deviations of the expected value, including the bias
+func (u Uncertainty) Range(nominal float64) (min, max float64) {
+	mid := nominal * (1 + u.Bias)
+	half := math.Abs(nominal) * UncertaintyCoverage * u.CV
+	return mid - half, mid + half
+}
+
+// RelativeError returns the largest difference between the nominal value and
+// the ends of its range, as a fraction of the nominal value
+func (u Uncertainty) RelativeError() float64 {
+	return math.Abs(u.Bias) + UncertaintyCoverage*u.CV
+}
+
+func (u Uncertainty) String() string {
+	return fmt.Sprintf("CV %.2g%% bias %+.2g%%", u.CV*100, u.Bias*100)
+}
+
+// VolumeUncertainty returns the accuracy of transfers made with the policy,
+// given by its VolumeCV and VolumeBias items
+func (policy LHPolicy) VolumeUncertainty() Uncertainty {
+	var u Uncertainty
+	if cv, ok := policy[VolumeCV].(float64); ok {
+		u.CV = cv / 100
+	}
+	if bias, ok := policy[VolumeBias].(float64); ok {
+		u.Bias = bias / 100
+	}
+	return u
+}
+
+// LiquidUncertainty is the uncertainty in the volume of a liquid and in the
+// concentrations of each of its components
+type LiquidUncertainty struct {
+	Volume         Uncertainty            `json:"volume"`
+	Concentrations map[string]Uncertainty `json:"concentrations,omitempty"`
+}
+
+// IsZero returns true if the liquid has no uncertainty
+func (u LiquidUncertainty) IsZero() bool {
+	for _, c := range u.Concentrations {
+		if !c.IsZero() {
+			return false
+		}
+	}
+	return u.Volume.IsZero()
+}
+
+// HasUncertainty returns true if an uncertainty has been set for the liquid
+func (lhc *Liquid) HasUncertainty() bool {
+	if lhc == nil {
+		return false
+	}
+	_, found := lhc.Extra[UNCERTAINTYKEY]
+	return found
+}
+
+// GetUncertainty returns the uncertainty of the liquid. Liquids with no
+// uncertainty set are assumed to be exact.
+func (lhc *Liquid) GetUncertainty() (LiquidUncertainty, error) {
+	var u LiquidUncertainty
+	if lhc == nil {
+		return u, nil
+	}
+	v, found := lhc.Extra[UNCERTAINTYKEY]
+	if !found {
+		return u, nil
+	}
+	if lu, ok := v.(LiquidUncertainty); ok {
+		return lu, nil
+	}
+
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return u, err
+	}
+	if err := json.Unmarshal(bts, &u); err != nil {
+		return u, fmt.Errorf("Problem getting %s uncertainty. Uncertainty found: %+v; error: %s", lhc.Name(), v, err.Error())
+	}
+	return u, nil
+}
+
+// SetUncertainty sets the uncertainty of the liquid
+func (lhc *Liquid) SetUncertainty(u LiquidUncertainty) {
+	if lhc.Extra == nil {
+		lhc.Extra = make(map[string]interface{})
+	}
+	lhc.Extra[UNCERTAINTYKEY] = u
+}
+
+// SetVolumeUncertainty sets the uncertainty in the volume of the liquid, for
+// instance the accuracy of the transfer which moves it. The uncertainty of the
+// concentrations of its components is unchanged.
+func (lhc *Liquid) SetVolumeUncertainty(u Uncertainty) error {
+	lu, err := lhc.GetUncertainty()
+	if err != nil {
+		return err
+	}
+	lu.Volume = u
+	lhc.SetUncertainty(lu)
+	return nil
+}
+
+// uncertainSample is a liquid reduced to what is needed to propagate
+// uncertainty through a mix
+type uncertainSample struct {
+	volume      float64            // SI units
+	concs       map[string]float64 // SI units
+	uncertainty LiquidUncertainty
+}
+
+// componentConcentrations returns the concentrations of the components of a
+// liquid in the same way as SimulateMix: a liquid with no sub components is
+// its own component.
+func componentConcentrations(lhc *Liquid) map[string]float64 {
+	ret := make(map[string]float64)
+	if len(lhc.SubComponents.Components) != 0 {
+		for name, conc := range lhc.SubComponents.Components {
+			ret[removeConcUnitFromName(name)] += conc.SIValue()
+		}
+	} else if name := lhc.Name(); len(name) != 0 {
+		if lhc.Conc == 0 {
+			ret[removeConcUnitFromName(name)] = 1
+		} else {
+			ret[removeConcUnitFromName(name)] = lhc.Concentration().SIValue()
+		}
+	}
+	return ret
+}
+
+func newUncertainSample(lhc *Liquid, volume wunit.Volume) (uncertainSample, error) {
+	u, err := lhc.GetUncertainty()
+	if err != nil {
+		return uncertainSample{}, err
+	}
+	return uncertainSample{
+		volume:      volume.SIValue(),
+		concs:       componentConcentrations(lhc),
+		uncertainty: u,
+	}, nil
+}
+
+// mixedUncertainty returns the uncertainty of the liquid made by adding cmp2
+// to cmp. A cmp2 with no volume but a total volume tops cmp up to that volume.
+func mixedUncertainty(cmp, cmp2 *Liquid) (LiquidUncertainty, error) {
+	v1 := cmp.Volume()
+	v2 := cmp2.Volume()
+	if v2.IsZero() && cmp2.Tvol > 0 {
+		tvol := wunit.NewVolume(cmp2.Tvol, cmp2.Vunit)
+		if tvol.GreaterThan(v1) {
+			v2 = wunit.SubtractVolumes(tvol, v1)
+		}
+	}
+
+	s1, err := newUncertainSample(cmp, v1)
+	if err != nil {
+		return LiquidUncertainty{}, err
+	}
+	s2, err := newUncertainSample(cmp2, v2)
+	if err != nil {
+		return LiquidUncertainty{}, err
+	}
+	return mixUncertainty(s1, s2), nil
+}
+
+// mixUncertainty propagates the uncertainties of samples to their mixture
+// to first order, assuming that the errors of each sample are independent.
+//
+// For a component with concentration c_i and volume fraction f_i in sample
+// i, its share of the component in the mixture is w_i = f_i c_i / C where
+// C = sum(f_i c_i). An error in the volume of sample i dilutes or
+// concentrates the component in proportion to (w_i - f_i), and an error in
+// c_i changes C in proportion to w_i.
+func mixUncertainty(samples ...uncertainSample) LiquidUncertainty {
+	var total float64
+	for _, s := range samples {
+		total += s.volume
+	}
+	ret := LiquidUncertainty{Concentrations: make(map[string]Uncertainty)}
+	if total <= 0 {
+		return ret
+	}
+
+	names := make(map[string]bool)
+	var variance float64
+	for _, s := range samples {
+		f := s.volume / total
+		variance += math.Pow(f*s.uncertainty.Volume.CV, 2)
+		ret.Volume.Bias += f * s.uncertainty.Volume.Bias
+		for name := range s.concs {
+			names[name] = true
+		}
+	}
+	ret.Volume.CV = math.Sqrt(variance)
+
+	for name := range names {
+		var conc float64
+		for _, s := range samples {
+			conc += s.volume / total * s.concs[name]
+		}
+		if conc <= 0 {
+			continue
+		}
+
+		var u Uncertainty
+		variance = 0
+		for _, s := range samples {
+			f := s.volume / total
+			w := f * s.concs[name] / conc
+			c := s.uncertainty.Concentrations[name]
+			variance += math.Pow((w-f)*s.uncertainty.Volume.CV, 2) + math.Pow(w*c.CV, 2)
+			u.Bias += (w-f)*s.uncertainty.Volume.Bias + w*c.Bias
+		}
+		u.CV = math.Sqrt(variance)
+		ret.Concentrations[name] = u
+	}
+
+	return ret
+}
+
+// A ConcentrationRange is the range of concentrations of a component of a
+// liquid expected given the uncertainty of the transfers which made it
+type ConcentrationRange struct {
+	Component   string
+	Nominal     wunit.Concentration
+	Min         wunit.Concentration
+	Max         wunit.Concentration
+	Uncertainty Uncertainty
+}
+
+func (cr ConcentrationRange) String() string {
+	return fmt.Sprintf("%s: %s (%s - %s)", cr.Component, cr.Nominal, cr.Min, cr.Max)
+}
+
+// Exceeds returns true if either end of the range differs from the nominal
+// concentration by more than tolerance, given as a fraction of the nominal
+// concentration
+func (cr ConcentrationRange) Exceeds(tolerance float64) bool {
+	return cr.Uncertainty.RelativeError() > tolerance
+}
+
+// ConcentrationRanges returns the range of concentrations of each component
+// of the liquid, sorted by component name
+func (lhc *Liquid) ConcentrationRanges() ([]ConcentrationRange, error) {
+	u, err := lhc.GetUncertainty()
+	if err != nil {
+		return nil, err
+	}
+
+	concs := make(map[string]wunit.Concentration)
+	if len(lhc.SubComponents.Components) != 0 {
+		for name, conc := range lhc.SubComponents.Components {
+			concs[removeConcUnitFromName(name)] = conc
+		}
+	} else if name := lhc.Name(); len(name) != 0 {
+		if lhc.Conc == 0 {
+			concs[removeConcUnitFromName(name)] = wunit.NewConcentration(1.0, "v/v")
+		} else {
+			concs[removeConcUnitFromName(name)] = lhc.Concentration()
+		}
+	}
+
+	ret := make([]ConcentrationRange, 0, len(concs))
+	for name, conc := range concs {
+		cu := u.Concentrations[name]
+		min, max := cu.Range(conc.RawValue())
+		ret = append(ret, ConcentrationRange{
+			Component:   name,
+			Nominal:     conc,
+			Min:         wunit.NewConcentration(min, conc.Unit().PrefixedSymbol()),
+			Max:         wunit.NewConcentration(max, conc.Unit().PrefixedSymbol()),
+			Uncertainty: cu,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Component < ret[j].Component
+	})
+	return ret, nil
+}
@@ -0,0 +1,144 @@
+package wtype
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+)
+
+func equalFloat(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func makeUncertainLiquid(name string, conc wunit.Concentration, volume float64, u Uncertainty) *Liquid {
+	c := NewLHComponent()
+	c.CName = name
+	c.Type = LTWater
+	c.Vol = volume
+	c.Vunit = "ul"
+	if conc.RawValue() != 0 {
+		c.SetConcentration(conc)
+	}
+	if err := c.SetVolumeUncertainty(u); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestMixUncertainty(t *testing.T) {
+	dye := makeUncertainLiquid("dye", wunit.NewConcentration(1, "g/l"), 10, Uncertainty{CV: 0.05, Bias: 0.02})
+	water := makeUncertainLiquid("water", wunit.NewConcentration(0, "g/l"), 90, Uncertainty{CV: 0.01})
+
+	product := NewLHComponent()
+	product.Mix(dye)
+	product.Mix(water)
+
+	u, err := product.GetUncertainty()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e, g := math.Sqrt(math.Pow(0.1*0.05, 2)+math.Pow(0.9*0.01, 2)), u.Volume.CV; !equalFloat(e, g) {
+		t.Errorf("volume CV: expected %g, got %g", e, g)
+	}
+	if e, g := 0.1*0.02, u.Volume.Bias; !equalFloat(e, g) {
+		t.Errorf("volume bias: expected %g, got %g", e, g)
+	}
+
+	// errors in either volume change the dilution of the dye
+	if e, g := math.Sqrt(math.Pow(0.9*0.05, 2)+math.Pow(0.9*0.01, 2)), u.Concentrations["dye"].CV; !equalFloat(e, g) {
+		t.Errorf("dye CV: expected %g, got %g", e, g)
+	}
+	if e, g := 0.9*0.02, u.Concentrations["dye"].Bias; !equalFloat(e, g) {
+		t.Errorf("dye bias: expected %g, got %g", e, g)
+	}
+
+	ranges, err := product.ConcentrationRanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 2 || ranges[0].Component != "dye" || ranges[1].Component != "water" {
+		t.Fatalf("expected ranges for dye and water, got %v", ranges)
+	}
+	dyeRange := ranges[0]
+	cv := u.Concentrations["dye"].CV
+	if e, g := 0.1*(1.018-2*cv), dyeRange.Min.RawValue(); !equalFloat(e, g) {
+		t.Errorf("dye min: expected %g, got %g", e, g)
+	}
+	if e, g := 0.1*(1.018+2*cv), dyeRange.Max.RawValue(); !equalFloat(e, g) {
+		t.Errorf("dye max: expected %g, got %g", e, g)
+	}
+	if !dyeRange.Exceeds(0.1) || dyeRange.Exceeds(0.2) {
+		t.Errorf("relative error %g should exceed 10%% but not 20%%", dyeRange.Uncertainty.RelativeError())
+	}
+}
+
+func TestMixUncertaintyPropagates(t *testing.T) {
+	// a dilution of a dilution carries the uncertainty of the first and adds
+	// that of its own transfers
+	first := NewLHComponent()
+	first.Mix(makeUncertainLiquid("dye", wunit.NewConcentration(1, "g/l"), 10, Uncertainty{CV: 0.05}))
+	first.Mix(makeUncertainLiquid("water", wunit.NewConcentration(0, "g/l"), 90, Uncertainty{}))
+	u1, _ := first.GetUncertainty()
+
+	sample := first.Dup()
+	sample.Vol = 50
+	if err := sample.SetVolumeUncertainty(Uncertainty{}); err != nil {
+		t.Fatal(err)
+	}
+	second := NewLHComponent()
+	second.Mix(sample)
+	second.Mix(makeUncertainLiquid("water", wunit.NewConcentration(0, "g/l"), 50, Uncertainty{CV: 0.02}))
+	u2, _ := second.GetUncertainty()
+
+	if e, g := math.Sqrt(math.Pow(u1.Concentrations["dye"].CV, 2)+math.Pow(0.5*0.02, 2)), u2.Concentrations["dye"].CV; !equalFloat(e, g) {
+		t.Errorf("expected dye CV %g, got %g", e, g)
+	}
+}
+
+func TestMixWithoutUncertainty(t *testing.T) {
+	a := NewLHComponent()
+	a.CName = "a"
+	a.Vol = 10
+	b := NewLHComponent()
+	b.CName = "b"
+	b.Vol = 10
+	a.Mix(b)
+	if a.HasUncertainty() {
+		t.Error("mixing exact liquids should not add an uncertainty")
+	}
+}
+
+func TestUncertaintySerialization(t *testing.T) {
+	c := makeUncertainLiquid("dye", wunit.NewConcentration(1, "g/l"), 10, Uncertainty{CV: 0.05, Bias: -0.01})
+
+	bs, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c2 Liquid
+	if err := json.Unmarshal(bs, &c2); err != nil {
+		t.Fatal(err)
+	}
+
+	if u, err := c2.GetUncertainty(); err != nil {
+		t.Fatal(err)
+	} else if e := (Uncertainty{CV: 0.05, Bias: -0.01}); u.Volume != e {
+		t.Errorf("expected %v, got %v", e, u.Volume)
+	}
+}
+
+func TestPolicyVolumeUncertainty(t *testing.T) {
+	p := NewLHPolicy()
+	if err := p.Set(VolumeCV, 5.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Set(VolumeBias, -1.0); err != nil {
+		t.Fatal(err)
+	}
+	if e, g := (Uncertainty{CV: 0.05, Bias: -0.01}), p.VolumeUncertainty(); !equalFloat(e.CV, g.CV) || !equalFloat(e.Bias, g.Bias) {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+}
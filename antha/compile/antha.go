@@ -204,24 +204,27 @@ func NewAntha(root *AnthaRoot) *Antha {
 	}
 
 	p.intrinsics = map[string]string{
-		"Centrifuge":    "execute.Centrifuge",
-		"Electroshock":  "execute.Electroshock",
-		"ExecuteMixes":  "execute.ExecuteMixes",
-		"Errorf":        "execute.Errorf",
-		"Handle":        "execute.Handle",
-		"Incubate":      "execute.Incubate",
-		"Mix":           "execute.Mix",
-		"MixInto":       "execute.MixInto",
-		"MixNamed":      "execute.MixNamed",
-		"MixTo":         "execute.MixTo",
-		"MixerPrompt":   "execute.MixerPrompt",
-		"NewComponent":  "execute.NewComponent",
-		"NewPlate":      "execute.NewPlate",
-		"Prompt":        "execute.Prompt",
-		"ReadEM":        "execute.ReadEM",
-		"Sample":        "execute.Sample",
-		"SetInputPlate": "execute.SetInputPlate",
-		"SplitSample":   "execute.SplitSample",
+		"Centrifuge":     "execute.Centrifuge",
+		"Electroshock":   "execute.Electroshock",
+		"ExecuteMixes":   "execute.ExecuteMixes",
+		"Errorf":         "execute.Errorf",
+		"Handle":         "execute.Handle",
+		"Incubate":       "execute.Incubate",
+		"Mix":            "execute.Mix",
+		"MixInto":        "execute.MixInto",
+		"MixNamed":       "execute.MixNamed",
+		"MixTo":          "execute.MixTo",
+		"MixPlateMap":    "execute.MixPlateMap",
+		"MixerPrompt":    "execute.MixerPrompt",
+		"NewComponent":   "execute.NewComponent",
+		"NewPlate":       "execute.NewPlate",
+		"Normalise":      "execute.Normalise",
+		"Prompt":         "execute.Prompt",
+		"ReadEM":         "execute.ReadEM",
+		"Sample":         "execute.Sample",
+		"SerialDilution": "execute.SerialDilution",
+		"SetInputPlate":  "execute.SetInputPlate",
+		"SplitSample":    "execute.SplitSample",
 	}
 
 	p.types = map[string]string{
@@ -231,6 +234,7 @@ func NewAntha(root *AnthaRoot) *Antha {
 		"Area":                 "wunit.Area",
 		"Capacitance":          "wunit.Capacitance",
 		"Concentration":        "wunit.Concentration",
+		"Condition":            "execute.Condition",
 		"DNASequence":          "wtype.DNASequence",
 		"Density":              "wunit.Density",
 		"DeviceMetadata":       "api.DeviceMetadata",
@@ -251,11 +255,15 @@ func NewAntha(root *AnthaRoot) *Antha {
 		"LiquidType":           "wtype.LiquidType",
 		"Mass":                 "wunit.Mass",
 		"Moles":                "wunit.Moles",
+		"NormaliseOpt":         "execute.NormaliseOpt",
+		"PlateMap":             "execute.PlateMap",
+		"PlateMapOpt":          "execute.PlateMapOpt",
 		"PolicyName":           "wtype.PolicyName",
 		"Plate":                "wtype.Plate",
 		"Pressure":             "wunit.Pressure",
 		"Rate":                 "wunit.Rate",
 		"Resistance":           "wunit.Resistance",
+		"SerialDilutionOpt":    "execute.SerialDilutionOpt",
 		"SpecificHeatCapacity": "wunit.SpecificHeatCapacity",
 		"SubstanceQuantity":    "wunit.SubstanceQuantity",
 		"Temperature":          "wunit.Temperature",
@@ -813,6 +821,12 @@ type Output struct {
 	{{ end }}
 }
 
+type AnalysisInput struct {
+	{{ range .Inputs }}{{ .Name }} {{ .Value }}
+	{{ end }}{{ range .MergedOutputs }}{{ .Name }} {{ .Value }}
+	{{ end }}
+}
+
 type RunStepsOutput struct {
 	Data struct {
 		{{ range .Data }}{{ .Name }} {{ .Value }}
@@ -1157,6 +1171,9 @@ func (p *Antha) printFunctions(out io.Writer, lineMap map[int]int) error {
 type Element struct {
 }
 
+// Run runs the Setup, Steps, Analysis and Validation blocks. If analysis
+// is deferred, Analysis and Validation are left to RunAnalysisValidation,
+// once instruments have returned measured data.
 func (Element) Run(_ctx context.Context, request *{{ .ModelPackage }}.Input) (response *{{ .ModelPackage }}.Output, err error) {
 	_ctx = execute.WithElementName(_ctx, {{ .ElementName }})
 	bs, err := json.Marshal(request)
@@ -1172,13 +1189,18 @@ func (Element) Run(_ctx context.Context, request *{{ .ModelPackage }}.Input) (re
 	response = &{{ .ModelPackage }}.Output{}
 	{{if .HasSetup}}_Setup(_ctx, in, response){{end}}
 	{{if .HasSteps}}_Steps(_ctx, in, response){{end}}
-	{{if .HasAnalysis}}_Analysis(_ctx, in, response){{end}}
-	{{if .HasValidation}}_Validation(_ctx, in, response){{end}}
+	{{if or .HasAnalysis .HasValidation}}if !execute.AnalysisDeferred(_ctx) {
+		{{if .HasAnalysis}}_Analysis(_ctx, in, response){{end}}
+		{{if .HasValidation}}_Validation(_ctx, in, response){{end}}
+	}{{end}}
 	return
 }
 
-func (Element) RunAnalysisValidation(_ctx context.Context, request *{{ .ModelPackage }}.Input) (response *{{ .ModelPackage }}.Output, err error) {
-	response = &{{ .ModelPackage }}.Output{}
+// RunAnalysisValidation runs the Analysis and Validation blocks given the
+// outputs of Run, into which measured data may have been injected
+func (Element) RunAnalysisValidation(_ctx context.Context, request *{{ .ModelPackage }}.Input, outputs *{{ .ModelPackage }}.Output) (response *{{ .ModelPackage }}.Output, err error) {
+	_ctx = execute.WithElementName(_ctx, {{ .ElementName }})
+	response = outputs
 	{{if .HasAnalysis}}_Analysis(_ctx, request, response){{end}}
 	{{if .HasValidation}}_Validation(_ctx, request, response){{end}}
 	return
@@ -1193,16 +1215,20 @@ func _newAVRunner() interface{} {
 	return &inject.CheckedRunner {
 		RunFunc: func(_ctx context.Context, value inject.Value) (inject.Value, error) {
 			request := &{{ .ModelPackage }}.Input{}
-			if err := inject.Assign(value, request); err != nil {
+			if err := inject.AssignSome(value, request); err != nil {
 				return nil, err
 			}
-			resp, err := elem.RunAnalysisValidation(_ctx, request)
+			outputs := &{{ .ModelPackage }}.Output{}
+			if err := inject.AssignSome(value, outputs); err != nil {
+				return nil, err
+			}
+			resp, err := elem.RunAnalysisValidation(_ctx, request, outputs)
 			if err != nil {
 				return nil, err
 			}
 			return inject.MakeValue(resp), nil
 		},
-		In: &{{ .ModelPackage }}.Input{},
+		In: &{{ .ModelPackage }}.AnalysisInput{},
 		Out: &{{ .ModelPackage }}.Output{},
 	}
 }
@@ -1251,6 +1277,7 @@ func GetComponent() []*component.Component{
 			Description: component.Description{
 				Desc: {{ .Desc }},
 				Path: {{ .Path }},
+				SourceSha256: _metadata.SourceSha256,
 				Params: []component.ParamDesc{
 					{{range .Params}}component.ParamDesc{
 						Name: {{ .Name }},
@@ -1268,6 +1295,7 @@ func GetComponent() []*component.Component{
 			Description: component.Description{
 				Desc: {{ .Desc }},
 				Path: {{ .Path }},
+				SourceSha256: _metadata.SourceSha256,
 			},
 		},
 	}
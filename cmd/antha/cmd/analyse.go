@@ -0,0 +1,176 @@
+// analyse.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	api "github.com/antha-lang/antha/api/v1"
+	"github.com/antha-lang/antha/cmd/antha/pretty"
+	"github.com/antha-lang/antha/execute"
+	"github.com/antha-lang/antha/execute/executeutil"
+	"github.com/antha-lang/antha/target/auto"
+	"github.com/antha-lang/antha/target/mixer"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var analyseCmd = &cobra.Command{
+	Use:           "analyse",
+	Short:         "Run the analysis and validation of an antha workflow with measured data",
+	RunE:          analyseWorkflow,
+	SilenceErrors: true,
+}
+
+// readData returns measured data from a JSON file of values by process and
+// data name and from files given as process.name=filename
+func readData(dataFile string, files []string) (map[string]map[string]json.RawMessage, error) {
+	data := make(map[string]map[string]json.RawMessage)
+	if dataFile != "" {
+		bs, err := ioutil.ReadFile(dataFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(bs, &data); err != nil {
+			return nil, fmt.Errorf("cannot read data file %q: %s", dataFile, err)
+		}
+	}
+
+	var m jsonpb.Marshaler
+	for _, kv := range files {
+		idx := strings.Index(kv, "=")
+		dot := strings.LastIndex(kv[:idx+1], ".")
+		if idx < 0 || dot < 0 {
+			return nil, fmt.Errorf("invalid data file %q: expecting process.name=filename", kv)
+		}
+		process, name, filename := kv[:dot], kv[dot+1:idx], kv[idx+1:]
+
+		s, err := m.MarshalToString(&api.Blob{
+			Name: filepath.Base(filename),
+			From: &api.Blob_HostFile{
+				HostFile: &api.FromHostFile{
+					Filename: filename,
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if data[process] == nil {
+			data[process] = make(map[string]json.RawMessage)
+		}
+		data[process][name] = json.RawMessage(s)
+	}
+	return data, nil
+}
+
+func analyseWorkflow(cmd *cobra.Command, args []string) error {
+	if err := viper.BindPFlags(cmd.Flags()); err != nil {
+		return err
+	}
+
+	bundle, err := executeutil.UnmarshalSingle(viper.GetString("bundle"), viper.GetString("workflow"), viper.GetString("parameters"))
+	if err != nil {
+		return err
+	}
+
+	data, err := readData(viper.GetString("data"), GetStringSlice("dataFile"))
+	if err != nil {
+		return err
+	}
+
+	// Analysis needs the outputs of the completed run, which are
+	// regenerated, or restored from the cache of the run, by simulating the
+	// workflow again
+	mixerOpt := mixer.DefaultOpt.Merge(bundle.RawParams.Config)
+	t, err := auto.New(auto.Opt{
+		MaybeArgs: []interface{}{mixerOpt},
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, err := makeContext()
+	if err != nil {
+		return err
+	}
+
+	var cache *execute.Cache
+	if viper.GetBool("resume") {
+		if cache, err = openCache(viper.GetString("cacheDir")); err != nil {
+			return err
+		}
+		cache.Resume = true
+	}
+
+	rout, err := execute.Run(ctx, execute.Opt{
+		Target:                     t.Target,
+		Workflow:                   &bundle.Desc,
+		Params:                     &bundle.RawParams,
+		TransitionalReadLocalFiles: true,
+		MaxParallel:                viper.GetInt("maxParallel"),
+		Cache:                      cache,
+		DeferAnalysis:              true,
+	})
+	if err != nil {
+		return err
+	}
+
+	analysis, err := execute.Analyse(ctx, rout, execute.AnalyseOpt{
+		Data:           data,
+		ReadLocalFiles: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := pretty.Analysis(os.Stdout, analysis); err != nil {
+		return err
+	}
+
+	if failed := analysis.Failed(); len(failed) != 0 {
+		return fmt.Errorf("%d of %d elements did not pass validation", len(failed), len(analysis.Results))
+	}
+	return nil
+}
+
+func init() {
+	c := analyseCmd
+	flags := c.Flags()
+	RootCmd.AddCommand(c)
+
+	flags.String("bundle", "", "Input bundle with parameters and workflow together (overrides parameter and workflow arguments)")
+	flags.String("parameters", "", "Parameters to workflow")
+	flags.String("workflow", "", "Workflow definition file")
+	flags.Int("maxParallel", 1, "Maximum number of workflow processes to run concurrently")
+	flags.String("cacheDir", defaultCacheDir, "Directory in which the run saved the outputs of each element")
+	flags.Bool("resume", false, "Reuse outputs saved in cacheDir by the run rather than re-executing elements")
+	flags.String("data", "", "JSON file of measured data by process and then by data name")
+	flags.StringSlice("dataFile", nil, "File of measured data, such as a plate reader export, for a data output of a process (process.name=filename); use multiple flags for multiple files")
+}
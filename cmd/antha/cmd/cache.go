@@ -0,0 +1,133 @@
+// cache.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/antha-lang/antha/execute"
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the cache of element outputs",
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cached element outputs",
+	RunE:  listCache,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear [element...]",
+	Short: "Remove cached element outputs, optionally only of the given elements",
+	RunE:  clearCache,
+}
+
+// defaultCacheDir is the cache directory used by run, analyse and cache
+// when none is given
+const defaultCacheDir = ".antha-cache"
+
+// openCache opens the cache in dir keyed by the sources of the elements in
+// the library
+func openCache(dir string) (*execute.Cache, error) {
+	sources := make(map[string][]byte)
+	for _, c := range runComponents() {
+		sources[c.Name] = c.Description.SourceSha256
+	}
+	return execute.OpenCache(dir, sources)
+}
+
+func listCache(cmd *cobra.Command, args []string) error {
+	if err := viper.BindPFlags(cmd.Flags()); err != nil {
+		return err
+	}
+
+	cache, err := openCache(viper.GetString("cacheDir"))
+	if err != nil {
+		return err
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		return err
+	}
+
+	switch output := viper.GetString("output"); output {
+	case jsonOutput:
+		bs, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(bs))
+		return err
+	case yamlOutput:
+		bs, err := yaml.Marshal(entries)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Print(string(bs))
+		return err
+	case textOutput:
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "Element\tCreated\tInstructions\tSize\tKey") // nolint: errcheck
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", e.Element, e.Created.Format(time.RFC3339), e.NumInsts, e.Size, e.Key) // nolint: errcheck
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", output)
+	}
+}
+
+func clearCache(cmd *cobra.Command, args []string) error {
+	if err := viper.BindPFlags(cmd.Flags()); err != nil {
+		return err
+	}
+
+	cache, err := openCache(viper.GetString("cacheDir"))
+	if err != nil {
+		return err
+	}
+
+	return cache.Clear(args...)
+}
+
+func init() {
+	c := cacheCmd
+	flags := c.PersistentFlags()
+	RootCmd.AddCommand(c)
+	c.AddCommand(cacheListCmd)
+	c.AddCommand(cacheClearCmd)
+
+	flags.String("cacheDir", defaultCacheDir, "Directory of cached element outputs")
+	cacheListCmd.Flags().String("output", textOutput, fmt.Sprintf("Output format: one of {%s,%s,%s}", textOutput, yamlOutput, jsonOutput))
+}
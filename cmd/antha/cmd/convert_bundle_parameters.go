@@ -54,6 +54,7 @@ func convertProcesses(in map[string]workflow.Process, newElementNames Conversion
 		}
 		ret[k] = workflow.Process{
 			Component: comp,
+			Workflow:  v.Workflow,
 			Metadata:  v.Metadata,
 		}
 	}
@@ -23,6 +23,7 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -31,6 +32,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/antha-lang/antha/antha/anthalib/wtype"
 	"github.com/antha-lang/antha/antha/anthalib/wtype/liquidtype"
@@ -41,6 +43,7 @@ import (
 	"github.com/antha-lang/antha/inject"
 	"github.com/antha-lang/antha/inventory/testinventory"
 	"github.com/antha-lang/antha/target"
+	"github.com/antha-lang/antha/target/acoustic"
 	"github.com/antha-lang/antha/target/auto"
 	"github.com/antha-lang/antha/target/mixer"
 	"github.com/antha-lang/antha/workflowtest"
@@ -55,6 +58,24 @@ var runCmd = &cobra.Command{
 	SilenceErrors: true,
 }
 
+// makeAcousticOpt returns the options of the acoustic dispenser given by
+// the acousticDispenser flag or nil if there is none
+func makeAcousticOpt() (*acoustic.Opt, error) {
+	fn := viper.GetString("acousticDispenser")
+	if fn == "" {
+		return nil, nil
+	}
+	bs, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var opt acoustic.Opt
+	if err := json.Unmarshal(bs, &opt); err != nil {
+		return nil, fmt.Errorf("cannot parse acoustic dispenser options %s: %s", fn, err)
+	}
+	return &opt, nil
+}
+
 func makeMixerOpt(ctx context.Context) (mixer.Opt, error) {
 	opt := mixer.Opt{}
 	if i := viper.GetInt("maxPlates"); i != 0 {
@@ -110,6 +131,12 @@ func makeMixerOpt(ctx context.Context) (mixer.Opt, error) {
 
 	opt.FixVolumes = viper.GetBool("fixVolumes")
 
+	if f := viper.GetFloat64("concentrationTolerance"); f != 0 {
+		opt.ConcentrationTolerance = &f
+	}
+
+	opt.OptimiseConsumables = viper.GetBool("optimiseConsumables")
+
 	return opt, nil
 }
 
@@ -131,6 +158,7 @@ func makeContext() (context.Context, error) {
 
 type runOpt struct {
 	MixerOpt               mixer.Opt
+	AcousticOpt            *acoustic.Opt
 	Drivers                []string
 	BundleFile             string
 	ParametersFile         string
@@ -138,6 +166,86 @@ type runOpt struct {
 	MixInstructionFileName string
 	TestBundleFileName     string
 	RunTest                bool
+	MaxParallel            int
+	CacheDir               string
+	Cache                  bool
+	Resume                 bool
+	ScheduleOpt            target.ScheduleOpt
+	ScheduleFile           string
+	GanttFile              string
+	CriticalPath           bool
+	DeferAnalysis          bool
+}
+
+// makeScheduleOpt returns the durations of instructions that cannot
+// estimate their own time
+func makeScheduleOpt() (target.ScheduleOpt, error) {
+	opt := target.ScheduleOpt{
+		ManualDuration: viper.GetDuration("manualDuration"),
+		PromptDuration: viper.GetDuration("promptDuration"),
+		RunDuration:    viper.GetDuration("runDuration"),
+		Durations:      make(map[string]time.Duration),
+	}
+	for _, kv := range GetStringSlice("instDuration") {
+		idx := strings.LastIndex(kv, "=")
+		if idx < 0 {
+			return opt, fmt.Errorf("invalid instruction duration %q: expecting label=duration", kv)
+		}
+		d, err := time.ParseDuration(kv[idx+1:])
+		if err != nil {
+			return opt, fmt.Errorf("invalid instruction duration %q: %s", kv, err)
+		}
+		opt.Durations[kv[:idx]] = d
+	}
+	return opt, nil
+}
+
+// writeSchedule estimates the schedule of the instructions of a run and
+// writes it in the requested formats
+func (a *runOpt) writeSchedule(t *auto.Auto, rout *execute.Result) error {
+	if !a.CriticalPath && a.ScheduleFile == "" && a.GanttFile == "" {
+		return nil
+	}
+
+	opt := a.ScheduleOpt
+	opt.Insts = rout.Insts
+	s, err := target.MakeSchedule(opt)
+	if err != nil {
+		return err
+	}
+
+	if a.CriticalPath {
+		if err := pretty.CriticalPath(os.Stdout, t, s); err != nil {
+			return err
+		}
+	}
+
+	if a.ScheduleFile != "" {
+		bs, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(a.ScheduleFile, bs, 0666); err != nil {
+			return err
+		}
+	}
+
+	if a.GanttFile != "" {
+		var buf bytes.Buffer
+		if strings.HasSuffix(strings.ToLower(a.GanttFile), ".svg") {
+			err = pretty.GanttSVG(&buf, s)
+		} else {
+			err = pretty.GanttHTML(&buf, s)
+		}
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(a.GanttFile, buf.Bytes(), 0666); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (a *runOpt) Run() error {
@@ -149,7 +257,8 @@ func (a *runOpt) Run() error {
 	mixerOpt := mixer.DefaultOpt.Merge(bundle.RawParams.Config).Merge(&a.MixerOpt)
 
 	opt := auto.Opt{
-		MaybeArgs: []interface{}{mixerOpt},
+		MaybeArgs:         []interface{}{mixerOpt},
+		AcousticDispenser: a.AcousticOpt,
 	}
 	for _, uri := range a.Drivers {
 		opt.Endpoints = append(opt.Endpoints, auto.Endpoint{URI: uri})
@@ -166,16 +275,37 @@ func (a *runOpt) Run() error {
 		return err
 	}
 
+	var cache *execute.Cache
+	if a.Cache || a.Resume {
+		if a.CacheDir == "" {
+			return fmt.Errorf("cannot cache element outputs without a cache directory")
+		}
+		if cache, err = openCache(a.CacheDir); err != nil {
+			return err
+		}
+		cache.Resume = a.Resume
+	}
+
 	rout, err := execute.Run(ctx, execute.Opt{
 		Target:                     t.Target,
 		Workflow:                   &bundle.Desc,
 		Params:                     &bundle.RawParams,
 		TransitionalReadLocalFiles: true,
+		MaxParallel:                a.MaxParallel,
+		Cache:                      cache,
+		DeferAnalysis:              a.DeferAnalysis,
 	})
 	if err != nil {
 		return err
 	}
 
+	// report the consumables of mixes where they were optimised
+	for _, inst := range rout.Insts {
+		if mi, ok := inst.(*target.Mix); ok && mi.Request != nil && mi.Request.ConsumablesReport != nil {
+			fmt.Print(mi.Request.ConsumablesReport)
+		}
+	}
+
 	// if option is set, add liquid handling instruction output
 	if a.MixInstructionFileName != "" {
 		countFiles := 1
@@ -233,6 +363,14 @@ func (a *runOpt) Run() error {
 		return err
 	}
 
+	if err := pretty.Uncertainty(os.Stdout, t, rout); err != nil {
+		return err
+	}
+
+	if err := a.writeSchedule(t, rout); err != nil {
+		return err
+	}
+
 	if err := pretty.Run(os.Stdout, os.Stdin, t, rout); err != nil {
 		return err
 	}
@@ -283,8 +421,19 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	aopt, err := makeAcousticOpt()
+	if err != nil {
+		return err
+	}
+
+	sopt, err := makeScheduleOpt()
+	if err != nil {
+		return err
+	}
+
 	opt := &runOpt{
 		MixerOpt:               mopt,
+		AcousticOpt:            aopt,
 		Drivers:                drivers,
 		BundleFile:             viper.GetString("bundle"),
 		ParametersFile:         viper.GetString("parameters"),
@@ -292,6 +441,15 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 		MixInstructionFileName: viper.GetString("mixInstructionFileName"),
 		TestBundleFileName:     viper.GetString("makeTestBundle"),
 		RunTest:                viper.GetBool("runTest"),
+		MaxParallel:            viper.GetInt("maxParallel"),
+		CacheDir:               viper.GetString("cacheDir"),
+		Cache:                  viper.GetBool("cache"),
+		Resume:                 viper.GetBool("resume"),
+		ScheduleOpt:            sopt,
+		ScheduleFile:           viper.GetString("scheduleFile"),
+		GanttFile:              viper.GetString("ganttFile"),
+		CriticalPath:           viper.GetBool("criticalPath"),
+		DeferAnalysis:          viper.GetBool("deferAnalysis"),
 	}
 
 	return opt.Run()
@@ -308,6 +466,7 @@ func init() {
 	flags.Bool("ignorePhysicalSimulation", false, "Ignore errors when physically simulating the workflow - use to suppress issues caused by bugs in physical simulations")
 	flags.Bool("withMulti", false, "Allow use of new multichannel planning - deprecated")
 	flags.Float64("residualVolumeWeight", 0.0, "Residual volume weight")
+	flags.Int("maxParallel", 1, "Maximum number of workflow processes to run concurrently")
 	flags.Int("maxPlates", 0, "Maximum number of plates")
 	flags.Int("maxWells", 0, "Maximum number of wells on a plate")
 	flags.String("bundle", "", "Input bundle with parameters and workflow together (overrides parameter and workflow arguments)")
@@ -323,7 +482,21 @@ func init() {
 	flags.StringSlice("tipTypes", nil, "Names of permitted tip types")
 	flags.Bool("runTest", false, "compare mix instructions and time estimates with results previously generated by using the makeTestBundle flag. ")
 	flags.Bool("fixVolumes", true, "Make all volumes sufficient for later uses")
+	flags.Float64("concentrationTolerance", 0.0, "Flag outputs whose component concentrations may differ from nominal by more than this percentage")
+	flags.Bool("optimiseConsumables", false, "Regroup transfers and choose output plate types to reduce the cost of consumables and robot time, and report consumables used")
+	flags.String("cacheDir", defaultCacheDir, "Directory in which to save the outputs of each element")
+	flags.Bool("cache", false, "Save the outputs of each element in cacheDir")
+	flags.Bool("resume", false, "Reuse outputs saved in cacheDir for elements whose inputs are unchanged (implies --cache)")
 	flags.String("policyFile", "", "Design file of custom liquid policies in format of .xlsx JMP file")
+	flags.Duration("manualDuration", 5*time.Minute, "Estimated duration of manual instructions")
+	flags.Duration("promptDuration", time.Minute, "Estimated duration of prompts")
+	flags.Duration("runDuration", time.Minute, "Estimated duration of device runs without their own estimate, e.g., plate reads")
+	flags.StringSlice("instDuration", nil, "Estimated duration of manual instructions or device runs with a given label (label=duration); use multiple flags for multiple labels")
+	flags.String("scheduleFile", "", "File to write the estimated instruction schedule to as JSON")
+	flags.String("ganttFile", "", "File to write a Gantt chart of the estimated schedule to (.svg or .html)")
+	flags.String("acousticDispenser", "", "JSON file of acoustic dispenser options; adds an acoustic dispenser for mixes requested with execute.WithAcousticDispensing")
+	flags.Bool("criticalPath", false, "Print the critical path of the estimated schedule")
+	flags.Bool("deferAnalysis", false, "Skip the Analysis and Validation blocks of elements, to be run by antha analyse once measured data is available")
 }
 
 func idempotentRun1Addition(name string) string {
@@ -0,0 +1,110 @@
+// seqdb.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/sequences/seqdb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var seqdbCmd = &cobra.Command{
+	Use:   "seqdb",
+	Short: "Build or update a local sequence database for searching offline",
+}
+
+var seqdbBuildCmd = &cobra.Command{
+	Use:   "build <database> <directory...>",
+	Short: "Import the sequence files in directories into a database, creating it if necessary",
+	RunE:  buildSeqDB,
+}
+
+var seqdbUpdateCmd = &cobra.Command{
+	Use:   "update <database>",
+	Short: "Reimport the sequence files of a database which have been added, modified or deleted",
+	RunE:  updateSeqDB,
+}
+
+func buildSeqDB(cmd *cobra.Command, args []string) error {
+	if err := viper.BindPFlags(cmd.Flags()); err != nil {
+		return err
+	}
+
+	switch len(args) {
+	case 0:
+		return fmt.Errorf("no database given")
+	case 1:
+		return fmt.Errorf("no directories given")
+	}
+
+	db, err := seqdb.OpenFile(args[0])
+	if os.IsNotExist(err) {
+		db = seqdb.New(viper.GetString("name"), viper.GetInt("k"))
+	} else if err != nil {
+		return err
+	}
+
+	for _, dir := range args[1:] {
+		n, err := db.ImportDir(dir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d files changed\n", dir, n)
+	}
+	fmt.Printf("%s: %d sequences\n", args[0], len(db.Entries))
+
+	return db.SaveFile(args[0])
+}
+
+func updateSeqDB(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no database given")
+	}
+
+	db, err := seqdb.OpenFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	n, err := db.Update()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d files changed, %d sequences\n", args[0], n, len(db.Entries))
+
+	return db.SaveFile(args[0])
+}
+
+func init() {
+	c := seqdbCmd
+	RootCmd.AddCommand(c)
+	c.AddCommand(seqdbBuildCmd)
+	c.AddCommand(seqdbUpdateCmd)
+
+	flags := seqdbBuildCmd.Flags()
+	flags.String("name", "local", "Name of a new database")
+	flags.Int("k", seqdb.DefaultK, "Length of the k-mers indexed by a new database")
+}
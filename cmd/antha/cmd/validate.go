@@ -0,0 +1,104 @@
+// validate.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/antha-lang/antha/execute/executeutil"
+	"github.com/antha-lang/antha/workflow"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check an antha workflow for errors without running it",
+	RunE:  validateWorkflow,
+}
+
+// requiredInputs returns whether an input of a component must have a value.
+// Physical inputs must be given; other parameters default to their zero
+// value unless strict is true.
+func requiredInputs(strict bool) func(funcName, port string) bool {
+	kinds := make(map[string]map[string]string)
+	for _, c := range runComponents() {
+		m := make(map[string]string)
+		for _, p := range c.Description.Params {
+			m[p.Name] = p.Kind
+		}
+		kinds[c.Name] = m
+	}
+
+	return func(funcName, port string) bool {
+		if strict {
+			return true
+		}
+		return kinds[funcName][port] == "Inputs"
+	}
+}
+
+func validateWorkflow(cmd *cobra.Command, args []string) error {
+	if err := viper.BindPFlags(cmd.Flags()); err != nil {
+		return err
+	}
+
+	bundle, err := executeutil.UnmarshalSingle(viper.GetString("bundle"), viper.GetString("workflow"), viper.GetString("parameters"))
+	if err != nil {
+		return err
+	}
+
+	ctx, err := makeContext()
+	if err != nil {
+		return err
+	}
+
+	var params []workflow.Port
+	for process, ps := range bundle.RawParams.Parameters {
+		for name := range ps {
+			params = append(params, workflow.Port{Process: process, Port: name})
+		}
+	}
+
+	if err := workflow.Validate(ctx, workflow.ValidateOpt{
+		Desc:     &bundle.Desc,
+		Params:   params,
+		Required: requiredInputs(viper.GetBool("strict")),
+	}); err != nil {
+		return err
+	}
+
+	_, err = fmt.Println("workflow is valid")
+	return err
+}
+
+func init() {
+	c := validateCmd
+	flags := c.Flags()
+	RootCmd.AddCommand(c)
+
+	flags.String("bundle", "", "Input bundle with parameters and workflow together (overrides parameter and workflow arguments)")
+	flags.String("parameters", "", "Parameters to workflow")
+	flags.String("workflow", "", "Workflow definition file")
+	flags.Bool("strict", false, "Require every input of every element to have a value, not just physical inputs")
+}
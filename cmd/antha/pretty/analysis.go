@@ -0,0 +1,44 @@
+package pretty
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/execute"
+)
+
+// Analysis creates a pretty printed summary of the analysis and validation
+// of each element
+func Analysis(out io.Writer, a *execute.Analysis) error {
+	var lines []string
+	lines = append(lines, "== Analysis and Validation:\n")
+	for _, r := range a.Results {
+		line := fmt.Sprintf("    * %s: %s", r.Process, strings.ToUpper(r.Status.String()))
+		if r.Err != nil {
+			line += fmt.Sprintf(": %s", r.Err)
+		}
+		lines = append(lines, line+"\n")
+
+		var names []string
+		for name := range r.Outputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var s string
+			bs, err := json.Marshal(r.Outputs[name])
+			if err == nil {
+				s = string(bs)
+			} else {
+				s = fmt.Sprintf("<cannot unmarshal: %s>", err)
+			}
+			lines = append(lines, fmt.Sprintf("        - %s: %s\n", name, s))
+		}
+	}
+
+	_, err := fmt.Fprint(out, strings.Join(lines, ""))
+	return err
+}
@@ -2,15 +2,16 @@ package pretty
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 
 	"github.com/antha-lang/antha/ast"
 	"github.com/antha-lang/antha/execute"
 	"github.com/antha-lang/antha/target"
 	"github.com/antha-lang/antha/target/auto"
-	"golang.org/x/net/context"
 )
 
 func shouldWait(inst ast.Inst) bool {
@@ -27,34 +28,51 @@ func Run(out io.Writer, in io.Reader, a *auto.Auto, result *execute.Result) erro
 		return err
 	}
 
+	// Instructions on other devices run while waiting for an answer, so
+	// hold their events until the prompt is answered rather than printing
+	// them over it
+	var lock sync.Mutex
 	bin := bufio.NewReader(in)
-	ctx := context.Background()
-	for _, inst := range result.Insts {
-		if _, err := fmt.Fprintf(out, "    * %s", a.Pretty(inst)); err != nil {
-			return err
-		}
-
-		run := true
-		if shouldWait(inst) {
-			if _, err := fmt.Fprintf(out, " (Run? [yes,skip]) "); err != nil {
-				return err
-			} else if s, err := bin.ReadString('\n'); err != nil {
-				return err
-			} else {
-				run = strings.HasPrefix(strings.ToLower(s), "yes")
+	e := a.NewEngine(auto.EngineOpt{
+		Insts: result.Insts,
+		Sink: func(e auto.Event) {
+			lock.Lock()
+			defer lock.Unlock()
+			printEvent(out, a, e)
+		},
+		Confirm: func(ctx context.Context, inst ast.Inst) (bool, error) {
+			if !shouldWait(inst) {
+				return true, nil
 			}
-		}
-
-		if run {
-			if err := a.Execute(ctx, inst); err != nil {
-				fmt.Fprintf(out, " [FAIL]\n") // nolint
-				return err
+			lock.Lock()
+			defer lock.Unlock()
+			if _, err := fmt.Fprintf(out, "    * %s (Run? [yes,skip]) ", a.Pretty(inst)); err != nil {
+				return false, err
+			}
+			s, err := bin.ReadString('\n')
+			if err != nil {
+				return false, err
 			}
-		}
+			return strings.HasPrefix(strings.ToLower(s), "yes"), nil
+		},
+	})
+
+	return e.Run(context.Background())
+}
 
-		if _, err := fmt.Fprintf(out, " [OK]\n"); err != nil {
-			return err
-		}
+func printEvent(out io.Writer, a *auto.Auto, e auto.Event) {
+	var status string
+	switch e.Kind {
+	case auto.InstStarted:
+		status = "[START]"
+	case auto.InstFinished:
+		status = fmt.Sprintf("[OK] (%s)", e.Elapsed)
+	case auto.InstSkipped:
+		status = "[SKIP]"
+	case auto.InstFailed:
+		status = fmt.Sprintf("[FAIL] (%s): %s", e.Elapsed, e.Err)
+	case auto.InstMessage:
+		status = fmt.Sprintf("[%s] %s", e.Message.Code, e.Message.Data)
 	}
-	return nil
+	fmt.Fprintf(out, "    * %s %s\n", a.Pretty(e.Inst), status) // nolint
 }
@@ -32,9 +32,9 @@ func SaveFiles(out io.Writer, result *execute.Result) error {
 		Struct: s.saveFiles,
 	}
 
-	for _, output := range result.Workflow.Outputs {
+	for _, port := range result.Workflow.OutputPorts() {
 		// Just marshal for the side-effect
-		if _, err := m.Marshal(output); err != nil {
+		if _, err := m.Marshal(result.Workflow.Outputs[port]); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,114 @@
+package pretty
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/antha-lang/antha/target"
+	"github.com/antha-lang/antha/target/auto"
+)
+
+// CriticalPath prints the makespan and critical path of a schedule
+func CriticalPath(out io.Writer, a *auto.Auto, s *target.Schedule) error {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("== Estimated Makespan: %s\n", s.Makespan.Round(time.Second)))
+	lines = append(lines, "== Critical Path:\n")
+	for _, si := range s.CriticalPath {
+		if si.End == si.Start {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("    * %10s - %10s %s\n",
+			si.Start.Round(time.Second), si.End.Round(time.Second), a.Pretty(si.Inst)))
+	}
+
+	_, err := fmt.Fprint(out, strings.Join(lines, ""))
+	return err
+}
+
+const (
+	ganttLabelWidth = 200
+	ganttWidth      = 1000
+	ganttRowHeight  = 24
+	ganttTicks      = 10
+)
+
+// waitRow is the row of instructions that use no resource
+const waitRow = "waits"
+
+// GanttSVG writes a schedule as an SVG Gantt chart with a row per resource.
+// Instructions on the critical path are highlighted.
+func GanttSVG(out io.Writer, s *target.Schedule) error {
+	rows := s.Resources()
+	rowOf := make(map[string]int)
+	for i, r := range rows {
+		rowOf[r] = i
+	}
+	for _, si := range s.Insts {
+		if len(si.Resource) == 0 {
+			rowOf[""] = len(rows)
+			rows = append(rows, waitRow)
+			break
+		}
+	}
+
+	span := s.Makespan
+	if span <= 0 {
+		span = time.Second
+	}
+	x := func(d time.Duration) float64 {
+		return ganttLabelWidth + float64(d)/float64(span)*ganttWidth
+	}
+	height := (len(rows) + 1) * ganttRowHeight
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n",
+		ganttLabelWidth+ganttWidth+ganttLabelWidth/2, height)
+
+	for i, r := range rows {
+		y := i * ganttRowHeight
+		fmt.Fprintf(&b, `<text x="4" y="%d">%s</text>`+"\n", y+ganttRowHeight*2/3, html.EscapeString(r))
+		fmt.Fprintf(&b, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="#ddd"/>`+"\n", y+ganttRowHeight, ganttLabelWidth+ganttWidth, y+ganttRowHeight)
+	}
+
+	axis := len(rows) * ganttRowHeight
+	for i := 0; i <= ganttTicks; i++ {
+		d := span * time.Duration(i) / ganttTicks
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="0" x2="%.1f" y2="%d" stroke="#eee"/>`+"\n", x(d), x(d), axis)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n", x(d), axis+ganttRowHeight*2/3, d.Round(time.Second))
+	}
+
+	for _, si := range s.Insts {
+		color := "steelblue"
+		if si.Critical {
+			color = "firebrick"
+		}
+		w := x(si.End) - x(si.Start)
+		if w < 1 {
+			w = 1
+		}
+		title := fmt.Sprintf("[%s] %s: %s - %s", si.Kind, si.Label, si.Start.Round(time.Second), si.End.Round(time.Second))
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s"><title>%s</title></rect>`+"\n",
+			x(si.Start), rowOf[si.Resource]*ganttRowHeight+2, w, ganttRowHeight-4, color, html.EscapeString(title))
+	}
+
+	b.WriteString("</svg>\n")
+
+	_, err := out.Write(b.Bytes())
+	return err
+}
+
+// GanttHTML writes a schedule as an HTML page containing an SVG Gantt chart
+func GanttHTML(out io.Writer, s *target.Schedule) error {
+	if _, err := fmt.Fprintf(out, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>Schedule</title></head>\n<body>\n<h1>Estimated makespan: %s</h1>\n", s.Makespan.Round(time.Second)); err != nil {
+		return err
+	}
+	if err := GanttSVG(out, s); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(out, "</body>\n</html>\n")
+	return err
+}
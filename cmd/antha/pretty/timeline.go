@@ -35,7 +35,8 @@ func Timeline(out io.Writer, a *auto.Auto, result *execute.Result) error {
 
 	lines = append(lines, "== Workflow Outputs:\n")
 
-	for k, v := range result.Workflow.Outputs {
+	for _, k := range result.Workflow.OutputPorts() {
+		v := result.Workflow.Outputs[k]
 		var s string
 		bs, err := json.Marshal(v)
 		if err == nil {
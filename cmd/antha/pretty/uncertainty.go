@@ -0,0 +1,46 @@
+package pretty
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/antha-lang/antha/execute"
+	"github.com/antha-lang/antha/target"
+	"github.com/antha-lang/antha/target/auto"
+)
+
+// Uncertainty prints the expected range of the concentrations of the
+// outputs of mixes, marking those outside tolerance
+func Uncertainty(out io.Writer, a *auto.Auto, result *execute.Result) error {
+	var lines []string
+	for _, inst := range result.Insts {
+		mix, ok := inst.(*target.Mix)
+		if !ok || mix.Request == nil || len(mix.Request.OutputUncertainties) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("== Output Concentrations: %s\n", a.Pretty(mix)))
+		for _, ou := range mix.Request.OutputUncertainties {
+			plate := ou.PlateID
+			if p, ok := mix.Request.GetPlate(ou.PlateID); ok && len(p.PlateName) != 0 {
+				plate = p.PlateName
+			}
+			lines = append(lines, fmt.Sprintf("    * %s in %s@%s\n", ou.Name, ou.Well, plate))
+
+			exceeded := make(map[string]bool)
+			for _, c := range ou.Exceeded {
+				exceeded[c] = true
+			}
+			for _, r := range ou.Ranges {
+				var flag string
+				if exceeded[r.Component] {
+					flag = " [OUT OF TOLERANCE]"
+				}
+				lines = append(lines, fmt.Sprintf("        - %s: %s (%s to %s)%s\n", r.Component, r.Nominal, r.Min, r.Max, flag))
+			}
+		}
+	}
+
+	_, err := fmt.Fprint(out, strings.Join(lines, ""))
+	return err
+}
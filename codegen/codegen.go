@@ -201,6 +201,10 @@ func (a *ir) assignDevices(t *target.Target) error {
 		colors[n] = devices
 	}
 
+	if err := a.distributeMixes(colors); err != nil {
+		return err
+	}
+
 	var devices []ast.Device
 	d2c := make(map[ast.Device]int)
 	for _, ds := range colors {
@@ -235,6 +239,7 @@ func (a *ir) assignDevices(t *target.Target) error {
 	}
 
 	a.coalesceDevices(ret)
+	a.mergeLiquidHandlerRuns()
 
 	return nil
 }
@@ -343,6 +348,8 @@ func (a *ir) tryPlan(ctx context.Context) error {
 		a.output[d] = insts
 	}
 
+	a.addPlateMoves(runs)
+
 	return a.addImplicitInsts(runs)
 }
 
@@ -457,20 +464,27 @@ func Compile(ctx context.Context, t *target.Target, roots []ast.Node) ([]ast.Ins
 		return nil, fmt.Errorf("error generating instructions: %s", err)
 	}
 
-	// TODO: discard programs that create multiple setups until we get their
-	// semantics correct; also true of incubating components under multiple
-	// conditions
-	var setupMixes int
+	// TODO: discard programs that create multiple setups of the same device
+	// until we get their semantics correct; also true of incubating
+	// components under multiple conditions
+	setupMixes := make(map[ast.Device]int)
 	var setupIncubators int
 	for _, inst := range insts {
-		switch inst.(type) {
+		switch inst := inst.(type) {
 		case *target.SetupMixer:
-			setupMixes++
+			for _, mix := range inst.Mixes {
+				setupMixes[mix.Dev]++
+			}
 		case *target.SetupIncubator:
 			setupIncubators++
 		}
 	}
-	if setupMixes > 1 || setupIncubators > 1 {
+	for _, n := range setupMixes {
+		if n > 1 {
+			return nil, fmt.Errorf("multiple incubates or multiple mixes on the same device not supported")
+		}
+	}
+	if setupIncubators > 1 {
 		return nil, fmt.Errorf("multiple incubates or multiple mixes not supported")
 	}
 
@@ -102,3 +102,135 @@ func TestWellFormed(t *testing.T) {
 		t.Errorf("expected %d dependencies found %d", 1, n)
 	}
 }
+
+type liquidHandler struct {
+	name       string
+	plateTypes []string
+	channels   int
+}
+
+func (a *liquidHandler) String() string {
+	return a.name
+}
+
+func (a *liquidHandler) CanCompile(req ast.Request) bool {
+	can := ast.Request{}
+	can.Selector = append(can.Selector, target.DriverSelectorV1Mixer)
+	return can.Contains(req)
+}
+
+func (a *liquidHandler) CanMix(mix *wtype.LHInstruction) error {
+	if len(a.plateTypes) == 0 {
+		return nil
+	}
+	for _, t := range a.plateTypes {
+		if t == mix.Platetype {
+			return nil
+		}
+	}
+	return fmt.Errorf("plate type %q not supported", mix.Platetype)
+}
+
+func (a *liquidHandler) Channels() int {
+	return a.channels
+}
+
+func (a *liquidHandler) Compile(ctx context.Context, nodes []ast.Node) ([]ast.Inst, error) {
+	mix := &target.Mix{Dev: a}
+	for _, n := range nodes {
+		if c, ok := n.(*ast.Command); !ok {
+			return nil, fmt.Errorf("unexpected node %T", n)
+		} else if _, ok := c.Inst.(*wtype.LHInstruction); !ok {
+			return nil, fmt.Errorf("unexpected inst %T", c.Inst)
+		}
+	}
+	return []ast.Inst{mix}, nil
+}
+
+func TestDistributeMixes(t *testing.T) {
+	ctx := context.Background()
+
+	mix := func(plateID, plateType string, from ...ast.Node) *ast.Command {
+		out := wtype.NewLHComponent()
+		out.CName = plateID
+		inst := wtype.NewLHMixInstruction()
+		inst.PlateID = plateID
+		inst.Platetype = plateType
+		inst.Outputs = append(inst.Outputs, out)
+		for _, f := range from {
+			// Sample the output of another mix
+			in := wtype.NewLHComponent()
+			in.ParentID = f.(*ast.Command).Inst.(*wtype.LHInstruction).Outputs[0].ID
+			in.SetSample(true)
+			inst.Inputs = append(inst.Inputs, in)
+		}
+		if len(from) == 0 {
+			inst.Inputs = append(inst.Inputs, wtype.NewLHComponent(), wtype.NewLHComponent())
+		}
+
+		c := &ast.Command{
+			Requests: []ast.Request{
+				{
+					Selector: []ast.NameValue{
+						target.DriverSelectorV1Mixer,
+					},
+				},
+			},
+			Inst: inst,
+		}
+		for _, f := range from {
+			u := &ast.UseComp{}
+			u.From = append(u.From, f)
+			c.From = append(c.From, u)
+		}
+		return c
+	}
+
+	pcr1 := mix("pcr1", "pcrplate")
+	pcr2 := mix("pcr2", "pcrplate")
+	deep := mix("deep", "deepwell")
+	// Uses the outputs of pcr1 and deep so must wait for both
+	final := mix("deep", "deepwell", pcr1, deep)
+
+	a := &liquidHandler{name: "A", plateTypes: []string{"pcrplate"}, channels: 8}
+	b := &liquidHandler{name: "B", channels: 8}
+
+	machine := target.New()
+	machine.AddDevice(a)
+	machine.AddDevice(b)
+	machine.AddDevice(human.New(human.Opt{}))
+
+	insts, err := Compile(ctx, machine, []ast.Node{pcr2, final})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mixes := make(map[ast.Device]int)
+	var moves []*target.Manual
+	for _, inst := range insts {
+		switch inst := inst.(type) {
+		case *target.Mix:
+			mixes[inst.Dev]++
+		case *target.Manual:
+			if inst.Label == "move plates" {
+				moves = append(moves, inst)
+			}
+		}
+	}
+
+	if mixes[a] != 1 || mixes[b] != 1 {
+		t.Errorf("expecting one mix on each device but found %d on A and %d on B", mixes[a], mixes[b])
+	}
+	if len(moves) != 1 {
+		t.Fatalf("expecting one plate move but found %d", len(moves))
+	} else if e := "move plates containing pcr1 from A to B"; moves[0].Details != e {
+		t.Errorf("expecting %q but found %q", e, moves[0].Details)
+	}
+
+	// Only A can hold PCR plates and only B deep well plates
+	a.plateTypes = []string{"deepwell"}
+	b.plateTypes = []string{"deepwell"}
+	if _, err := Compile(ctx, machine, []ast.Node{mix("pcr3", "pcrplate")}); err == nil {
+		t.Errorf("expecting error compiling mix onto unsupported plate type")
+	}
+}
@@ -0,0 +1,351 @@
+package codegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/ast"
+	"github.com/antha-lang/antha/graph"
+	"github.com/antha-lang/antha/target"
+	"github.com/antha-lang/antha/target/human"
+)
+
+// mixOf returns the mix instruction of a command node if any
+func mixOf(n ast.Node) *wtype.LHInstruction {
+	c, ok := n.(*ast.Command)
+	if !ok {
+		return nil
+	}
+	m, _ := c.Inst.(*wtype.LHInstruction)
+	return m
+}
+
+// plateOf returns a key identifying the output plate requested by a mix or
+// the empty string if the mix does not request a particular plate
+func plateOf(m *wtype.LHInstruction) string {
+	switch {
+	case m.OutPlate != nil:
+		return "id:" + m.OutPlate.ID
+	case len(m.PlateID) != 0:
+		return "id:" + m.PlateID
+	case len(m.PlateName) != 0:
+		return "name:" + m.PlateName
+	}
+	return ""
+}
+
+// A mixGroup is a set of mixes that must run on the same liquid handler
+// because they share an output plate or mix in place
+type mixGroup struct {
+	Nodes []ast.Node
+	Cost  int // Number of transfers
+}
+
+// groupMixes partitions mix nodes into mixGroups ordered by the position of
+// their first node in nodes
+func groupMixes(nodes []ast.Node) []*mixGroup {
+	parent := make(map[ast.Node]ast.Node)
+	var find func(n ast.Node) ast.Node
+	find = func(n ast.Node) ast.Node {
+		if p := parent[n]; p != n {
+			parent[n] = find(p)
+		}
+		return parent[n]
+	}
+	union := func(a, b ast.Node) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	byPlate := make(map[string]ast.Node)
+	byOutput := make(map[string]ast.Node)
+	for _, n := range nodes {
+		parent[n] = n
+		m := mixOf(n)
+		if p := plateOf(m); len(p) != 0 {
+			if other, seen := byPlate[p]; seen {
+				union(other, n)
+			} else {
+				byPlate[p] = n
+			}
+		}
+		for _, out := range m.Outputs {
+			if out != nil {
+				byOutput[out.ID] = n
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		m := mixOf(n)
+		if !m.IsMixInPlace() || m.Inputs[0] == nil {
+			continue
+		}
+		if other, seen := byOutput[m.Inputs[0].ID]; seen {
+			union(other, n)
+		}
+	}
+
+	var groups []*mixGroup
+	byRoot := make(map[ast.Node]*mixGroup)
+	for _, n := range nodes {
+		r := find(n)
+		g := byRoot[r]
+		if g == nil {
+			g = &mixGroup{}
+			byRoot[r] = g
+			groups = append(groups, g)
+		}
+		g.Nodes = append(g.Nodes, n)
+		g.Cost += len(mixOf(n).Inputs)
+	}
+
+	return groups
+}
+
+// distributeMixes chooses a liquid handler for each mix when several could
+// compile it. Mixes that share an output plate or mix in place are kept
+// together. Each group goes to the compatible liquid handler with the least
+// load, measured in transfers per channel. The chosen device is moved to the
+// front of the colors of each mix.
+func (a *ir) distributeMixes(colors map[ast.Node][]ast.Device) error {
+	var nodes []ast.Node
+	for i, inum := 0, a.Commands.NumNodes(); i < inum; i++ {
+		n := a.Commands.Node(i).(ast.Node)
+		if mixOf(n) == nil {
+			continue
+		}
+		for _, d := range colors[n] {
+			if _, ok := d.(target.LiquidHandler); ok {
+				nodes = append(nodes, n)
+				break
+			}
+		}
+	}
+
+	load := make(map[target.LiquidHandler]int)
+	for _, g := range groupMixes(nodes) {
+		var candidates []target.LiquidHandler
+		var reasons []string
+		for _, d := range colors[g.Nodes[0]] {
+			lh, ok := d.(target.LiquidHandler)
+			if !ok {
+				continue
+			}
+			if err := canMixAll(lh, g.Nodes, colors); err != nil {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", lh, err))
+				continue
+			}
+			candidates = append(candidates, lh)
+		}
+
+		if len(candidates) == 0 {
+			return fmt.Errorf("no liquid handler can execute %s: %s", mixOf(g.Nodes[0]), strings.Join(reasons, "; "))
+		}
+
+		best := candidates[0]
+		score := func(lh target.LiquidHandler) float64 {
+			return float64(load[lh]+g.Cost) / float64(lh.Channels())
+		}
+		for _, lh := range candidates[1:] {
+			if score(lh) < score(best) {
+				best = lh
+			}
+		}
+		load[best] += g.Cost
+
+		for _, n := range g.Nodes {
+			ds := []ast.Device{best}
+			for _, d := range colors[n] {
+				if d != best {
+					ds = append(ds, d)
+				}
+			}
+			colors[n] = ds
+		}
+	}
+
+	// Like bundles that no device can compile, bundles whose children are
+	// now split between devices fall back to a human
+	for n, ds := range colors {
+		if _, ok := n.(*ast.Bundle); !ok || len(ds) == 0 {
+			continue
+		} else if _, ok := ds[0].(target.LiquidHandler); !ok {
+			continue
+		}
+		for i, inum := 0, a.Commands.NumOuts(n); i < inum; i++ {
+			kid := a.Commands.Out(n, i).(ast.Node)
+			if kds := colors[kid]; len(kds) != 0 && kds[0] != ds[0] {
+				colors[n] = []ast.Device{human.New(human.Opt{})}
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// canMixAll returns nil if lh can compile and execute every mix in nodes
+func canMixAll(lh target.LiquidHandler, nodes []ast.Node, colors map[ast.Node][]ast.Device) error {
+	for _, n := range nodes {
+		found := false
+		for _, d := range colors[n] {
+			found = found || d == lh
+		}
+		if !found {
+			return fmt.Errorf("cannot compile %s", mixOf(n))
+		}
+		if err := lh.CanMix(mixOf(n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addPlateMoves adds manual instructions to move plates between liquid
+// handlers when a mix uses the result of a mix on another device. The
+// instructions precede the device run of the consuming mix.
+func (a *ir) addPlateMoves(runs []*drun) {
+	type move struct {
+		From    ast.Device
+		Liquids []string
+	}
+
+	moves := make(map[*drun][]*move)
+	for i, inum := 0, a.Commands.NumNodes(); i < inum; i++ {
+		n := a.Commands.Node(i).(ast.Node)
+		if mixOf(n) == nil {
+			continue
+		}
+		run := a.assignment[n]
+		if _, ok := run.Device.(target.LiquidHandler); !ok {
+			continue
+		}
+
+		for j, jnum := 0, a.Commands.NumOuts(n); j < jnum; j++ {
+			kid := a.Commands.Out(n, j).(ast.Node)
+			km := mixOf(kid)
+			if km == nil {
+				continue
+			}
+			from := a.assignment[kid].Device
+			if _, ok := from.(target.LiquidHandler); !ok || from == run.Device {
+				continue
+			}
+
+			var mv *move
+			for _, m := range moves[run] {
+				if m.From == from {
+					mv = m
+				}
+			}
+			if mv == nil {
+				mv = &move{From: from}
+				moves[run] = append(moves[run], mv)
+			}
+			for _, out := range km.Outputs {
+				if out == nil {
+					continue
+				}
+				name := out.CName
+				if len(km.PlateName) != 0 {
+					name = fmt.Sprintf("%s (plate %s)", name, km.PlateName)
+				}
+				mv.Liquids = append(mv.Liquids, name)
+			}
+		}
+	}
+
+	for _, run := range runs {
+		mvs := moves[run]
+		if len(mvs) == 0 || len(a.output[run]) == 0 {
+			continue
+		}
+
+		var details []string
+		for _, mv := range mvs {
+			sort.Strings(mv.Liquids)
+			details = append(details, fmt.Sprintf("move plates containing %s from %s to %s",
+				strings.Join(uniqueStrings(mv.Liquids), ", "), mv.From, run.Device))
+		}
+
+		inst := &target.Manual{
+			Label:   "move plates",
+			Details: strings.Join(details, "\n"),
+		}
+		insts := a.output[run]
+		insts[0].AppendDependsOn(inst)
+		a.output[run] = append([]ast.Inst{inst}, insts...)
+	}
+}
+
+// uniqueStrings removes adjacent duplicates from a sorted slice
+func uniqueStrings(xs []string) []string {
+	var ret []string
+	for i, x := range xs {
+		if i == 0 || xs[i-1] != x {
+			ret = append(ret, x)
+		}
+	}
+	return ret
+}
+
+// mergeLiquidHandlerRuns merges runs of the same liquid handler whenever
+// that does not create a cyclic dependency between runs. Distributing mixes
+// between devices otherwise splits the mixes of one device into several runs
+// whenever they depend on mixes on another device.
+func (a *ir) mergeLiquidHandlerRuns() {
+	var runs []*drun
+	seen := make(map[*drun]bool)
+	for i, inum := 0, a.Commands.NumNodes(); i < inum; i++ {
+		r := a.assignment[a.Commands.Node(i).(ast.Node)]
+		if _, ok := r.Device.(target.LiquidHandler); !ok || seen[r] {
+			continue
+		}
+		seen[r] = true
+		runs = append(runs, r)
+	}
+
+	acyclic := func() bool {
+		_, err := graph.TopoSort(graph.TopoSortOpt{
+			Graph: graph.MakeQuotient(graph.MakeQuotientOpt{
+				Graph: a.Commands,
+				Colorer: func(n graph.Node) interface{} {
+					return a.assignment[n.(ast.Node)]
+				},
+			}),
+		})
+		return err == nil
+	}
+
+	merged := make(map[*drun]bool)
+	for i, ri := range runs {
+		if merged[ri] {
+			continue
+		}
+		for _, rj := range runs[i+1:] {
+			if merged[rj] || rj.Device != ri.Device {
+				continue
+			}
+
+			var moved []ast.Node
+			for n, r := range a.assignment {
+				if r == rj {
+					a.assignment[n] = ri
+					moved = append(moved, n)
+				}
+			}
+			if acyclic() {
+				merged[rj] = true
+				continue
+			}
+			for _, n := range moved {
+				a.assignment[n] = rj
+			}
+		}
+	}
+}
@@ -31,9 +31,10 @@ type ParamDesc struct {
 
 // Description is a description of a component.
 type Description struct {
-	Desc   string
-	Path   string
-	Params []ParamDesc
+	Desc         string
+	Path         string
+	Params       []ParamDesc
+	SourceSha256 []byte // Hash of the source of the component
 }
 
 // Component is an antha component / element.
@@ -112,7 +113,20 @@ func (a *Component) params() ([]typeDesc, error) {
 	if err != nil {
 		return nil, err
 	}
-	return append(inTypes, outTypes...), nil
+
+	// The analysis stage of a component also takes its outputs as inputs
+	seen := make(map[string]reflect.Type)
+	for _, v := range inTypes {
+		seen[v.Name] = v.Type
+	}
+	params := inTypes
+	for _, v := range outTypes {
+		if t, ok := seen[v.Name]; ok && t == v.Type {
+			continue
+		}
+		params = append(params, v)
+	}
+	return params, nil
 }
 
 // UpdateParamTypes updates types in description of a component based return
@@ -10,6 +10,7 @@ It is generated from these files:
 It has these top-level messages:
 	ProtocolRunRequest
 	BoolReply
+	ProtocolResultReply
 */
 package antha_platereader_v1
 
@@ -89,9 +90,51 @@ func (m *BoolReply) GetResult() bool {
 	return false
 }
 
+type ProtocolResultReply struct {
+	Result bool `protobuf:"varint,1,opt,name=result" json:"result,omitempty"`
+	// Format of Data, e.g., "mars" or "spectramax"
+	Format   string `protobuf:"bytes,2,opt,name=Format" json:"Format,omitempty"`
+	Filename string `protobuf:"bytes,3,opt,name=Filename" json:"Filename,omitempty"`
+	Data     []byte `protobuf:"bytes,4,opt,name=Data,proto3" json:"Data,omitempty"`
+}
+
+func (m *ProtocolResultReply) Reset()                    { *m = ProtocolResultReply{} }
+func (m *ProtocolResultReply) String() string            { return proto.CompactTextString(m) }
+func (*ProtocolResultReply) ProtoMessage()               {}
+func (*ProtocolResultReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *ProtocolResultReply) GetResult() bool {
+	if m != nil {
+		return m.Result
+	}
+	return false
+}
+
+func (m *ProtocolResultReply) GetFormat() string {
+	if m != nil {
+		return m.Format
+	}
+	return ""
+}
+
+func (m *ProtocolResultReply) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *ProtocolResultReply) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*ProtocolRunRequest)(nil), "antha.platereader.v1.ProtocolRunRequest")
 	proto.RegisterType((*BoolReply)(nil), "antha.platereader.v1.BoolReply")
+	proto.RegisterType((*ProtocolResultReply)(nil), "antha.platereader.v1.ProtocolResultReply")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -106,6 +149,7 @@ const _ = grpc.SupportPackageIsVersion4
 
 type PlateReaderClient interface {
 	PRRunProtocolByName(ctx context.Context, in *ProtocolRunRequest, opts ...grpc.CallOption) (*BoolReply, error)
+	PRRunProtocolByNameWithResult(ctx context.Context, in *ProtocolRunRequest, opts ...grpc.CallOption) (*ProtocolResultReply, error)
 }
 
 type plateReaderClient struct {
@@ -125,10 +169,20 @@ func (c *plateReaderClient) PRRunProtocolByName(ctx context.Context, in *Protoco
 	return out, nil
 }
 
+func (c *plateReaderClient) PRRunProtocolByNameWithResult(ctx context.Context, in *ProtocolRunRequest, opts ...grpc.CallOption) (*ProtocolResultReply, error) {
+	out := new(ProtocolResultReply)
+	err := grpc.Invoke(ctx, "/antha.platereader.v1.PlateReader/PRRunProtocolByNameWithResult", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for PlateReader service
 
 type PlateReaderServer interface {
 	PRRunProtocolByName(context.Context, *ProtocolRunRequest) (*BoolReply, error)
+	PRRunProtocolByNameWithResult(context.Context, *ProtocolRunRequest) (*ProtocolResultReply, error)
 }
 
 func RegisterPlateReaderServer(s *grpc.Server, srv PlateReaderServer) {
@@ -153,6 +207,24 @@ func _PlateReader_PRRunProtocolByName_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PlateReader_PRRunProtocolByNameWithResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProtocolRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlateReaderServer).PRRunProtocolByNameWithResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/antha.platereader.v1.PlateReader/PRRunProtocolByNameWithResult",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlateReaderServer).PRRunProtocolByNameWithResult(ctx, req.(*ProtocolRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _PlateReader_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "antha.platereader.v1.PlateReader",
 	HandlerType: (*PlateReaderServer)(nil),
@@ -161,6 +233,10 @@ var _PlateReader_serviceDesc = grpc.ServiceDesc{
 			MethodName: "PRRunProtocolByName",
 			Handler:    _PlateReader_PRRunProtocolByName_Handler,
 		},
+		{
+			MethodName: "PRRunProtocolByNameWithResult",
+			Handler:    _PlateReader_PRRunProtocolByNameWithResult_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "github.com/antha-lang/antha/driver/antha_platereader_v1/platereader.proto",
@@ -171,21 +247,25 @@ func init() {
 }
 
 var fileDescriptor0 = []byte{
-	// 251 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x50, 0xdd, 0x4a, 0xc3, 0x30,
-	0x14, 0xb6, 0x2a, 0xd3, 0x1d, 0x05, 0x21, 0x8a, 0x04, 0x6f, 0x1c, 0xf5, 0xa6, 0x37, 0xa6, 0x4c,
-	0xdf, 0x60, 0x78, 0x33, 0x10, 0x2d, 0x79, 0x81, 0x91, 0x6e, 0x87, 0xad, 0x90, 0x25, 0x5d, 0x9a,
-	0x14, 0xfa, 0x32, 0x3e, 0xab, 0xec, 0x6c, 0x91, 0xaa, 0xbd, 0x3b, 0xdf, 0x0f, 0xdf, 0xf9, 0xce,
-	0x81, 0xf9, 0xba, 0xf2, 0x9b, 0x50, 0x8a, 0xa5, 0xdd, 0xe6, 0xca, 0xf8, 0x8d, 0x7a, 0xd6, 0xca,
-	0xac, 0x0f, 0x63, 0xbe, 0x72, 0x55, 0x8b, 0xee, 0x00, 0x16, 0xb5, 0x56, 0x1e, 0x1d, 0xaa, 0x15,
-	0xba, 0x45, 0x3b, 0xcd, 0x7b, 0x50, 0xd4, 0xce, 0x7a, 0xcb, 0xee, 0xc8, 0x27, 0xfa, 0x42, 0x3b,
-	0x4d, 0xbf, 0x12, 0x60, 0xc5, 0x5e, 0x5f, 0x5a, 0x2d, 0x83, 0x91, 0xb8, 0x0b, 0xd8, 0x78, 0x96,
-	0xc2, 0x75, 0x64, 0x3f, 0xd4, 0x16, 0x79, 0x32, 0x49, 0xb2, 0xb1, 0xfc, 0xc5, 0x31, 0x0e, 0x17,
-	0xc5, 0x3e, 0x6c, 0xfe, 0xc6, 0x4f, 0x49, 0x8e, 0x90, 0x4d, 0xe0, 0x8a, 0xc6, 0x77, 0xd5, 0xd9,
-	0xe0, 0xf9, 0x19, 0xa9, 0x7d, 0x8a, 0x65, 0x70, 0x13, 0xb3, 0x3e, 0x6b, 0x5f, 0x59, 0xd3, 0xf0,
-	0x73, 0x72, 0xfd, 0xa5, 0xd3, 0x27, 0x18, 0xcf, 0xac, 0xd5, 0x12, 0x6b, 0xdd, 0xb1, 0x7b, 0x18,
-	0x39, 0x6c, 0x82, 0xf6, 0x54, 0xe8, 0x52, 0x1e, 0xd1, 0xcb, 0xee, 0xb8, 0x50, 0xd2, 0x5d, 0xac,
-	0x84, 0xdb, 0x42, 0xca, 0x60, 0x62, 0xd6, 0xac, 0xa3, 0xc2, 0x99, 0x18, 0x7a, 0x81, 0xf8, 0x7f,
-	0xfe, 0xc3, 0xe3, 0xb0, 0xf3, 0xa7, 0x48, 0x7a, 0x52, 0x8e, 0xe8, 0xab, 0xaf, 0xdf, 0x01, 0x00,
-	0x00, 0xff, 0xff, 0x65, 0x42, 0x37, 0x33, 0xa2, 0x01, 0x00, 0x00,
+	// 317 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x52, 0x51, 0x4b, 0x32, 0x41,
+	0x14, 0x75, 0xbf, 0x4f, 0x4c, 0x6f, 0x42, 0x70, 0x8d, 0x58, 0x84, 0x48, 0xa6, 0x17, 0x7b, 0x68,
+	0xc5, 0xfa, 0x07, 0x22, 0x82, 0x10, 0x25, 0xf3, 0xd2, 0xa3, 0x8c, 0x3a, 0xe8, 0xc2, 0x38, 0xb3,
+	0x8d, 0x33, 0x0b, 0xfe, 0x99, 0xfe, 0x5f, 0xff, 0x22, 0xf6, 0xee, 0xae, 0x59, 0x2d, 0x45, 0x6f,
+	0xf7, 0x9c, 0x7b, 0x98, 0x7b, 0xce, 0x61, 0x60, 0xba, 0x8e, 0xdd, 0xc6, 0x2f, 0xa2, 0xa5, 0xd9,
+	0x0e, 0x84, 0x76, 0x1b, 0x71, 0xab, 0x84, 0x5e, 0xe7, 0xe3, 0x60, 0x65, 0xe3, 0x54, 0xda, 0x1c,
+	0xcc, 0x13, 0x25, 0x9c, 0xb4, 0x52, 0xac, 0xa4, 0x9d, 0xa7, 0xc3, 0xc1, 0x11, 0x8c, 0x12, 0x6b,
+	0x9c, 0xc1, 0x73, 0xd2, 0x45, 0xc7, 0x8b, 0x74, 0xc8, 0x5e, 0x03, 0xc0, 0x59, 0xb6, 0x5f, 0x1a,
+	0xc5, 0xbd, 0xe6, 0xf2, 0xc5, 0xcb, 0x9d, 0x43, 0x06, 0xed, 0x92, 0x7d, 0x14, 0x5b, 0x19, 0x06,
+	0xbd, 0xa0, 0xdf, 0xe2, 0x9f, 0x38, 0x0c, 0xe1, 0x64, 0x96, 0x3d, 0x36, 0x1d, 0x87, 0xff, 0x68,
+	0x5d, 0x42, 0xec, 0xc1, 0x29, 0x8d, 0x0f, 0x62, 0x6f, 0xbc, 0x0b, 0xff, 0xd3, 0xf6, 0x98, 0xc2,
+	0x3e, 0x9c, 0x95, 0x6f, 0x3d, 0x25, 0x2e, 0x36, 0x7a, 0x17, 0xd6, 0x49, 0xf5, 0x95, 0x66, 0xd7,
+	0xd0, 0x1a, 0x19, 0xa3, 0xb8, 0x4c, 0xd4, 0x1e, 0x2f, 0xa0, 0x61, 0xe5, 0xce, 0x2b, 0x47, 0x86,
+	0x9a, 0xbc, 0x40, 0xcc, 0x43, 0xe7, 0x10, 0x82, 0x98, 0x1f, 0xe5, 0x19, 0x3f, 0x31, 0x76, 0x2b,
+	0x5c, 0x61, 0xbc, 0x40, 0xd8, 0x85, 0xe6, 0x24, 0x56, 0x52, 0x67, 0x89, 0x73, 0xd3, 0x07, 0x8c,
+	0x08, 0xf5, 0xb1, 0x70, 0x82, 0x6c, 0xb6, 0x39, 0xcd, 0x77, 0x6f, 0x41, 0x11, 0x94, 0x53, 0x9f,
+	0xb8, 0x80, 0xce, 0x8c, 0x73, 0xaf, 0x4b, 0x2f, 0xa3, 0x3d, 0x15, 0xd5, 0x8f, 0xaa, 0xaa, 0x8f,
+	0xbe, 0xd7, 0xde, 0xbd, 0xaa, 0x56, 0x1e, 0x0a, 0x60, 0x35, 0x4c, 0xe1, 0xb2, 0xe2, 0xc6, 0x73,
+	0xec, 0x36, 0x79, 0xf2, 0x3f, 0x5c, 0xbb, 0xf9, 0x45, 0xf9, 0xd1, 0x24, 0xab, 0x2d, 0x1a, 0xf4,
+	0x8b, 0xee, 0xdf, 0x07, 0x00, 0xcd, 0xb8, 0xc1, 0x91, 0x92, 0x02, 0x00, 0x00,
 }
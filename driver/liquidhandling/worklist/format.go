@@ -0,0 +1,108 @@
+package worklist
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+func formatVolume(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// writeGWL writes transfers as Tecan GWL records. The channels of each
+// transfer aspirate together, then dispense together, then are washed or
+// their tips replaced.
+func (a *Driver) writeGWL(w io.Writer) error {
+	record := func(kind string, t transfer, position, well string) error {
+		l := a.labware(position)
+		idx, err := a.wellIndex(position, well)
+		if err != nil {
+			return err
+		}
+		// Kind;RackLabel;RackID;RackType;Position;TubeID;Volume;LiquidClass;TubeType;TipMask;ForcedRackType
+		_, err = fmt.Fprintf(w, "%s;%s;;%s;%d;;%s;%s;;%d;\r\n", kind, l.Label, l.Type, idx, formatVolume(t.Volume), a.liquidClass(t.What), 1<<uint(t.Channel))
+		return err
+	}
+
+	for _, group := range a.transfers {
+		for _, t := range group {
+			if err := record("A", t, t.PlateFrom, t.WellFrom); err != nil {
+				return err
+			}
+		}
+		for _, t := range group {
+			if err := record("D", t, t.PlateTo, t.WellTo); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "W;\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHamilton writes transfers as a Hamilton-style CSV with one row per
+// channel of each transfer
+func (a *Driver) writeHamilton(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"Source Labware", "Source Type", "Source Position",
+		"Destination Labware", "Destination Type", "Destination Position",
+		"Volume", "Liquid Class", "Channel",
+	}); err != nil {
+		return err
+	}
+
+	for _, group := range a.transfers {
+		for _, t := range group {
+			from, to := a.labware(t.PlateFrom), a.labware(t.PlateTo)
+			fromIdx, err := a.wellIndex(t.PlateFrom, t.WellFrom)
+			if err != nil {
+				return err
+			}
+			toIdx, err := a.wellIndex(t.PlateTo, t.WellTo)
+			if err != nil {
+				return err
+			}
+			if err := cw.Write([]string{
+				from.Label, from.Type, strconv.Itoa(fromIdx),
+				to.Label, to.Type, strconv.Itoa(toIdx),
+				formatVolume(t.Volume), a.liquidClass(t.What), strconv.Itoa(t.Channel + 1),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeGeneric writes transfers as a CSV of plates, wells in A1 format and
+// volumes in ul
+func (a *Driver) writeGeneric(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"Source Plate", "Source Well", "Destination Plate", "Destination Well", "Volume (ul)", "Liquid Type",
+	}); err != nil {
+		return err
+	}
+
+	for _, group := range a.transfers {
+		for _, t := range group {
+			if err := cw.Write([]string{
+				a.labware(t.PlateFrom).Label, t.WellFrom,
+				a.labware(t.PlateTo).Label, t.WellTo,
+				formatVolume(t.Volume), t.What,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
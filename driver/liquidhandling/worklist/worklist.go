@@ -0,0 +1,264 @@
+// Package worklist provides an in-process high-level liquid handling driver
+// that writes the transfers it is given as a worklist file for robots that
+// are programmed by worklists rather than by remote drivers.
+package worklist
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/microArch/driver"
+	"github.com/antha-lang/antha/microArch/driver/liquidhandling"
+)
+
+// A Format is a worklist file format
+type Format string
+
+// Supported worklist formats
+const (
+	// Tecan Gemini/EVOware worklist of aspirate (A), dispense (D) and wash
+	// (W) records
+	TecanGWL Format = "gwl"
+	// Hamilton-style CSV of labware ids and well positions
+	HamiltonCSV Format = "hamilton"
+	// CSV of source and destination plates, wells and volumes
+	GenericCSV Format = "csv"
+)
+
+// Extension returns the file extension of worklists in this format
+func (a Format) Extension() string {
+	switch a {
+	case TecanGWL:
+		return ".gwl"
+	default:
+		return ".csv"
+	}
+}
+
+// Labware is the vendor description of a plate
+type Labware struct {
+	Label string // Label or id of labware, e.g., "Src1"
+	Type  string // Vendor labware type, e.g., "96 Well Microplate"
+}
+
+// Opt are options for a Driver
+type Opt struct {
+	Format Format
+	// Properties of the liquid handler. LHType should be
+	// liquidhandling.HLLiquidHandler.
+	Properties *liquidhandling.LHProperties
+	// Labware by deck position, e.g., position_1. Plates at positions not
+	// in Positions or Plates keep their Antha name and type.
+	Positions map[string]Labware
+	// Labware by Antha plate name. Overrides Positions.
+	Plates map[string]Labware
+	// Vendor liquid classes by Antha liquid type. Liquid types not in
+	// LiquidClasses are written as they are.
+	LiquidClasses map[string]string
+}
+
+// A placement is a plate added to the deck
+type placement struct {
+	Name  string
+	Plate *wtype.Plate
+}
+
+// A transfer is a single-channel movement of liquid
+type transfer struct {
+	Channel            int // Zero-based channel index
+	What               string
+	PlateFrom, PlateTo string // Deck positions
+	WellFrom, WellTo   string // A1 format
+	Volume             float64
+}
+
+// A Driver is a HighLevelLiquidhandlingDriver that records transfers as a
+// worklist. Worklists are returned by GetOutputFile and restart when the
+// driver is initialized.
+type Driver struct {
+	opt Opt
+
+	lock      sync.Mutex
+	plates    map[string]placement // by deck position
+	transfers [][]transfer         // grouped by call to Transfer
+}
+
+var _ liquidhandling.HighLevelLiquidhandlingDriver = (*Driver)(nil)
+
+// New returns a new worklist driver
+func New(opt Opt) (*Driver, error) {
+	switch opt.Format {
+	case TecanGWL, HamiltonCSV, GenericCSV:
+	default:
+		return nil, fmt.Errorf("unknown worklist format %q", opt.Format)
+	}
+	if opt.Properties == nil {
+		return nil, fmt.Errorf("no liquid handler properties")
+	}
+	return &Driver{
+		opt:    opt,
+		plates: make(map[string]placement),
+	}, nil
+}
+
+// OutputFileName returns the name of worklist files
+func (a *Driver) OutputFileName() string {
+	return "worklist" + a.opt.Format.Extension()
+}
+
+// AddPlateTo implements a LiquidhandlingDriver
+func (a *Driver) AddPlateTo(position string, plate interface{}, name string) driver.CommandStatus {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	// Tipboxes, tipwastes and the like do not appear in worklists
+	if p, ok := plate.(*wtype.Plate); ok {
+		a.plates[position] = placement{Name: name, Plate: p}
+	}
+	return driver.CommandOk()
+}
+
+// RemoveAllPlates implements a LiquidhandlingDriver
+func (a *Driver) RemoveAllPlates() driver.CommandStatus {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.plates = make(map[string]placement)
+	return driver.CommandOk()
+}
+
+// RemovePlateAt implements a LiquidhandlingDriver
+func (a *Driver) RemovePlateAt(position string) driver.CommandStatus {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	delete(a.plates, position)
+	return driver.CommandOk()
+}
+
+// Initialize implements a LiquidhandlingDriver. It starts a new worklist.
+func (a *Driver) Initialize() driver.CommandStatus {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.transfers = nil
+	return driver.CommandOk()
+}
+
+// Finalize implements a LiquidhandlingDriver
+func (a *Driver) Finalize() driver.CommandStatus {
+	return driver.CommandOk()
+}
+
+// Message implements a LiquidhandlingDriver. Worklists cannot show
+// messages so they are ignored.
+func (a *Driver) Message(level int, title, text string, showcancel bool) driver.CommandStatus {
+	return driver.CommandOk()
+}
+
+// GetCapabilities implements a LiquidhandlingDriver
+func (a *Driver) GetCapabilities() (liquidhandling.LHProperties, driver.CommandStatus) {
+	return *a.opt.Properties.Dup(), driver.CommandOk()
+}
+
+// Transfer implements a HighLevelLiquidhandlingDriver. Each argument has an
+// entry for each channel; channels without a source are unused.
+func (a *Driver) Transfer(what, platefrom, wellfrom, plateto, wellto []string, volume []float64) driver.CommandStatus {
+	n := len(what)
+	for _, l := range []int{len(platefrom), len(wellfrom), len(plateto), len(wellto), len(volume)} {
+		if l != n {
+			return driver.CommandError(fmt.Sprintf("transfer arguments have different lengths: %d and %d", n, l))
+		}
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var group []transfer
+	for i := 0; i < n; i++ {
+		if len(platefrom[i]) == 0 || volume[i] <= 0 {
+			continue
+		}
+		for _, pos := range []string{platefrom[i], plateto[i]} {
+			if _, seen := a.plates[pos]; !seen {
+				return driver.CommandError(fmt.Sprintf("no plate at position %s", pos))
+			}
+		}
+		group = append(group, transfer{
+			Channel:   i,
+			What:      what[i],
+			PlateFrom: platefrom[i],
+			PlateTo:   plateto[i],
+			WellFrom:  wellfrom[i],
+			WellTo:    wellto[i],
+			Volume:    volume[i],
+		})
+	}
+	if len(group) != 0 {
+		a.transfers = append(a.transfers, group)
+	}
+	return driver.CommandOk()
+}
+
+// GetOutputFile implements a LiquidhandlingDriver. It returns the worklist
+// of transfers since the driver was last initialized.
+func (a *Driver) GetOutputFile() ([]byte, driver.CommandStatus) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if len(a.transfers) == 0 {
+		return nil, driver.CommandOk()
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch a.opt.Format {
+	case TecanGWL:
+		err = a.writeGWL(&buf)
+	case HamiltonCSV:
+		err = a.writeHamilton(&buf)
+	case GenericCSV:
+		err = a.writeGeneric(&buf)
+	}
+	if err != nil {
+		return nil, driver.CommandError(err.Error())
+	}
+	return buf.Bytes(), driver.CommandOk()
+}
+
+// labware returns the vendor labware at a deck position
+func (a *Driver) labware(position string) Labware {
+	p := a.plates[position]
+	l := a.opt.Positions[position]
+	if pl, ok := a.opt.Plates[p.Name]; ok {
+		l = pl
+	}
+	if len(l.Label) == 0 {
+		l.Label = p.Name
+	}
+	if len(l.Type) == 0 {
+		l.Type = p.Plate.Type
+	}
+	return l
+}
+
+// liquidClass returns the vendor liquid class of an Antha liquid type
+func (a *Driver) liquidClass(what string) string {
+	if c, ok := a.opt.LiquidClasses[what]; ok {
+		return c
+	}
+	return what
+}
+
+// wellIndex returns the one-based index of a well counting down columns,
+// as used by Tecan and Hamilton software
+func (a *Driver) wellIndex(position, well string) (int, error) {
+	p := a.plates[position].Plate
+	wc := wtype.MakeWellCoordsA1(well)
+	if wc.IsZero() || wc.X >= p.WellsX() || wc.Y >= p.WellsY() {
+		return 0, fmt.Errorf("invalid well %q of plate at position %s", well, position)
+	}
+	return wc.X*p.WellsY() + wc.Y + 1, nil
+}
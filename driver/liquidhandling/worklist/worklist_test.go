@@ -0,0 +1,143 @@
+package worklist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/microArch/driver/liquidhandling"
+)
+
+func makePlate(name string) *wtype.Plate {
+	shp := wtype.NewShape("box", "mm", 8.2, 8.2, 41.3)
+	welltype := wtype.NewLHWell("ul", 200, 10, shp, wtype.VWellBottom, 8.2, 8.2, 41.3, 4.7, "mm")
+	p := wtype.NewLHPlate("DSW96", "none", 8, 12, wtype.Coordinates{X: 127.76, Y: 85.48, Z: 43.1}, welltype, 9.0, 9.0, 0.5, 0.5, 0.5)
+	p.PlateName = name
+	return p
+}
+
+func makeDriver(t *testing.T, opt Opt) *Driver {
+	opt.Properties = liquidhandling.NewLHProperties("Evo", "Tecan", liquidhandling.HLLiquidHandler, liquidhandling.DisposableTips, nil)
+	d, err := New(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []struct{ Position, Name string }{
+		{"position_1", "input"},
+		{"position_2", "output"},
+	} {
+		if err := d.AddPlateTo(s.Position, makePlate(s.Name), s.Name).GetError(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.Initialize().GetError(); err != nil {
+		t.Fatal(err)
+	}
+	// Two channels then only the second channel
+	if err := d.Transfer(
+		[]string{"water", "water"},
+		[]string{"position_1", "position_1"},
+		[]string{"A1", "B1"},
+		[]string{"position_2", "position_2"},
+		[]string{"A2", "B2"},
+		[]float64{10, 10},
+	).GetError(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Transfer(
+		[]string{"", "glycerol"},
+		[]string{"", "position_1"},
+		[]string{"", "H12"},
+		[]string{"", "position_2"},
+		[]string{"", "C1"},
+		[]float64{0, 2.5},
+	).GetError(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func assertOutput(t *testing.T, d *Driver, expected []string) {
+	bs, status := d.GetOutputFile()
+	if err := status.GetError(); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSpace(strings.Replace(string(bs), "\r\n", "\n", -1)), "\n")
+	if len(got) != len(expected) {
+		t.Fatalf("expecting %d lines but got %d:\n%s", len(expected), len(got), bs)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("line %d: expecting %q but got %q", i+1, expected[i], got[i])
+		}
+	}
+}
+
+func TestTecanGWL(t *testing.T) {
+	d := makeDriver(t, Opt{
+		Format: TecanGWL,
+		Positions: map[string]Labware{
+			"position_1": {Label: "Src1", Type: "96 Well Microplate"},
+		},
+		Plates: map[string]Labware{
+			"output": {Label: "Dest1"},
+		},
+		LiquidClasses: map[string]string{"water": "Water Free Single"},
+	})
+	assertOutput(t, d, []string{
+		"A;Src1;;96 Well Microplate;1;;10;Water Free Single;;1;",
+		"A;Src1;;96 Well Microplate;2;;10;Water Free Single;;2;",
+		"D;Dest1;;DSW96;9;;10;Water Free Single;;1;",
+		"D;Dest1;;DSW96;10;;10;Water Free Single;;2;",
+		"W;",
+		"A;Src1;;96 Well Microplate;96;;2.5;glycerol;;2;",
+		"D;Dest1;;DSW96;3;;2.5;glycerol;;2;",
+		"W;",
+	})
+
+	if name := d.OutputFileName(); name != "worklist.gwl" {
+		t.Errorf("unexpected file name %q", name)
+	}
+
+	// Initializing starts a new worklist
+	d.Initialize()
+	if bs, _ := d.GetOutputFile(); len(bs) != 0 {
+		t.Errorf("expecting empty worklist but got %q", bs)
+	}
+}
+
+func TestHamiltonCSV(t *testing.T) {
+	d := makeDriver(t, Opt{Format: HamiltonCSV})
+	assertOutput(t, d, []string{
+		"Source Labware,Source Type,Source Position,Destination Labware,Destination Type,Destination Position,Volume,Liquid Class,Channel",
+		"input,DSW96,1,output,DSW96,9,10,water,1",
+		"input,DSW96,2,output,DSW96,10,10,water,2",
+		"input,DSW96,96,output,DSW96,3,2.5,glycerol,2",
+	})
+}
+
+func TestGenericCSV(t *testing.T) {
+	d := makeDriver(t, Opt{Format: GenericCSV})
+	assertOutput(t, d, []string{
+		"Source Plate,Source Well,Destination Plate,Destination Well,Volume (ul),Liquid Type",
+		"input,A1,output,A2,10,water",
+		"input,B1,output,B2,10,water",
+		"input,H12,output,C1,2.5,glycerol",
+	})
+}
+
+func TestTransferErrors(t *testing.T) {
+	d := makeDriver(t, Opt{Format: GenericCSV})
+	if err := d.Transfer([]string{"water"}, []string{"position_3"}, []string{"A1"}, []string{"position_2"}, []string{"A1"}, []float64{1}).GetError(); err == nil {
+		t.Errorf("expecting error transferring from empty position")
+	}
+	if err := d.Transfer([]string{"water"}, []string{"position_1"}, []string{"A1"}, []string{"position_2"}, nil, []float64{1}).GetError(); err == nil {
+		t.Errorf("expecting error for missing destination wells")
+	}
+
+	if _, err := New(Opt{Format: "xls"}); err == nil {
+		t.Errorf("expecting error for unknown format")
+	}
+}
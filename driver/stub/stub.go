@@ -0,0 +1,202 @@
+// Package stub provides drivers that accept requests without controlling
+// any hardware. They allow instructions to be executed end-to-end in tests.
+package stub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	driver "github.com/antha-lang/antha/driver/antha_driver_v1"
+	platereader "github.com/antha-lang/antha/driver/antha_platereader_v1"
+	runner "github.com/antha-lang/antha/driver/antha_runner_v1"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+)
+
+var errNotImplemented = errors.New("not implemented")
+
+// A Driver is a device driver that accepts every call and replies with an
+// empty message
+type Driver struct {
+	Type string // Type of driver reported to clients
+
+	lock  sync.Mutex
+	calls []string
+}
+
+// DriverType implements a DriverServer
+func (a *Driver) DriverType(context.Context, *driver.TypeRequest) (*driver.TypeReply, error) {
+	return &driver.TypeReply{Type: a.Type}, nil
+}
+
+// Calls returns the methods called on the driver in order
+func (a *Driver) Calls() []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]string(nil), a.calls...)
+}
+
+// handle accepts calls to methods that the driver does not implement
+func (a *Driver) handle(srv interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	if err := stream.RecvMsg(&empty.Empty{}); err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	a.calls = append(a.calls, method)
+	a.lock.Unlock()
+
+	return stream.SendMsg(&empty.Empty{})
+}
+
+// A Runner is a runner that accepts every run of its supported types. Each
+// run reports Replies followed by a stop message.
+type Runner struct {
+	Types   []string
+	Replies []*runner.MessagesReply_Message
+
+	lock sync.Mutex
+	runs []*runner.RunRequest
+	sent map[string]bool
+}
+
+// DriverType implements a DriverServer
+func (a *Runner) DriverType(context.Context, *driver.TypeRequest) (*driver.TypeReply, error) {
+	return &driver.TypeReply{Type: "antha.runner.v1.Runner"}, nil
+}
+
+// Run implements a RunnerServer
+func (a *Runner) Run(ctx context.Context, req *runner.RunRequest) (*runner.RunReply, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.runs = append(a.runs, req)
+	return &runner.RunReply{Id: fmt.Sprint(len(a.runs))}, nil
+}
+
+// RunRef implements a RunnerServer
+func (a *Runner) RunRef(context.Context, *runner.RunRefRequest) (*runner.RunReply, error) {
+	return nil, errNotImplemented
+}
+
+// Messages implements a RunnerServer
+func (a *Runner) Messages(ctx context.Context, req *runner.MessagesRequest) (*runner.MessagesReply, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.sent == nil {
+		a.sent = make(map[string]bool)
+	}
+	if a.sent[req.Id] {
+		return &runner.MessagesReply{}, nil
+	}
+	a.sent[req.Id] = true
+
+	values := append([]*runner.MessagesReply_Message(nil), a.Replies...)
+	values = append(values, &runner.MessagesReply_Message{
+		Code: "stop",
+		Seq:  int32(len(values)),
+	})
+	return &runner.MessagesReply{Values: values}, nil
+}
+
+// SupportedRunTypes implements a RunnerServer
+func (a *Runner) SupportedRunTypes(context.Context, *runner.SupportedRunTypesRequest) (*runner.SupportedRunTypesReply, error) {
+	return &runner.SupportedRunTypesReply{Types: a.Types}, nil
+}
+
+// Runs returns the runs requested so far
+func (a *Runner) Runs() []*runner.RunRequest {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]*runner.RunRequest(nil), a.runs...)
+}
+
+// A PlateReader is a plate reader that replies to every protocol run with
+// the same canned result file
+type PlateReader struct {
+	Format   string // Format of Data, e.g., "spectramax"
+	Filename string
+	Data     []byte
+
+	lock sync.Mutex
+	runs []*platereader.ProtocolRunRequest
+}
+
+// DriverType implements a DriverServer
+func (a *PlateReader) DriverType(context.Context, *driver.TypeRequest) (*driver.TypeReply, error) {
+	return &driver.TypeReply{Type: "antha.platereader.v1.ReadBackPlateReader"}, nil
+}
+
+// PRRunProtocolByName implements a PlateReaderServer
+func (a *PlateReader) PRRunProtocolByName(ctx context.Context, req *platereader.ProtocolRunRequest) (*platereader.BoolReply, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.runs = append(a.runs, req)
+	return &platereader.BoolReply{Result: true}, nil
+}
+
+// PRRunProtocolByNameWithResult implements a PlateReaderServer
+func (a *PlateReader) PRRunProtocolByNameWithResult(ctx context.Context, req *platereader.ProtocolRunRequest) (*platereader.ProtocolResultReply, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.runs = append(a.runs, req)
+	return &platereader.ProtocolResultReply{
+		Result:   true,
+		Format:   a.Format,
+		Filename: a.Filename,
+		Data:     a.Data,
+	}, nil
+}
+
+// Runs returns the protocol runs requested so far
+func (a *PlateReader) Runs() []*platereader.ProtocolRunRequest {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]*platereader.ProtocolRunRequest(nil), a.runs...)
+}
+
+// A Server serves a stub driver on a local port
+type Server struct {
+	URI    string
+	server *grpc.Server
+}
+
+// Close stops the server
+func (a *Server) Close() {
+	a.server.Stop()
+}
+
+// Serve starts serving a Driver, Runner or PlateReader on a free local port
+func Serve(d interface{}) (*Server, error) {
+	var s *grpc.Server
+	switch d := d.(type) {
+	case *Driver:
+		s = grpc.NewServer(grpc.UnknownServiceHandler(d.handle))
+		driver.RegisterDriverServer(s, d)
+	case *Runner:
+		s = grpc.NewServer()
+		driver.RegisterDriverServer(s, d)
+		runner.RegisterRunnerServer(s, d)
+	case *PlateReader:
+		s = grpc.NewServer()
+		driver.RegisterDriverServer(s, d)
+		platereader.RegisterPlateReaderServer(s, d)
+	default:
+		return nil, fmt.Errorf("unknown driver %T", d)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	go s.Serve(lis) // nolint: errcheck
+
+	return &Server{
+		URI:    lis.Addr().String(),
+		server: s,
+	}, nil
+}
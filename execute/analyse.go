@@ -0,0 +1,140 @@
+package execute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	api "github.com/antha-lang/antha/api/v1"
+	"github.com/antha-lang/antha/inject"
+	"github.com/antha-lang/antha/meta"
+	"github.com/antha-lang/antha/workflow"
+)
+
+// AnalyseOpt are options for Analyse.
+type AnalyseOpt struct {
+	// Data measured by instruments, such as plate reader or qPCR exports,
+	// by process and then by data output of the process. Values are in the
+	// same format as RawParams.Parameters.
+	Data map[string]map[string]json.RawMessage
+	// If true, read content for each wtype.File from the host file named
+	// in its blob.
+	ReadLocalFiles bool
+}
+
+// Analysis is the result of the analysis and validation stage of a
+// workflow.
+type Analysis struct {
+	Results []workflow.AnalysisResult
+}
+
+// Passed returns whether every process passed analysis and validation.
+func (a *Analysis) Passed() bool {
+	for _, r := range a.Results {
+		if r.Status != workflow.AnalysisPassed {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the results of processes that failed or were skipped.
+func (a *Analysis) Failed() []workflow.AnalysisResult {
+	var ret []workflow.AnalysisResult
+	for _, r := range a.Results {
+		if r.Status != workflow.AnalysisPassed {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// unmarshalData converts raw data for a process to the types of the
+// analysis stage of its component
+func unmarshalData(ctx context.Context, um *unmarshaler, w *workflow.Workflow, process string, raw map[string]json.RawMessage) (inject.Value, error) {
+	c, err := w.FuncName(process)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get component for process %q: %s", process, err)
+	}
+	runner, err := inject.Find(ctx, inject.NameQuery{
+		Repo:  c,
+		Stage: api.ElementStage_ANALYSIS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("component %q has no analysis stage: %s", c, err)
+	}
+	tr, ok := runner.(inject.TypedRunner)
+	if !ok {
+		return nil, fmt.Errorf("cannot get type information for component %q: type %T", c, runner)
+	}
+	in := inject.MakeValue(tr.Input())
+
+	var names []string
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	value := make(inject.Value)
+	for _, name := range names {
+		v, ok := in[name]
+		if !ok {
+			return nil, fmt.Errorf("cannot assign data %q of process %q: %s", name, process, errUnknownParam)
+		}
+		m := &meta.Unmarshaler{
+			Struct: func(data []byte, obj interface{}) error {
+				return um.unmarshalStruct(ctx, data, obj)
+			},
+		}
+		if err := m.Unmarshal(raw[name], &v); err != nil {
+			return nil, fmt.Errorf("cannot assign data %q of process %q to %s: %s", name, process, string(raw[name]), err)
+		}
+		value[name] = v
+	}
+	return value, nil
+}
+
+// Analyse runs the Analysis and Validation blocks of the elements of a
+// completed run, made with DeferAnalysis, in dependency order. Measured data is injected into the
+// outputs of each element before its Analysis block runs. The analysis
+// fails, rather than Analyse returning an error, when elements report
+// errors.
+func Analyse(parent context.Context, res *Result, opt AnalyseOpt) (*Analysis, error) {
+	if res == nil || res.Workflow == nil {
+		return nil, fmt.Errorf("no workflow to analyse")
+	}
+	w := res.Workflow
+
+	um := &unmarshaler{
+		ReadLocalFiles: opt.ReadLocalFiles,
+	}
+
+	// Data of sub-workflow processes are outputs of the processes within
+	// them
+	resolved := make(map[string]map[string]json.RawMessage)
+	for process, raw := range opt.Data {
+		for name, v := range raw {
+			port := w.ResolveOutput(workflow.Port{Process: process, Port: name})
+			if resolved[port.Process] == nil {
+				resolved[port.Process] = make(map[string]json.RawMessage)
+			}
+			resolved[port.Process][port.Port] = v
+		}
+	}
+
+	data := make(map[string]inject.Value)
+	for process, raw := range resolved {
+		value, err := unmarshalData(parent, um, w, process, raw)
+		if err != nil {
+			return nil, err
+		}
+		data[process] = value
+	}
+
+	results, err := w.RunAnalysis(parent, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Analysis{Results: results}, nil
+}
@@ -0,0 +1,506 @@
+package execute
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	api "github.com/antha-lang/antha/api/v1"
+	"github.com/antha-lang/antha/ast"
+	"github.com/antha-lang/antha/inject"
+	"github.com/antha-lang/antha/microArch/sampletracker"
+	"github.com/antha-lang/antha/workflow"
+)
+
+const cacheSuffix = ".json"
+
+var errNotCacheable = errors.New("not cacheable")
+
+// identityKeys are fields of liquids and plates that are regenerated on
+// every execution (e.g., the IDs of liquids made from parameters) and so are
+// ignored when comparing inputs.
+var identityKeys = map[string]bool{
+	"ID":         true,
+	"BlockID":    true,
+	"DaughterID": true,
+	"ParentID":   true,
+	"Inst":       true,
+}
+
+// identitySignatures are the keys of the serialized forms of liquids and
+// plates. Identity keys are only removed from objects that have every key of
+// one of these signatures, and from the objects nested within them.
+var identitySignatures = []map[string]bool{
+	jsonKeys(wtype.NewLHComponent()),
+	jsonKeys(&wtype.Plate{}),
+}
+
+func jsonKeys(v interface{}) map[string]bool {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(bs, &m); err != nil {
+		panic(err)
+	}
+	keys := make(map[string]bool)
+	for k := range m {
+		keys[k] = true
+	}
+	return keys
+}
+
+// hasIdentity returns true if obj is the serialized form of a liquid or plate
+func hasIdentity(obj map[string]interface{}) bool {
+	for _, sig := range identitySignatures {
+		matches := true
+		for k := range sig {
+			if _, ok := obj[k]; !ok {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// A Cache is an on-disk store of the outputs of elements and the
+// instructions they issued. Entries are keyed by element name, the hash of
+// the element source and the element inputs. Cache implements
+// workflow.Cache.
+type Cache struct {
+	// Directory containing cache entries
+	Dir string
+	// If true, restore outputs of unchanged elements; otherwise only record
+	// outputs for later executions
+	Resume  bool
+	sources map[string][]byte
+}
+
+// OpenCache returns a cache stored in dir, creating the directory if
+// needed. Sources maps element names to the SHA-256 of their source.
+func OpenCache(dir string, sources map[string][]byte) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{
+		Dir:     dir,
+		sources: sources,
+	}, nil
+}
+
+// A CacheEntry describes an element execution stored in a cache.
+type CacheEntry struct {
+	Key      string    `json:"key"`
+	Element  string    `json:"element"`
+	Created  time.Time `json:"created"`
+	NumInsts int       `json:"numInsts"`
+	Size     int64     `json:"size"`
+}
+
+type cacheRecord struct {
+	Element     string          `json:"element"`
+	Created     time.Time       `json:"created"`
+	Outputs     json.RawMessage `json:"outputs"`
+	Insts       []cachedInst    `json:"insts"`
+	InputPlates []*wtype.Plate  `json:"inputPlates,omitempty"`
+}
+
+// A cachedInst is the serialized form of a commandInst.
+type cachedInst struct {
+	Kind       string          `json:"kind"`
+	Inst       json.RawMessage `json:"inst"`
+	Generation int             `json:"generation,omitempty"`
+	Args       []*wtype.Liquid `json:"args"`
+	Result     []*wtype.Liquid `json:"result"`
+	Requests   []ast.Request   `json:"requests,omitempty"`
+}
+
+// canonicalParams returns a serialization of params with the identity
+// fields of liquids and plates removed.
+func canonicalParams(params inject.Value) ([]byte, error) {
+	bs, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(bs, &v); err != nil {
+		return nil, err
+	}
+
+	var strip func(v interface{}, inIdentity bool) interface{}
+	strip = func(v interface{}, inIdentity bool) interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			inIdentity = inIdentity || hasIdentity(v)
+			for k, x := range v {
+				if inIdentity && identityKeys[k] {
+					delete(v, k)
+				} else {
+					v[k] = strip(x, inIdentity)
+				}
+			}
+		case []interface{}:
+			for i, x := range v {
+				v[i] = strip(x, inIdentity)
+			}
+		}
+		return v
+	}
+
+	// Maps are serialized with sorted keys
+	return json.Marshal(strip(v, false))
+}
+
+// key identifies the outputs of an element given its inputs. Outputs of
+// elements whose analysis was deferred are kept apart from the others.
+func (a *Cache) key(ctx context.Context, funcName string, params inject.Value) (string, error) {
+	bs, err := canonicalParams(params)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\n", funcName)
+	fmt.Fprintf(h, "%x\n", a.sources[funcName])
+	if AnalysisDeferred(ctx) {
+		fmt.Fprintf(h, "deferred analysis\n")
+	}
+	h.Write(bs) // nolint: errcheck
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (a *Cache) path(key string) string {
+	return filepath.Join(a.Dir, key+cacheSuffix)
+}
+
+func marshalInst(in *commandInst) (*cachedInst, error) {
+	c := &cachedInst{
+		Args:     in.Args,
+		Result:   in.result,
+		Requests: in.Command.Requests,
+	}
+	switch inst := in.Command.Inst.(type) {
+	case *wtype.LHInstruction:
+		c.Kind = "LHInstruction"
+		c.Generation = inst.Generation()
+	case *wtype.PRInstruction:
+		c.Kind = "PRInstruction"
+	case *ast.IncubateInst:
+		c.Kind = "IncubateInst"
+	case *ast.PromptInst:
+		c.Kind = "PromptInst"
+	case *ast.QPCRInstruction:
+		c.Kind = "QPCRInstruction"
+	default:
+		// Notably, ast.HandleInst has opaque driver calls
+		return nil, errNotCacheable
+	}
+
+	bs, err := json.Marshal(in.Command.Inst)
+	if err != nil {
+		return nil, err
+	}
+	c.Inst = bs
+	return c, nil
+}
+
+// unmarshalInst restores an instruction, reusing the liquids referenced by
+// the instruction itself as arguments and results so that pointer identity
+// is the same as when the instruction was first issued.
+func unmarshalInst(c cachedInst) (*commandInst, error) {
+	in := &commandInst{
+		Args:   c.Args,
+		result: c.Result,
+		Command: &ast.Command{
+			Requests: c.Requests,
+		},
+	}
+
+	switch c.Kind {
+	case "LHInstruction":
+		var inst wtype.LHInstruction
+		if err := json.Unmarshal(c.Inst, &inst); err != nil {
+			return nil, err
+		}
+		inst.SetGeneration(c.Generation)
+		switch inst.Type {
+		case wtype.LHIMIX:
+			in.Args = inst.Inputs
+			in.result = inst.Outputs[:1]
+		case wtype.LHIPRM:
+			in.Args = inst.Inputs
+			in.result = inst.Outputs
+			for id, pass := range inst.PassThrough {
+				for _, out := range inst.Outputs {
+					if pass.ID == out.ID {
+						inst.PassThrough[id] = out
+					}
+				}
+			}
+		case wtype.LHISPL:
+			in.result = inst.Outputs
+		}
+		in.Command.Inst = &inst
+	case "PRInstruction":
+		var inst wtype.PRInstruction
+		if err := json.Unmarshal(c.Inst, &inst); err != nil {
+			return nil, err
+		}
+		in.Args = []*wtype.Liquid{inst.ComponentIn}
+		in.result = []*wtype.Liquid{inst.ComponentOut}
+		in.Command.Inst = &inst
+	case "IncubateInst":
+		var inst ast.IncubateInst
+		if err := json.Unmarshal(c.Inst, &inst); err != nil {
+			return nil, err
+		}
+		in.Command.Inst = &inst
+	case "PromptInst":
+		var inst ast.PromptInst
+		if err := json.Unmarshal(c.Inst, &inst); err != nil {
+			return nil, err
+		}
+		in.Command.Inst = &inst
+	case "QPCRInstruction":
+		var inst ast.QPCRInstruction
+		if err := json.Unmarshal(c.Inst, &inst); err != nil {
+			return nil, err
+		}
+		in.Args = inst.ComponentIn
+		in.result = inst.ComponentOut
+		in.Command.Inst = &inst
+	default:
+		return nil, fmt.Errorf("unknown instruction kind %q", c.Kind)
+	}
+
+	return in, nil
+}
+
+// replay repeats the updates to liquid identities that issuing an
+// instruction makes when it is first called
+func replay(ctx context.Context, in *commandInst) {
+	st := sampletracker.FromContext(ctx)
+	if lh, ok := in.Command.Inst.(*wtype.LHInstruction); ok {
+		switch lh.Type {
+		case wtype.LHIMIX:
+			for _, arg := range in.Args {
+				getMaker(ctx).UpdateAfterInst(arg.ID, in.result[0].ID)
+			}
+			return
+		case wtype.LHISPL:
+			st.UpdateIDOf(in.Args[0].ID, in.result[1].ID)
+			return
+		}
+	}
+
+	for i, res := range in.result {
+		arg := in.Args[0]
+		if len(in.Args) == len(in.result) {
+			arg = in.Args[i]
+		}
+		getMaker(ctx).UpdateAfterInst(arg.ID, res.ID)
+		st.UpdateIDOf(arg.ID, res.ID)
+	}
+}
+
+func outputOf(ctx context.Context, funcName string) (interface{}, error) {
+	r, err := inject.Find(ctx, inject.NameQuery{
+		Repo:  funcName,
+		Stage: api.ElementStage_STEPS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tr, ok := r.(inject.TypedRunner)
+	if !ok {
+		return nil, errNotCacheable
+	}
+	return reflect.New(reflect.TypeOf(tr.Output()).Elem()).Interface(), nil
+}
+
+// Load implements workflow.Cache
+func (a *Cache) Load(ctx context.Context, funcName string, params inject.Value) (inject.Value, bool, error) {
+	if !a.Resume {
+		return nil, false, nil
+	}
+
+	key, err := a.key(ctx, funcName, params)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	bs, err := ioutil.ReadFile(a.path(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	var rec cacheRecord
+	if err := json.Unmarshal(bs, &rec); err != nil {
+		return nil, false, fmt.Errorf("cannot read cache entry %s: %s", key, err)
+	}
+
+	out, err := outputOf(ctx, funcName)
+	if err == errNotCacheable {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	if err := json.Unmarshal(rec.Outputs, out); err != nil {
+		return nil, false, fmt.Errorf("cannot read outputs of cache entry %s: %s", key, err)
+	}
+
+	var insts []*commandInst
+	for _, c := range rec.Insts {
+		in, err := unmarshalInst(c)
+		if err != nil {
+			return nil, false, fmt.Errorf("cannot read instructions of cache entry %s: %s", key, err)
+		}
+		insts = append(insts, in)
+	}
+
+	for _, p := range rec.InputPlates {
+		SetInputPlate(ctx, p)
+	}
+	for _, in := range insts {
+		replay(ctx, in)
+		Issue(ctx, in)
+	}
+
+	return inject.MakeValue(out), true, nil
+}
+
+// Store implements workflow.Cache. Elements that issue instructions which
+// cannot be serialized are silently not stored.
+func (a *Cache) Store(ctx context.Context, funcName string, params inject.Value, out inject.Value) error {
+	tr, ok := findTrace(ctx)
+	if !ok {
+		return nil
+	}
+	order, ok := workflow.ProcessOrder(ctx)
+	if !ok {
+		return nil
+	}
+
+	key, err := a.key(ctx, funcName, params)
+	if err != nil {
+		return nil
+	}
+
+	outputs, err := json.Marshal(out)
+	if err != nil {
+		return nil
+	}
+
+	insts, plates := tr.issuedBy(order)
+	rec := cacheRecord{
+		Element:     funcName,
+		Created:     time.Now(),
+		Outputs:     outputs,
+		InputPlates: plates,
+	}
+	for _, in := range insts {
+		c, err := marshalInst(in)
+		if err != nil {
+			return nil
+		}
+		rec.Insts = append(rec.Insts, *c)
+	}
+
+	bs, err := json.Marshal(rec)
+	if err != nil {
+		return nil
+	}
+
+	// Write then rename so that readers never see partial entries
+	f, err := ioutil.TempFile(a.Dir, key)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(bs); err != nil {
+		f.Close()           // nolint: errcheck
+		os.Remove(f.Name()) // nolint: errcheck
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name()) // nolint: errcheck
+		return err
+	}
+	return os.Rename(f.Name(), a.path(key))
+}
+
+// Entries returns the entries in the cache ordered by creation time
+func (a *Cache) Entries() ([]CacheEntry, error) {
+	files, err := ioutil.ReadDir(a.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), cacheSuffix) {
+			continue
+		}
+		bs, err := ioutil.ReadFile(filepath.Join(a.Dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var rec cacheRecord
+		if err := json.Unmarshal(bs, &rec); err != nil {
+			return nil, fmt.Errorf("cannot read cache entry %s: %s", fi.Name(), err)
+		}
+		entries = append(entries, CacheEntry{
+			Key:      strings.TrimSuffix(fi.Name(), cacheSuffix),
+			Element:  rec.Element,
+			Created:  rec.Created,
+			NumInsts: len(rec.Insts),
+			Size:     fi.Size(),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Created.Before(entries[j].Created)
+	})
+
+	return entries, nil
+}
+
+// Clear removes all entries from the cache, or only the entries of the
+// given elements if any are given
+func (a *Cache) Clear(elements ...string) error {
+	entries, err := a.Entries()
+	if err != nil {
+		return err
+	}
+
+	remove := make(map[string]bool)
+	for _, e := range elements {
+		remove[e] = true
+	}
+
+	for _, e := range entries {
+		if len(remove) > 0 && !remove[e.Element] {
+			continue
+		}
+		if err := os.Remove(a.path(e.Key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package execute
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	api "github.com/antha-lang/antha/api/v1"
+	"github.com/antha-lang/antha/ast"
+	"github.com/antha-lang/antha/inject"
+	"github.com/antha-lang/antha/microArch/sampletracker"
+	"github.com/antha-lang/antha/workflow"
+)
+
+type promptInput struct {
+	In      *wtype.Liquid
+	Message string
+}
+
+type promptOutput struct {
+	Out *wtype.Liquid
+}
+
+func runPromptWorkflow(cache *Cache, in *wtype.Liquid, runFunc inject.RunFunc) (*workflow.Workflow, []*commandInst, error) {
+	ctx := inject.NewContext(sampletracker.NewContext(withID(context.Background(), "test")))
+	if err := inject.Add(ctx, inject.Name{Repo: "Prompter", Stage: api.ElementStage_STEPS}, &inject.CheckedRunner{
+		RunFunc: runFunc,
+		In:      &promptInput{},
+		Out:     &promptOutput{},
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	w, err := workflow.New(workflow.Opt{
+		FromDesc: &workflow.Desc{
+			Processes: map[string]workflow.Process{
+				"Prompter": {Component: "Prompter"},
+			},
+		},
+		Cache: cache,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := w.SetParam(workflow.Port{Process: "Prompter", Port: "In"}, in); err != nil {
+		return nil, nil, err
+	}
+	if err := w.SetParam(workflow.Port{Process: "Prompter", Port: "Message"}, "hello"); err != nil {
+		return nil, nil, err
+	}
+
+	ctxTr, tr := WithTrace(ctx)
+	if err := w.Run(ctxTr); err != nil {
+		return nil, nil, err
+	}
+	return w, tr.Instructions(), nil
+}
+
+func TestCacheResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	cache, err := OpenCache(dir, map[string][]byte{"Prompter": []byte("v1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newLiquid := func() *wtype.Liquid {
+		l := wtype.NewLHComponent()
+		l.CName = "water"
+		return l
+	}
+
+	calls := 0
+	prompt := func(ctx context.Context, value inject.Value) (inject.Value, error) {
+		calls++
+		in := value["In"].(*wtype.Liquid)
+		return inject.MakeValue(&promptOutput{
+			Out: Prompt(ctx, in, value["Message"].(string)),
+		}), nil
+	}
+
+	w1, insts1, err := runPromptWorkflow(cache, newLiquid(), prompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 1 || entries[0].Element != "Prompter" || entries[0].NumInsts != 1 {
+		t.Fatalf("unexpected cache entries %+v", entries)
+	}
+
+	// A fresh liquid has a different ID but is otherwise the same input
+	cache.Resume = true
+	w2, insts2, err := runPromptWorkflow(cache, newLiquid(), prompt)
+	if err != nil {
+		t.Fatal(err)
+	} else if calls != 1 {
+		t.Errorf("expecting element to be restored from cache but it ran %d times", calls)
+	}
+
+	port := workflow.Port{Process: "Prompter", Port: "Out"}
+	out1, out2 := w1.Outputs[port].(*wtype.Liquid), w2.Outputs[port].(*wtype.Liquid)
+	if out1 == nil || out2 == nil {
+		t.Fatalf("missing outputs %v and %v", out1, out2)
+	} else if out1.ID != out2.ID {
+		t.Errorf("expecting output %s but got %s", out1.ID, out2.ID)
+	}
+
+	if len(insts1) != 1 || len(insts2) != 1 {
+		t.Fatalf("expecting one instruction but got %d and %d", len(insts1), len(insts2))
+	} else if p, ok := insts2[0].Command.Inst.(*ast.PromptInst); !ok || p.Message != "hello" {
+		t.Errorf("expecting prompt instruction but got %+v", insts2[0].Command.Inst)
+	} else if insts2[0].result[0].ID != out2.ID {
+		t.Errorf("expecting instruction result %s but got %s", out2.ID, insts2[0].result[0].ID)
+	}
+
+	// Changing the element source invalidates the cache
+	cache.sources["Prompter"] = []byte("v2")
+	if _, _, err := runPromptWorkflow(cache, newLiquid(), prompt); err != nil {
+		t.Fatal(err)
+	} else if calls != 2 {
+		t.Errorf("expecting element to run after source changed")
+	}
+
+	if err := cache.Clear(); err != nil {
+		t.Fatal(err)
+	} else if entries, err := cache.Entries(); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 0 {
+		t.Errorf("expecting empty cache but found %d entries", len(entries))
+	}
+}
+
+func TestCacheSkipsUnserializable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	cache, err := OpenCache(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle := func(ctx context.Context, value inject.Value) (inject.Value, error) {
+		in := value["In"].(*wtype.Liquid)
+		if in == nil {
+			return nil, fmt.Errorf("missing input")
+		}
+		return inject.MakeValue(&promptOutput{
+			Out: Handle(ctx, HandleOpt{Component: in}),
+		}), nil
+	}
+
+	l := wtype.NewLHComponent()
+	l.CName = "water"
+	if _, _, err := runPromptWorkflow(cache, l, handle); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, err := cache.Entries(); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 0 {
+		t.Errorf("expecting no cache entries for opaque instructions but found %d", len(entries))
+	}
+}
+
+func TestCanonicalParams(t *testing.T) {
+	canonical := func(v inject.Value) string {
+		bs, err := canonicalParams(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(bs)
+	}
+
+	a, b := wtype.NewLHComponent(), wtype.NewLHComponent()
+	a.CName, b.CName = "water", "water"
+	if ca, cb := canonical(inject.Value{"In": a}), canonical(inject.Value{"In": b}); ca != cb {
+		t.Errorf("expecting liquids differing only by ID to match:\n%s\n%s", ca, cb)
+	}
+	if ca, cb := canonical(inject.Value{"In": []*wtype.Liquid{a}}), canonical(inject.Value{"In": []*wtype.Liquid{b}}); ca != cb {
+		t.Errorf("expecting nested liquids differing only by ID to match:\n%s\n%s", ca, cb)
+	}
+
+	type params struct {
+		ID      string
+		BlockID int
+	}
+	if c1, c2 := canonical(inject.Value{"P": params{ID: "a"}}), canonical(inject.Value{"P": params{ID: "b"}}); c1 == c2 {
+		t.Errorf("expecting parameters named ID to be compared: %s", c1)
+	}
+	if c1, c2 := canonical(inject.Value{"ID": "a"}), canonical(inject.Value{"ID": "b"}); c1 == c2 {
+		t.Errorf("expecting parameters named ID to be compared: %s", c1)
+	}
+}
@@ -47,6 +47,39 @@ func WithElementName(parent context.Context, name string) context.Context {
 	})
 }
 
+type acousticDispensingKey int
+
+const theAcousticDispensingKey acousticDispensingKey = 0
+
+// WithAcousticDispensing returns a new context in which mixes are requested
+// from acoustic dispensers rather than tip-based liquid handlers
+func WithAcousticDispensing(parent context.Context) context.Context {
+	return context.WithValue(parent, theAcousticDispensingKey, true)
+}
+
+func acousticDispensing(ctx context.Context) bool {
+	v, _ := ctx.Value(theAcousticDispensingKey).(bool)
+	return v
+}
+
+type deferredAnalysisKey int
+
+const theDeferredAnalysisKey deferredAnalysisKey = 0
+
+// WithDeferredAnalysis returns a new context in which elements skip their
+// Analysis and Validation blocks, to be run later by Analyse once
+// instruments have returned measured data
+func WithDeferredAnalysis(parent context.Context) context.Context {
+	return context.WithValue(parent, theDeferredAnalysisKey, true)
+}
+
+// AnalysisDeferred returns whether elements should skip their Analysis and
+// Validation blocks
+func AnalysisDeferred(ctx context.Context) bool {
+	v, _ := ctx.Value(theDeferredAnalysisKey).(bool)
+	return v
+}
+
 func getElementName(ctx context.Context) string {
 	v, ok := ctx.Value(theElementNameKey).(*withElementName)
 	if !ok {
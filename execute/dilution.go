@@ -0,0 +1,326 @@
+package execute
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/antha-lang/antha/antha/anthalib/mixer"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+)
+
+// WellSeries returns n consecutive wells of plate starting at start, which
+// defaults to A1. Wells run along rows, continuing at the start of the next
+// row, or down columns if byColumn is true.
+func WellSeries(plate *wtype.Plate, start string, n int, byColumn bool) ([]string, error) {
+	if start == "" {
+		start = "A1"
+	}
+	wc := wtype.MakeWellCoords(start)
+	if wc.IsZero() || wc.X >= plate.WellsX() || wc.Y >= plate.WellsY() {
+		return nil, fmt.Errorf("invalid start well %s for plate type %s", start, plate.Type)
+	}
+
+	wells := make([]string, 0, n)
+	for len(wells) < n {
+		if wc.X >= plate.WellsX() || wc.Y >= plate.WellsY() {
+			return nil, fmt.Errorf("%d wells starting at %s do not fit on plate type %s", n, start, plate.Type)
+		}
+		wells = append(wells, wc.FormatA1())
+
+		if byColumn {
+			wc.Y += 1
+			if wc.Y == plate.WellsY() {
+				wc.X, wc.Y = wc.X+1, 0
+			}
+		} else {
+			wc.X += 1
+			if wc.X == plate.WellsX() {
+				wc.X, wc.Y = 0, wc.Y+1
+			}
+		}
+	}
+	return wells, nil
+}
+
+// SerialDilutionOpt are options to SerialDilution
+type SerialDilutionOpt struct {
+	// Liquid to dilute
+	Stock *wtype.Liquid
+	// Liquid with which to dilute it
+	Diluent *wtype.Liquid
+	// Factor by which each member of the series is more dilute than the last
+	Fold float64
+	// Number of members of the series
+	Steps int
+	// Volume carried from each member of the series into the next. Each
+	// member is made from this and Fold-1 times as much diluent.
+	CarryOver wunit.Volume
+	// Plate on which to make the series. If nil, the planner chooses wells.
+	Plate *wtype.Plate
+	// Well of the first member of the series, by default A1
+	Start string
+	// Lay the series down columns rather than along rows
+	ByColumn bool
+	// Dispense the diluent to every member before carrying the stock along
+	// the series, so that it is dispensed into empty wells
+	InPlace bool
+}
+
+func (opt SerialDilutionOpt) validate() error {
+	if opt.Stock == nil || opt.Diluent == nil {
+		return fmt.Errorf("stock and diluent must be given")
+	}
+	if opt.Fold <= 1.0 {
+		return fmt.Errorf("fold %g must be greater than 1", opt.Fold)
+	}
+	if opt.Steps < 1 {
+		return fmt.Errorf("%d steps requested", opt.Steps)
+	}
+	if opt.CarryOver.ConcreteMeasurement == nil || !opt.CarryOver.IsPositive() {
+		return fmt.Errorf("carry-over volume %s must be positive", opt.CarryOver)
+	}
+	return nil
+}
+
+// SerialDilution makes a series of dilutions of a stock, each Fold times as
+// dilute as the last, returning the members of the series from the most
+// concentrated. Each member is made with a sample of the one before, so the
+// planner orders the series as a chain. All but the last member lose the
+// carry-over volume to the next.
+func SerialDilution(ctx context.Context, opt SerialDilutionOpt) []*wtype.Liquid {
+	if err := opt.validate(); err != nil {
+		Errorf(ctx, "cannot make serial dilution: %s", err)
+	}
+
+	var wells []string
+	if opt.Plate != nil {
+		var err error
+		if wells, err = WellSeries(opt.Plate, opt.Start, opt.Steps, opt.ByColumn); err != nil {
+			Errorf(ctx, "cannot make serial dilution of %s: %s", opt.Stock.CName, err)
+		}
+	}
+
+	mixTo := func(i int, components ...*wtype.Liquid) *wtype.Liquid {
+		if opt.Plate == nil {
+			return Mix(ctx, components...)
+		}
+		return MixInto(ctx, opt.Plate, wells[i], components...)
+	}
+
+	diluentVolume := wunit.MultiplyVolume(opt.CarryOver, opt.Fold-1.0)
+	series := make([]*wtype.Liquid, opt.Steps)
+	if opt.InPlace {
+		for i := range series {
+			series[i] = mixTo(i, mixer.Sample(opt.Diluent, diluentVolume))
+		}
+	}
+
+	last := opt.Stock
+	for i := range series {
+		carry := mixer.Sample(last, opt.CarryOver)
+		if opt.InPlace {
+			series[i] = Mix(ctx, series[i], carry)
+		} else {
+			series[i] = mixTo(i, mixer.Sample(opt.Diluent, diluentVolume), carry)
+		}
+		last = series[i]
+	}
+
+	return series
+}
+
+// A PlateLayout determines how MakePlateMap arranges replicates
+type PlateLayout int
+
+const (
+	// BlockedLayout puts the replicates of each condition in consecutive
+	// wells
+	BlockedLayout PlateLayout = iota
+	// InterleavedLayout puts one replicate of every condition in each run
+	// of consecutive wells
+	InterleavedLayout
+	// RandomisedLayout puts replicates in random wells
+	RandomisedLayout
+)
+
+// PlateMapOpt are options to MakePlateMap
+type PlateMapOpt struct {
+	// Plate to lay out
+	Plate *wtype.Plate
+	// Wells to use in order. If empty, all wells of the plate are used
+	// down columns.
+	Wells []string
+	// Number of replicates of each condition
+	Replicates int
+	// Arrangement of replicates
+	Layout PlateLayout
+	// Seed of randomised layouts
+	Seed int64
+}
+
+// A PlateMap gives the wells of the replicates of each condition
+type PlateMap map[string][]string
+
+// MakePlateMap assigns wells to the replicates of each condition
+func MakePlateMap(conditions []string, opt PlateMapOpt) (PlateMap, error) {
+	if opt.Replicates < 1 {
+		return nil, fmt.Errorf("%d replicates requested", opt.Replicates)
+	}
+	seen := make(map[string]bool, len(conditions))
+	for _, c := range conditions {
+		if seen[c] {
+			return nil, fmt.Errorf("condition %s repeated", c)
+		}
+		seen[c] = true
+	}
+
+	wells := opt.Wells
+	if len(wells) == 0 {
+		if opt.Plate == nil {
+			return nil, fmt.Errorf("either a plate or wells must be given")
+		}
+		var err error
+		if wells, err = WellSeries(opt.Plate, "A1", opt.Plate.WellsX()*opt.Plate.WellsY(), true); err != nil {
+			return nil, err
+		}
+	}
+
+	n := len(conditions) * opt.Replicates
+	if n > len(wells) {
+		return nil, fmt.Errorf("%d replicates of %d conditions need %d wells, only %d available", opt.Replicates, len(conditions), n, len(wells))
+	}
+
+	// the condition of each well in order
+	slots := make([]string, 0, n)
+	switch opt.Layout {
+	case BlockedLayout, RandomisedLayout:
+		for _, c := range conditions {
+			for r := 0; r < opt.Replicates; r++ {
+				slots = append(slots, c)
+			}
+		}
+	case InterleavedLayout:
+		for r := 0; r < opt.Replicates; r++ {
+			slots = append(slots, conditions...)
+		}
+	default:
+		return nil, fmt.Errorf("unknown plate layout %d", opt.Layout)
+	}
+
+	if opt.Layout == RandomisedLayout {
+		rng := rand.New(rand.NewSource(opt.Seed))
+		rng.Shuffle(len(slots), func(i, j int) {
+			slots[i], slots[j] = slots[j], slots[i]
+		})
+	}
+
+	pm := make(PlateMap, len(conditions))
+	for i, c := range slots {
+		pm[c] = append(pm[c], wells[i])
+	}
+	return pm, nil
+}
+
+// A Condition is a mixture made by MixPlateMap
+type Condition struct {
+	Name string
+	// Liquids to mix and the volume of each
+	Liquids []*wtype.Liquid
+	Volumes []wunit.Volume
+}
+
+// MixPlateMap makes the replicates of each condition in the wells of plate
+// given by a plate map, returning them by condition name
+func MixPlateMap(ctx context.Context, plate *wtype.Plate, pm PlateMap, conditions []Condition) map[string][]*wtype.Liquid {
+	replicates := make(map[string][]*wtype.Liquid, len(conditions))
+	for _, c := range conditions {
+		if len(c.Liquids) != len(c.Volumes) || len(c.Liquids) == 0 {
+			Errorf(ctx, "condition %s has %d liquids and %d volumes", c.Name, len(c.Liquids), len(c.Volumes))
+		}
+		wells, ok := pm[c.Name]
+		if !ok {
+			Errorf(ctx, "no wells for condition %s in plate map", c.Name)
+		}
+
+		for _, well := range wells {
+			components := make([]*wtype.Liquid, len(c.Liquids))
+			for i, l := range c.Liquids {
+				components[i] = mixer.Sample(l, c.Volumes[i])
+			}
+			replicates[c.Name] = append(replicates[c.Name], MixInto(ctx, plate, well, components...))
+		}
+	}
+	return replicates
+}
+
+// NormaliseOpt are options to Normalise
+type NormaliseOpt struct {
+	// Liquids to normalise, whose concentrations must be set
+	Samples []*wtype.Liquid
+	// Liquid with which to dilute them
+	Diluent *wtype.Liquid
+	// Concentration of every normalised sample
+	Target wunit.Concentration
+	// Volume of every normalised sample
+	Volume wunit.Volume
+	// Plate on which to normalise. If nil, the planner chooses wells.
+	Plate *wtype.Plate
+	// Wells of the normalised samples. If empty, consecutive wells from A1
+	// down columns are used.
+	Wells []string
+}
+
+// normalisationVolumes returns the volume of each sample needed to make it
+// up to volume at the target concentration
+func normalisationVolumes(samples []*wtype.Liquid, target wunit.Concentration, volume wunit.Volume) ([]wunit.Volume, error) {
+	volumes := make([]wunit.Volume, len(samples))
+	for i, s := range samples {
+		if !s.HasConcentration() {
+			return nil, fmt.Errorf("concentration of %s not set", s.CName)
+		}
+		v, err := wunit.VolumeForTargetConcentration(target, s.Concentration(), volume)
+		if err != nil {
+			return nil, fmt.Errorf("normalising %s: %s", s.CName, err)
+		}
+		volumes[i] = v
+	}
+	return volumes, nil
+}
+
+// Normalise dilutes each sample to the target concentration, returning the
+// normalised samples in the same order
+func Normalise(ctx context.Context, opt NormaliseOpt) []*wtype.Liquid {
+	volumes, err := normalisationVolumes(opt.Samples, opt.Target, opt.Volume)
+	if err != nil {
+		Errorf(ctx, "cannot normalise samples: %s", err)
+	}
+
+	wells := opt.Wells
+	if opt.Plate != nil && len(wells) == 0 {
+		if wells, err = WellSeries(opt.Plate, "A1", len(opt.Samples), true); err != nil {
+			Errorf(ctx, "cannot normalise samples: %s", err)
+		}
+	}
+	if opt.Plate != nil && len(wells) != len(opt.Samples) {
+		Errorf(ctx, "cannot normalise %d samples into %d wells", len(opt.Samples), len(wells))
+	}
+
+	normalised := make([]*wtype.Liquid, len(opt.Samples))
+	for i, s := range opt.Samples {
+		var components []*wtype.Liquid
+		diluentVolume := wunit.SubtractVolumes(opt.Volume, volumes[i])
+		if diluentVolume.IsPositive() {
+			components = append(components, mixer.Sample(opt.Diluent, diluentVolume))
+		}
+		components = append(components, mixer.Sample(s, volumes[i]))
+
+		if opt.Plate == nil {
+			normalised[i] = Mix(ctx, components...)
+		} else {
+			normalised[i] = MixInto(ctx, opt.Plate, wells[i], components...)
+		}
+	}
+	return normalised
+}
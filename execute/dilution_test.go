@@ -0,0 +1,235 @@
+package execute
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+	"github.com/antha-lang/antha/inventory"
+	"github.com/antha-lang/antha/inventory/testinventory"
+	"github.com/antha-lang/antha/microArch/sampletracker"
+)
+
+func makeDilutionContext(t *testing.T) (context.Context, *Trace, *wtype.Plate) {
+	ctx := sampletracker.NewContext(withID(testinventory.NewContext(context.Background()), "test"))
+	ctx, tr := WithTrace(ctx)
+	plate, err := inventory.NewPlate(ctx, "pcrplate_skirted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx, tr, plate
+}
+
+func makeLiquidForTest(name string, volume float64) *wtype.Liquid {
+	l := wtype.NewLHComponent()
+	l.CName = name
+	l.Type = wtype.LTWater
+	l.Vol = volume
+	l.Vunit = "ul"
+	return l
+}
+
+func issuedMixes(tr *Trace) []*wtype.LHInstruction {
+	var mixes []*wtype.LHInstruction
+	for _, inst := range tr.Instructions() {
+		if mix, ok := inst.Command.Inst.(*wtype.LHInstruction); ok && mix.Type == wtype.LHIMIX {
+			mixes = append(mixes, mix)
+		}
+	}
+	return mixes
+}
+
+func TestWellSeries(t *testing.T) {
+	_, _, plate := makeDilutionContext(t)
+
+	if wells, err := WellSeries(plate, "A11", 3, false); err != nil {
+		t.Error(err)
+	} else if e := []string{"A11", "A12", "B1"}; !reflect.DeepEqual(e, wells) {
+		t.Errorf("expected %v, got %v", e, wells)
+	}
+
+	if wells, err := WellSeries(plate, "G1", 3, true); err != nil {
+		t.Error(err)
+	} else if e := []string{"G1", "H1", "A2"}; !reflect.DeepEqual(e, wells) {
+		t.Errorf("expected %v, got %v", e, wells)
+	}
+
+	if _, err := WellSeries(plate, "H12", 2, false); err == nil {
+		t.Error("expected an error running off the plate")
+	}
+	if _, err := WellSeries(plate, "Z1", 1, false); err == nil {
+		t.Error("expected an error for an invalid start well")
+	}
+}
+
+func TestSerialDilution(t *testing.T) {
+	ctx, tr, plate := makeDilutionContext(t)
+
+	stock := makeLiquidForTest("stock", 1000.0)
+	diluent := makeLiquidForTest("diluent", 1000.0)
+
+	series := SerialDilution(ctx, SerialDilutionOpt{
+		Stock:     stock,
+		Diluent:   diluent,
+		Fold:      3.0,
+		Steps:     4,
+		CarryOver: wunit.NewVolume(25.0, "ul"),
+		Plate:     plate,
+		Start:     "B1",
+	})
+
+	mixes := issuedMixes(tr)
+	if e, g := 4, len(mixes); e != g {
+		t.Fatalf("expected %d mixes, got %d", e, g)
+	}
+	if e, g := 4, len(series); e != g {
+		t.Fatalf("expected %d members of series, got %d", e, g)
+	}
+
+	for i, mix := range mixes {
+		if e, g := []string{"B1", "B2", "B3", "B4"}[i], mix.Welladdress; e != g {
+			t.Errorf("expected member %d in %s, got %s", i, e, g)
+		}
+		if e, g := 2, len(mix.Inputs); e != g {
+			t.Fatalf("expected %d inputs to member %d, got %d", e, i, g)
+		}
+		if d := mix.Inputs[0]; d.ParentID != diluent.ID || d.Vol != 50.0 {
+			t.Errorf("expected 50 ul of diluent first in member %d, got %s", i, d)
+		}
+		parent := stock.ID
+		if i > 0 {
+			parent = series[i-1].ID
+		}
+		if c := mix.Inputs[1]; c.ParentID != parent || c.Vol != 25.0 {
+			t.Errorf("expected member %d to carry 25 ul from %s, got %s of %s", i, parent, c, c.ParentID)
+		}
+	}
+}
+
+func TestSerialDilutionInPlace(t *testing.T) {
+	ctx, tr, plate := makeDilutionContext(t)
+
+	series := SerialDilution(ctx, SerialDilutionOpt{
+		Stock:     makeLiquidForTest("stock", 1000.0),
+		Diluent:   makeLiquidForTest("diluent", 1000.0),
+		Fold:      10.0,
+		Steps:     3,
+		CarryOver: wunit.NewVolume(10.0, "ul"),
+		Plate:     plate,
+		ByColumn:  true,
+		InPlace:   true,
+	})
+
+	mixes := issuedMixes(tr)
+	if e, g := 6, len(mixes); e != g {
+		t.Fatalf("expected %d mixes, got %d", e, g)
+	}
+
+	for i, mix := range mixes[:3] {
+		if e, g := []string{"A1", "B1", "C1"}[i], mix.Welladdress; e != g {
+			t.Errorf("expected diluent %d in %s, got %s", i, e, g)
+		}
+		if len(mix.Inputs) != 1 || mix.Inputs[0].Vol != 90.0 {
+			t.Errorf("expected 90 ul of diluent alone in %s, got %v", mix.Welladdress, mix.Inputs)
+		}
+	}
+	for i, mix := range mixes[3:] {
+		if !mix.IsMixInPlace() {
+			t.Errorf("expected member %d to be mixed in place", i)
+		}
+		if e, g := mixes[i].Outputs[0].ID, mix.Inputs[0].ID; e != g {
+			t.Errorf("expected member %d to be mixed into diluent %s, got %s", i, e, g)
+		}
+		if e, g := series[i].ID, mix.Outputs[0].ID; e != g {
+			t.Errorf("expected member %d to be %s, got %s", i, e, g)
+		}
+	}
+}
+
+func TestMakePlateMap(t *testing.T) {
+	_, _, plate := makeDilutionContext(t)
+	conditions := []string{"a", "b", "c"}
+
+	blocked, err := MakePlateMap(conditions, PlateMapOpt{Plate: plate, Replicates: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := (PlateMap{"a": {"A1", "B1"}, "b": {"C1", "D1"}, "c": {"E1", "F1"}}); !reflect.DeepEqual(e, blocked) {
+		t.Errorf("expected %v, got %v", e, blocked)
+	}
+
+	interleaved, err := MakePlateMap(conditions, PlateMapOpt{Wells: []string{"A1", "A2", "A3", "B1", "B2", "B3"}, Replicates: 2, Layout: InterleavedLayout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := (PlateMap{"a": {"A1", "B1"}, "b": {"A2", "B2"}, "c": {"A3", "B3"}}); !reflect.DeepEqual(e, interleaved) {
+		t.Errorf("expected %v, got %v", e, interleaved)
+	}
+
+	opt := PlateMapOpt{Plate: plate, Replicates: 4, Layout: RandomisedLayout, Seed: 7}
+	randomised, err := MakePlateMap(conditions, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wells []string
+	for _, c := range conditions {
+		if e, g := 4, len(randomised[c]); e != g {
+			t.Errorf("expected %d replicates of %s, got %d", e, c, g)
+		}
+		wells = append(wells, randomised[c]...)
+	}
+	sort.Strings(wells)
+	expected, _ := WellSeries(plate, "A1", 12, true)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(expected, wells) {
+		t.Errorf("expected wells %v, got %v", expected, wells)
+	}
+	if again, _ := MakePlateMap(conditions, opt); !reflect.DeepEqual(randomised, again) {
+		t.Error("expected the same layout from the same seed")
+	}
+
+	if _, err := MakePlateMap(conditions, PlateMapOpt{Wells: []string{"A1", "A2"}, Replicates: 1}); err == nil {
+		t.Error("expected an error with too few wells")
+	}
+}
+
+func TestNormalise(t *testing.T) {
+	ctx, tr, plate := makeDilutionContext(t)
+
+	a := makeLiquidForTest("a", 100.0)
+	a.SetConcentration(wunit.NewConcentration(10.0, "g/L"))
+	b := makeLiquidForTest("b", 100.0)
+	b.SetConcentration(wunit.NewConcentration(2.0, "g/L"))
+	diluent := makeLiquidForTest("diluent", 1000.0)
+
+	normalised := Normalise(ctx, NormaliseOpt{
+		Samples: []*wtype.Liquid{a, b},
+		Diluent: diluent,
+		Target:  wunit.NewConcentration(2.0, "g/L"),
+		Volume:  wunit.NewVolume(50.0, "ul"),
+		Plate:   plate,
+	})
+	if e, g := 2, len(normalised); e != g {
+		t.Fatalf("expected %d normalised samples, got %d", e, g)
+	}
+
+	mixes := issuedMixes(tr)
+	if e, g := 2, len(mixes); e != g {
+		t.Fatalf("expected %d mixes, got %d", e, g)
+	}
+	// a is diluted five fold, b is already at the target
+	if in := mixes[0].Inputs; len(in) != 2 || in[0].Vol != 40.0 || in[1].Vol != 10.0 || mixes[0].Welladdress != "A1" {
+		t.Errorf("unexpected normalisation of a: %v in %s", in, mixes[0].Welladdress)
+	}
+	if in := mixes[1].Inputs; len(in) != 1 || in[0].Vol != 50.0 || mixes[1].Welladdress != "B1" {
+		t.Errorf("unexpected normalisation of b: %v in %s", in, mixes[1].Welladdress)
+	}
+
+	b.SetConcentration(wunit.NewConcentration(1.0, "g/L"))
+	if _, err := normalisationVolumes([]*wtype.Liquid{b}, wunit.NewConcentration(2.0, "g/L"), wunit.NewVolume(50.0, "ul")); err == nil {
+		t.Error("expected an error normalising a sample below the target")
+	}
+}
@@ -31,17 +31,35 @@ type Opt struct {
 	Params *RawParams
 	// Job ID.
 	ID string
+	// Maximum number of workflow processes to run concurrently.
+	MaxParallel int
+	// If not nil, cache of element outputs to resume execution from.
+	Cache *Cache
 	// Deprecated for separate assignment of values to workflow. If true, read
 	// content for each wtype.File from file of the same name in the current
 	// directory.
 	TransitionalReadLocalFiles bool
+	// If true, elements skip their Analysis and Validation blocks, which
+	// are run by Analyse once instruments have returned measured data.
+	DeferAnalysis bool
 }
 
 // Run is a simple entrypoint for one-shot execution of workflows.
 func Run(parent context.Context, opt Opt) (res *Result, err error) {
 	ctx := sampletracker.NewContext(target.WithTarget(withID(parent, opt.ID), opt.Target))
+	if opt.DeferAnalysis {
+		ctx = WithDeferredAnalysis(ctx)
+	}
 
-	w, err := workflow.New(workflow.Opt{FromDesc: opt.Workflow})
+	wopt := workflow.Opt{
+		FromDesc:    opt.Workflow,
+		MaxParallel: opt.MaxParallel,
+	}
+	if opt.Cache != nil {
+		wopt.Cache = opt.Cache
+	}
+
+	w, err := workflow.New(wopt)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +70,15 @@ func Run(parent context.Context, opt Opt) (res *Result, err error) {
 
 	ctxTr, tr := WithTrace(ctx)
 	defer func() {
-		if res := recover(); res == nil {
+		res := recover()
+		stack := inject.ElementStackTrace
+		if p, ok := res.(*workflow.Panic); ok {
+			// The process panicked on another goroutine, which
+			// already captured its stack
+			res = p.Value
+			stack = func() string { return p.Stack }
+		}
+		if res == nil {
 			return
 		} else if uErr, ok := res.(UserError); ok {
 			// Errorf internally calls panic, which is *not* the Go
@@ -61,7 +87,7 @@ func Run(parent context.Context, opt Opt) (res *Result, err error) {
 			// do not attach a stack trace to it.
 			err = uErr
 		} else {
-			err = fmt.Errorf("%s\n%s", res, inject.ElementStackTrace())
+			err = fmt.Errorf("%s\n%s", res, stack())
 		}
 	}()
 	if err := w.Run(ctxTr); err != nil {
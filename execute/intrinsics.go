@@ -11,6 +11,7 @@ import (
 	"github.com/antha-lang/antha/inventory"
 	"github.com/antha-lang/antha/microArch/sampletracker"
 	"github.com/antha-lang/antha/target"
+	"github.com/antha-lang/antha/workflow"
 )
 
 // a commandInst is a generic intrinsic instruction
@@ -26,6 +27,10 @@ type commandInst struct {
 // supplied. This modifies the argument to mark each well as such.
 func SetInputPlate(ctx context.Context, plate *wtype.Plate) {
 	sampletracker.FromContext(ctx).SetInputPlate(plate)
+	if tr, ok := findTrace(ctx); ok {
+		order, _ := workflow.ProcessOrder(ctx)
+		tr.setInputPlate(order, plate)
+	}
 }
 
 // An IncubateOpt are options to an incubate command
@@ -324,6 +329,13 @@ func mix(ctx context.Context, inst *wtype.LHInstruction) *commandInst {
 	result := inst.Outputs[0]
 	//result.BlockID = inst.BlockID // DELETEME
 
+	selector := []ast.NameValue{
+		target.DriverSelectorV1Mixer,
+	}
+	if acousticDispensing(ctx) {
+		selector = append(selector, target.DriverSelectorV1AcousticDispenser)
+	}
+
 	mx := 0
 	var reqs []ast.Request
 	// from the protocol POV components need to be passed by value
@@ -332,9 +344,7 @@ func mix(ctx context.Context, inst *wtype.LHInstruction) *commandInst {
 			panic("Nameless Component used in Mix - this is not permitted")
 		}
 		reqs = append(reqs, ast.Request{
-			Selector: []ast.NameValue{
-				target.DriverSelectorV1Mixer,
-			},
+			Selector: selector,
 		})
 		c.Order = i
 
@@ -165,24 +165,27 @@ func setParams(ctx context.Context, w *workflow.Workflow, params *RawParams, rea
 	}
 
 	for process, params := range params.Parameters {
-		c, err := w.FuncName(process)
-		if err != nil {
-			return nil, fmt.Errorf("cannot get component for process %q: %s", process, err)
-		}
-		runner, err := inject.Find(ctx, inject.NameQuery{
-			Repo:  c,
-			Stage: api.ElementStage_STEPS,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("unknown component %q: %s", c, err)
-		}
-		cr, ok := runner.(inject.TypedRunner)
-		if !ok {
-			return nil, fmt.Errorf("cannot get type information for component %q: type %T", c, runner)
-		}
-		in := inject.MakeValue(cr.Input())
 		for name, value := range params {
-			if err := setParam(ctx, um, w, process, name, value, in); err != nil {
+			// Parameters of sub-workflow processes are inputs of the
+			// processes within them
+			port := w.ResolveInput(workflow.Port{Process: process, Port: name})
+			c, err := w.FuncName(port.Process)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get component for process %q: %s", port.Process, err)
+			}
+			runner, err := inject.Find(ctx, inject.NameQuery{
+				Repo:  c,
+				Stage: api.ElementStage_STEPS,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("unknown component %q: %s", c, err)
+			}
+			cr, ok := runner.(inject.TypedRunner)
+			if !ok {
+				return nil, fmt.Errorf("cannot get type information for component %q: type %T", c, runner)
+			}
+			in := inject.MakeValue(cr.Input())
+			if err := setParam(ctx, um, w, port.Process, port.Port, value, in); err != nil {
 				return nil, fmt.Errorf("cannot assign parameter %q of process %q to %s: %s",
 					name, process, string(value), err)
 			}
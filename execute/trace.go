@@ -2,7 +2,11 @@ package execute
 
 import (
 	"context"
+	"sort"
 	"sync"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/workflow"
 )
 
 // This is pretty gross. What we do here is add into a context a
@@ -13,31 +17,88 @@ import (
 
 type Trace struct {
 	lock         sync.Mutex
-	instructions []*commandInst
+	instructions []tracedInst
+	inputPlates  []tracedPlate
+}
+
+// A tracedPlate is an input plate along with the position of the workflow
+// process that declared it.
+type tracedPlate struct {
+	order int
+	plate *wtype.Plate
+}
+
+// A tracedInst is an instruction along with the position of the issuing
+// workflow process in the serial execution order.
+type tracedInst struct {
+	order int
+	inst  *commandInst
 }
 
 // Issue an instruction - this records the instruction into the trace.
 func (tr *Trace) Issue(instruction *commandInst) {
+	tr.issue(0, instruction)
+}
+
+func (tr *Trace) issue(order int, instruction *commandInst) {
 	tr.lock.Lock()
 	defer tr.lock.Unlock()
 
-	tr.instructions = append(tr.instructions, instruction)
+	tr.instructions = append(tr.instructions, tracedInst{order: order, inst: instruction})
 }
 
 // Returns a (shallow) copy (to avoid data races) of the issued
-// instructions.
+// instructions. Instructions are ordered by the serial execution order of
+// the processes that issued them, and then by the order in which they were
+// issued, so the result does not depend on whether processes ran
+// concurrently.
 func (tr *Trace) Instructions() []*commandInst {
 	tr.lock.Lock()
 	defer tr.lock.Unlock()
 
-	clone := make([]*commandInst, len(tr.instructions))
-	copy(clone, tr.instructions)
+	sorted := make([]tracedInst, len(tr.instructions))
+	copy(sorted, tr.instructions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].order < sorted[j].order
+	})
+
+	clone := make([]*commandInst, len(sorted))
+	for i, ti := range sorted {
+		clone[i] = ti.inst
+	}
 	return clone
 }
 
+// issuedBy returns the instructions and input plates recorded by the
+// workflow process at the given position in the serial execution order.
+func (tr *Trace) issuedBy(order int) (insts []*commandInst, plates []*wtype.Plate) {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+
+	for _, ti := range tr.instructions {
+		if ti.order == order {
+			insts = append(insts, ti.inst)
+		}
+	}
+	for _, tp := range tr.inputPlates {
+		if tp.order == order {
+			plates = append(plates, tp.plate)
+		}
+	}
+	return
+}
+
+func (tr *Trace) setInputPlate(order int, plate *wtype.Plate) {
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+
+	tr.inputPlates = append(tr.inputPlates, tracedPlate{order: order, plate: plate})
+}
+
 // Issue an instruction - this records the instruction into the trace.
 func Issue(ctx context.Context, instruction *commandInst) {
-	getTrace(ctx).Issue(instruction)
+	order, _ := workflow.ProcessOrder(ctx)
+	getTrace(ctx).issue(order, instruction)
 }
 
 type traceKey int
@@ -45,13 +106,18 @@ type traceKey int
 const theTraceKey traceKey = 0
 
 func getTrace(ctx context.Context) *Trace {
-	tr, ok := ctx.Value(theTraceKey).(*Trace)
-	if !ok || tr == nil {
+	tr, ok := findTrace(ctx)
+	if !ok {
 		panic("trace: trace not found")
 	}
 	return tr
 }
 
+func findTrace(ctx context.Context) (*Trace, bool) {
+	tr, ok := ctx.Value(theTraceKey).(*Trace)
+	return tr, ok && tr != nil
+}
+
 func WithTrace(parent context.Context) (context.Context, *Trace) {
 	tr := &Trace{}
 	return context.WithValue(parent, theTraceKey, tr), tr
@@ -68,6 +68,12 @@ func Find(parent context.Context, query NameQuery) (Runner, error) {
 	return nil, errFuncNotFound
 }
 
+// IsNotFound returns whether err is the error returned by Find when no
+// runner satisfies a query
+func IsNotFound(err error) bool {
+	return err == errFuncNotFound
+}
+
 // Call a function that satisfies the query
 func Call(parent context.Context, query NameQuery, value Value) (Value, error) {
 	r, err := Find(parent, query)
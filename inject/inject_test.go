@@ -2,6 +2,7 @@ package inject
 
 import (
 	"context"
+	"errors"
 	"testing"
 )
 
@@ -109,3 +110,13 @@ func TestCheckedRunnerWithInterface(t *testing.T) {
 		t.Fatalf("expecting %d but got %d", x+y, output.Sum)
 	}
 }
+
+func TestIsNotFound(t *testing.T) {
+	ctx := NewContext(context.Background())
+	if _, err := Find(ctx, NameQuery{Repo: "missing"}); !IsNotFound(err) {
+		t.Errorf("expecting not found error, got %v", err)
+	}
+	if IsNotFound(errors.New("other")) {
+		t.Error("expecting other errors not to be not found")
+	}
+}
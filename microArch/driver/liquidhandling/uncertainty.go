@@ -0,0 +1,145 @@
+// anthalib/driver/liquidhandling/uncertainty.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package liquidhandling
+
+import (
+	"context"
+	"math"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+)
+
+// UNC is the type of instruction used to look up the volume uncertainty of a
+// transfer. It is never generated or sent to a driver.
+var UNC = NewInstructionType("UNC", "TransferUncertainty")
+
+// A transferQuery describes a single transfer so that the policy which applies
+// to it can be found without generating instructions
+type transferQuery struct {
+	BaseRobotInstruction
+	*InstructionType
+	What       string
+	Volume     wunit.Volume
+	FPlateType string
+	TPlateType string
+	TipType    string
+	Prms       *wtype.LHChannelParameter
+	Channel    int
+}
+
+func newTransferQuery() *transferQuery {
+	v := &transferQuery{
+		InstructionType: UNC,
+	}
+	v.BaseRobotInstruction = NewBaseRobotInstruction(v)
+	return v
+}
+
+func (ins *transferQuery) Visit(visitor RobotInstructionVisitor) {}
+
+func (ins *transferQuery) GetParameter(name InstructionParameter) interface{} {
+	switch name {
+	case LIQUIDCLASS:
+		return []string{ins.What}
+	case VOLUME:
+		return []wunit.Volume{ins.Volume}
+	case FROMPLATETYPE:
+		return []string{ins.FPlateType}
+	case TOPLATETYPE:
+		return []string{ins.TPlateType}
+	case TIPTYPE:
+		return ins.TipType
+	case HEAD:
+		return float64(ins.Prms.Head)
+	case CHANNEL:
+		return float64(ins.Channel)
+	case PARAMS:
+		return ins.Prms
+	case PLATFORM:
+		return ins.Prms.Platform
+	default:
+		return ins.BaseRobotInstruction.GetParameter(name)
+	}
+}
+
+func (ins *transferQuery) Generate(ctx context.Context, policy *wtype.LHPolicyRuleSet, prms *LHProperties) ([]RobotInstruction, error) {
+	return nil, nil
+}
+
+// TransferPolicy returns the policy which applies to a transfer of volume
+// of liquid type what between plates of the given types, along with the
+// channel and tip which would be used for it
+func TransferPolicy(policy *wtype.LHPolicyRuleSet, prms *LHProperties, what string, volume wunit.Volume, fromPlateType, toPlateType string, well wtype.WellCoords) (wtype.LHPolicy, *wtype.LHChannelParameter, *wtype.LHTip, error) {
+	channel, tip, err := ChooseChannel(volume, prms)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ins := newTransferQuery()
+	ins.What = what
+	ins.Volume = volume
+	ins.FPlateType = fromPlateType
+	ins.TPlateType = toPlateType
+	ins.TipType = tip.Type
+	ins.Prms = channel
+	if channel.Multi > 1 && channel.Orientation == wtype.LHVChannel && well.Y >= 0 {
+		ins.Channel = well.Y % channel.Multi
+	}
+
+	pol, err := GetPolicyFor(policy, ins)
+	if err != nil {
+		if _, ok := err.(ErrInvalidLiquidType); ok {
+			return nil, nil, nil, err
+		}
+		if pol, err = GetDefaultPolicy(policy, ins); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return pol, channel, tip, nil
+}
+
+// TransferUncertainty returns the uncertainty in the volume of liquid of the
+// given class moved to well, as set by the VolumeCV and VolumeBias items of
+// the policy which applies to the transfer. Rules may match on the volume,
+// tip type, head and channel used as well as the usual liquid class and plate
+// types. Transfers to a column of a plate using a vertical multichannel head
+// are assumed to use the channel with the same index as the row of the well.
+// Volumes too large for a single transfer are split, which reduces the random
+// error.
+func TransferUncertainty(policy *wtype.LHPolicyRuleSet, prms *LHProperties, what string, volume wunit.Volume, fromPlateType, toPlateType string, well wtype.WellCoords) (wtype.Uncertainty, error) {
+	pol, channel, tip, err := TransferPolicy(policy, prms, what, volume, fromPlateType, toPlateType, well)
+	if err != nil {
+		return wtype.Uncertainty{}, err
+	}
+
+	u := pol.VolumeUncertainty()
+
+	merged := channel.MergeWithTip(tip)
+	if tvs, err := TransferVolumes(volume, merged.Minvol, merged.Maxvol); err == nil && len(tvs) > 1 {
+		u.CV /= math.Sqrt(float64(len(tvs)))
+	}
+
+	return u, nil
+}
@@ -0,0 +1,663 @@
+// liquidhandling/consumables.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package liquidhandling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/inventory"
+	"github.com/antha-lang/antha/microArch/driver/liquidhandling"
+)
+
+// maximum number of passes made over the instruction chain when regrouping
+const maxRegroupPasses = 10
+
+// number of tips assumed in a box of tips of a type not in the request
+const defaultTipBoxSize = 96
+
+// ConsumablesCostModel prices the consumables and robot time used by a plan
+type ConsumablesCostModel struct {
+	TipCost          map[string]float64 // cost of a tip by tip type
+	DefaultTipCost   float64            // cost of a tip of a type not in TipCost
+	TipBoxCost       float64            // cost of loading a box of tips, e.g. to refill the robot
+	PlateCost        map[string]float64 // cost of a plate by plate type
+	DefaultPlateCost float64            // cost of a plate of a type not in PlateCost
+	CostPerMinute    float64            // cost of a minute of robot time
+}
+
+// NewConsumablesCostModel returns a cost model which prices all tips and
+// plates alike, in arbitrary units
+func NewConsumablesCostModel() ConsumablesCostModel {
+	return ConsumablesCostModel{
+		TipCost:          make(map[string]float64),
+		DefaultTipCost:   0.1,
+		TipBoxCost:       1.0,
+		PlateCost:        make(map[string]float64),
+		DefaultPlateCost: 5.0,
+		CostPerMinute:    1.0,
+	}
+}
+
+func (cm ConsumablesCostModel) tipCost(tipType string) float64 {
+	if c, ok := cm.TipCost[tipType]; ok {
+		return c
+	}
+	return cm.DefaultTipCost
+}
+
+func (cm ConsumablesCostModel) plateCost(plateType string) float64 {
+	if c, ok := cm.PlateCost[plateType]; ok {
+		return c
+	}
+	return cm.DefaultPlateCost
+}
+
+// Cost returns the total cost of the consumables and robot time in cu
+func (cm ConsumablesCostModel) Cost(cu ConsumablesUsage) float64 {
+	var cost float64
+	for tt, n := range cu.Tips {
+		cost += float64(n) * cm.tipCost(tt)
+	}
+	cost += float64(cu.TipBoxes) * cm.TipBoxCost
+	for pt, n := range cu.Plates {
+		cost += float64(n) * cm.plateCost(pt)
+	}
+	return cost + cu.Minutes*cm.CostPerMinute
+}
+
+// ConsumablesUsage counts the tips, plates and robot time used by a plan
+type ConsumablesUsage struct {
+	Tips     map[string]int // tips by tip type
+	TipBoxes int            // tip boxes loaded
+	Plates   map[string]int // plates by plate type
+	Minutes  float64        // robot time
+}
+
+// NTips returns the number of tips of all types used
+func (cu ConsumablesUsage) NTips() int {
+	r := 0
+	for _, n := range cu.Tips {
+		r += n
+	}
+	return r
+}
+
+// NPlates returns the number of plates of all types used
+func (cu ConsumablesUsage) NPlates() int {
+	r := 0
+	for _, n := range cu.Plates {
+		r += n
+	}
+	return r
+}
+
+// ConsumablesReport compares the consumables and robot time estimated to be
+// needed for the instruction chain before and after optimisation, and
+// records those counted from the instructions finally generated. Estimates
+// include only the plates supplied by the user or made for outputs; plates
+// made to hold inputs are counted once planned.
+type ConsumablesReport struct {
+	Before          ConsumablesUsage // estimated for the chain as sorted
+	After           ConsumablesUsage // estimated for the chain as optimised
+	Moved           int              // instructions moved to another link of the chain
+	OutputPlatetype string           // plate type chosen for outputs, if changed
+	Planned         ConsumablesUsage // counted from the robot instructions
+	CostModel       ConsumablesCostModel
+}
+
+func (cr ConsumablesReport) describe(cu ConsumablesUsage) string {
+	return fmt.Sprintf("%d tips in %d boxes, %d plates, %.1f minutes (cost %.2f)", cu.NTips(), cu.TipBoxes, cu.NPlates(), cu.Minutes, cr.CostModel.Cost(cu))
+}
+
+func (cr ConsumablesReport) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Consumables Report:\n")
+	fmt.Fprintf(&buf, "  estimated before optimisation: %s\n", cr.describe(cr.Before))
+	fmt.Fprintf(&buf, "  estimated after optimisation:  %s\n", cr.describe(cr.After))
+	fmt.Fprintf(&buf, "  instructions moved: %d\n", cr.Moved)
+	if cr.OutputPlatetype != "" {
+		fmt.Fprintf(&buf, "  output plate type: %s\n", cr.OutputPlatetype)
+	}
+	fmt.Fprintf(&buf, "  planned: %s\n", cr.describe(cr.Planned))
+	return buf.String()
+}
+
+// tipGroup identifies transfers which may share a tip: those of the same
+// liquid using the same type of tip. Limit is the number of times the tip
+// may be reused, or negative if it may not be reused.
+type tipGroup struct {
+	Key     string
+	TipType string
+	Limit   int
+}
+
+// tips returns the number of tips needed for n transfers in the group
+func (g tipGroup) tips(n int) int {
+	if g.Limit < 0 {
+		return n
+	}
+	perTip := g.Limit + 1
+	return (n + perTip - 1) / perTip
+}
+
+func policyInt(pol wtype.LHPolicy, key string) int {
+	switch v := pol[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// transferTipGroups returns the tip groups of the transfers made by a mix.
+// As when generating transfers, a tip must be changed after it has been
+// dipped into the destination or has mixed there.
+func (this *Liquidhandler) transferTipGroups(request *LHRequest, policies *wtype.LHPolicyRuleSet, ins *wtype.LHInstruction) ([]tipGroup, error) {
+	var groups []tipGroup
+	well := wtype.MakeWellCoords(ins.Welladdress)
+	for i, in := range ins.Inputs {
+		// the first input of a mix in place is not moved
+		if i == 0 && ins.IsMixInPlace() {
+			continue
+		}
+		volume := in.Volume()
+		if volume.IsZero() {
+			continue
+		}
+		var fromPlateType string
+		if plate, ok := request.GetPlate(in.PlateLocation().ID); ok {
+			fromPlateType = plate.Type
+		}
+		pol, _, tip, err := liquidhandling.TransferPolicy(policies, this.Properties, in.TypeName(), volume, fromPlateType, ins.Platetype, well)
+		if err != nil {
+			return nil, fmt.Errorf("finding policy for %s of %s: %s", volume, in.CName, err)
+		}
+
+		intoLiquid := i > 0 || ins.IsMixInPlace()
+		dirty := policyInt(pol, "DSPREFERENCE") == 0 && intoLiquid
+		dirty = dirty || policyInt(pol, "PRE_MIX") > 0 || policyInt(pol, "POST_MIX") > 0
+
+		limit := policyInt(pol, "TIP_REUSE_LIMIT")
+		if dirty {
+			limit = -1
+		}
+		groups = append(groups, tipGroup{Key: in.CName + "@" + tip.Type, TipType: tip.Type, Limit: limit})
+	}
+	return groups, nil
+}
+
+// mixTipGroups returns the tip groups of the transfers made by each mix in
+// the links of the chain
+func (this *Liquidhandler) mixTipGroups(request *LHRequest, links [][]*wtype.LHInstruction) (map[*wtype.LHInstruction][]tipGroup, error) {
+	policies := request.Policies()
+	groups := make(map[*wtype.LHInstruction][]tipGroup)
+	for _, link := range links {
+		for _, ins := range link {
+			if ins.Type != wtype.LHIMIX {
+				continue
+			}
+			g, err := this.transferTipGroups(request, policies, ins)
+			if err != nil {
+				return nil, err
+			}
+			groups[ins] = g
+		}
+	}
+	return groups, nil
+}
+
+// estimateTips estimates the tips needed for each link of the chain assuming
+// that transfers in the same link and tip group share tips as far as their
+// reuse limit allows
+func estimateTips(links [][]*wtype.LHInstruction, groups map[*wtype.LHInstruction][]tipGroup) map[string]int {
+	tips := make(map[string]int)
+	for _, link := range links {
+		uses := make(map[tipGroup]int)
+		for _, ins := range link {
+			for _, g := range groups[ins] {
+				uses[g] += 1
+			}
+		}
+		for g, n := range uses {
+			tips[g.TipType] += g.tips(n)
+		}
+	}
+	return tips
+}
+
+// tipBoxSize returns the number of tips in a box of the given type of tip
+func (this *Liquidhandler) tipBoxSize(request *LHRequest, tipType string) int {
+	boxes := append([]*wtype.LHTipbox{}, request.Tips...)
+	for _, bx := range this.Properties.Tipboxes {
+		boxes = append(boxes, bx)
+	}
+	for _, bx := range boxes {
+		if bx != nil && bx.Tiptype != nil && bx.Tiptype.Type == tipType && bx.NTips > 0 {
+			return bx.NTips
+		}
+	}
+	return defaultTipBoxSize
+}
+
+// instructionTimes returns the robot time taken to change a tip, i.e. to
+// move to a tip box, load a tip, move to the tip waste and unload it, and
+// the time taken to move to a well and aspirate or dispense there
+func (this *Liquidhandler) instructionTimes() (change, transfer time.Duration) {
+	timer := this.Properties.GetTimer()
+	if timer == nil {
+		return 0, 0
+	}
+	move := timer.TimeFor(liquidhandling.NewMoveInstruction())
+	change = 2*move + timer.TimeFor(liquidhandling.NewLoadTipsInstruction()) + timer.TimeFor(liquidhandling.NewUnloadTipsInstruction())
+	transfer = 2*move + timer.TimeFor(liquidhandling.NewAspirateInstruction()) + timer.TimeFor(liquidhandling.NewDispenseInstruction())
+	return change, transfer
+}
+
+// marginalTipCosts returns the cost of using one more tip of each type:
+// the tip itself, its share of a tip box and the time taken to change it
+func (this *Liquidhandler) marginalTipCosts(request *LHRequest, groups map[*wtype.LHInstruction][]tipGroup) map[string]float64 {
+	cm := request.Options.ConsumablesCost
+	change, _ := this.instructionTimes()
+	costs := make(map[string]float64)
+	for _, gs := range groups {
+		for _, g := range gs {
+			if _, ok := costs[g.TipType]; ok {
+				continue
+			}
+			costs[g.TipType] = cm.tipCost(g.TipType) + cm.TipBoxCost/float64(this.tipBoxSize(request, g.TipType)) + change.Minutes()*cm.CostPerMinute
+		}
+	}
+	return costs
+}
+
+// estimatePlates estimates the plates needed for the mixes in the links of
+// the chain: those supplied by the user, those already chosen for mixes and
+// enough plates of each type for the mixes which layout must place. As in
+// layout, mixes without a plate type go into the plates supplied by the user
+// if there are any, otherwise into plates of the first output plate type.
+func estimatePlates(ctx context.Context, request *LHRequest, links [][]*wtype.LHInstruction) (map[string]int, error) {
+	plates := make(map[string]int)
+	userPlates := make(map[string]bool)
+	for _, ps := range []map[string]*wtype.Plate{request.InputPlates, request.OutputPlates} {
+		for id, p := range ps {
+			if !userPlates[id] {
+				userPlates[id] = true
+				plates[p.Type] += 1
+			}
+		}
+	}
+
+	chosen := make(map[string]bool)
+	wells := make(map[string]int)
+	untyped := 0
+	for _, link := range links {
+		for _, ins := range link {
+			switch {
+			case ins.Type != wtype.LHIMIX:
+			case ins.PlateID != "":
+				if !userPlates[ins.PlateID] && !chosen[ins.PlateID] {
+					chosen[ins.PlateID] = true
+					plates[ins.Platetype] += 1
+				}
+			case ins.Platetype != "":
+				wells[ins.Platetype] += 1
+			default:
+				untyped += 1
+			}
+		}
+	}
+	if untyped != 0 && len(userPlates) == 0 && len(request.OutputPlatetypes) != 0 {
+		wells[request.OutputPlatetypes[0].Type] += untyped
+	}
+
+	for pt, n := range wells {
+		plate, err := inventory.NewPlate(ctx, pt)
+		if err != nil {
+			return nil, err
+		}
+		plates[pt] += (n + plate.Nwells - 1) / plate.Nwells
+	}
+	return plates, nil
+}
+
+// estimateUsage estimates the consumables and robot time needed for the
+// links of the chain
+func (this *Liquidhandler) estimateUsage(ctx context.Context, request *LHRequest, links [][]*wtype.LHInstruction, groups map[*wtype.LHInstruction][]tipGroup) (ConsumablesUsage, error) {
+	usage := ConsumablesUsage{Tips: estimateTips(links, groups)}
+	for tt, n := range usage.Tips {
+		size := this.tipBoxSize(request, tt)
+		usage.TipBoxes += (n + size - 1) / size
+	}
+
+	plates, err := estimatePlates(ctx, request, links)
+	if err != nil {
+		return usage, err
+	}
+	usage.Plates = plates
+
+	nTransfers := 0
+	for _, gs := range groups {
+		nTransfers += len(gs)
+	}
+	change, transfer := this.instructionTimes()
+	usage.Minutes = (time.Duration(usage.NTips())*change + time.Duration(nTransfers)*transfer).Minutes()
+
+	return usage, nil
+}
+
+// regroupTransfers moves mixes between consecutive links of mixes in the
+// chain so that transfers which may share tips are generated together. A mix
+// may only move to a link after all the instructions it depends on and before
+// all those which depend on it; prompts and splits are never moved. Each mix
+// moves to the link where the cost of the tips needed, given by tipCosts for
+// each type of tip, is least; between links of equal cost it prefers that
+// with most transfers sharing its tips. Returns the number of instructions
+// moved.
+func regroupTransfers(links [][]*wtype.LHInstruction, groups map[*wtype.LHInstruction][]tipGroup, tipCosts map[string]float64) (int, error) {
+	var all []*wtype.LHInstruction
+	place := make(map[*wtype.LHInstruction]int)
+	for i, link := range links {
+		for _, ins := range link {
+			all = append(all, ins)
+			place[ins] = i
+		}
+	}
+	original := make(map[*wtype.LHInstruction]int, len(place))
+	for ins, i := range place {
+		original[ins] = i
+	}
+
+	tg, err := wtype.MakeTGraph(all)
+	if err != nil {
+		return 0, err
+	}
+	deps := make(map[*wtype.LHInstruction][]*wtype.LHInstruction, len(all))
+	dependents := make(map[*wtype.LHInstruction][]*wtype.LHInstruction, len(all))
+	for _, ins := range all {
+		for i := 0; i < tg.NumOuts(ins); i++ {
+			dep := tg.Out(ins, i).(*wtype.LHInstruction)
+			deps[ins] = append(deps[ins], dep)
+			dependents[dep] = append(dependents[dep], ins)
+		}
+	}
+
+	// links only ever contain one type of instruction
+	isMixLink := make([]bool, len(links))
+	for i, link := range links {
+		isMixLink[i] = len(link) != 0 && link[0].Type == wtype.LHIMIX
+	}
+
+	// number of transfers of each mix and in each link in each tip group
+	// which may share tips
+	counts := make(map[*wtype.LHInstruction]map[tipGroup]int, len(all))
+	uses := make([]map[tipGroup]int, len(links))
+	for i := range uses {
+		uses[i] = make(map[tipGroup]int)
+	}
+	for _, ins := range all {
+		counts[ins] = make(map[tipGroup]int)
+		for _, g := range groups[ins] {
+			if g.Limit > 0 {
+				counts[ins][g] += 1
+				uses[place[ins]][g] += 1
+			}
+		}
+	}
+
+	// cost of the tips the link needs with ins in it, less the cost without,
+	// and the number of other transfers in the link sharing tips with ins
+	score := func(ins *wtype.LHInstruction, link int) (float64, int) {
+		cost, shared := 0.0, 0
+		for g, n := range counts[ins] {
+			others := uses[link][g]
+			if place[ins] == link {
+				others -= n
+			}
+			cost += float64(g.tips(others+n)-g.tips(others)) * tipCosts[g.TipType]
+			shared += others
+		}
+		return cost, shared
+	}
+
+	for pass := 0; pass < maxRegroupPasses; pass++ {
+		moved := false
+		for _, ins := range all {
+			if ins.Type != wtype.LHIMIX {
+				continue
+			}
+			cur := place[ins]
+			first, last := cur, cur
+			for first > 0 && isMixLink[first-1] {
+				first -= 1
+			}
+			for last < len(links)-1 && isMixLink[last+1] {
+				last += 1
+			}
+			for _, dep := range deps[ins] {
+				if place[dep]+1 > first {
+					first = place[dep] + 1
+				}
+			}
+			for _, dep := range dependents[ins] {
+				if place[dep]-1 < last {
+					last = place[dep] - 1
+				}
+			}
+
+			best := cur
+			bestCost, bestShared := score(ins, cur)
+			for l := first; l <= last; l++ {
+				c, s := score(ins, l)
+				if c < bestCost || (c == bestCost && s > bestShared) {
+					best, bestCost, bestShared = l, c, s
+				}
+			}
+			if best == cur {
+				continue
+			}
+
+			for g, n := range counts[ins] {
+				uses[cur][g] -= n
+				uses[best][g] += n
+			}
+			place[ins] = best
+			moved = true
+		}
+		if !moved {
+			break
+		}
+	}
+
+	// rebuild the links keeping the existing order of instructions
+	n := 0
+	for i := range links {
+		links[i] = links[i][:0]
+	}
+	for _, ins := range all {
+		links[place[ins]] = append(links[place[ins]], ins)
+		if place[ins] != original[ins] {
+			n += 1
+		}
+	}
+
+	return n, nil
+}
+
+// chooseOutputPlatetype chooses the type of plate for the mixes which layout
+// is free to place, i.e. those without a plate or plate type when the user
+// supplies no plates. Of the request's output plate types with wells large
+// enough for every such mix, it chooses that which holds them all at least
+// cost; layout would otherwise use the first. Returns the type chosen, or ""
+// if unchanged.
+func chooseOutputPlatetype(ctx context.Context, request *LHRequest, links [][]*wtype.LHInstruction) (string, error) {
+	if len(request.InputPlates) != 0 || len(request.OutputPlates) != 0 || len(request.OutputPlatetypes) == 0 {
+		return "", nil
+	}
+
+	var free []*wtype.LHInstruction
+	for _, link := range links {
+		for _, ins := range link {
+			if ins.Type == wtype.LHIMIX && ins.PlateID == "" && ins.Platetype == "" {
+				free = append(free, ins)
+			}
+		}
+	}
+	if len(free) == 0 {
+		return "", nil
+	}
+
+	cm := request.Options.ConsumablesCost
+	cost := func(plate *wtype.Plate) (float64, bool) {
+		maxVol := plate.Welltype.MaxVolume()
+		for _, ins := range free {
+			if maxVol.LessThan(ins.Outputs[0].Volume()) {
+				return 0, false
+			}
+		}
+		n := (len(free) + plate.Nwells - 1) / plate.Nwells
+		return float64(n) * cm.plateCost(plate.Type), true
+	}
+
+	first := request.OutputPlatetypes[0]
+	best := first
+	bestCost, ok := cost(first)
+	for _, plate := range request.OutputPlatetypes[1:] {
+		if c, fits := cost(plate); fits && (!ok || c < bestCost) {
+			best, bestCost, ok = plate, c, true
+		}
+	}
+	if best == first {
+		return "", nil
+	}
+
+	for _, ins := range free {
+		ins.Platetype = best.Type
+	}
+	return best.Type, nil
+}
+
+// optimiseConsumables regroups the transfers in the instruction chain and
+// chooses the type of output plates to reduce the cost of the tips, tip
+// boxes, plates and robot time needed according to the request's cost
+// model, recording its estimates in the request
+func (this *Liquidhandler) optimiseConsumables(ctx context.Context, request *LHRequest) error {
+	var links [][]*wtype.LHInstruction
+	for _, link := range request.InstructionChain.AsList(nil) {
+		links = append(links, append([]*wtype.LHInstruction{}, link.Values...))
+	}
+
+	groups, err := this.mixTipGroups(request, links)
+	if err != nil {
+		return err
+	}
+
+	report := &ConsumablesReport{CostModel: request.Options.ConsumablesCost}
+	if report.Before, err = this.estimateUsage(ctx, request, links, groups); err != nil {
+		return err
+	}
+
+	moved, err := regroupTransfers(links, groups, this.marginalTipCosts(request, groups))
+	if err != nil {
+		return err
+	}
+	report.Moved = moved
+
+	if report.OutputPlatetype, err = chooseOutputPlatetype(ctx, request, links); err != nil {
+		return err
+	}
+
+	// the plate type chosen may change the policies of transfers
+	if groups, err = this.mixTipGroups(request, links); err != nil {
+		return err
+	}
+	if report.After, err = this.estimateUsage(ctx, request, links, groups); err != nil {
+		return err
+	}
+
+	if moved != 0 {
+		// links emptied by moving their instructions are dropped
+		var head, ch *wtype.IChain
+		for _, link := range links {
+			if len(link) == 0 {
+				continue
+			}
+			ch = wtype.NewIChain(ch)
+			ch.Values = link
+			if head == nil {
+				head = ch
+			}
+		}
+		request.InstructionChain = head
+		request.InstructionChain.SortInstructions(request.Options.OutputSort)
+		request.OutputOrder = request.InstructionChain.FlattenInstructionIDs()
+	}
+
+	request.ConsumablesReport = report
+	return nil
+}
+
+// countConsumables records the tips, plates and robot time used by the
+// instructions generated for the request
+func (this *Liquidhandler) countConsumables(request *LHRequest) {
+	if request.ConsumablesReport == nil {
+		return
+	}
+
+	usage := ConsumablesUsage{
+		Tips:   make(map[string]int),
+		Plates: make(map[string]int),
+	}
+
+	timer := this.Properties.GetTimer()
+	var d time.Duration
+	for _, ins := range request.Instructions {
+		ins.Visit(liquidhandling.RobotInstructionBaseVisitor{
+			HandleLoadTips: func(ins *liquidhandling.LoadTipsInstruction) {
+				for _, pos := range ins.Pos {
+					if bx, ok := this.Properties.Tipboxes[pos]; ok && bx.Tiptype != nil {
+						usage.Tips[bx.Tiptype.Type] += 1
+					}
+				}
+			},
+		})
+		if timer != nil {
+			d += timer.TimeFor(ins)
+		}
+	}
+	usage.Minutes = d.Minutes()
+
+	for _, te := range request.TipsUsed {
+		usage.TipBoxes += te.NTipBoxes
+	}
+
+	for _, plate := range request.MergedInputOutputPlates() {
+		usage.Plates[plate.Type] += 1
+	}
+
+	request.ConsumablesReport.Planned = usage
+}
@@ -0,0 +1,140 @@
+package liquidhandling
+
+import (
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+func TestRegroupTransfers(t *testing.T) {
+	a, aOut := GetMixForTest("a", GetLiquidForTest("water", 10.0), GetLiquidForTest("dna", 1.0))
+	b, _ := GetMixForTest("b", aOut, GetLiquidForTest("buffer", 5.0))
+	d, _ := GetMixForTest("d", GetLiquidForTest("buffer", 5.0), GetLiquidForTest("enzyme", 1.0))
+
+	ichain, err := buildInstructionChain(map[string]*wtype.LHInstruction{"a": a, "b": b, "d": d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ichain.SortInstructions(false)
+
+	var links [][]*wtype.LHInstruction
+	for _, link := range ichain.AsList(nil) {
+		links = append(links, append([]*wtype.LHInstruction{}, link.Values...))
+	}
+	if e, g := 2, len(links); e != g {
+		t.Fatalf("expected %d links, got %d", e, g)
+	}
+
+	groups := map[*wtype.LHInstruction][]tipGroup{
+		a: {{Key: "water@tip", TipType: "tip", Limit: -1}},
+		b: {{Key: "buffer@tip", TipType: "tip", Limit: 5}},
+		d: {{Key: "buffer@tip", TipType: "tip", Limit: 5}},
+	}
+
+	if e, g := 3, estimateTips(links, groups)["tip"]; e != g {
+		t.Errorf("expected %d tips before regrouping, got %d", e, g)
+	}
+
+	moved, err := regroupTransfers(links, groups, map[string]float64{"tip": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, g := 1, moved; e != g {
+		t.Errorf("expected %d instruction moved, got %d", e, g)
+	}
+
+	// d has no dependents so joins b, which cannot move before a
+	if len(links[0]) != 1 || links[0][0] != a {
+		t.Errorf("expected only a in first link, got %v", links[0])
+	}
+	if len(links[1]) != 2 || links[1][0] != d || links[1][1] != b {
+		t.Errorf("expected d and b in second link, got %v", links[1])
+	}
+
+	if e, g := 2, estimateTips(links, groups)["tip"]; e != g {
+		t.Errorf("expected %d tips after regrouping, got %d", e, g)
+	}
+}
+
+func TestOptimiseConsumables(t *testing.T) {
+	ctx := GetContextForTest()
+
+	lh := GetLiquidHandlerForTest(ctx)
+	rq := GetLHRequestForTest()
+	configure_request_simple(ctx, rq)
+	rq.InputPlatetypes = append(rq.InputPlatetypes, GetPlateForTest())
+	rq.OutputPlatetypes = append(rq.OutputPlatetypes, GetPlateForTest())
+	rq.Options.OptimiseConsumables = true
+
+	if err := lh.Plan(ctx, rq); err != nil {
+		t.Fatal(err)
+	}
+
+	report := rq.ConsumablesReport
+	if report == nil {
+		t.Fatal("expecting a consumables report")
+	}
+	if report.After.NTips() > report.Before.NTips() {
+		t.Errorf("optimisation increased the estimated tips from %d to %d", report.Before.NTips(), report.After.NTips())
+	}
+	for _, est := range []ConsumablesUsage{report.Before, report.After} {
+		if est.TipBoxes == 0 || est.NPlates() == 0 || est.Minutes <= 0 {
+			t.Errorf("expecting tip boxes, plates and time to be estimated, got %+v", est)
+		}
+	}
+	if before, after := report.CostModel.Cost(report.Before), report.CostModel.Cost(report.After); after > before {
+		t.Errorf("optimisation increased the estimated cost from %f to %f", before, after)
+	}
+
+	nTips, nBoxes := 0, 0
+	for _, te := range rq.TipsUsed {
+		nTips += te.NTips
+		nBoxes += te.NTipBoxes
+	}
+	if e, g := nTips, report.Planned.NTips(); e != g {
+		t.Errorf("expecting %d planned tips, found %d", e, g)
+	}
+	if e, g := nBoxes, report.Planned.TipBoxes; e != g {
+		t.Errorf("expecting %d planned tip boxes, found %d", e, g)
+	}
+	if report.Planned.NPlates() == 0 {
+		t.Error("expecting plates to be counted")
+	}
+	if cost := report.CostModel.Cost(report.Planned); cost <= 0 {
+		t.Errorf("expecting a positive cost, got %f", cost)
+	}
+}
+
+func TestChooseOutputPlatetype(t *testing.T) {
+	ctx := GetContextForTest()
+
+	rq := GetLHRequestForTest()
+	configure_request_simple(ctx, rq)
+	cheap := GetPlateForTest()
+	cheap.Type = "cheapplate"
+	small := GetPlateForTest()
+	small.Type = "smallplate"
+	small.Welltype = wtype.NewLHWell("ul", 10, 1, small.Welltype.WShape, wtype.UWellBottom, 5.5, 5.5, 20.4, 1.4, "mm")
+	rq.OutputPlatetypes = append(rq.OutputPlatetypes, GetPlateForTest(), small, cheap)
+	rq.Options.ConsumablesCost.PlateCost["smallplate"] = 0.5
+	rq.Options.ConsumablesCost.PlateCost["cheapplate"] = 1.0
+
+	var mixes []*wtype.LHInstruction
+	for _, ins := range rq.LHInstructions {
+		mixes = append(mixes, ins)
+	}
+
+	// the small plate is cheapest but its wells cannot hold the mixes
+	pt, err := chooseOutputPlatetype(ctx, rq, [][]*wtype.LHInstruction{mixes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, g := "cheapplate", pt; e != g {
+		t.Errorf("expected %q to be chosen, got %q", e, g)
+	}
+	for _, ins := range mixes {
+		if ins.Platetype != pt {
+			t.Errorf("expected mix to use plate type %q, got %q", pt, ins.Platetype)
+		}
+	}
+}
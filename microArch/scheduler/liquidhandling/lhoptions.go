@@ -8,9 +8,23 @@ type LHOptions struct {
 	LegacyVolume             bool
 	FixVolumes               bool
 	IgnorePhysicalSimulation bool
+
+	// ConcentrationTolerance is the largest acceptable error in the
+	// concentration of any component of an output, as a fraction of its
+	// nominal concentration. Outputs which may exceed it are flagged; zero
+	// flags none.
+	ConcentrationTolerance float64
+
+	// OptimiseConsumables regroups transfers along the instruction chain and
+	// chooses the type of output plates to reduce the cost of tips, tip
+	// boxes, plates and robot time according to ConsumablesCost, reporting
+	// the consumables used in the request's ConsumablesReport
+	OptimiseConsumables bool
+	ConsumablesCost     ConsumablesCostModel
 }
 
 func NewLHOptions() LHOptions {
 	var lho LHOptions
+	lho.ConsumablesCost = NewConsumablesCostModel()
 	return lho
 }
@@ -66,6 +66,8 @@ type LHRequest struct {
 	OutputSort            bool
 	TipsUsed              []wtype.TipEstimate
 	InputSolutions        *InputSolutions //store properties related to the Liquids for the request
+	OutputUncertainties   []OutputUncertainty
+	ConsumablesReport     *ConsumablesReport
 }
 
 func (req *LHRequest) GetPlate(id string) (*wtype.Plate, bool) {
@@ -889,6 +889,14 @@ func (this *Liquidhandler) Plan(ctx context.Context, request *LHRequest) error {
 	request.LHInstructions = instructions
 	request.Stockconcs = stockconcs
 
+	// regroup transfers and choose output plates to save consumables
+	// before they are laid out
+	if request.Options.OptimiseConsumables {
+		if err := this.optimiseConsumables(ctx, request); err != nil {
+			return err
+		}
+	}
+
 	// set up the mapping of the outputs
 	// tried moving here to see if we can use results in fixVolumes
 	request, err = this.Layout(ctx, request)
@@ -989,6 +997,7 @@ func (this *Liquidhandler) Plan(ctx context.Context, request *LHRequest) error {
 	if err != nil {
 		return err
 	}
+	this.countConsumables(request)
 
 	// Ensures tip boxes and wastes are correct for initial and final robot states
 	this.Refresh_tipboxes_tipwastes(request)
@@ -999,6 +1008,12 @@ func (this *Liquidhandler) Plan(ctx context.Context, request *LHRequest) error {
 	if err != nil {
 		return err
 	}
+
+	// propagate the accuracy of transfers to the concentrations of the outputs
+	if err := this.estimateUncertainty(request); err != nil {
+		return err
+	}
+
 	// ensure the after state is correct
 	this.fix_post_ids()
 	err = this.fix_post_names(request)
@@ -0,0 +1,179 @@
+// liquidhandling/uncertainty.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package liquidhandling
+
+import (
+	"fmt"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+	"github.com/antha-lang/antha/microArch/driver/liquidhandling"
+)
+
+// OutputUncertainty is the range of concentrations expected in the output of
+// a mix given the accuracy of the transfers which made it
+type OutputUncertainty struct {
+	InstructionID string
+	Name          string
+	PlateID       string
+	Well          string
+	Ranges        []wtype.ConcentrationRange
+	Exceeded      []string // components whose range exceeds the tolerance
+}
+
+func (ou OutputUncertainty) String() string {
+	return fmt.Sprintf("%s in %s@%s: %v", ou.Name, ou.Well, ou.PlateID, ou.Ranges)
+}
+
+// definesVolumeUncertainty returns true if any policy sets the accuracy of
+// transfers
+func definesVolumeUncertainty(policies *wtype.LHPolicyRuleSet) bool {
+	for _, p := range policies.Policies {
+		if _, ok := p[wtype.VolumeCV]; ok {
+			return true
+		}
+		if _, ok := p[wtype.VolumeBias]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceID returns the ID of the liquid that cmp is taken from
+func sourceID(cmp *wtype.Liquid) string {
+	if cmp.IsSample() {
+		return cmp.ParentID
+	}
+	return cmp.ID
+}
+
+// estimateUncertainty propagates the accuracy of each transfer given by the
+// liquid policies along the instruction chain, setting the uncertainty of the
+// output of each mix and recording the range of concentrations of its
+// components in the request.
+func (this *Liquidhandler) estimateUncertainty(request *LHRequest) error {
+	policies := request.Policies()
+	if !definesVolumeUncertainty(policies) {
+		return nil
+	}
+
+	request.OutputUncertainties = nil
+	known := make(map[string]wtype.LiquidUncertainty)
+	for _, link := range request.InstructionChain.AsList(nil) {
+		for _, ins := range link.Values {
+			switch ins.Type {
+			case wtype.LHIPRM:
+				for id, out := range ins.PassThrough {
+					if u, ok := known[id]; ok {
+						known[out.ID] = u
+					}
+				}
+			case wtype.LHISPL:
+				if u, ok := known[sourceID(ins.Inputs[0])]; ok {
+					for _, out := range ins.Outputs {
+						known[out.ID] = u
+					}
+				}
+			case wtype.LHIMIX:
+				ou, err := this.mixUncertainty(request, policies, ins, known)
+				if err != nil {
+					return err
+				}
+				request.OutputUncertainties = append(request.OutputUncertainties, ou)
+			}
+		}
+	}
+
+	return nil
+}
+
+// mixUncertainty mixes copies of the inputs of a mix, each with the
+// uncertainty of its source and of the transfer which moves it
+func (this *Liquidhandler) mixUncertainty(request *LHRequest, policies *wtype.LHPolicyRuleSet, ins *wtype.LHInstruction, known map[string]wtype.LiquidUncertainty) (OutputUncertainty, error) {
+	var total wunit.Volume
+	for _, in := range ins.Inputs {
+		total = wunit.AddVolumes(total, in.Volume())
+	}
+
+	well := wtype.MakeWellCoords(ins.Welladdress)
+	product := wtype.NewLHComponent()
+	for i, in := range ins.Inputs {
+		sample := in.Dup()
+		if u, ok := known[sourceID(in)]; ok {
+			sample.SetUncertainty(u)
+		} else if u, err := in.GetUncertainty(); err != nil {
+			return OutputUncertainty{}, err
+		} else {
+			sample.SetUncertainty(u)
+		}
+
+		// the first input of a mix in place is not moved
+		if i != 0 || !ins.IsMixInPlace() {
+			volume := in.Volume()
+			if volume.IsZero() && in.Tvol > 0 {
+				volume = wunit.SubtractVolumes(wunit.NewVolume(in.Tvol, in.Vunit), total)
+			}
+			var fromPlateType string
+			if plate, ok := request.GetPlate(in.PlateLocation().ID); ok {
+				fromPlateType = plate.Type
+			}
+			u, err := liquidhandling.TransferUncertainty(policies, this.Properties, in.TypeName(), volume, fromPlateType, ins.Platetype, well)
+			if err != nil {
+				return OutputUncertainty{}, fmt.Errorf("estimating uncertainty of %s of %s: %s", volume, in.CName, err)
+			}
+			if err := sample.SetVolumeUncertainty(u); err != nil {
+				return OutputUncertainty{}, err
+			}
+		}
+
+		product.MixPreserveTvol(sample)
+	}
+
+	u, err := product.GetUncertainty()
+	if err != nil {
+		return OutputUncertainty{}, err
+	}
+	out := ins.Outputs[0]
+	out.SetUncertainty(u)
+	known[out.ID] = u
+
+	ranges, err := product.ConcentrationRanges()
+	if err != nil {
+		return OutputUncertainty{}, err
+	}
+	ou := OutputUncertainty{
+		InstructionID: ins.ID,
+		Name:          out.CName,
+		PlateID:       ins.PlateID,
+		Well:          ins.Welladdress,
+		Ranges:        ranges,
+	}
+	if tol := request.Options.ConcentrationTolerance; tol > 0 {
+		for _, r := range ranges {
+			if r.Exceeds(tol) {
+				ou.Exceeded = append(ou.Exceeded, r.Component)
+			}
+		}
+	}
+	return ou, nil
+}
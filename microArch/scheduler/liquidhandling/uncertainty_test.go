@@ -0,0 +1,70 @@
+package liquidhandling
+
+import (
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+func TestEstimateUncertainty(t *testing.T) {
+	ctx := GetContextForTest()
+
+	lh := GetLiquidHandlerForTest(ctx)
+	rq := GetLHRequestForTest()
+	configure_request_simple(ctx, rq)
+	rq.InputPlatetypes = append(rq.InputPlatetypes, GetPlateForTest())
+	rq.OutputPlatetypes = append(rq.OutputPlatetypes, GetPlateForTest())
+	rq.Options.ConcentrationTolerance = 0.01
+
+	rule := wtype.NewLHPolicyRule("accuracy")
+	if err := rule.AddNumericConditionOn("VOLUME", 0.0, 1000.0); err != nil {
+		t.Fatal(err)
+	}
+	policy := wtype.NewLHPolicy()
+	if err := policy.Set(wtype.VolumeCV, 2.0); err != nil {
+		t.Fatal(err)
+	}
+	policies := wtype.NewLHPolicyRuleSet()
+	policies.AddRule(rule, policy)
+	rq.AddUserPolicies(policies)
+
+	if err := lh.Plan(ctx, rq); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, f := 9, len(rq.OutputUncertainties); e != f {
+		t.Fatalf("expecting %d output uncertainties, found %d", e, f)
+	}
+
+	for _, ou := range rq.OutputUncertainties {
+		if len(ou.Ranges) == 0 {
+			t.Errorf("no concentration ranges for %s", ou)
+		}
+		for _, r := range ou.Ranges {
+			if r.Uncertainty.IsZero() {
+				t.Errorf("zero uncertainty for %s in %s", r.Component, ou)
+			}
+		}
+		if len(ou.Exceeded) == 0 {
+			t.Errorf("expecting %s to exceed a tolerance of 1%%", ou)
+		}
+	}
+}
+
+func TestEstimateUncertaintyUnset(t *testing.T) {
+	ctx := GetContextForTest()
+
+	lh := GetLiquidHandlerForTest(ctx)
+	rq := GetLHRequestForTest()
+	configure_request_simple(ctx, rq)
+	rq.InputPlatetypes = append(rq.InputPlatetypes, GetPlateForTest())
+	rq.OutputPlatetypes = append(rq.OutputPlatetypes, GetPlateForTest())
+
+	if err := lh.Plan(ctx, rq); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rq.OutputUncertainties) != 0 {
+		t.Errorf("expecting no output uncertainties, found %v", rq.OutputUncertainties)
+	}
+}
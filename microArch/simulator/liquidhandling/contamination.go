@@ -0,0 +1,228 @@
+// /anthalib/simulator/liquidhandling/contamination.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package liquidhandling
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+)
+
+// ContaminantsKey is the key in the Extra map of well contents which lists
+// the trace contaminants carried into the well by tips
+const ContaminantsKey = "contaminants"
+
+// Actions which bring a tip into contact with a well
+const (
+	ContactAspirate = "aspirate"
+	ContactDispense = "dispense"
+	ContactMix      = "mix"
+)
+
+// TipContact is a single contact between a tip and a well
+type TipContact struct {
+	Action   string
+	Well     string
+	Contents []string // the components in the well at the time of contact
+}
+
+// WellContamination lists the trace contaminants in an output well
+type WellContamination struct {
+	Plate        string
+	Well         string
+	Contents     []string
+	Contaminants []string
+}
+
+func (self WellContamination) String() string {
+	if len(self.Contaminants) == 0 {
+		return fmt.Sprintf("%s@%s: clean", self.Well, self.Plate)
+	}
+	return fmt.Sprintf("%s@%s: %s contaminated with %s", self.Well, self.Plate,
+		strings.Join(self.Contents, "+"), strings.Join(self.Contaminants, ", "))
+}
+
+// tipHistory is every well a tip has touched, the residue it carries as a
+// result and the components it aspirated since it was last emptied
+type tipHistory struct {
+	contacts []TipContact
+	residue  map[string]bool
+	carrying map[string]bool
+}
+
+// contaminationTracker follows residue on tips between wells. Every contact
+// with a well leaves the tip carrying traces of what the well holds, and any
+// residue of components the well should not hold is left behind as trace
+// contaminants.
+type contaminationTracker struct {
+	tips    map[*wtype.LHTip]*tipHistory
+	wells   map[*wtype.LHWell]map[string]bool
+	outputs []*wtype.LHWell // wells dispensed to, in order
+}
+
+func newContaminationTracker() *contaminationTracker {
+	return &contaminationTracker{
+		tips:  make(map[*wtype.LHTip]*tipHistory),
+		wells: make(map[*wtype.LHWell]map[string]bool),
+	}
+}
+
+// componentNames returns the names of the components of a liquid
+func componentNames(c *wtype.Liquid) []string {
+	ret := getUnique(strings.Split(c.Name(), "+"), true)
+	sort.Strings(ret)
+	return ret
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var ret []string
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (self *contaminationTracker) history(tip *wtype.LHTip) *tipHistory {
+	h, ok := self.tips[tip]
+	if !ok {
+		h = &tipHistory{
+			residue:  make(map[string]bool),
+			carrying: make(map[string]bool),
+		}
+		self.tips[tip] = h
+	}
+	return h
+}
+
+// contact records that the tip touched the well after liquid was moved and
+// returns the contaminants that the tip left in the well. The well may hold
+// the components it held before the contact, given by before, and during a
+// dispense also the components the tip aspirated. If before is nil the
+// current contents of the well are used.
+func (self *contaminationTracker) contact(action string, tip *wtype.LHTip, well *wtype.LHWell, before []string) []string {
+	h := self.history(tip)
+	contents := componentNames(well.Contents())
+	h.contacts = append(h.contacts, TipContact{
+		Action:   action,
+		Well:     well.GetName(),
+		Contents: contents,
+	})
+
+	if before == nil {
+		before = contents
+	}
+	allowed := make(map[string]bool, len(before))
+	for _, c := range before {
+		allowed[c] = true
+	}
+	switch action {
+	case ContactAspirate:
+		for c := range allowed {
+			h.carrying[c] = true
+		}
+	case ContactDispense:
+		for c := range h.carrying {
+			allowed[c] = true
+		}
+		if _, seen := self.wells[well]; !seen {
+			self.wells[well] = make(map[string]bool)
+			self.outputs = append(self.outputs, well)
+		}
+		if tip.CurrentVolume().IsZero() {
+			h.carrying = make(map[string]bool)
+		}
+	}
+
+	// residue on the tip, including any merged into the liquid it moved
+	carried := make(map[string]bool, len(h.residue)+len(contents))
+	for c := range h.residue {
+		carried[c] = true
+	}
+	for _, c := range contents {
+		carried[c] = true
+	}
+
+	var added []string
+	contaminants := self.wells[well]
+	for _, c := range sortedKeys(carried) {
+		if allowed[c] || contaminants[c] {
+			continue
+		}
+		if contaminants == nil {
+			contaminants = make(map[string]bool)
+			self.wells[well] = contaminants
+		}
+		contaminants[c] = true
+		added = append(added, c)
+	}
+
+	// the tip picks up traces of everything in the well
+	for _, c := range contents {
+		h.residue[c] = true
+	}
+	for c := range contaminants {
+		h.residue[c] = true
+	}
+
+	if len(contaminants) != 0 {
+		c := well.Contents()
+		if c.Extra == nil {
+			c.Extra = make(map[string]interface{})
+		}
+		c.Extra[ContaminantsKey] = sortedKeys(contaminants)
+	}
+
+	return added
+}
+
+// Contacts returns the contact history of a tip
+func (self *contaminationTracker) Contacts(tip *wtype.LHTip) []TipContact {
+	if h, ok := self.tips[tip]; ok {
+		return append([]TipContact(nil), h.contacts...)
+	}
+	return nil
+}
+
+// Report returns the contaminants in every well that was dispensed to
+func (self *contaminationTracker) Report() []WellContamination {
+	ret := make([]WellContamination, 0, len(self.outputs))
+	for _, well := range self.outputs {
+		contaminants := self.wells[well]
+		var contents []string
+		for _, c := range componentNames(well.Contents()) {
+			if !contaminants[c] {
+				contents = append(contents, c)
+			}
+		}
+		ret = append(ret, WellContamination{
+			Plate:        wtype.NameOf(well.Plate),
+			Well:         well.Crds.FormatA1(),
+			Contents:     contents,
+			Contaminants: sortedKeys(contaminants),
+		})
+	}
+	return ret
+}
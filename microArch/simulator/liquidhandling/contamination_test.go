@@ -0,0 +1,148 @@
+// /anthalib/simulator/liquidhandling/contamination_test.go: Part of the Antha language
+// Copyright (C) 2018 The Antha authors. All rights reserved.
+//
+// This program is free software; you can redistribute it and/or
+// modify it under the terms of the GNU General Public License
+// as published by the Free Software Foundation; either version 2
+// of the License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program; if not, write to the Free Software
+// Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA  02110-1301, USA.
+//
+// For more information relating to the software or licensing issues please
+// contact license@antha-lang.org or write to the Antha team c/o
+// Synthace Ltd. The London Bioscience Innovation Centre
+// 2 Royal College St, London NW1 0NH UK
+
+package liquidhandling
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/microArch/driver/liquidhandling"
+	"github.com/antha-lang/antha/microArch/simulator"
+)
+
+func Test_Contamination(t *testing.T) {
+
+	one := func(s string) []string {
+		return []string{s, "", "", "", "", "", "", ""}
+	}
+	move := func(pos, well, plt string, ref int, z float64) *Move {
+		return &Move{
+			one(pos),
+			one(well),
+			[]int{ref, ref, ref, ref, ref, ref, ref, ref},
+			[]float64{0, 0, 0, 0, 0, 0, 0, 0},
+			[]float64{0, 0, 0, 0, 0, 0, 0, 0},
+			[]float64{z, z, z, z, z, z, z, z},
+			one(plt),
+			0,
+		}
+	}
+	suck := func(pos, well, what string, volume float64) []TestRobotInstruction {
+		return []TestRobotInstruction{
+			move(pos, well, "plate", 0, 0.5),
+			&Aspirate{[]float64{volume, 0, 0, 0, 0, 0, 0, 0}, false, 0, 1, one("plate"), one(what), make([]bool, 8)},
+		}
+	}
+	blow := func(pos, well, what string, volume float64) []TestRobotInstruction {
+		return []TestRobotInstruction{
+			move(pos, well, "plate", 1, 1),
+			&Dispense{[]float64{volume, 0, 0, 0, 0, 0, 0, 0}, make([]bool, 8), 0, 1, one("plate"), one(what), make([]bool, 8)},
+		}
+	}
+
+	input_plate := defaultLHPlate("input")
+	for _, c := range []struct {
+		well, name string
+	}{{"A1", "water"}, {"A2", "red"}} {
+		comp := component(c.name)
+		comp.Vol = 200.
+		if err := input_plate.Wellcoords[c.well].AddComponent(comp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inst := []TestRobotInstruction{
+		&Initialize{},
+		&AddPlateTo{"input_1", input_plate, "input"},
+		&AddPlateTo{"output_1", defaultLHPlate("output"), "output"},
+		&AddPlateTo{"tipbox_1", smallLHTipbox("tipbox"), "tipbox"},
+		move("tipbox_1", "H12", "tipbox", 1, 5),
+		&LoadTips{[]int{0}, 0, 1, one("tipbox"), one("tipbox_1"), one("H12")},
+	}
+	//reuse the same tip for water then red
+	inst = append(inst, suck("input_1", "A1", "water", 10)...)
+	inst = append(inst, blow("output_1", "A1", "water", 10)...)
+	inst = append(inst, suck("input_1", "A2", "red", 10)...)
+	inst = append(inst, blow("output_1", "B1", "red", 10)...)
+
+	run := func(severity simulator.ErrorSeverity) *VirtualLiquidHandler {
+		settings := DefaultSimulatorSettings()
+		settings.SetContaminationSeverity(severity)
+		vlh, err := NewVirtualLiquidHandler(defaultLHProperties(), settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ris := make([]liquidhandling.TerminalRobotInstruction, 0, len(inst))
+		for _, i := range inst {
+			ris = append(ris, i.Convert())
+		}
+		vlh.Simulate(ris)
+		return vlh
+	}
+
+	//contamination is tracked but not reported by default
+	if errs := run(simulator.SeverityNone).GetErrors(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	vlh := run(simulator.SeverityWarning)
+	test := SimulatorTest{
+		ExpectedErrors: []string{
+			"(warn) Aspirate[11]: 10 ul of red to head 0 channel 0: tip on channel 0 carries water into well A2@input",
+			"(warn) Dispense[13]: 10 ul of red from head 0 channel 0 to B1@output: tip on channel 0 carries water into well B1@output",
+		},
+	}
+	test.compareErrors(t, vlh.GetErrors())
+
+	expected := []WellContamination{
+		{Plate: "output", Well: "A1", Contents: []string{"water"}},
+		{Plate: "output", Well: "B1", Contents: []string{"red"}, Contaminants: []string{"water"}},
+	}
+	if report := vlh.GetContaminationReport(); !reflect.DeepEqual(report, expected) {
+		t.Errorf("contamination report: expected %v, got %v", expected, report)
+	}
+
+	output := vlh.GetObjectAt("output_1").(*wtype.Plate)
+	if c := output.Wellcoords["B1"].Contents().Extra[ContaminantsKey]; !reflect.DeepEqual(c, []string{"water"}) {
+		t.Errorf("expected contaminants [water] in B1, got %v", c)
+	}
+
+	history, err := vlh.GetTipHistory(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedHistory := []TipContact{
+		{ContactAspirate, "A1@input", []string{"water"}},
+		{ContactDispense, "A1@output", []string{"water"}},
+		{ContactAspirate, "A2@input", []string{"red"}},
+		{ContactDispense, "B1@output", []string{"red", "water"}},
+	}
+	if !reflect.DeepEqual(history, expectedHistory) {
+		t.Errorf("tip history: expected %v, got %v", expectedHistory, history)
+	}
+
+	if _, err := vlh.GetTipHistory(0, 1); err == nil {
+		t.Error("expected an error for a channel without a tip")
+	}
+}
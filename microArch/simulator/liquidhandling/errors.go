@@ -149,6 +149,28 @@ func (self *TipsNotInWellError) lastTargetString() string {
 
 }
 
+// ContaminationError generated when a tip carries residue of components into a
+// well which should not contain them
+type ContaminationError struct {
+	*GenericError
+	Channel      int
+	Well         string
+	Contaminants []string
+}
+
+func NewContaminationError(vlh *VirtualLiquidHandler, description string, channel int, well *wtype.LHWell, contaminants []string) LiquidhandlingError {
+	ge := NewGenericError(
+		vlh.getState(),
+		vlh.settings.ContaminationSeverity(),
+		fmt.Sprintf("%s: tip on channel %d carries %s into well %s", description, channel, strings.Join(contaminants, ", "), well.GetName()))
+	return &ContaminationError{
+		GenericError: ge.(*GenericError),
+		Channel:      channel,
+		Well:         well.GetName(),
+		Contaminants: contaminants,
+	}
+}
+
 //CollisionError generated when a physical collision occurs
 type CollisionError struct {
 	description          string
@@ -22,6 +22,10 @@
 
 package liquidhandling
 
+import (
+	"github.com/antha-lang/antha/microArch/simulator"
+)
+
 type Frequency int
 
 const (
@@ -38,6 +42,8 @@ type SimulatorSettings struct {
 	max_dispense_height     float64   //maximum height to dispense from in mm
 	warnPipetteSpeed        Frequency //Raise warnings for pipette speed out of range
 	warnLiquidType          Frequency //raise warnings when liquid types don't match
+
+	contaminationSeverity simulator.ErrorSeverity //severity of errors raised when tips carry residue into wells
 }
 
 func DefaultSimulatorSettings() *SimulatorSettings {
@@ -49,6 +55,7 @@ func DefaultSimulatorSettings() *SimulatorSettings {
 		max_dispense_height:     5.,
 		warnPipetteSpeed:        WarnAlways,
 		warnLiquidType:          WarnNever,
+		contaminationSeverity:   simulator.SeverityNone,
 	}
 	return &ss
 }
@@ -128,3 +135,13 @@ func (self *SimulatorSettings) IsLiquidTypeWarningEnabled() bool {
 func (self *SimulatorSettings) EnableLiquidTypeWarning(f Frequency) {
 	self.warnLiquidType = f
 }
+
+// ContaminationSeverity the severity of errors raised when a tip carries residue
+// into a well. Contamination is still tracked when this is SeverityNone.
+func (self *SimulatorSettings) ContaminationSeverity() simulator.ErrorSeverity {
+	return self.contaminationSeverity
+}
+
+func (self *SimulatorSettings) SetContaminationSeverity(s simulator.ErrorSeverity) {
+	self.contaminationSeverity = s
+}
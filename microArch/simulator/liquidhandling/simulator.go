@@ -48,6 +48,7 @@ type VirtualLiquidHandler struct {
 	settings           *SimulatorSettings
 	lastMove           string
 	lastTarget         wtype.LHObject
+	contamination      *contaminationTracker
 }
 
 //coneRadius hardcoded radius to assume for cones
@@ -59,6 +60,7 @@ func NewVirtualLiquidHandler(props *liquidhandling.LHProperties, settings *Simul
 	vlh.errors = make([]LiquidhandlingError, 0)
 	vlh.errorHistory = make([][]LiquidhandlingError, 0)
 	vlh.instructionHistory = make([]liquidhandling.TerminalRobotInstruction, 0)
+	vlh.contamination = newContaminationTracker()
 
 	if settings == nil {
 		vlh.settings = DefaultSimulatorSettings()
@@ -178,12 +180,34 @@ func (self *VirtualLiquidHandler) GetFirstError(minimumSeverity simulator.ErrorS
 	return nil
 }
 
+//GetTipHistory get the wells touched by the tip currently loaded on the channel
+func (self *VirtualLiquidHandler) GetTipHistory(head, channel int) ([]TipContact, error) {
+	adaptor, err := self.GetAdaptorState(head)
+	if err != nil {
+		return nil, err
+	}
+	if channel < 0 || channel >= adaptor.GetChannelCount() {
+		return nil, errors.Errorf("unknown channel %d on head %d", channel, head)
+	}
+	tip := adaptor.GetChannel(channel).GetTip()
+	if tip == nil {
+		return nil, errors.Errorf("no tip loaded on channel %d of head %d", channel, head)
+	}
+	return self.contamination.Contacts(tip), nil
+}
+
+//GetContaminationReport list the trace contaminants in each well that was dispensed to
+func (self *VirtualLiquidHandler) GetContaminationReport() []WellContamination {
+	return self.contamination.Report()
+}
+
 // ------------------------------------------------------------------------------- Useful Utilities
 
 func (self *VirtualLiquidHandler) resetState() {
 	self.errorHistory = make([][]LiquidhandlingError, 0)
 	self.instructionHistory = make([]liquidhandling.TerminalRobotInstruction, 0)
 	self.errors = make([]LiquidhandlingError, 0)
+	self.contamination = newContaminationTracker()
 }
 
 func (self *VirtualLiquidHandler) popLastState() (liquidhandling.TerminalRobotInstruction, []LiquidhandlingError) {
@@ -280,6 +304,37 @@ func (self *VirtualLiquidHandler) testSliceLength(slice_lengths map[string]int,
 	return nil
 }
 
+//wellComponents the names of the components in each well, to be passed to trackContacts
+func wellComponents(wells []*wtype.LHWell) [][]string {
+	ret := make([][]string, len(wells))
+	for i, w := range wells {
+		if w != nil {
+			ret[i] = componentNames(w.Contents())
+		}
+	}
+	return ret
+}
+
+//trackContacts records the tips on each channel touching the well below them,
+//raising errors for any contaminants they leave behind. before optionally holds
+//the components of each well before liquid was moved
+func (self *VirtualLiquidHandler) trackContacts(action, description string, adaptor *AdaptorState, channels []int, wells []*wtype.LHWell, before [][]string) {
+	for _, ch := range channels {
+		tip := adaptor.GetChannel(ch).GetTip()
+		if tip == nil || ch >= len(wells) || wells[ch] == nil {
+			continue
+		}
+		var b []string
+		if ch < len(before) {
+			b = before[ch]
+		}
+		added := self.contamination.contact(action, tip, wells[ch], b)
+		if len(added) != 0 && self.settings.ContaminationSeverity() != simulator.SeverityNone {
+			self.addLHError(NewContaminationError(self, description, ch, wells[ch], added))
+		}
+	}
+}
+
 func contains(v int, s []int) bool {
 	for _, val := range s {
 		if v == val {
@@ -832,6 +887,8 @@ func (self *VirtualLiquidHandler) Aspirate(volume []float64, overstroke []bool,
 		self.addLHError(NewTipsNotInWellError(self, describe(), no_well))
 	}
 
+	self.trackContacts(ContactAspirate, describe(), arg.adaptor, arg.channels, wells, nil)
+
 	return ret
 }
 
@@ -966,6 +1023,7 @@ func (self *VirtualLiquidHandler) Dispense(volume []float64, blowout []bool, hea
 	}
 
 	//dispense
+	before := wellComponents(wells)
 	for _, i := range arg.channels {
 		v := wunit.NewVolume(volume[i], "ul")
 		tip := arg.adaptor.GetChannel(i).GetTip()
@@ -991,6 +1049,8 @@ func (self *VirtualLiquidHandler) Dispense(volume []float64, blowout []bool, hea
 		}
 	}
 
+	self.trackContacts(ContactDispense, describe(), arg.adaptor, arg.channels, wells, before)
+
 	return ret
 }
 
@@ -1631,6 +1691,8 @@ func (self *VirtualLiquidHandler) Mix(head int, volume []float64, platetype []st
 		}
 	}
 
+	self.trackContacts(ContactMix, describe(), arg.adaptor, arg.channels, wells, nil)
+
 	return ret
 }
 
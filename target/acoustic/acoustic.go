@@ -0,0 +1,242 @@
+// Package acoustic provides a device for acoustic (contactless) liquid
+// dispensers. These move liquids from source wells to destination wells as
+// droplets of a fixed volume, without tips.
+package acoustic
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/ast"
+	driver "github.com/antha-lang/antha/microArch/driver/liquidhandling"
+	planner "github.com/antha-lang/antha/microArch/scheduler/liquidhandling"
+	"github.com/antha-lang/antha/target"
+)
+
+const picklistFileName = "picklist.csv"
+
+var (
+	_ ast.Device           = &Dispenser{}
+	_ target.LiquidHandler = &Dispenser{}
+)
+
+// A Dispenser is a device plugin for acoustic dispensers
+type Dispenser struct {
+	opt Opt
+}
+
+// New returns a new Dispenser
+func New(opt Opt) (*Dispenser, error) {
+	opt = opt.withDefaults()
+	if len(opt.SourcePlateType) != 0 {
+		if _, ok := opt.SourcePlates[opt.SourcePlateType]; !ok {
+			return nil, fmt.Errorf("no working volume for source plate type %q", opt.SourcePlateType)
+		}
+	}
+	for typ, wv := range opt.SourcePlates {
+		if min, max := nanolitres(wv.Min), nanolitres(wv.Max); min < 0 || max <= min {
+			return nil, fmt.Errorf("invalid working volume for source plate type %q: %s to %s", typ, wv.Min, wv.Max)
+		}
+	}
+	return &Dispenser{opt: opt}, nil
+}
+
+func (a *Dispenser) String() string {
+	if m := strings.TrimSpace(a.opt.Manufacturer + " " + a.opt.Model); len(m) != 0 {
+		return fmt.Sprintf("Acoustic dispenser (%s)", m)
+	}
+	return "Acoustic dispenser"
+}
+
+// CanCompile implements a Device. Only mixes that request an acoustic
+// dispenser are compiled; see execute.WithAcousticDispensing.
+func (a *Dispenser) CanCompile(req ast.Request) bool {
+	acoustic := ast.Request{
+		Selector: []ast.NameValue{
+			target.DriverSelectorV1AcousticDispenser,
+		},
+	}
+	can := ast.Request{
+		Selector: []ast.NameValue{
+			target.DriverSelectorV1Mixer,
+			target.DriverSelectorV1AcousticDispenser,
+		},
+	}
+	return req.Contains(acoustic) && can.Contains(req)
+}
+
+// CanMix implements a LiquidHandler
+func (a *Dispenser) CanMix(mix *wtype.LHInstruction) error {
+	if mix.Type != wtype.LHIMIX {
+		return fmt.Errorf("cannot execute %s instructions", mix.InsType())
+	}
+
+	typ := outputPlateType(mix, a.opt)
+	if len(typ) == 0 {
+		return fmt.Errorf("no output plate type")
+	}
+	if len(a.opt.PlateTypes) != 0 {
+		found := false
+		for _, t := range a.opt.PlateTypes {
+			found = found || t == typ
+		}
+		if !found {
+			return fmt.Errorf("plate type %q not supported", typ)
+		}
+	}
+
+	vols, err := mixVolumes(mix)
+	if err != nil {
+		return err
+	}
+	droplet := nanolitres(a.opt.DropletVolume)
+	for i, v := range vols {
+		if i == 0 && mix.IsMixInPlace() {
+			continue
+		}
+		if n, _ := quantise(v, droplet); n == 0 {
+			return fmt.Errorf("volume %g nl of %s is less than half a droplet", v, mix.Inputs[i].CName)
+		}
+	}
+
+	return nil
+}
+
+// Channels implements a LiquidHandler. Acoustic dispensers fire one droplet
+// at a time.
+func (a *Dispenser) Channels() int {
+	return 1
+}
+
+// FileType returns the file type for generated files
+func (a *Dispenser) FileType() string {
+	if m := a.opt.Manufacturer; len(m) != 0 {
+		return fmt.Sprintf("application/%s", strings.ToLower(m))
+	}
+	return "application/acoustic"
+}
+
+// Compile implements a Device
+func (a *Dispenser) Compile(ctx context.Context, nodes []ast.Node) ([]ast.Inst, error) {
+	var mixes []*wtype.LHInstruction
+	for _, node := range nodes {
+		if c, ok := node.(*ast.Command); !ok {
+			return nil, fmt.Errorf("cannot compile %T", node)
+		} else if m, ok := c.Inst.(*wtype.LHInstruction); !ok {
+			return nil, fmt.Errorf("cannot compile %T", c.Inst)
+		} else if err := a.CanMix(m); err != nil {
+			return nil, fmt.Errorf("cannot compile %s: %s", m, err)
+		} else {
+			mixes = append(mixes, m)
+		}
+	}
+
+	mix, err := a.makeMix(ctx, mixes)
+	if err != nil {
+		return nil, err
+	}
+
+	return []ast.Inst{mix}, nil
+}
+
+func (a *Dispenser) makeMix(ctx context.Context, mixes []*wtype.LHInstruction) (*target.Mix, error) {
+	p := newPlan(a.opt)
+	if err := p.Run(ctx, mixes); err != nil {
+		return nil, err
+	}
+
+	picklist, err := p.Picklist()
+	if err != nil {
+		return nil, err
+	}
+	tarball, err := makeTarball(picklistFileName, picklist)
+	if err != nil {
+		return nil, err
+	}
+
+	req := planner.NewLHRequest()
+	for _, m := range mixes {
+		req.LHInstructions[m.ID] = m
+	}
+	for _, plate := range p.Sources {
+		req.InputPlates[plate.ID] = plate
+	}
+	for _, plate := range p.Destinations {
+		req.OutputPlates[plate.ID] = plate
+	}
+	req.TimeEstimate = p.TimeEstimate().Seconds()
+	req.InstructionText = p.Summary()
+
+	final := make(map[string]string)
+	for _, plate := range p.Initial.Plates {
+		final[plate.ID] = plate.ID
+	}
+
+	return &target.Mix{
+		Dev:             a,
+		Request:         req,
+		Properties:      p.Initial,
+		FinalProperties: p.Final,
+		Final:           final,
+		Files: target.Files{
+			Tarball: tarball,
+			Type:    a.FileType(),
+		},
+	}, nil
+}
+
+// makeTarball returns a gzipped tarball with a single file
+func makeTarball(name string, bs []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(bs)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return nil, err
+	} else if _, err := tw.Write(bs); err != nil {
+		return nil, err
+	} else if err := tw.Close(); err != nil {
+		return nil, err
+	} else if err := gw.Close(); err != nil {
+		return nil, err
+	} else {
+		return buf.Bytes(), nil
+	}
+}
+
+// newProperties returns the state of a dispenser holding the given plates.
+// Each plate has its own position, although only one source and one
+// destination plate are loaded at a time.
+func newProperties(opt Opt, sources, destinations []*wtype.Plate) (*driver.LHProperties, error) {
+	positions := make(map[string]*wtype.LHPosition)
+	plates := make(map[string]*wtype.Plate)
+	for prefix, ps := range map[string][]*wtype.Plate{
+		"source":      sources,
+		"destination": destinations,
+	} {
+		for i, plate := range ps {
+			name := fmt.Sprintf("%s_%d", prefix, i+1)
+			positions[name] = wtype.NewLHPosition(name, wtype.Coordinates{})
+			plates[name] = plate
+		}
+	}
+
+	props := driver.NewLHProperties(opt.Model, opt.Manufacturer, driver.HLLiquidHandler, driver.NoTips, positions)
+	for name, plate := range plates {
+		if err := props.AddPlateTo(name, plate); err != nil {
+			return nil, err
+		}
+	}
+	return props, nil
+}
@@ -0,0 +1,243 @@
+package acoustic
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/mixer"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+	"github.com/antha-lang/antha/ast"
+	"github.com/antha-lang/antha/inventory"
+	"github.com/antha-lang/antha/inventory/testinventory"
+	"github.com/antha-lang/antha/target"
+)
+
+func makeOpt() Opt {
+	return Opt{
+		Model:        "Echo",
+		Manufacturer: "Labcyte",
+		SourcePlates: map[string]WorkingVolume{
+			"greiner384": {Min: wunit.NewVolume(20, "ul"), Max: wunit.NewVolume(65, "ul")},
+		},
+		SourcePlateType: "greiner384",
+		OutputPlateType: "pcrplate_skirted",
+	}
+}
+
+func makeLiquid(name string) *wtype.Liquid {
+	l := wtype.NewLHComponent()
+	l.CName = name
+	l.Vunit = "ul"
+	l.Vol = 1000
+	return l
+}
+
+func makeMix(well string, samples ...*wtype.Liquid) *wtype.LHInstruction {
+	m := wtype.NewLHMixInstruction()
+	m.Inputs = samples
+	m.AddOutput(wtype.NewLHComponent())
+	m.Welladdress = well
+	return m
+}
+
+func checkPicklist(t *testing.T, p *plan, expected []string) {
+	bs, err := p.Picklist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSpace(string(bs)), "\n")[1:]
+	if len(got) != len(expected) {
+		t.Fatalf("expecting %d transfers but got %d:\n%s", len(expected), len(got), bs)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("transfer %d: expecting %q but got %q", i+1, expected[i], got[i])
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	ctx := testinventory.NewContext(context.Background())
+
+	// A buffer already on a source plate
+	input, err := inventory.NewPlate(ctx, "greiner384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.SetName("buffers")
+	buffer := makeLiquid("buffer")
+	buffer.Vol = 30
+	if err := input.Wellcoords["B2"].SetContents(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	opt := makeOpt()
+	opt.InputPlates = []*wtype.Plate{input}
+
+	water := makeLiquid("water")
+	dye := makeLiquid("dye")
+	mixes := []*wtype.LHInstruction{
+		makeMix("A1",
+			mixer.Sample(water, wunit.NewVolume(1, "ul")),
+			mixer.Sample(buffer, wunit.NewVolume(100, "nl")),
+			mixer.Sample(dye, wunit.NewVolume(26, "nl"))),
+		makeMix("B1",
+			mixer.Sample(buffer, wunit.NewVolume(100, "nl")),
+			mixer.Sample(water, wunit.NewVolume(1, "ul")),
+			mixer.Sample(dye, wunit.NewVolume(10, "nl"))),
+	}
+
+	p := newPlan(opt.withDefaults())
+	if err := p.Run(ctx, mixes); err != nil {
+		t.Fatal(err)
+	}
+
+	// Transfers are grouped by source plate and rounded to 2.5 nl droplets
+	checkPicklist(t, p, []string{
+		"source_2,greiner384,A1,destination_1,pcrplate_skirted,A1,1000,1000,0,water",
+		"source_2,greiner384,B1,destination_1,pcrplate_skirted,A1,25,26,-1,dye",
+		"source_2,greiner384,A1,destination_1,pcrplate_skirted,B1,1000,1000,0,water",
+		"source_2,greiner384,B1,destination_1,pcrplate_skirted,B1,10,10,0,dye",
+		"buffers,greiner384,B2,destination_1,pcrplate_skirted,A1,100,100,0,buffer",
+		"buffers,greiner384,B2,destination_1,pcrplate_skirted,B1,100,100,0,buffer",
+	})
+
+	if srcs, dsts := p.PlateSwaps(); srcs != 2 || dsts != 1 {
+		t.Errorf("expecting 2 source and 1 destination plate loads but got %d and %d", srcs, dsts)
+	}
+	if n := p.Droplets(); n != 894 {
+		t.Errorf("expecting 894 droplets but got %d", n)
+	}
+	if !strings.Contains(p.Summary(), "total rounding error -1 nl") {
+		t.Errorf("expecting rounding error in summary:\n%s", p.Summary())
+	}
+
+	// Loaded sources hold what is drawn plus the dead volume
+	src, _ := p.Initial.Plates["source_2"].WellAtString("A1")
+	if v := src.CurrentVolume(); !v.EqualTo(wunit.NewVolume(22, "ul")) {
+		t.Errorf("expecting 22 ul of water to be loaded but got %s", v)
+	}
+
+	// Mixes are found in their final wells
+	dst, _ := p.Final.Plates["destination_1"].WellAtString("A1")
+	if v := dst.CurrentVolume(); !v.EqualTo(wunit.NewVolume(1.125, "ul")) {
+		t.Errorf("expecting 1.125 ul in A1 but got %s", v)
+	}
+	if !dst.Contents().HasParent(mixes[0].Outputs[0].ID) {
+		t.Errorf("expecting A1 to hold result of first mix")
+	}
+	if loc := mixes[1].Outputs[0].WellLocation(); loc != "B1" {
+		t.Errorf("expecting result of second mix in B1 but got %s", loc)
+	}
+}
+
+func TestWorkingVolume(t *testing.T) {
+	ctx := testinventory.NewContext(context.Background())
+
+	input, err := inventory.NewPlate(ctx, "greiner384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := makeLiquid("buffer")
+	buffer.Vol = 21
+	if err := input.Wellcoords["A1"].SetContents(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	opt := makeOpt()
+	opt.InputPlates = []*wtype.Plate{input}
+	mixes := []*wtype.LHInstruction{
+		makeMix("A1", mixer.Sample(buffer, wunit.NewVolume(0.5, "ul"))),
+		makeMix("A2", mixer.Sample(buffer, wunit.NewVolume(0.5, "ul"))),
+		makeMix("A3", mixer.Sample(buffer, wunit.NewVolume(0.5, "ul"))),
+	}
+
+	if err := newPlan(opt.withDefaults()).Run(ctx, mixes); err == nil {
+		t.Error("expecting error drawing below minimum working volume")
+	}
+
+	// Mixes cannot be sources when their plate type has no working volume
+	water := makeLiquid("water")
+	first := makeMix("A1", mixer.Sample(water, wunit.NewVolume(1, "ul")))
+	first.Outputs[0].CName = "diluted water"
+	second := makeMix("A2", mixer.Sample(first.Outputs[0], wunit.NewVolume(100, "nl")))
+	if err := newPlan(makeOpt().withDefaults()).Run(ctx, []*wtype.LHInstruction{second, first}); err == nil {
+		t.Error("expecting error dispensing from output plate")
+	}
+}
+
+func TestCanMix(t *testing.T) {
+	opt := makeOpt()
+	opt.PlateTypes = []string{"pcrplate_skirted"}
+	d, err := New(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	water := makeLiquid("water")
+	if err := d.CanMix(makeMix("A1", mixer.Sample(water, wunit.NewVolume(10, "nl")))); err != nil {
+		t.Error(err)
+	}
+	if err := d.CanMix(makeMix("A1", mixer.Sample(water, wunit.NewVolume(1, "nl")))); err == nil {
+		t.Error("expecting error for volume less than a droplet")
+	}
+	m := makeMix("A1", mixer.Sample(water, wunit.NewVolume(10, "nl")))
+	m.Platetype = "greiner384"
+	if err := d.CanMix(m); err == nil {
+		t.Error("expecting error for unsupported plate type")
+	}
+
+	opt.SourcePlateType = "pcrplate_skirted"
+	if _, err := New(opt); err == nil {
+		t.Error("expecting error for source plate type without working volume")
+	}
+}
+
+func TestCompile(t *testing.T) {
+	ctx := testinventory.NewContext(context.Background())
+	d, err := New(makeOpt())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.CanCompile(ast.Request{Selector: []ast.NameValue{target.DriverSelectorV1Mixer}}) {
+		t.Error("expecting dispenser not to compile mixes that do not request acoustic dispensing")
+	}
+	if d.CanCompile(ast.Request{Selector: []ast.NameValue{target.DriverSelectorV1AcousticDispenser, target.DriverSelectorV1Prompter}}) {
+		t.Error("expecting dispenser not to compile prompts")
+	}
+	req := ast.Request{Selector: []ast.NameValue{target.DriverSelectorV1Mixer, target.DriverSelectorV1AcousticDispenser}}
+	if !d.CanCompile(req) {
+		t.Fatal("expecting dispenser to compile acoustic mixes")
+	}
+
+	water := makeLiquid("water")
+	insts, err := d.Compile(ctx, []ast.Node{
+		&ast.Command{
+			Requests: []ast.Request{req},
+			Inst:     makeMix("A1", mixer.Sample(water, wunit.NewVolume(1, "ul"))),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(insts) != 1 {
+		t.Fatalf("expecting 1 instruction but got %d", len(insts))
+	}
+	mix, ok := insts[0].(*target.Mix)
+	if !ok {
+		t.Fatalf("expecting mix but got %T", insts[0])
+	}
+	if mix.Files.Type != "application/labcyte" || len(mix.Files.Tarball) == 0 {
+		t.Errorf("expecting picklist but got %q of %d bytes", mix.Files.Type, len(mix.Files.Tarball))
+	}
+	// 2 plate loads, 1 transfer and 400 droplets
+	if est := mix.GetTimeEstimate(); est != 41.5 {
+		t.Errorf("expecting time estimate of 41.5s but got %gs", est)
+	}
+	if len(mix.Request.InputPlates) != 1 || len(mix.Request.OutputPlates) != 1 {
+		t.Errorf("expecting 1 input and 1 output plate but got %d and %d", len(mix.Request.InputPlates), len(mix.Request.OutputPlates))
+	}
+}
@@ -0,0 +1,76 @@
+package acoustic
+
+import (
+	"time"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+)
+
+var (
+	// DefaultOpt is the default Dispenser Opt
+	DefaultOpt = Opt{
+		DropletVolume:     wunit.NewVolume(2.5, "nl"),
+		DropletsPerSecond: 400,
+		TransferTime:      500 * time.Millisecond,
+		PlateSwapTime:     20 * time.Second,
+	}
+)
+
+// A WorkingVolume is the range of volumes a source well can be dispensed
+// from. Below Min, the transducer can no longer form droplets; above Max,
+// the meniscus is out of focus.
+type WorkingVolume struct {
+	Min wunit.Volume `json:"min"`
+	Max wunit.Volume `json:"max"`
+}
+
+// Opt are options for a Dispenser
+type Opt struct {
+	Model        string `json:"model,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+
+	// Volume of a single droplet. Every transfer is rounded to a whole
+	// number of droplets.
+	DropletVolume wunit.Volume `json:"dropletVolume"`
+
+	// Working volumes of the plate types the device can dispense from.
+	// Liquids on any other plate type cannot be sources.
+	SourcePlates map[string]WorkingVolume `json:"sourcePlates"`
+
+	// Plate type to load liquids that are not already on a plate
+	SourcePlateType string `json:"sourcePlateType"`
+
+	// Plate type for mixes that do not request one
+	OutputPlateType string `json:"outputPlateType,omitempty"`
+
+	// Destination plate types that the device can hold. If empty, any plate
+	// type is allowed.
+	PlateTypes []string `json:"plateTypes,omitempty"`
+
+	// Plates holding liquids before the dispense
+	InputPlates []*wtype.Plate `json:"inputPlates,omitempty"`
+
+	// Timings used to estimate the duration of a dispense
+	DropletsPerSecond float64       `json:"dropletsPerSecond,omitempty"`
+	TransferTime      time.Duration `json:"transferTime,omitempty"`  // Per transfer overhead
+	PlateSwapTime     time.Duration `json:"plateSwapTime,omitempty"` // Per plate loaded
+}
+
+// withDefaults returns a copy of the Opt with unset values taken from
+// DefaultOpt
+func (a Opt) withDefaults() Opt {
+	if a.DropletVolume.IsZero() {
+		a.DropletVolume = DefaultOpt.DropletVolume
+	}
+	if a.DropletsPerSecond <= 0 {
+		a.DropletsPerSecond = DefaultOpt.DropletsPerSecond
+	}
+	if a.TransferTime <= 0 {
+		a.TransferTime = DefaultOpt.TransferTime
+	}
+	if a.PlateSwapTime <= 0 {
+		a.PlateSwapTime = DefaultOpt.PlateSwapTime
+	}
+	return a
+}
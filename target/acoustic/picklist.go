@@ -0,0 +1,114 @@
+package acoustic
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var picklistHeader = []string{
+	"Source Plate Name",
+	"Source Plate Type",
+	"Source Well",
+	"Destination Plate Name",
+	"Destination Plate Type",
+	"Destination Well",
+	"Transfer Volume",
+	"Requested Volume",
+	"Rounding Error",
+	"Sample Name",
+}
+
+// formatVolume formats a volume in nl to the picolitre
+func formatVolume(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+// Picklist returns the transfers of the plan as CSV. Volumes are in nl.
+func (a *plan) Picklist() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(picklistHeader); err != nil {
+		return nil, err
+	}
+	for _, t := range a.transfers {
+		if err := w.Write([]string{
+			t.Source.Plate.PlateName,
+			t.Source.Plate.Type,
+			t.Source.Well,
+			t.Dest.Plate.PlateName,
+			t.Dest.Plate.Type,
+			t.Dest.Well,
+			formatVolume(t.Volume),
+			formatVolume(t.Requested),
+			formatVolume(t.RoundingError()),
+			t.Component.CName,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// PlateSwaps returns the number of times a source and a destination plate
+// are loaded
+func (a *plan) PlateSwaps() (sources, destinations int) {
+	for i, t := range a.transfers {
+		if i == 0 || a.transfers[i-1].Source.Plate != t.Source.Plate {
+			sources++
+		}
+		if i == 0 || a.transfers[i-1].Dest.Plate != t.Dest.Plate {
+			destinations++
+		}
+	}
+	return
+}
+
+// Droplets returns the total number of droplets dispensed
+func (a *plan) Droplets() (n int) {
+	for _, t := range a.transfers {
+		n += t.Droplets
+	}
+	return
+}
+
+// TimeEstimate returns the expected duration of the dispense
+func (a *plan) TimeEstimate() time.Duration {
+	srcs, dsts := a.PlateSwaps()
+	est := time.Duration(srcs+dsts) * a.opt.PlateSwapTime
+	est += time.Duration(len(a.transfers)) * a.opt.TransferTime
+	est += time.Duration(float64(a.Droplets()) / a.opt.DropletsPerSecond * float64(time.Second))
+	return est
+}
+
+// Summary returns a human readable description of the plan including the
+// error introduced by rounding to whole droplets
+func (a *plan) Summary() string {
+	var total float64
+	var worst *transfer
+	for _, t := range a.transfers {
+		total += t.RoundingError()
+		if worst == nil || math.Abs(t.RoundingError()) > math.Abs(worst.RoundingError()) {
+			worst = t
+		}
+	}
+	srcs, dsts := a.PlateSwaps()
+
+	var lines []string
+	lines = append(lines,
+		fmt.Sprintf("%d transfers of %d droplets of %s nl", len(a.transfers), a.Droplets(), formatVolume(a.droplet)),
+		fmt.Sprintf("source plates loaded %d times, destination plates loaded %d times", srcs, dsts),
+		fmt.Sprintf("total rounding error %s nl", formatVolume(total)),
+	)
+	if worst != nil && worst.RoundingError() != 0 {
+		lines = append(lines, fmt.Sprintf("largest rounding error %s nl of %s to well %s of plate %s",
+			formatVolume(worst.RoundingError()), worst.Component.CName, worst.Dest.Well, worst.Dest.Plate.PlateName))
+	}
+	lines = append(lines, fmt.Sprintf("estimated time %s", a.TimeEstimate()))
+	return strings.Join(lines, "\n") + "\n"
+}
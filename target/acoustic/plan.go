@@ -0,0 +1,542 @@
+package acoustic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/antha/anthalib/wunit"
+	"github.com/antha-lang/antha/inventory"
+	driver "github.com/antha-lang/antha/microArch/driver/liquidhandling"
+)
+
+// Tolerance in nl when comparing volumes
+const epsilon = 1e-6
+
+// A location is a well on a plate
+type location struct {
+	Plate *wtype.Plate
+	Well  string
+}
+
+// A transfer moves a whole number of droplets of a component from one well
+// to another
+type transfer struct {
+	Component *wtype.Liquid
+	Source    location
+	Dest      location
+	Requested float64 // in nl
+	Droplets  int
+	Volume    float64 // in nl; Droplets times the droplet volume
+	// Transfers only depend on transfers at lower levels
+	Level int
+}
+
+// RoundingError returns the difference between the dispensed and the
+// requested volume in nl
+func (a *transfer) RoundingError() float64 {
+	return a.Volume - a.Requested
+}
+
+// A result is where a mix is made
+type result struct {
+	Location location
+	Level    int
+}
+
+// A plan turns mixes into transfers between wells
+type plan struct {
+	opt     Opt
+	droplet float64 // in nl
+
+	Sources      []*wtype.Plate // Plates dispensed from
+	Destinations []*wtype.Plate // Plates dispensed to
+	Initial      *driver.LHProperties
+	Final        *driver.LHProperties
+
+	transfers  []*transfer
+	plates     map[string]*wtype.Plate // All known plates by id
+	registered map[*wtype.Plate]bool   // Plates in Sources or Destinations
+	inputs     map[string]location     // Liquids on input plates by id
+	results    map[string]result       // Mix results by liquid id
+	used       map[location]bool       // Destination wells
+	shared     map[string]*wtype.Plate // Plates for mixes without a plate by type
+	pending    map[string][]*transfer  // Transfers of liquids not yet on a plate
+	pendingIDs []string                // ... in order of first use
+}
+
+func newPlan(opt Opt) *plan {
+	return &plan{
+		opt:        opt,
+		droplet:    nanolitres(opt.DropletVolume),
+		plates:     make(map[string]*wtype.Plate),
+		registered: make(map[*wtype.Plate]bool),
+		inputs:     make(map[string]location),
+		results:    make(map[string]result),
+		used:       make(map[location]bool),
+		shared:     make(map[string]*wtype.Plate),
+		pending:    make(map[string][]*transfer),
+	}
+}
+
+// nanolitres returns a volume in nl
+func nanolitres(v wunit.Volume) float64 {
+	if v.IsZero() {
+		return 0
+	}
+	return v.ConvertToString("nl")
+}
+
+// quantise rounds a volume in nl to the nearest whole number of droplets
+func quantise(v, droplet float64) (int, float64) {
+	n := int(math.Floor(v/droplet + 0.5))
+	return n, float64(n) * droplet
+}
+
+// outputPlateType returns the plate type a mix is made in
+func outputPlateType(mix *wtype.LHInstruction, opt Opt) string {
+	if mix.OutPlate != nil {
+		return mix.OutPlate.Type
+	} else if len(mix.Platetype) != 0 {
+		return mix.Platetype
+	}
+	return opt.OutputPlateType
+}
+
+// mixVolumes returns the volume in nl of each input of a mix. A component
+// made up to a total volume gets the volume remaining after the other
+// components.
+func mixVolumes(mix *wtype.LHInstruction) ([]float64, error) {
+	vols := make([]float64, len(mix.Inputs))
+	topUp := -1
+	var total float64
+	for i, in := range mix.Inputs {
+		switch {
+		case !in.Volume().IsZero():
+			vols[i] = nanolitres(in.Volume())
+			total += vols[i]
+		case !in.TotalVolume().IsZero():
+			if topUp >= 0 {
+				return nil, fmt.Errorf("both %s and %s are made up to a total volume", mix.Inputs[topUp].CName, in.CName)
+			}
+			topUp = i
+		case i == 0 && mix.IsMixInPlace():
+		default:
+			return nil, fmt.Errorf("no volume for %s", in.CName)
+		}
+	}
+
+	if topUp >= 0 {
+		in := mix.Inputs[topUp]
+		v := nanolitres(in.TotalVolume()) - total
+		if v <= 0 {
+			return nil, fmt.Errorf("cannot make %s up to %s as other components are %g nl", in.CName, in.TotalVolume(), total)
+		}
+		vols[topUp] = v
+	}
+
+	return vols, nil
+}
+
+// orderMixes returns mixes in an order where each mix comes after the mixes
+// that make its inputs
+func orderMixes(mixes []*wtype.LHInstruction) ([]*wtype.LHInstruction, error) {
+	producer := make(map[string]*wtype.LHInstruction)
+	for _, m := range mixes {
+		for _, out := range m.Outputs {
+			producer[out.ID] = m
+		}
+	}
+
+	var ret []*wtype.LHInstruction
+	done := make(map[*wtype.LHInstruction]bool)
+	visiting := make(map[*wtype.LHInstruction]bool)
+	var visit func(m *wtype.LHInstruction) error
+	visit = func(m *wtype.LHInstruction) error {
+		if done[m] {
+			return nil
+		} else if visiting[m] {
+			return fmt.Errorf("cycle between mixes at %s", m)
+		}
+		visiting[m] = true
+		for _, in := range m.Inputs {
+			for _, id := range []string{in.ID, in.ParentID} {
+				if p, ok := producer[id]; ok && p != m {
+					if err := visit(p); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		done[m] = true
+		ret = append(ret, m)
+		return nil
+	}
+
+	for _, m := range mixes {
+		if err := visit(m); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// register adds a plate to the sources or destinations of the plan unless
+// it is already in one of them
+func (a *plan) register(plate *wtype.Plate, source bool) {
+	if a.registered[plate] {
+		return
+	}
+	a.registered[plate] = true
+	a.plates[plate.ID] = plate
+	if source {
+		a.Sources = append(a.Sources, plate)
+		if len(plate.PlateName) == 0 {
+			plate.SetName(fmt.Sprintf("source_%d", len(a.Sources)))
+		}
+	} else {
+		a.Destinations = append(a.Destinations, plate)
+		if len(plate.PlateName) == 0 {
+			plate.SetName(fmt.Sprintf("destination_%d", len(a.Destinations)))
+		}
+	}
+}
+
+// find returns where the liquid with the given id is
+func (a *plan) find(id string) (location, int, bool) {
+	if r, ok := a.results[id]; ok {
+		return r.Location, r.Level, true
+	} else if loc, ok := a.inputs[id]; ok {
+		a.register(loc.Plate, true)
+		return loc, 0, true
+	}
+	return location{}, 0, false
+}
+
+// freeWell returns the first well in column order of a plate that is empty
+// and not already a destination
+func (a *plan) freeWell(plate *wtype.Plate) string {
+	for _, addr := range plate.AllWellPositions(wtype.BYCOLUMN) {
+		well, ok := plate.WellAtString(addr)
+		if ok && well.IsEmpty() && !a.used[location{Plate: plate, Well: addr}] {
+			return addr
+		}
+	}
+	return ""
+}
+
+// destination returns where a mix should be made
+func (a *plan) destination(ctx context.Context, mix *wtype.LHInstruction) (location, error) {
+	typ := outputPlateType(mix, a.opt)
+	newPlate := func() (*wtype.Plate, error) {
+		p, err := inventory.NewPlate(ctx, typ)
+		if err != nil {
+			return nil, err
+		}
+		// Plates without a name are named when they are registered
+		p.SetName(mix.PlateName)
+		return p, nil
+	}
+
+	var plate *wtype.Plate
+	switch {
+	case mix.OutPlate != nil:
+		if plate = a.plates[mix.OutPlate.ID]; plate == nil {
+			plate = mix.OutPlate.DupKeepIDs()
+		}
+	case len(mix.PlateID) != 0:
+		if plate = a.plates[mix.PlateID]; plate == nil {
+			p, err := newPlate()
+			if err != nil {
+				return location{}, err
+			}
+			p.ID = mix.PlateID
+			plate = p
+		}
+	default:
+		plate = a.shared[typ]
+		if plate == nil || (len(mix.Welladdress) == 0 && len(a.freeWell(plate)) == 0) {
+			p, err := newPlate()
+			if err != nil {
+				return location{}, err
+			}
+			a.shared[typ] = p
+			plate = p
+		}
+	}
+	a.register(plate, false)
+
+	addr := mix.Welladdress
+	if len(addr) == 0 {
+		if addr = a.freeWell(plate); len(addr) == 0 {
+			return location{}, fmt.Errorf("no free wells on plate %s", plate.PlateName)
+		}
+	}
+	well, ok := plate.WellAtString(addr)
+	if !ok {
+		return location{}, fmt.Errorf("no well %s on plate %s", addr, plate.PlateName)
+	}
+
+	loc := location{Plate: plate, Well: well.Crds.FormatA1()}
+	a.used[loc] = true
+	return loc, nil
+}
+
+// addMix adds the transfers to make a mix
+func (a *plan) addMix(ctx context.Context, mix *wtype.LHInstruction) error {
+	vols, err := mixVolumes(mix)
+	if err != nil {
+		return err
+	}
+
+	inputs := mix.Inputs
+	level := 1
+	var dest location
+	if mix.IsMixInPlace() {
+		loc, l, ok := a.find(inputs[0].ID)
+		if !ok {
+			return fmt.Errorf("cannot find %s to mix in place", inputs[0].CName)
+		}
+		dest, level = loc, l+1
+		inputs, vols = inputs[1:], vols[1:]
+	} else if dest, err = a.destination(ctx, mix); err != nil {
+		return err
+	}
+
+	var ts []*transfer
+	for i, in := range inputs {
+		t := &transfer{
+			Component: in,
+			Dest:      dest,
+			Requested: vols[i],
+		}
+		t.Droplets, t.Volume = quantise(vols[i], a.droplet)
+		if t.Droplets == 0 {
+			return fmt.Errorf("volume %g nl of %s is less than half a droplet", vols[i], in.CName)
+		}
+
+		if loc, l, ok := a.find(in.ParentID); ok {
+			t.Source = loc
+			if l+1 > level {
+				level = l + 1
+			}
+		} else {
+			key := in.ParentID
+			if len(key) == 0 {
+				key = in.ID
+			}
+			if _, seen := a.pending[key]; !seen {
+				a.pendingIDs = append(a.pendingIDs, key)
+			}
+			a.pending[key] = append(a.pending[key], t)
+		}
+		ts = append(ts, t)
+	}
+
+	for _, t := range ts {
+		t.Level = level
+	}
+	a.transfers = append(a.transfers, ts...)
+
+	for _, out := range mix.Outputs {
+		a.results[out.ID] = result{Location: dest, Level: level}
+		out.Loc = dest.Plate.ID + ":" + dest.Well
+	}
+
+	return nil
+}
+
+// allocateSources loads liquids that are not already on a plate into wells
+// of source plates. Each well holds at most its working volume plus its dead
+// volume.
+func (a *plan) allocateSources(ctx context.Context) error {
+	if len(a.pendingIDs) == 0 {
+		return nil
+	}
+
+	typ := a.opt.SourcePlateType
+	if len(typ) == 0 {
+		var names []string
+		for _, id := range a.pendingIDs {
+			names = append(names, a.pending[id][0].Component.CName)
+		}
+		return fmt.Errorf("no source plate type to load %s", strings.Join(names, ", "))
+	}
+	wv := a.opt.SourcePlates[typ]
+	min := nanolitres(wv.Min)
+	capacity := nanolitres(wv.Max) - min
+
+	var plate *wtype.Plate
+	var free []string
+	nextWell := func() (*wtype.LHWell, location, error) {
+		if len(free) == 0 {
+			p, err := inventory.NewPlate(ctx, typ)
+			if err != nil {
+				return nil, location{}, err
+			}
+			p.SetName("")
+			a.register(p, true)
+			plate = p
+			free = p.AllWellPositions(wtype.BYCOLUMN)
+		}
+		addr := free[0]
+		free = free[1:]
+		well, _ := plate.WellAtString(addr)
+		return well, location{Plate: plate, Well: addr}, nil
+	}
+
+	fill := func(well *wtype.LHWell, sample *wtype.Liquid, v float64) error {
+		l := sample.Dup()
+		l.ID = wtype.GetUUID()
+		l.SetSample(false)
+		l.Vol = v / 1000.0
+		l.Vunit = "ul"
+		l.Tvol = 0
+		return well.SetContents(l)
+	}
+
+	for _, id := range a.pendingIDs {
+		var well *wtype.LHWell
+		var loc location
+		var drawn float64
+		for _, t := range a.pending[id] {
+			if t.Volume > capacity+epsilon {
+				return fmt.Errorf("%g nl of %s is more than the working volume of a %s well", t.Volume, t.Component.CName, typ)
+			}
+			if well == nil || drawn+t.Volume > capacity+epsilon {
+				if well != nil {
+					if err := fill(well, t.Component, drawn+min); err != nil {
+						return err
+					}
+				}
+				w, l, err := nextWell()
+				if err != nil {
+					return err
+				}
+				well, loc, drawn = w, l, 0
+			}
+			drawn += t.Volume
+			t.Source = loc
+		}
+		if err := fill(well, a.pending[id][0].Component, drawn+min); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortTransfers groups transfers by source plate and then destination plate
+// to minimise plate swaps without moving a transfer before the transfers it
+// depends on
+func (a *plan) sortTransfers() {
+	srcRank := make(map[*wtype.Plate]int)
+	dstRank := make(map[*wtype.Plate]int)
+	for _, t := range a.transfers {
+		if _, seen := srcRank[t.Source.Plate]; !seen {
+			srcRank[t.Source.Plate] = len(srcRank)
+		}
+		if _, seen := dstRank[t.Dest.Plate]; !seen {
+			dstRank[t.Dest.Plate] = len(dstRank)
+		}
+	}
+
+	sort.SliceStable(a.transfers, func(i, j int) bool {
+		ti, tj := a.transfers[i], a.transfers[j]
+		if ti.Level != tj.Level {
+			return ti.Level < tj.Level
+		} else if si, sj := srcRank[ti.Source.Plate], srcRank[tj.Source.Plate]; si != sj {
+			return si < sj
+		}
+		return dstRank[ti.Dest.Plate] < dstRank[tj.Dest.Plate]
+	})
+}
+
+// simulate executes transfers on the plates of the plan, checking that every
+// source well stays within the working volume of its plate type
+func (a *plan) simulate() error {
+	checked := make(map[location]bool)
+	for _, t := range a.transfers {
+		src, _ := t.Source.Plate.WellAtString(t.Source.Well)
+		dst, _ := t.Dest.Plate.WellAtString(t.Dest.Well)
+
+		wv, ok := a.opt.SourcePlates[t.Source.Plate.Type]
+		if !ok {
+			return fmt.Errorf("cannot dispense %s from plate type %q", t.Component.CName, t.Source.Plate.Type)
+		}
+		if !checked[t.Source] {
+			checked[t.Source] = true
+			if v := nanolitres(src.CurrentVolume()); v > nanolitres(wv.Max)+epsilon {
+				return fmt.Errorf("well %s of plate %s holds %g nl which is more than the maximum working volume %s", t.Source.Well, t.Source.Plate.PlateName, v, wv.Max)
+			}
+		}
+
+		v := wunit.NewVolume(t.Volume, "nl")
+		l := src.Contents().Dup()
+		l.ID = wtype.GetUUID()
+		l.Vol = v.ConvertToString(l.Vunit)
+		src.Contents().Remove(v)
+		if left := nanolitres(src.CurrentVolume()); left < nanolitres(wv.Min)-epsilon {
+			return fmt.Errorf("dispensing %g nl of %s would leave %g nl in well %s of plate %s which is less than the minimum working volume %s", t.Volume, t.Component.CName, left, t.Source.Well, t.Source.Plate.PlateName, wv.Min)
+		}
+
+		if err := dst.AddComponent(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run plans the transfers for a set of mixes
+func (a *plan) Run(ctx context.Context, mixes []*wtype.LHInstruction) error {
+	for _, plate := range a.opt.InputPlates {
+		p := plate.DupKeepIDs()
+		a.plates[p.ID] = p
+		for addr, well := range p.Wellcoords {
+			if !well.IsEmpty() {
+				a.inputs[well.WContents.ID] = location{Plate: p, Well: addr}
+			}
+		}
+	}
+
+	ordered, err := orderMixes(mixes)
+	if err != nil {
+		return err
+	}
+	for _, m := range ordered {
+		if err := a.addMix(ctx, m); err != nil {
+			return err
+		}
+	}
+	if err := a.allocateSources(ctx); err != nil {
+		return err
+	}
+	a.sortTransfers()
+
+	dupAll := func(plates []*wtype.Plate) (ret []*wtype.Plate) {
+		for _, p := range plates {
+			ret = append(ret, p.DupKeepIDs())
+		}
+		return
+	}
+	if a.Initial, err = newProperties(a.opt, dupAll(a.Sources), dupAll(a.Destinations)); err != nil {
+		return err
+	}
+
+	if err := a.simulate(); err != nil {
+		return err
+	}
+	for _, m := range ordered {
+		for _, out := range m.Outputs {
+			loc := a.results[out.ID].Location
+			well, _ := loc.Plate.WellAtString(loc.Well)
+			well.Contents().AddParentComponent(out)
+		}
+	}
+
+	a.Final, err = newProperties(a.opt, a.Sources, a.Destinations)
+	return err
+}
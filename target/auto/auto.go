@@ -9,6 +9,7 @@ import (
 	"github.com/antha-lang/antha/ast"
 	runner "github.com/antha-lang/antha/driver/antha_runner_v1"
 	"github.com/antha-lang/antha/target"
+	"github.com/antha-lang/antha/target/acoustic"
 	"github.com/antha-lang/antha/target/human"
 	"google.golang.org/grpc"
 )
@@ -27,6 +28,9 @@ type Endpoint struct {
 type Opt struct {
 	Endpoints []Endpoint
 	MaybeArgs []interface{}
+	// If not nil, add an acoustic dispenser that needs no driver; its
+	// picklists are run by a runner for its file type
+	AcousticDispenser *acoustic.Opt
 }
 
 // An Auto contains the state of autodiscovery of device plugins
@@ -86,6 +90,14 @@ func New(opt Opt) (ret *Auto, err error) {
 		}
 	}
 
+	if opt.AcousticDispenser != nil {
+		var d *acoustic.Dispenser
+		if d, err = acoustic.New(*opt.AcousticDispenser); err != nil {
+			return
+		}
+		ret.Target.AddDevice(d)
+	}
+
 	ret.Target.AddDevice(human.New(tryer.HumanOpt))
 
 	return
@@ -0,0 +1,291 @@
+package auto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/antha-lang/antha/ast"
+	runner "github.com/antha-lang/antha/driver/antha_runner_v1"
+)
+
+// ErrAborted is returned by Engine.Run when execution is aborted
+var ErrAborted = errors.New("execution aborted")
+
+// An EventKind is the kind of an Event
+type EventKind int
+
+// Kinds of events
+const (
+	InstStarted EventKind = iota
+	InstFinished
+	InstFailed
+	InstSkipped
+	InstMessage
+)
+
+func (a EventKind) String() string {
+	switch a {
+	case InstStarted:
+		return "started"
+	case InstFinished:
+		return "finished"
+	case InstFailed:
+		return "failed"
+	case InstSkipped:
+		return "skipped"
+	case InstMessage:
+		return "message"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(a))
+	}
+}
+
+// An Event reports the progress of an instruction
+type Event struct {
+	Kind    EventKind
+	Inst    ast.Inst
+	Device  ast.Device
+	Elapsed time.Duration                 // Time since the instruction started
+	Message *runner.MessagesReply_Message // For InstMessage events
+	Err     error                         // For InstFailed events
+}
+
+// A Sink receives events. Calls to a sink are never concurrent.
+type Sink func(Event)
+
+// EngineOpt are options for NewEngine
+type EngineOpt struct {
+	// Instructions to execute in an order consistent with their dependencies
+	Insts []ast.Inst
+	// Receives progress events if not nil
+	Sink Sink
+	// If not nil, called before executing each instruction. Instructions
+	// that are not confirmed are skipped but instructions that depend on
+	// them still run. Calls are never concurrent.
+	Confirm func(ctx context.Context, inst ast.Inst) (bool, error)
+	// Interval between requests for messages from runners. Defaults to 5s.
+	PollInterval time.Duration
+}
+
+// An Engine executes a DAG of instructions. Instructions run once the
+// instructions they depend on have finished. Instructions on different
+// devices run in parallel but each device runs one instruction at a time.
+type Engine struct {
+	auto *Auto
+	opt  EngineOpt
+
+	lock    sync.Mutex
+	running chan struct{} // Closed unless paused
+	aborted bool
+	cancel  context.CancelFunc
+
+	sinkLock    sync.Mutex
+	confirmLock sync.Mutex
+}
+
+// NewEngine returns an engine that executes instructions on this target
+func (a *Auto) NewEngine(opt EngineOpt) *Engine {
+	if opt.PollInterval == 0 {
+		opt.PollInterval = defaultPollInterval
+	}
+	running := make(chan struct{})
+	close(running)
+	return &Engine{
+		auto:    a,
+		opt:     opt,
+		running: running,
+	}
+}
+
+// Pause stops new instructions from starting until Resume is called.
+// Instructions already running are not interrupted.
+func (a *Engine) Pause() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	select {
+	case <-a.running:
+		a.running = make(chan struct{})
+	default:
+	}
+}
+
+// Resume undoes Pause
+func (a *Engine) Resume() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	select {
+	case <-a.running:
+	default:
+		close(a.running)
+	}
+}
+
+// Abort cancels running instructions and stops any further instructions
+// from starting
+func (a *Engine) Abort() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.aborted = true
+	if a.cancel != nil {
+		a.cancel()
+	}
+}
+
+func (a *Engine) emit(e Event) {
+	if a.opt.Sink == nil {
+		return
+	}
+	a.sinkLock.Lock()
+	defer a.sinkLock.Unlock()
+	a.opt.Sink(e)
+}
+
+// waitRunning blocks while the engine is paused
+func (a *Engine) waitRunning(ctx context.Context) error {
+	a.lock.Lock()
+	running := a.running
+	a.lock.Unlock()
+
+	select {
+	case <-running:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *Engine) confirm(ctx context.Context, inst ast.Inst) (bool, error) {
+	if a.opt.Confirm == nil {
+		return true, nil
+	}
+	a.confirmLock.Lock()
+	defer a.confirmLock.Unlock()
+	return a.opt.Confirm(ctx, inst)
+}
+
+// runInst executes a single instruction once it may start
+func (a *Engine) runInst(ctx context.Context, inst ast.Inst, devices map[ast.Device]chan struct{}) error {
+	if err := a.waitRunning(ctx); err != nil {
+		return err
+	}
+
+	dev := inst.Device()
+	if sem := devices[dev]; sem != nil {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if ok, err := a.confirm(ctx, inst); err != nil {
+		return err
+	} else if !ok {
+		a.emit(Event{Kind: InstSkipped, Inst: inst, Device: dev})
+		return nil
+	}
+
+	start := time.Now()
+	a.emit(Event{Kind: InstStarted, Inst: inst, Device: dev})
+
+	err := a.auto.execute(ctx, inst, a.opt.PollInterval, func(m *runner.MessagesReply_Message) {
+		a.emit(Event{Kind: InstMessage, Inst: inst, Device: dev, Elapsed: time.Since(start), Message: m})
+	})
+	if err != nil {
+		a.emit(Event{Kind: InstFailed, Inst: inst, Device: dev, Elapsed: time.Since(start), Err: err})
+		return err
+	}
+
+	a.emit(Event{Kind: InstFinished, Inst: inst, Device: dev, Elapsed: time.Since(start)})
+	return nil
+}
+
+// Run executes the instructions and returns the first error, if any. After
+// an error, running instructions are cancelled and no more are started.
+func (a *Engine) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	a.lock.Lock()
+	a.cancel = cancel
+	aborted := a.aborted
+	a.lock.Unlock()
+	if aborted {
+		return ErrAborted
+	}
+
+	// failed is written before done is closed and only read after
+	type status struct {
+		done   chan struct{}
+		failed bool
+	}
+	states := make(map[ast.Inst]*status)
+	devices := make(map[ast.Device]chan struct{})
+	for _, inst := range a.opt.Insts {
+		states[inst] = &status{done: make(chan struct{})}
+		if dev := inst.Device(); dev != nil && devices[dev] == nil {
+			devices[dev] = make(chan struct{}, 1)
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+	)
+
+	for _, inst := range a.opt.Insts {
+		wg.Add(1)
+		go func(inst ast.Inst) {
+			defer wg.Done()
+
+			s := states[inst]
+			err := func() error {
+				for _, dep := range inst.DependsOn() {
+					ds, ok := states[dep]
+					if !ok {
+						continue
+					}
+					select {
+					case <-ds.done:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+					if ds.failed {
+						return context.Canceled
+					}
+				}
+				return a.runInst(ctx, inst, devices)
+			}()
+
+			if err != nil {
+				s.failed = true
+				errLock.Lock()
+				if firstErr == nil && err != context.Canceled {
+					firstErr = err
+				}
+				errLock.Unlock()
+				cancel()
+			}
+			close(s.done)
+		}(inst)
+	}
+
+	wg.Wait()
+
+	a.lock.Lock()
+	aborted = a.aborted
+	a.lock.Unlock()
+
+	switch {
+	case aborted:
+		return ErrAborted
+	case firstErr != nil:
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,229 @@
+package auto
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antha-lang/antha/ast"
+	driverpkg "github.com/antha-lang/antha/driver"
+	driver "github.com/antha-lang/antha/driver/antha_driver_v1"
+	runner "github.com/antha-lang/antha/driver/antha_runner_v1"
+	"github.com/antha-lang/antha/driver/stub"
+	"github.com/antha-lang/antha/target"
+	"github.com/antha-lang/antha/target/acoustic"
+)
+
+type stubTarget struct {
+	Auto   *Auto
+	Runner *stub.Runner
+	Driver *stub.Driver
+	Device ast.Device // Device of Driver
+	close  func()
+}
+
+func newStubTarget(t *testing.T, msgs ...*runner.MessagesReply_Message) *stubTarget {
+	r := &stub.Runner{Types: []string{"test"}, Replies: msgs}
+	d := &stub.Driver{Type: "test.Reader"}
+
+	rs, err := stub.Serve(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := stub.Serve(d)
+	if err != nil {
+		rs.Close()
+		t.Fatal(err)
+	}
+
+	a, err := New(Opt{
+		Endpoints: []Endpoint{{URI: rs.URI}, {URI: ds.URI}},
+	})
+	if err != nil {
+		rs.Close()
+		ds.Close()
+		t.Fatal(err)
+	}
+
+	var dev ast.Device
+	for d := range a.handler {
+		dev = d
+	}
+
+	return &stubTarget{
+		Auto:   a,
+		Runner: r,
+		Driver: d,
+		Device: dev,
+		close: func() {
+			a.Close() // nolint: errcheck
+			rs.Close()
+			ds.Close()
+		},
+	}
+}
+
+func (a *stubTarget) Close() {
+	a.close()
+}
+
+// makeInsts returns a mix and a run that depends on it
+func (a *stubTarget) makeInsts() (*target.Mix, *target.Run) {
+	mix := &target.Mix{
+		Files: target.Files{Type: "test", Tarball: []byte("protocol")},
+	}
+	run := &target.Run{
+		Dev:   a.Device,
+		Label: "read",
+		Calls: []driverpkg.Call{
+			{
+				Method: "/test.Reader/Read",
+				Args:   &driver.TypeRequest{},
+				Reply:  &driver.TypeReply{},
+			},
+		},
+	}
+	run.SetDependsOn(mix)
+	return mix, run
+}
+
+func TestEngineRun(t *testing.T) {
+	st := newStubTarget(t, &runner.MessagesReply_Message{Code: "info", Data: []byte("aspirating")})
+	defer st.Close()
+
+	mix, run := st.makeInsts()
+
+	var events []Event
+	e := st.Auto.NewEngine(EngineOpt{
+		Insts:        []ast.Inst{mix, run},
+		Sink:         func(e Event) { events = append(events, e) },
+		PollInterval: time.Millisecond,
+	})
+	if err := e.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	type kindInst struct {
+		Kind EventKind
+		Inst ast.Inst
+		Code string
+	}
+	expected := []kindInst{
+		{InstStarted, mix, ""},
+		{InstMessage, mix, "info"},
+		{InstMessage, mix, "stop"},
+		{InstFinished, mix, ""},
+		{InstStarted, run, ""},
+		{InstFinished, run, ""},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expecting %d events but got %d: %v", len(expected), len(events), events)
+	}
+	for i, e := range events {
+		var code string
+		if e.Message != nil {
+			code = e.Message.Code
+		}
+		if g := (kindInst{e.Kind, e.Inst, code}); g != expected[i] {
+			t.Errorf("event %d: expecting %v but got %v", i, expected[i], g)
+		}
+	}
+
+	if runs := st.Runner.Runs(); len(runs) != 1 || string(runs[0].Data) != "protocol" {
+		t.Errorf("unexpected runs %v", runs)
+	}
+	if calls := st.Driver.Calls(); len(calls) != 1 || calls[0] != "/test.Reader/Read" {
+		t.Errorf("unexpected calls %v", calls)
+	}
+}
+
+func TestEngineFailure(t *testing.T) {
+	st := newStubTarget(t, &runner.MessagesReply_Message{Code: "fatal"})
+	defer st.Close()
+
+	mix, run := st.makeInsts()
+
+	var kinds []EventKind
+	e := st.Auto.NewEngine(EngineOpt{
+		Insts: []ast.Inst{mix, run},
+		Sink: func(e Event) {
+			if e.Kind != InstMessage {
+				kinds = append(kinds, e.Kind)
+			}
+		},
+		PollInterval: time.Millisecond,
+	})
+	if err := e.Run(context.Background()); err == nil {
+		t.Fatal("expecting error")
+	}
+
+	if len(kinds) != 2 || kinds[0] != InstStarted || kinds[1] != InstFailed {
+		t.Errorf("expecting mix to start and fail but got %v", kinds)
+	}
+	if calls := st.Driver.Calls(); len(calls) != 0 {
+		t.Errorf("expecting no calls after failure but got %v", calls)
+	}
+}
+
+func TestEnginePauseAbort(t *testing.T) {
+	st := newStubTarget(t)
+	defer st.Close()
+
+	mix, run := st.makeInsts()
+
+	started := 0
+	e := st.Auto.NewEngine(EngineOpt{
+		Insts: []ast.Inst{mix, run},
+		Sink: func(e Event) {
+			if e.Kind == InstStarted {
+				started++
+			}
+		},
+		PollInterval: time.Millisecond,
+	})
+
+	e.Pause()
+	errs := make(chan error)
+	go func() {
+		errs <- e.Run(context.Background())
+	}()
+
+	select {
+	case err := <-errs:
+		t.Fatalf("expecting paused engine to wait but got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	e.Abort()
+	if err := <-errs; err != ErrAborted {
+		t.Errorf("expecting %s but got %v", ErrAborted, err)
+	}
+	if started != 0 {
+		t.Errorf("expecting no instructions to start but %d did", started)
+	}
+}
+
+func TestAcousticDispenser(t *testing.T) {
+	a, err := New(Opt{AcousticDispenser: &acoustic.Opt{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close() // nolint: errcheck
+
+	acousticMix := ast.Request{Selector: []ast.NameValue{target.DriverSelectorV1Mixer, target.DriverSelectorV1AcousticDispenser}}
+	found := false
+	for _, d := range a.Target.CanCompile(acousticMix) {
+		_, ok := d.(*acoustic.Dispenser)
+		found = found || ok
+	}
+	if !found {
+		t.Error("expecting acoustic dispenser to compile acoustic mixes")
+	}
+
+	mix := ast.Request{Selector: []ast.NameValue{target.DriverSelectorV1Mixer}}
+	for _, d := range a.Target.CanCompile(mix) {
+		if _, ok := d.(*acoustic.Dispenser); ok {
+			t.Error("expecting acoustic dispenser not to compile other mixes")
+		}
+	}
+}
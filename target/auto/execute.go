@@ -12,11 +12,21 @@ import (
 	"google.golang.org/grpc"
 )
 
+// defaultPollInterval is the interval between requests for messages from
+// runners
+const defaultPollInterval = 5 * time.Second
+
 // Execute runs an instruction based on current target
 func (a *Auto) Execute(ctx context.Context, inst ast.Inst) error {
+	return a.execute(ctx, inst, defaultPollInterval, nil)
+}
+
+// execute runs an instruction, passing any messages from runners to
+// onMessage if it is not nil
+func (a *Auto) execute(ctx context.Context, inst ast.Inst, poll time.Duration, onMessage func(*runner.MessagesReply_Message)) error {
 	switch inst := inst.(type) {
 	case *target.Mix:
-		return a.executeMix(ctx, inst)
+		return a.executeMix(ctx, inst, poll, onMessage)
 	case *target.Run:
 		return a.executeRun(ctx, inst)
 	case *target.Manual:
@@ -29,6 +39,8 @@ func (a *Auto) Execute(ctx context.Context, inst ast.Inst) error {
 		return nil
 	case *target.SetupMixer:
 		return nil
+	case *target.SetupIncubator:
+		return nil
 	case *target.Prompt:
 		return nil
 	case *target.TimedWait:
@@ -49,10 +61,14 @@ func (a *Auto) executeRun(ctx context.Context, inst *target.Run) error {
 			return err
 		}
 	}
+
+	if h, ok := inst.Dev.(target.ReplyHandler); ok {
+		return h.HandleReplies(ctx, inst)
+	}
 	return nil
 }
 
-func (a *Auto) executeMix(ctx context.Context, inst *target.Mix) error {
+func (a *Auto) executeMix(ctx context.Context, inst *target.Mix, poll time.Duration, onMessage func(*runner.MessagesReply_Message)) error {
 	rs := a.runners[inst.Files.Type]
 	if len(rs) == 0 {
 		return fmt.Errorf("no runner for %s", inst.Files.Type)
@@ -68,10 +84,16 @@ func (a *Auto) executeMix(ctx context.Context, inst *target.Mix) error {
 
 	// Proof of concept
 	var errors []string
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(poll)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
 		msgs, err := r.Messages(ctx, &runner.MessagesRequest{
 			Id: reply.Id,
 		})
@@ -79,6 +101,9 @@ func (a *Auto) executeMix(ctx context.Context, inst *target.Mix) error {
 			return err
 		}
 		for _, m := range msgs.Values {
+			if onMessage != nil {
+				onMessage(m)
+			}
 			if m.Code == "error" {
 				errors = append(errors, string(m.Data))
 			}
@@ -90,6 +115,4 @@ func (a *Auto) executeMix(ctx context.Context, inst *target.Mix) error {
 			}
 		}
 	}
-
-	return nil
 }
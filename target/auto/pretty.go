@@ -33,6 +33,18 @@ func prettyManual(inst *target.Manual) string {
 }
 
 func prettyMix(inst *target.Mix) string {
+	if inst.Request != nil {
+		var flagged int
+		for _, ou := range inst.Request.OutputUncertainties {
+			if len(ou.Exceeded) != 0 {
+				flagged++
+			}
+		}
+		if flagged != 0 {
+			return fmt.Sprintf("[mix] (size: %d, %d of %d outputs outside concentration tolerance)",
+				len(inst.Files.Tarball), flagged, len(inst.Request.OutputUncertainties))
+		}
+	}
 	return fmt.Sprintf("[mix] (size: %d)", len(inst.Files.Tarball))
 }
 
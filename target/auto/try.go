@@ -7,9 +7,12 @@ import (
 	driver "github.com/antha-lang/antha/driver/antha_driver_v1"
 	runner "github.com/antha-lang/antha/driver/antha_runner_v1"
 	lhclient "github.com/antha-lang/antha/driver/liquidhandling/client"
+	"github.com/antha-lang/antha/target"
+	"github.com/antha-lang/antha/target/acoustic"
 	"github.com/antha-lang/antha/target/handler"
 	"github.com/antha-lang/antha/target/human"
 	"github.com/antha-lang/antha/target/mixer"
+	"github.com/antha-lang/antha/target/platereader"
 	"github.com/antha-lang/antha/target/shakerincubator"
 	"google.golang.org/grpc"
 )
@@ -49,6 +52,21 @@ func (a *tryer) AddDriver(ctx context.Context, conn *grpc.ClientConn, arg interf
 		a.Auto.Target.AddDevice(s)
 		return nil
 
+	case target.DriverSelectorV1AcousticDispenser.Value:
+		d, err := acoustic.New(getAcousticOpt(append([]interface{}{arg}, a.MaybeArgs...)))
+		if err != nil {
+			return err
+		}
+		a.Auto.handler[d] = conn
+		a.Auto.Target.AddDevice(d)
+		return nil
+
+	case target.DriverSelectorV1ReadBackPlateReader.Value:
+		p := platereader.New()
+		a.Auto.handler[p] = conn
+		a.Auto.Target.AddDevice(p)
+		return nil
+
 	default:
 		h := handler.New(
 			[]ast.NameValue{
@@ -116,6 +134,15 @@ func (a *tryer) addLowLevelMixer(ctx context.Context, conn *grpc.ClientConn, arg
 	return nil
 }
 
+func getAcousticOpt(maybeArgs []interface{}) (ret acoustic.Opt) {
+	for _, v := range maybeArgs {
+		if o, ok := v.(acoustic.Opt); ok {
+			return o
+		}
+	}
+	return
+}
+
 func getMixerOpt(maybeArgs []interface{}) (ret mixer.Opt) {
 	for _, v := range maybeArgs {
 		if o, ok := v.(mixer.Opt); ok {
@@ -1 +1,19 @@
 package target
+
+import (
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/ast"
+)
+
+// A LiquidHandler is a device that executes mixes. When more than one
+// liquid handler can compile a mix, code generation uses these methods to
+// distribute mixes between them.
+type LiquidHandler interface {
+	ast.Device
+	// CanMix returns nil if the device can execute the mix or otherwise the
+	// reason why it cannot (e.g., unsupported plate type)
+	CanMix(mix *wtype.LHInstruction) error
+	// Channels returns the number of channels available to execute
+	// transfers in parallel
+	Channels() int
+}
@@ -3,6 +3,7 @@ package human
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/antha-lang/antha/antha/anthalib/wtype"
 	"github.com/antha-lang/antha/ast"
@@ -82,9 +83,10 @@ func (a *Human) generate(cmd interface{}) ([]ast.Inst, error) {
 
 	case *ast.IncubateInst:
 		insts = append(insts, &target.Manual{
-			Dev:     a,
-			Label:   "incubate",
-			Details: fmt.Sprintf("incubate at %s for %s", cmd.Temp.ToString(), cmd.Time.ToString()),
+			Dev:      a,
+			Label:    "incubate",
+			Details:  fmt.Sprintf("incubate at %s for %s", cmd.Temp.ToString(), cmd.Time.ToString()),
+			Duration: time.Duration((cmd.PreTime.Seconds() + cmd.Time.Seconds()) * float64(time.Second)),
 		})
 
 	case *ast.HandleInst:
@@ -1,6 +1,7 @@
 package target
 
 import (
+	"context"
 	"time"
 
 	"github.com/antha-lang/antha/antha/anthalib/wtype"
@@ -32,6 +33,12 @@ type TipEstimator interface {
 	GetTipEstimates() []wtype.TipEstimate
 }
 
+// A ReplyHandler is a device that processes the replies to the calls of its
+// Run instructions once they have been executed
+type ReplyHandler interface {
+	HandleReplies(ctx context.Context, inst *Run) error
+}
+
 type dependsMixin struct {
 	Depends []ast.Inst
 }
@@ -142,6 +149,8 @@ type Manual struct {
 	Dev     ast.Device
 	Label   string
 	Details string
+	// Expected duration if known
+	Duration time.Duration
 }
 
 // Device implements an Inst
@@ -203,7 +212,14 @@ type Wait struct {
 // TimedWait is a wait for a period of time.
 type TimedWait struct {
 	dependsMixin
-	noDeviceMixin
 
+	// Device held for the duration of the wait, e.g., an incubator while it
+	// incubates, if any
+	Dev      ast.Device
 	Duration time.Duration
 }
+
+// Device implements an Inst
+func (a *TimedWait) Device() ast.Device {
+	return a.Dev
+}
@@ -20,7 +20,8 @@ import (
 )
 
 var (
-	_ ast.Device = &Mixer{}
+	_ ast.Device           = &Mixer{}
+	_ target.LiquidHandler = &Mixer{}
 )
 
 // A Mixer is a device plugin for mixer devices
@@ -31,6 +32,9 @@ type Mixer struct {
 }
 
 func (a *Mixer) String() string {
+	if m := strings.TrimSpace(a.properties.Mnfr + " " + a.properties.Model); len(m) != 0 {
+		return fmt.Sprintf("Mixer (%s)", m)
+	}
 	return "Mixer"
 }
 
@@ -49,6 +53,60 @@ func (a *Mixer) CanCompile(req ast.Request) bool {
 	return can.Contains(req)
 }
 
+// CanMix implements a LiquidHandler
+func (a *Mixer) CanMix(mix *wtype.LHInstruction) error {
+	typ := mix.Platetype
+	if mix.OutPlate != nil {
+		typ = mix.OutPlate.Type
+	}
+	if len(typ) != 0 && len(a.opt.PlateTypes) != 0 {
+		found := false
+		for _, t := range a.opt.PlateTypes {
+			found = found || t == typ
+		}
+		if !found {
+			return fmt.Errorf("plate type %q not supported", typ)
+		}
+	}
+
+	var channels []*wtype.LHChannelParameter
+	for _, ad := range a.properties.GetLoadedAdaptors() {
+		if ad != nil && ad.Params != nil {
+			channels = append(channels, ad.Params)
+		}
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+
+	for _, in := range mix.Inputs {
+		v := in.Volume()
+		if v.IsZero() {
+			continue
+		}
+		canMove := false
+		for _, c := range channels {
+			canMove = canMove || c.CanMove(v, false)
+		}
+		if !canMove {
+			return fmt.Errorf("volume %s of %s is less than the minimum volume of every channel", v.ToString(), in.CName)
+		}
+	}
+
+	return nil
+}
+
+// Channels implements a LiquidHandler
+func (a *Mixer) Channels() int {
+	max := 1
+	for _, ad := range a.properties.GetLoadedAdaptors() {
+		if ad != nil && ad.Params != nil && ad.Params.Multi > max {
+			max = ad.Params.Multi
+		}
+	}
+	return max
+}
+
 // FileType returns the file type for generated files
 func (a *Mixer) FileType() (ftype string) {
 	if m := a.properties.Mnfr; len(m) != 0 {
@@ -57,6 +115,11 @@ func (a *Mixer) FileType() (ftype string) {
 	return
 }
 
+// An outputFileNamer is a driver that chooses the name of its output file
+type outputFileNamer interface {
+	OutputFileName() string
+}
+
 type lhreq struct {
 	*planner.LHRequest     // A request
 	*driver.LHProperties   // ... its state
@@ -209,6 +272,28 @@ func (a *Mixer) makeLhreq(ctx context.Context) (*lhreq, error) {
 
 	req.Options.IgnorePhysicalSimulation = a.opt.IgnorePhysicalSimulation
 
+	// concentration tolerance
+
+	if p := a.opt.ConcentrationTolerance; p != nil {
+		req.Options.ConcentrationTolerance = *p / 100
+	}
+
+	// consumables optimisation
+
+	req.Options.OptimiseConsumables = a.opt.OptimiseConsumables
+	for tt, c := range a.opt.TipCosts {
+		req.Options.ConsumablesCost.TipCost[tt] = c
+	}
+	if p := a.opt.TipBoxCost; p != nil {
+		req.Options.ConsumablesCost.TipBoxCost = *p
+	}
+	for pt, c := range a.opt.PlateCosts {
+		req.Options.ConsumablesCost.PlateCost[pt] = c
+	}
+	if p := a.opt.CostPerMinute; p != nil {
+		req.Options.ConsumablesCost.CostPerMinute = *p
+	}
+
 	return &lhreq{
 		LHRequest:     req,
 		LHProperties:  prop,
@@ -429,6 +514,9 @@ func (a *Mixer) makeMix(ctx context.Context, mixes []*wtype.LHInstruction) (*tar
 	}
 
 	name := a.opt.DriverOutputFileName
+	if n, ok := a.driver.(outputFileNamer); ok && len(name) == 0 {
+		name = n.OutputFileName()
+	}
 	if len(name) == 0 {
 		// TODO: Desired filename not exposed in current driver interface, so pick
 		// a name. So far, at least Gilson software cares what the filename is, so
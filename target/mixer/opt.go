@@ -41,8 +41,13 @@ type Opt struct {
 	// Specify file name in the instruction stream of any driver generated file
 	DriverOutputFileName string `json:"driverOutputFileName,omitempty"`
 
-	// Driver specific options. Semantics are not stable. Will need to be
-	// revised when multi device execution is supported.
+	// Plate types that the device can hold. If empty, any plate type is
+	// allowed. Mixes onto other plate types are given to other devices when
+	// a target has several mixers.
+	PlateTypes []string `json:"plateTypes,omitempty"`
+
+	// Driver specific options. Semantics are not stable. When a target has
+	// several mixers, pass options for each device with auto.Endpoint.Arg.
 	DriverSpecificInputPreferences    []string `json:"driverSpecificInputPreferences,omitempty"`
 	DriverSpecificOutputPreferences   []string `json:"driverSpecificOutputPreferences,omitempty"`
 	DriverSpecificTipPreferences      []string `json:"driverSpecificTipPreferences,omitempty"` // Driver specific position names (e.g., position_1 or A2)
@@ -57,6 +62,21 @@ type Opt struct {
 	FixVolumes               bool `json:"fixVolumes"`               // Aim to revise requested volumes to service requirements
 	IgnorePhysicalSimulation bool `json:"ignorePhysicalSimulation"` //ignore errors in physical simulation
 
+	// Flag outputs whose component concentrations may differ from nominal
+	// by more than this percentage, given the accuracy of transfers set in
+	// liquid policies
+	ConcentrationTolerance *float64 `json:"concentrationTolerance,omitempty"`
+
+	// Regroup transfers and choose output plate types to reduce the cost of
+	// tips, tip boxes, plates and robot time, and report the consumables
+	// used. Costs of tips and plates are by type; any not given are priced
+	// by the default cost model.
+	OptimiseConsumables bool               `json:"optimiseConsumables"`
+	TipCosts            map[string]float64 `json:"tipCosts,omitempty"`
+	TipBoxCost          *float64           `json:"tipBoxCost,omitempty"`
+	PlateCosts          map[string]float64 `json:"plateCosts,omitempty"`
+	CostPerMinute       *float64           `json:"costPerMinute,omitempty"`
+
 	// Two ways to set user liquid policies rule set
 	CustomPolicyData    map[string]wtype.LHPolicy `json:"customPolicyData,omitempty"`    // Set rule set from policies
 	CustomPolicyRuleSet *wtype.LHPolicyRuleSet    `json:"customPolicyRuleSet,omitempty"` // Directly
@@ -0,0 +1,259 @@
+// Package platereader provides a plate reader device that returns its
+// measurements to the workflow
+package platereader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/platereader/dataset"
+	"github.com/antha-lang/antha/antha/AnthaStandardLibrary/Packages/platereader/dataset/parse"
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/ast"
+	"github.com/antha-lang/antha/driver"
+	pb "github.com/antha-lang/antha/driver/antha_platereader_v1"
+	"github.com/antha-lang/antha/target"
+)
+
+// A location is the position of a sample on a plate
+type location struct {
+	PlateID string
+	Well    string // A1 format
+}
+
+// A read is a run of a plate reader protocol on a plate
+type read struct {
+	PlateID string
+	Options string
+	Wells   []string
+	Liquids map[string][]*wtype.Liquid // Liquids measured by well
+}
+
+// PlateReader is a plate reader device that returns its result files. The
+// readings in them are attached to the liquids that the plate reader
+// measures.
+type PlateReader struct {
+	lock  sync.Mutex
+	reads map[*pb.ProtocolResultReply]*read
+}
+
+// Ensure satisfies Device and ReplyHandler interfaces
+var (
+	_ ast.Device          = (*PlateReader)(nil)
+	_ target.ReplyHandler = (*PlateReader)(nil)
+)
+
+// New returns a new plate reader
+func New() *PlateReader {
+	return &PlateReader{
+		reads: make(map[*pb.ProtocolResultReply]*read),
+	}
+}
+
+// CanCompile implements a Device. A plate reader can compile anything that
+// a write-only plate reader can.
+func (a *PlateReader) CanCompile(req ast.Request) bool {
+	for _, sel := range []ast.NameValue{
+		target.DriverSelectorV1ReadBackPlateReader,
+		target.DriverSelectorV1WriteOnlyPlateReader,
+	} {
+		can := ast.Request{Selector: []ast.NameValue{sel}}
+		if can.Contains(req) {
+			return true
+		}
+	}
+	return false
+}
+
+// findLocations returns the locations of the given samples after the mixes
+// that reach nodes
+func findLocations(nodes []ast.Node, cmpIDs map[string]bool) map[string]location {
+	locs := make(map[string]location)
+	for _, cmd := range ast.FindReachingCommands(nodes) {
+		for _, inst := range cmd.Output {
+			mix, ok := inst.(*target.Mix)
+			if !ok || mix.FinalProperties == nil {
+				continue
+			}
+			for _, plate := range mix.FinalProperties.Plates {
+				for _, well := range plate.Wellcoords {
+					if well.WContents == nil {
+						continue
+					}
+					for id := range cmpIDs {
+						if strings.Contains(well.WContents.ParentID, id) {
+							locs[id] = location{
+								PlateID: plate.ID,
+								Well:    well.Crds.FormatA1(),
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return locs
+}
+
+// Compile implements a Device
+func (a *PlateReader) Compile(ctx context.Context, nodes []ast.Node) ([]ast.Inst, error) {
+	var prInsts []*wtype.PRInstruction
+	cmpIDs := make(map[string]bool)
+	for _, node := range nodes {
+		cmd := node.(*ast.Command)
+		inst, ok := cmd.Inst.(*wtype.PRInstruction)
+		if !ok {
+			return nil, fmt.Errorf("expected PRInstruction. Got: %T", cmd.Inst)
+		}
+		prInsts = append(prInsts, inst)
+		cmpIDs[inst.ComponentIn.GetID()] = true
+	}
+
+	locs := findLocations(nodes, cmpIDs)
+
+	// Instructions on the same plate with the same options can be read in
+	// the same protocol run
+	type key struct {
+		PlateID, Options string
+	}
+	var reads []*read
+	byKey := make(map[key]*read)
+	for _, inst := range prInsts {
+		loc, ok := locs[inst.ComponentIn.GetID()]
+		if !ok {
+			return nil, fmt.Errorf("cannot find location of sample %s", inst.ComponentIn.CName)
+		}
+		k := key{PlateID: loc.PlateID, Options: inst.Options}
+		r, seen := byKey[k]
+		if !seen {
+			r = &read{
+				PlateID: loc.PlateID,
+				Options: inst.Options,
+				Liquids: make(map[string][]*wtype.Liquid),
+			}
+			byKey[k] = r
+			reads = append(reads, r)
+		}
+		if _, seen := r.Liquids[loc.Well]; !seen {
+			r.Wells = append(r.Wells, loc.Well)
+		}
+		r.Liquids[loc.Well] = append(r.Liquids[loc.Well], inst.ComponentOut)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var insts ast.Insts
+	for _, r := range reads {
+		reply := &pb.ProtocolResultReply{}
+		a.reads[reply] = r
+		insts = append(insts,
+			&target.Prompt{
+				Message: fmt.Sprintf("Please put plate %s into plate reader and click ok to start plate reader", r.PlateID),
+			},
+			&target.Run{
+				Dev:   a,
+				Label: "use plate reader",
+				Calls: []driver.Call{
+					{
+						Method: "/antha.platereader.v1.PlateReader/PRRunProtocolByNameWithResult",
+						Args: &pb.ProtocolRunRequest{
+							ProtocolName:    "Custom",
+							PlateID:         r.PlateID,
+							PlateLayout:     strings.Join(r.Wells, " "),
+							ProtocolOptions: r.Options,
+						},
+						Reply: reply,
+					},
+				},
+			})
+	}
+	insts.SequentialOrder()
+	return insts, nil
+}
+
+// HandleReplies implements a ReplyHandler. Result files are parsed and
+// their readings added to the liquids in each well that was read.
+func (a *PlateReader) HandleReplies(ctx context.Context, inst *target.Run) error {
+	for _, c := range inst.Calls {
+		reply, ok := c.Reply.(*pb.ProtocolResultReply)
+		if !ok {
+			continue
+		}
+		a.lock.Lock()
+		r := a.reads[reply]
+		a.lock.Unlock()
+		if r == nil {
+			continue
+		}
+
+		if !reply.Result {
+			return fmt.Errorf("plate reader failed to read plate %s", r.PlateID)
+		}
+		data, err := parse.ParseData(reply.Format, reply.Data)
+		if err != nil {
+			return fmt.Errorf("cannot parse result file %q of plate %s: %s", reply.Filename, r.PlateID, err)
+		}
+		if err := r.attach(data, reply.Format); err != nil {
+			return fmt.Errorf("cannot read result file %q of plate %s: %s", reply.Filename, r.PlateID, err)
+		}
+	}
+	return nil
+}
+
+// attach adds the readings in data to the liquids that were read
+func (a *read) attach(data dataset.AbsorbanceData, reader string) error {
+	all, err := data.AllAbsorbanceData()
+	if err != nil {
+		return err
+	}
+	absorbances := make(map[string][]wtype.Absorbance)
+	for well, rs := range all {
+		well = wtype.MakeWellCoordsA1(well).FormatA1()
+		for _, r := range rs {
+			if r.Reader == "" {
+				r.Reader = reader
+			}
+			absorbances[well] = append(absorbances[well], r)
+		}
+	}
+
+	fluorescences := make(map[string][]wtype.Fluorescence)
+	if fd, ok := data.(dataset.FluorescenceData); ok {
+		all, err := fd.AllFluorescenceData()
+		if err != nil {
+			return err
+		}
+		for well, rs := range all {
+			well = wtype.MakeWellCoordsA1(well).FormatA1()
+			for _, r := range rs {
+				if r.Reader == "" {
+					r.Reader = reader
+				}
+				fluorescences[well] = append(fluorescences[well], r)
+			}
+		}
+	}
+
+	for _, well := range a.Wells {
+		abs, fl := absorbances[well], fluorescences[well]
+		if len(abs) == 0 && len(fl) == 0 {
+			return fmt.Errorf("no readings for well %s", well)
+		}
+		for _, l := range a.Liquids[well] {
+			if len(abs) > 0 {
+				if err := l.AddAbsorbances(abs...); err != nil {
+					return err
+				}
+			}
+			if len(fl) > 0 {
+				if err := l.AddFluorescences(fl...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
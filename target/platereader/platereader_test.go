@@ -0,0 +1,145 @@
+package platereader_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/antha-lang/antha/antha/anthalib/wtype"
+	"github.com/antha-lang/antha/ast"
+	"github.com/antha-lang/antha/driver/stub"
+	"github.com/antha-lang/antha/microArch/driver/liquidhandling"
+	"github.com/antha-lang/antha/target"
+	"github.com/antha-lang/antha/target/auto"
+)
+
+func makePlate() *wtype.Plate {
+	shp := wtype.NewShape("box", "mm", 8.2, 8.2, 41.3)
+	welltype := wtype.NewLHWell("ul", 200, 10, shp, wtype.VWellBottom, 8.2, 8.2, 41.3, 4.7, "mm")
+	return wtype.NewLHPlate("DSW96", "none", 8, 12, wtype.Coordinates{X: 127.76, Y: 85.48, Z: 43.1}, welltype, 9.0, 9.0, 0.5, 0.5, 0.5)
+}
+
+// readCommand returns a plate read of a sample in well of plate
+func readCommand(plate *wtype.Plate, well string) *ast.Command {
+	sample := wtype.NewLHComponent()
+	sample.CName = "sample"
+	plate.Wellcoords[well].WContents.ParentID = sample.ID
+
+	mix := &ast.Command{
+		Output: []ast.Inst{
+			&target.Mix{
+				FinalProperties: &liquidhandling.LHProperties{
+					Plates: map[string]*wtype.Plate{"position_4": plate},
+				},
+			},
+		},
+	}
+
+	inst := wtype.NewPRInstruction()
+	inst.ComponentIn = sample
+	inst.ComponentOut = wtype.NewLHComponent()
+	inst.Options = "absorbance scan"
+	return &ast.Command{
+		From: []ast.Node{mix},
+		Inst: inst,
+		Requests: []ast.Request{
+			{Selector: []ast.NameValue{target.DriverSelectorV1WriteOnlyPlateReader}},
+		},
+	}
+}
+
+func TestReadBack(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "spectramax.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr := &stub.PlateReader{Format: "spectramax", Filename: "spectramax.xml", Data: data}
+	s, err := stub.Serve(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	a, err := auto.New(auto.Opt{Endpoints: []auto.Endpoint{{URI: s.URI}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close() // nolint: errcheck
+
+	plate := makePlate()
+	cmd := readCommand(plate, "B3")
+	devices := a.Target.CanCompile(cmd.Requests...)
+	if len(devices) == 0 {
+		t.Fatal("no device can read plates")
+	}
+
+	ctx := context.Background()
+	insts, err := devices[0].Compile(ctx, []ast.Node{cmd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inst := range insts {
+		if err := a.Execute(ctx, inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if runs := pr.Runs(); len(runs) != 1 {
+		t.Fatalf("expecting 1 run but got %d", len(runs))
+	} else if runs[0].PlateLayout != "B3" || runs[0].PlateID != plate.ID {
+		t.Errorf("expecting well B3 of plate %s but got %v", plate.ID, runs[0])
+	}
+
+	out := cmd.Inst.(*wtype.PRInstruction).ComponentOut
+	abs, err := out.Absorbances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[float64]float64{600: 0.512, 650: 0.498}
+	if len(abs) != len(expected) {
+		t.Fatalf("expecting %d readings but got %v", len(expected), abs)
+	}
+	for _, r := range abs {
+		if e, ok := expected[r.Wavelength]; !ok || e != r.Reading {
+			t.Errorf("unexpected reading %v", r)
+		}
+		if r.WellLocation.FormatA1() != "B3" || r.Reader != "spectramax" {
+			t.Errorf("unexpected reading %v", r)
+		}
+	}
+}
+
+func TestReadBackMissingWell(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "spectramax.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := stub.Serve(&stub.PlateReader{Format: "spectramax", Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	a, err := auto.New(auto.Opt{Endpoints: []auto.Endpoint{{URI: s.URI}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close() // nolint: errcheck
+
+	cmd := readCommand(makePlate(), "H12")
+	ctx := context.Background()
+	insts, err := a.Target.CanCompile(cmd.Requests...)[0].Compile(ctx, []ast.Node{cmd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failed bool
+	for _, inst := range insts {
+		if err := a.Execute(ctx, inst); err != nil {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Errorf("expecting error reading well without data")
+	}
+}
@@ -0,0 +1,291 @@
+package target
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/antha-lang/antha/ast"
+	"github.com/antha-lang/antha/graph"
+)
+
+// Operator is the name of the resource that carries out manual instructions
+// and prompts that are not assigned to a device
+const Operator = "operator"
+
+// ScheduleOpt are options for MakeSchedule
+type ScheduleOpt struct {
+	Insts []ast.Inst
+	// Duration of manual instructions without a duration of their own
+	ManualDuration time.Duration
+	// Duration of prompts
+	PromptDuration time.Duration
+	// Duration of device runs, e.g., plate reads
+	RunDuration time.Duration
+	// Durations of manual instructions and device runs by label. These
+	// override the durations above.
+	Durations map[string]time.Duration
+}
+
+// A ScheduledInst is an instruction with its estimated start and end times
+// relative to the start of execution
+type ScheduledInst struct {
+	Inst     ast.Inst      `json:"-"`
+	Kind     string        `json:"kind"`
+	Label    string        `json:"label,omitempty"`
+	Resource string        `json:"resource,omitempty"`
+	Start    time.Duration `json:"start"`
+	End      time.Duration `json:"end"`
+	Critical bool          `json:"critical"`
+}
+
+// A Schedule is an assignment of start and end times to instructions
+type Schedule struct {
+	// Instructions in order of start time
+	Insts    []*ScheduledInst `json:"insts"`
+	Makespan time.Duration    `json:"makespan"`
+	// Instructions that determine the makespan in order of execution
+	CriticalPath []*ScheduledInst `json:"-"`
+}
+
+// Resources returns the names of the resources used by the schedule in order
+// of first use
+func (a *Schedule) Resources() []string {
+	var rs []string
+	seen := make(map[string]bool)
+	for _, si := range a.Insts {
+		if len(si.Resource) == 0 || seen[si.Resource] {
+			continue
+		}
+		seen[si.Resource] = true
+		rs = append(rs, si.Resource)
+	}
+	return rs
+}
+
+func instKind(inst ast.Inst) (kind, label string) {
+	switch inst := inst.(type) {
+	case *Mix:
+		return "mix", ""
+	case *Manual:
+		return "manual", inst.Label
+	case *Order:
+		return "manual", inst.Label
+	case *PlatePrep:
+		return "manual", inst.Label
+	case *SetupMixer:
+		return "manual", inst.Label
+	case *SetupIncubator:
+		return "manual", inst.Label
+	case *Run:
+		return "run", inst.Label
+	case *Prompt:
+		return "prompt", inst.Message
+	case *Wait:
+		return "wait", ""
+	case *TimedWait:
+		return "timedWait", inst.Duration.String()
+	default:
+		return fmt.Sprintf("%T", inst), ""
+	}
+}
+
+// estimate returns the expected duration of an instruction
+func (a *ScheduleOpt) estimate(inst ast.Inst) time.Duration {
+	kind, label := instKind(inst)
+	if d, ok := a.Durations[label]; ok && (kind == "manual" || kind == "run") {
+		return d
+	}
+
+	switch inst := inst.(type) {
+	case TimeEstimator:
+		return time.Duration(inst.GetTimeEstimate() * float64(time.Second))
+	case *TimedWait:
+		return inst.Duration
+	case *Run:
+		return a.RunDuration
+	case *Prompt:
+		return a.PromptDuration
+	case *Wait:
+		return 0
+	}
+
+	if kind == "manual" {
+		if m := manualOf(inst); m != nil && m.Duration != 0 {
+			return m.Duration
+		}
+		return a.ManualDuration
+	}
+	return 0
+}
+
+func manualOf(inst ast.Inst) *Manual {
+	switch inst := inst.(type) {
+	case *Manual:
+		return inst
+	case *Order:
+		return &inst.Manual
+	case *PlatePrep:
+		return &inst.Manual
+	case *SetupMixer:
+		return &inst.Manual
+	case *SetupIncubator:
+		return &inst.Manual
+	}
+	return nil
+}
+
+// resourceNamer gives distinct names to devices
+type resourceNamer struct {
+	names map[ast.Device]string
+	used  map[string]bool
+}
+
+func (a *resourceNamer) name(inst ast.Inst) string {
+	dev := inst.Device()
+	if dev == nil {
+		switch inst.(type) {
+		case *Wait, *TimedWait:
+			return ""
+		}
+		return Operator
+	}
+	if name, ok := a.names[dev]; ok {
+		return name
+	}
+
+	base := fmt.Sprintf("%T", dev)
+	if s, ok := dev.(fmt.Stringer); ok {
+		base = s.String()
+	}
+	name := base
+	for i := 2; a.used[name]; i++ {
+		name = fmt.Sprintf("%s (%d)", base, i)
+	}
+	a.names[dev] = name
+	a.used[name] = true
+	return name
+}
+
+// MakeSchedule estimates when each instruction will run. Instructions start
+// once the instructions they depend on have finished and the resource they
+// use is free. Each device, and the operator, carry out one instruction at a
+// time. Timed waits hold their device, if any, for their duration; other
+// waits use no resource.
+func MakeSchedule(opt ScheduleOpt) (*Schedule, error) {
+	g := &Graph{Insts: opt.Insts}
+	order, err := graph.TopoSort(graph.TopoSortOpt{Graph: g})
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[ast.Inst]int)
+	for i, n := range order {
+		index[n.(ast.Inst)] = i
+	}
+
+	namer := &resourceNamer{
+		names: make(map[ast.Device]string),
+		used:  make(map[string]bool),
+	}
+
+	type state struct {
+		*ScheduledInst
+		ready   time.Duration // Time when all dependencies have finished
+		waiting int           // Number of unscheduled dependencies
+		binding *state        // Instruction that determined the start time
+	}
+
+	states := make(map[ast.Inst]*state)
+	dependents := make(map[ast.Inst][]ast.Inst)
+	for _, inst := range opt.Insts {
+		kind, label := instKind(inst)
+		states[inst] = &state{
+			ScheduledInst: &ScheduledInst{
+				Inst:     inst,
+				Kind:     kind,
+				Label:    label,
+				Resource: namer.name(inst),
+			},
+		}
+	}
+	for _, inst := range opt.Insts {
+		for _, dep := range inst.DependsOn() {
+			if _, ok := states[dep]; ok {
+				states[inst].waiting++
+				dependents[dep] = append(dependents[dep], inst)
+			}
+		}
+	}
+
+	var ready []*state
+	for _, inst := range opt.Insts {
+		if s := states[inst]; s.waiting == 0 {
+			ready = append(ready, s)
+		}
+	}
+
+	free := make(map[string]time.Duration)
+	last := make(map[string]*state)
+	sched := &Schedule{}
+	var end *state
+
+	// List scheduling: repeatedly start the ready instruction that can start
+	// earliest, breaking ties by topological order
+	for len(ready) != 0 {
+		start := func(s *state) time.Duration {
+			if t := free[s.Resource]; len(s.Resource) != 0 && t > s.ready {
+				return t
+			}
+			return s.ready
+		}
+		sort.SliceStable(ready, func(i, j int) bool {
+			si, sj := start(ready[i]), start(ready[j])
+			if si != sj {
+				return si < sj
+			}
+			return index[ready[i].Inst] < index[ready[j].Inst]
+		})
+
+		s := ready[0]
+		ready = ready[1:]
+
+		s.Start = start(s)
+		s.End = s.Start + opt.estimate(s.Inst)
+		if len(s.Resource) != 0 {
+			if s.Start > s.ready {
+				s.binding = last[s.Resource]
+			}
+			free[s.Resource] = s.End
+			last[s.Resource] = s
+		}
+		sched.Insts = append(sched.Insts, s.ScheduledInst)
+		if end == nil || s.End > end.End {
+			end = s
+		}
+
+		for _, inst := range dependents[s.Inst] {
+			d := states[inst]
+			if s.End >= d.ready && (d.binding == nil || s.End > d.ready) {
+				d.ready = s.End
+				d.binding = s
+			}
+			d.waiting--
+			if d.waiting == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if end == nil {
+		return sched, nil
+	}
+
+	sched.Makespan = end.End
+	for s := end; s != nil; s = s.binding {
+		s.Critical = true
+		sched.CriticalPath = append([]*ScheduledInst{s.ScheduledInst}, sched.CriticalPath...)
+	}
+
+	return sched, nil
+}
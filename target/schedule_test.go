@@ -0,0 +1,137 @@
+package target
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antha-lang/antha/ast"
+)
+
+type fakeDevice struct{}
+
+func (a *fakeDevice) CanCompile(ast.Request) bool { return true }
+
+func (a *fakeDevice) Compile(context.Context, []ast.Node) ([]ast.Inst, error) { return nil, nil }
+
+func TestMakeSchedule(t *testing.T) {
+	dev := &fakeDevice{}
+
+	// prep -> read1 -> incubate
+	//      -> read2
+	// read1 and read2 share a device
+	prep := &Manual{Label: "prep"}
+	read1 := &Run{Dev: dev, Label: "read"}
+	read2 := &Run{Dev: dev, Label: "read"}
+	incubate := &TimedWait{Duration: time.Hour}
+	prompt := &Prompt{Message: "hello"}
+	read1.SetDependsOn(prep)
+	read2.SetDependsOn(prep)
+	incubate.SetDependsOn(read2)
+
+	s, err := MakeSchedule(ScheduleOpt{
+		Insts:          []ast.Inst{prep, read1, read2, incubate, prompt},
+		ManualDuration: 10 * time.Minute,
+		PromptDuration: time.Minute,
+		RunDuration:    time.Minute,
+		Durations:      map[string]time.Duration{"read": 5 * time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	times := make(map[ast.Inst][2]time.Duration)
+	for _, si := range s.Insts {
+		times[si.Inst] = [2]time.Duration{si.Start, si.End}
+	}
+
+	expected := map[ast.Inst][2]time.Duration{
+		prep:     {0, 10 * time.Minute},
+		prompt:   {10 * time.Minute, 11 * time.Minute}, // operator is busy with prep
+		read1:    {10 * time.Minute, 15 * time.Minute},
+		read2:    {15 * time.Minute, 20 * time.Minute}, // device is busy with read1
+		incubate: {20 * time.Minute, 80 * time.Minute},
+	}
+	for inst, e := range expected {
+		if g := times[inst]; g != e {
+			t.Errorf("%T %+v: expecting %v but got %v", inst, inst, e, g)
+		}
+	}
+
+	if s.Makespan != 80*time.Minute {
+		t.Errorf("expecting makespan %s but got %s", 80*time.Minute, s.Makespan)
+	}
+
+	var path []ast.Inst
+	for _, si := range s.CriticalPath {
+		path = append(path, si.Inst)
+	}
+	if e := []ast.Inst{prep, read1, read2, incubate}; len(path) != len(e) {
+		t.Errorf("expecting critical path %v but got %v", e, path)
+	} else {
+		for i := range e {
+			if path[i] != e[i] {
+				t.Errorf("expecting critical path %v but got %v", e, path)
+				break
+			}
+		}
+	}
+}
+
+func TestScheduleTimedWaitHoldsDevice(t *testing.T) {
+	incubator := &fakeDevice{}
+
+	// start -> incubate
+	// prep -> restart
+	// incubate holds the incubator, so restart waits for it
+	start := &Run{Dev: incubator, Label: "start"}
+	incubate := &TimedWait{Dev: incubator, Duration: time.Hour}
+	prep := &Manual{Label: "prep"}
+	restart := &Run{Dev: incubator, Label: "restart"}
+	settle := &TimedWait{Duration: time.Minute}
+	incubate.SetDependsOn(start)
+	restart.SetDependsOn(prep)
+	settle.SetDependsOn(prep)
+
+	s, err := MakeSchedule(ScheduleOpt{
+		Insts:          []ast.Inst{start, incubate, prep, restart, settle},
+		ManualDuration: 10 * time.Minute,
+		RunDuration:    time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduled := make(map[ast.Inst]*ScheduledInst)
+	for _, si := range s.Insts {
+		scheduled[si.Inst] = si
+	}
+
+	expected := map[ast.Inst][2]time.Duration{
+		start:    {0, time.Minute},
+		incubate: {time.Minute, 61 * time.Minute},
+		prep:     {0, 10 * time.Minute},
+		restart:  {61 * time.Minute, 62 * time.Minute}, // incubator is busy incubating
+		settle:   {10 * time.Minute, 11 * time.Minute},
+	}
+	for inst, e := range expected {
+		if si := scheduled[inst]; si == nil || si.Start != e[0] || si.End != e[1] {
+			t.Errorf("%T %+v: expecting %v but got %+v", inst, inst, e, si)
+		}
+	}
+
+	if e, g := scheduled[start].Resource, scheduled[incubate].Resource; e != g {
+		t.Errorf("expecting incubation to use resource %q but got %q", e, g)
+	}
+	if g := scheduled[settle].Resource; len(g) != 0 {
+		t.Errorf("expecting wait without a device to use no resource but got %q", g)
+	}
+
+	var path []ast.Inst
+	for _, si := range s.CriticalPath {
+		path = append(path, si.Inst)
+	}
+	if e := []ast.Inst{start, incubate, restart}; len(path) != len(e) || path[0] != e[0] || path[1] != e[1] || path[2] != e[2] {
+		t.Errorf("expecting critical path %v but got %v", e, path)
+	}
+}
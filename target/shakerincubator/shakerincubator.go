@@ -140,6 +140,7 @@ func (a *ShakerIncubator) generate(cmd interface{}) ([]ast.Inst, error) {
 			Calls: calls,
 		})
 		insts = append(insts, &target.TimedWait{
+			Dev:      a,
 			Duration: time.Duration(inc.PreTime.Seconds() * float64(time.Second)),
 		})
 	}
@@ -162,6 +163,7 @@ func (a *ShakerIncubator) generate(cmd interface{}) ([]ast.Inst, error) {
 
 	if !inc.Time.IsNil() {
 		insts = append(insts, &target.TimedWait{
+			Dev:      a,
 			Duration: time.Duration(inc.Time.Seconds() * float64(time.Second)),
 		})
 	}
@@ -45,6 +45,14 @@ var (
 		Name:  DriverSelectorV1Name,
 		Value: "antha.platereader.v1.PlateReader",
 	}
+	DriverSelectorV1ReadBackPlateReader = ast.NameValue{
+		Name:  DriverSelectorV1Name,
+		Value: "antha.platereader.v1.ReadBackPlateReader",
+	}
+	DriverSelectorV1AcousticDispenser = ast.NameValue{
+		Name:  DriverSelectorV1Name,
+		Value: "antha.acousticdispenser.v1.AcousticDispenser",
+	}
 	DriverSelectorV1QPCRDevice = ast.NameValue{
 		Name:  DriverSelectorV1Name,
 		Value: "antha.quantstudio.v1.QuantStudioService",
@@ -0,0 +1,181 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	api "github.com/antha-lang/antha/api/v1"
+	"github.com/antha-lang/antha/inject"
+)
+
+// A processRun records the steps stage of a process so that its analysis
+// stage can be run later
+type processRun struct {
+	FuncName string
+	Order    int
+	Params   inject.Value
+	Out      inject.Value
+	Outs     map[string][]Port // Ports connected to each output
+}
+
+func newProcessRun(n *node, out inject.Value) *processRun {
+	r := &processRun{
+		FuncName: n.FuncName,
+		Order:    n.order,
+		Params:   n.Params,
+		Out:      out,
+		Outs:     make(map[string][]Port),
+	}
+	for name, eps := range n.Outs {
+		for _, ep := range eps {
+			r.Outs[name] = append(r.Outs[name], Port{Process: ep.Node.Process, Port: ep.Port})
+		}
+	}
+	return r
+}
+
+// An AnalysisStatus is the outcome of the analysis stage of a process
+type AnalysisStatus int
+
+// Outcomes of the analysis stage
+const (
+	AnalysisPassed AnalysisStatus = iota
+	AnalysisFailed
+	// Not run because an upstream process failed
+	AnalysisSkipped
+)
+
+func (a AnalysisStatus) String() string {
+	switch a {
+	case AnalysisPassed:
+		return "passed"
+	case AnalysisFailed:
+		return "failed"
+	case AnalysisSkipped:
+		return "skipped"
+	}
+	return fmt.Sprintf("AnalysisStatus(%d)", int(a))
+}
+
+// An AnalysisResult is the result of the analysis stage of a process
+type AnalysisResult struct {
+	Process string
+	Status  AnalysisStatus
+	Err     error        // Reason the process failed or was skipped
+	Outputs inject.Value // Outputs after analysis
+}
+
+// RunAnalysis runs the analysis and validation stage of each process that
+// completed Run, in the serial execution order of Run. Each process is
+// given its inputs and outputs from Run, overlaid by data[process] (e.g.,
+// measurements returned by instruments) and by the outputs of the analysis
+// stage of upstream processes. Processes without an analysis stage pass
+// with their outputs from Run and any data added to them.
+//
+// A process fails if its analysis stage returns an error or panics, as
+// validation does when it reports an error; processes downstream of it are
+// skipped. Outputs is updated with the outputs of processes that pass.
+func (a *Workflow) RunAnalysis(ctx context.Context, data map[string]inject.Value) ([]AnalysisResult, error) {
+	for process := range data {
+		if a.runs[process] == nil {
+			return nil, fmt.Errorf("cannot add data to process %q: %s", process, errUnknownProcess)
+		}
+	}
+
+	var names []string
+	for name := range a.runs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return a.runs[names[i]].Order < a.runs[names[j]].Order
+	})
+
+	// Inputs from analysis stages of upstream processes and processes
+	// that failed upstream
+	updated := make(map[string]inject.Value)
+	failed := make(map[string]string)
+
+	var results []AnalysisResult
+	for _, name := range names {
+		r := a.runs[name]
+		res := AnalysisResult{Process: name}
+
+		if up, seen := failed[name]; seen {
+			res.Status = AnalysisSkipped
+			res.Err = fmt.Errorf("upstream process %q failed", up)
+		} else {
+			value := merge(r.Params, updated[name], r.Out, data[name])
+			res.Outputs, res.Err = a.analyse(withProcess(ctx, &node{Process: name, order: r.Order}), r.FuncName, value)
+			if res.Err != nil {
+				res.Status = AnalysisFailed
+			} else if res.Outputs == nil {
+				res.Outputs = merge(r.Out, data[name])
+			}
+		}
+
+		for port, tgts := range r.Outs {
+			for _, tgt := range tgts {
+				if res.Status != AnalysisPassed {
+					if _, seen := failed[tgt.Process]; !seen {
+						failed[tgt.Process] = name
+					}
+					continue
+				}
+				if updated[tgt.Process] == nil {
+					updated[tgt.Process] = make(inject.Value)
+				}
+				updated[tgt.Process][tgt.Port] = res.Outputs[port]
+			}
+		}
+
+		if res.Status == AnalysisPassed {
+			a.lock.Lock()
+			for port, v := range res.Outputs {
+				if p := (Port{Process: name, Port: port}); len(r.Outs[port]) == 0 {
+					a.Outputs[p] = v
+				}
+			}
+			a.lock.Unlock()
+		}
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// merge returns the union of values; later values take precedence
+func merge(values ...inject.Value) inject.Value {
+	ret := make(inject.Value)
+	for _, v := range values {
+		for k, x := range v {
+			ret[k] = x
+		}
+	}
+	return ret
+}
+
+// analyse calls the analysis stage of a function, recovering from panics.
+// It returns nil outputs if the function has no analysis stage.
+func (a *Workflow) analyse(ctx context.Context, funcName string, value inject.Value) (out inject.Value, err error) {
+	query := inject.NameQuery{
+		Repo:  funcName,
+		Stage: api.ElementStage_ANALYSIS,
+	}
+	runner, err := inject.Find(ctx, query)
+	if inject.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if res := recover(); res != nil {
+			out = nil
+			err = fmt.Errorf("%v", res)
+		}
+	}()
+
+	return runner.Run(ctx, value)
+}
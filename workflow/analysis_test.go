@@ -0,0 +1,131 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	api "github.com/antha-lang/antha/api/v1"
+	"github.com/antha-lang/antha/inject"
+)
+
+// addMeasure adds a component whose steps stage passes on its input and
+// whose analysis stage reports a reading, failing validation if it is
+// "bad"
+func addMeasure(ctx context.Context) error {
+	if err := inject.Add(ctx, inject.Name{Repo: "Measure", Stage: api.ElementStage_STEPS}, &inject.FuncRunner{
+		RunFunc: func(_ context.Context, value inject.Value) (inject.Value, error) {
+			return inject.Value{"Out": value["In"], "Reading": ""}, nil
+		},
+	}); err != nil {
+		return err
+	}
+	return inject.Add(ctx, inject.Name{Repo: "Measure", Stage: api.ElementStage_ANALYSIS}, &inject.FuncRunner{
+		RunFunc: func(_ context.Context, value inject.Value) (inject.Value, error) {
+			reading, _ := value["Reading"].(string)
+			if reading == "bad" {
+				panic(fmt.Errorf("bad reading"))
+			}
+			return inject.Value{"Out": fmt.Sprint(value["In"], "+", reading), "Reading": reading}, nil
+		},
+	})
+}
+
+func newMeasureWorkflow(t *testing.T) (context.Context, *Workflow) {
+	ctx, err := createContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addMeasure(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// First -> Second -> Copy
+	w, err := New(Opt{FromDesc: &Desc{
+		Processes: map[string]Process{
+			"First":  {Component: "Measure"},
+			"Second": {Component: "Measure"},
+			"Copy":   {Component: "Copy"},
+		},
+		Connections: []Connection{
+			{Src: Port{Process: "First", Port: "Out"}, Tgt: Port{Process: "Second", Port: "In"}},
+			{Src: Port{Process: "Second", Port: "Out"}, Tgt: Port{Process: "Copy", Port: "In"}},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetParam(Port{Process: "First", Port: "In"}, "sample"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return ctx, w
+}
+
+func statuses(results []AnalysisResult) map[string]AnalysisStatus {
+	m := make(map[string]AnalysisStatus)
+	for _, r := range results {
+		m[r.Process] = r.Status
+	}
+	return m
+}
+
+func TestRunAnalysis(t *testing.T) {
+	ctx, w := newMeasureWorkflow(t)
+
+	results, err := w.RunAnalysis(ctx, map[string]inject.Value{
+		"First":  {"Reading": "a"},
+		"Second": {"Reading": "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, r := range results {
+		order = append(order, r.Process)
+		if r.Status != AnalysisPassed {
+			t.Errorf("process %s: expecting pass but got %s: %v", r.Process, r.Status, r.Err)
+		}
+	}
+	if fmt.Sprint(order) != "[First Second Copy]" {
+		t.Errorf("expecting processes in dependency order but got %v", order)
+	}
+
+	// Analysis outputs flow downstream
+	if out := results[1].Outputs["Out"]; out != "sample+a+b" {
+		t.Errorf("expecting analysed output but got %v", out)
+	}
+	if out := w.Outputs[Port{Process: "Second", Port: "Reading"}]; out != "b" {
+		t.Errorf("expecting reading b but got %v", out)
+	}
+
+	if _, err := w.RunAnalysis(ctx, map[string]inject.Value{"Unknown": {}}); err == nil {
+		t.Errorf("expecting error for data of unknown process")
+	}
+}
+
+func TestRunAnalysisValidationFailure(t *testing.T) {
+	ctx, w := newMeasureWorkflow(t)
+
+	results, err := w.RunAnalysis(ctx, map[string]inject.Value{
+		"First": {"Reading": "bad"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]AnalysisStatus{
+		"First":  AnalysisFailed,
+		"Second": AnalysisSkipped,
+		"Copy":   AnalysisSkipped,
+	}
+	if s := statuses(results); fmt.Sprint(s) != fmt.Sprint(expected) {
+		t.Errorf("expecting %v but got %v", expected, s)
+	}
+	if results[0].Err == nil || results[0].Err.Error() != "bad reading" {
+		t.Errorf("expecting validation error but got %v", results[0].Err)
+	}
+}
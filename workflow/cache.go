@@ -0,0 +1,54 @@
+package workflow
+
+import (
+	"context"
+
+	api "github.com/antha-lang/antha/api/v1"
+	"github.com/antha-lang/antha/inject"
+)
+
+// A Cache records the outputs of processes so that a later run of a
+// workflow can skip processes whose inputs are unchanged.
+//
+// Load and Store are called with the context the process would run with,
+// so implementations can restore or record side-effects of the process
+// alongside its outputs.
+type Cache interface {
+	// Load returns previously stored outputs of calling funcName with
+	// params, if any.
+	Load(ctx context.Context, funcName string, params inject.Value) (out inject.Value, found bool, err error)
+	// Store records the outputs of calling funcName with params.
+	Store(ctx context.Context, funcName string, params inject.Value, out inject.Value) error
+}
+
+// call runs the function of a node, or restores its outputs from the cache
+// when the node and all processes upstream of it are unchanged.
+func (a *Workflow) call(ctx context.Context, n *node) (inject.Value, error) {
+	if a.cache != nil && !n.stale {
+		out, found, err := a.cache.Load(ctx, n.FuncName, n.Params)
+		if err != nil {
+			return nil, err
+		} else if found {
+			return out, nil
+		}
+	}
+
+	query := inject.NameQuery{
+		Repo:  n.FuncName,
+		Stage: api.ElementStage_STEPS,
+	}
+	out, err := inject.Call(ctx, query, n.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.stale = true
+
+	if a.cache != nil {
+		if err := a.cache.Store(ctx, n.FuncName, n.Params, out); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
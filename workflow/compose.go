@@ -0,0 +1,199 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/antha-lang/antha/utils"
+)
+
+// ProcessSep separates the name of a sub-workflow process from the names of
+// the processes within it
+const ProcessSep = "/"
+
+var errRecursiveWorkflow = errors.New("recursive workflow")
+
+// A scope holds the workflow definitions visible to a workflow description
+type scope struct {
+	parent    *scope
+	workflows map[string]*Desc
+}
+
+// lookup finds a workflow definition and the scope that it is defined in
+func (a *scope) lookup(name string) (*Desc, *scope) {
+	for s := a; s != nil; s = s.parent {
+		if desc, ok := s.workflows[name]; ok && desc != nil {
+			return desc, s
+		}
+	}
+	return nil, nil
+}
+
+// An expander adds the processes of a workflow description, and of the
+// sub-workflows it uses, to a Workflow
+type expander struct {
+	*Workflow
+	errs  *utils.ErrorSlice // If not nil, collect errors and keep expanding
+	stack []*Desc           // Workflows being expanded
+	names []string          // Names of workflows being expanded
+}
+
+// fail returns err, or if errors are being collected, records err and
+// returns nil so that expansion continues past the problem
+func (a *expander) fail(err error) error {
+	if a.errs == nil {
+		return err
+	}
+	*a.errs = append(*a.errs, err)
+	return nil
+}
+
+func (a *expander) push(name string, desc *Desc) error {
+	for i, d := range a.stack {
+		if d == desc {
+			cycle := append(append([]string{}, a.names[i:]...), name)
+			return fmt.Errorf("%s: %s", errRecursiveWorkflow, strings.Join(cycle, " -> "))
+		}
+	}
+	a.stack = append(a.stack, desc)
+	a.names = append(a.names, name)
+	return nil
+}
+
+func (a *expander) pop() {
+	a.stack = a.stack[:len(a.stack)-1]
+	a.names = a.names[:len(a.names)-1]
+}
+
+// expand adds the processes of desc with their names prefixed by prefix and
+// returns the exported inputs and outputs of desc resolved to node ports.
+func (a *expander) expand(prefix string, desc *Desc, parent *scope) (map[string]Port, map[string]Port, error) {
+	s := &scope{parent: parent, workflows: desc.Workflows}
+
+	var names []string
+	for name := range desc.Processes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	subIns := make(map[string]map[string]Port)
+	subOuts := make(map[string]map[string]Port)
+	for _, name := range names {
+		process := desc.Processes[name]
+		full := prefix + name
+		if process.Workflow == "" {
+			if err := a.AddNode(full, process.Component); err != nil {
+				if err := a.fail(err); err != nil {
+					return nil, nil, err
+				}
+			}
+			continue
+		}
+
+		if process.Component != "" {
+			if err := a.fail(fmt.Errorf("process %q has both component %q and workflow %q", full, process.Component, process.Workflow)); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		sub, subScope := s.lookup(process.Workflow)
+		if sub == nil {
+			if err := a.fail(fmt.Errorf("process %q uses unknown workflow %q", full, process.Workflow)); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if err := a.push(process.Workflow, sub); err != nil {
+			if err := a.fail(fmt.Errorf("process %q: %s", full, err)); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		ins, outs, err := a.expand(full+ProcessSep, sub, subScope)
+		if err != nil {
+			return nil, nil, err
+		}
+		a.pop()
+
+		subIns[name] = ins
+		subOuts[name] = outs
+		for port, tgt := range ins {
+			a.inputs[Port{Process: full, Port: port}] = tgt
+		}
+		for port, src := range outs {
+			a.outputs[Port{Process: full, Port: port}] = src
+		}
+	}
+
+	resolve := func(port Port, exported map[string]map[string]Port, kind string) (Port, error) {
+		if _, ok := desc.Processes[port.Process]; !ok {
+			return Port{}, fmt.Errorf("unknown %s port %q", kind, Port{Process: prefix + port.Process, Port: port.Port})
+		}
+		ports, ok := exported[port.Process]
+		if !ok {
+			return Port{Process: prefix + port.Process, Port: port.Port}, nil
+		}
+		p, ok := ports[port.Port]
+		if !ok {
+			return Port{}, fmt.Errorf("workflow process %q has no %s %q", prefix+port.Process, kind, port.Port)
+		}
+		return p, nil
+	}
+
+	for _, c := range desc.Connections {
+		src, err := resolve(c.Src, subOuts, "output")
+		if err == nil {
+			var tgt Port
+			if tgt, err = resolve(c.Tgt, subIns, "input"); err == nil {
+				err = a.AddEdge(src, tgt)
+			}
+		}
+		if err != nil {
+			if err := a.fail(err); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	ins := make(map[string]Port)
+	for name, port := range desc.Inputs {
+		if p, err := resolve(port, subIns, "input"); err == nil {
+			ins[name] = p
+		} else if err := a.fail(err); err != nil {
+			return nil, nil, err
+		}
+	}
+	outs := make(map[string]Port)
+	for name, port := range desc.Outputs {
+		if p, err := resolve(port, subOuts, "output"); err == nil {
+			outs[name] = p
+		} else if err := a.fail(err); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return ins, outs, nil
+}
+
+// ResolveInput returns the port of the process that receives values set on
+// port. Exported inputs of sub-workflow processes resolve to the input of a
+// process within the sub-workflow; other ports are returned unchanged.
+func (a *Workflow) ResolveInput(port Port) Port {
+	if p, ok := a.inputs[port]; ok {
+		return p
+	}
+	return port
+}
+
+// ResolveOutput returns the port of the process that generates values for
+// port. Exported outputs of sub-workflow processes resolve to the output of a
+// process within the sub-workflow, which is where unconnected values appear
+// in Outputs; other ports are returned unchanged.
+func (a *Workflow) ResolveOutput(port Port) Port {
+	if p, ok := a.outputs[port]; ok {
+		return p
+	}
+	return port
+}
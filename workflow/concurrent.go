@@ -0,0 +1,134 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/antha-lang/antha/inject"
+)
+
+type processKey int
+
+const theProcessKey processKey = 0
+
+func withProcess(parent context.Context, n *node) context.Context {
+	return context.WithValue(parent, theProcessKey, n)
+}
+
+// ProcessOrder returns the position, in the serial execution order of its
+// workflow, of the process being run with the given context. Consumers that
+// collect side-effects of processes use it to order them reproducibly
+// regardless of how many processes ran concurrently.
+func ProcessOrder(ctx context.Context) (int, bool) {
+	n, ok := ctx.Value(theProcessKey).(*node)
+	if !ok {
+		return 0, false
+	}
+	return n.order, true
+}
+
+// A Panic is raised by Run in the calling goroutine when a process panics
+// during concurrent execution.
+type Panic struct {
+	Process string      // Name of the process that panicked
+	Value   interface{} // Value passed to panic
+	Stack   string      // Stack trace of the process at the panic
+}
+
+func (a *Panic) String() string {
+	return fmt.Sprintf("process %q: %v", a.Process, a.Value)
+}
+
+type result struct {
+	Node  *node
+	Ready []*node
+	Err   error
+	Panic *Panic
+}
+
+func (a *Workflow) runGuarded(ctx context.Context, n *node) (res result) {
+	res.Node = n
+	defer func() {
+		if v := recover(); v != nil {
+			res.Panic = &Panic{
+				Process: n.Process,
+				Value:   v,
+				Stack:   inject.ElementStackTrace(),
+			}
+		}
+	}()
+	res.Ready, res.Err = a.run(ctx, n)
+	return
+}
+
+// runConcurrently executes nodes on a bounded pool of workers. Ready nodes
+// are dispatched in serial execution order. After the first failure no new
+// nodes are started; the failure earliest in serial execution order is
+// reported once running nodes have finished.
+func (a *Workflow) runConcurrently(ctx context.Context, order []*node) error {
+	work := make(chan *node)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < a.maxParallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range work {
+				results <- a.runGuarded(ctx, n)
+			}
+		}()
+	}
+	defer func() {
+		close(work)
+		wg.Wait()
+	}()
+
+	var ready []*node
+	for _, n := range order {
+		if !n.hasIns() {
+			ready = append(ready, n)
+		}
+	}
+
+	var failed *result
+	running := 0
+	for {
+		for failed == nil && ctx.Err() == nil && len(ready) > 0 && running < a.maxParallel {
+			work <- ready[0]
+			ready = ready[1:]
+			running++
+		}
+		if running == 0 {
+			break
+		}
+
+		res := <-results
+		running--
+
+		if res.Err != nil || res.Panic != nil {
+			if failed == nil || res.Node.order < failed.Node.order {
+				failed = &res
+			}
+			continue
+		}
+
+		ready = append(ready, res.Ready...)
+		sort.Slice(ready, func(i, j int) bool {
+			return ready[i].order < ready[j].order
+		})
+	}
+
+	switch {
+	case failed != nil && failed.Panic != nil:
+		panic(failed.Panic)
+	case failed != nil:
+		return fmt.Errorf("cannot run process %q: %s", failed.Node.Process, failed.Err)
+	case ctx.Err() != nil:
+		return ctx.Err()
+	}
+
+	return nil
+}
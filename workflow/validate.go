@@ -0,0 +1,204 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	api "github.com/antha-lang/antha/api/v1"
+	"github.com/antha-lang/antha/inject"
+	"github.com/antha-lang/antha/utils"
+)
+
+// ValidateOpt are options for Validate
+type ValidateOpt struct {
+	Desc *Desc
+	// Ports given values before running, e.g., by workflow parameters.
+	// Ports of sub-workflow processes name their exported inputs.
+	Params []Port
+	// Reports whether an input port of a component must have a value. If
+	// nil, every input must have a value.
+	Required func(funcName, port string) bool
+}
+
+// portTypes are the types of the ports of a component. Nil maps mean the
+// component is untyped.
+type portTypes struct {
+	Ins  map[string]reflect.Type
+	Outs map[string]reflect.Type
+}
+
+// fieldTypes returns the types of the fields of a struct or pointer to
+// struct or nil for other values
+func fieldTypes(x interface{}) map[string]reflect.Type {
+	t := reflect.TypeOf(x)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	m := make(map[string]reflect.Type)
+	for i, l := 0, t.NumField(); i < l; i++ {
+		m[t.Field(i).Name] = t.Field(i).Type
+	}
+	return m
+}
+
+func typesOf(ctx context.Context, funcName string) (*portTypes, error) {
+	r, err := inject.Find(ctx, inject.NameQuery{
+		Repo:  funcName,
+		Stage: api.ElementStage_STEPS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unknown component %q: %s", funcName, err)
+	}
+	tr, ok := r.(inject.TypedRunner)
+	if !ok {
+		return &portTypes{}, nil
+	}
+	return &portTypes{
+		Ins:  fieldTypes(tr.Input()),
+		Outs: fieldTypes(tr.Output()),
+	}, nil
+}
+
+// compatible reports whether a value of type from could be assigned to type
+// to at run time. Interface values are compatible if some dynamic value
+// could be assigned.
+func compatible(from, to reflect.Type) bool {
+	if from.AssignableTo(to) {
+		return true
+	} else if from.Kind() != reflect.Interface {
+		return false
+	}
+	return to.Kind() == reflect.Interface || to.Implements(from)
+}
+
+// Validate checks a workflow description against the types of the
+// components it uses without running any of them. It reports, as a
+// utils.ErrorSlice, every unknown process or port, input without a value,
+// input assigned more than once, connection between incompatible types and
+// cycle of processes.
+func Validate(ctx context.Context, opt ValidateOpt) error {
+	var errs utils.ErrorSlice
+
+	desc := opt.Desc
+	if desc == nil {
+		desc = &Desc{}
+	}
+
+	w := newWorkflow()
+	e := &expander{Workflow: w, errs: &errs}
+	if _, _, err := e.expand("", desc, nil); err != nil {
+		errs = append(errs, err)
+	}
+
+	var names []string
+	for name := range w.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	types := make(map[string]*portTypes)
+	for _, name := range names {
+		n := w.nodes[name]
+		if _, seen := types[n.FuncName]; seen {
+			continue
+		}
+		t, err := typesOf(ctx, n.FuncName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("process %q: %s", name, err))
+		}
+		types[n.FuncName] = t
+	}
+
+	// Only ports of typed components can be checked
+	known := func(n *node, port string, in bool) bool {
+		t := types[n.FuncName]
+		if t == nil {
+			return true
+		}
+		m := t.Outs
+		if in {
+			m = t.Ins
+		}
+		if m == nil {
+			return true
+		}
+		_, ok := m[port]
+		return ok
+	}
+
+	set := make(map[Port]bool)
+	for _, p := range opt.Params {
+		port := w.ResolveInput(p)
+		n := w.nodes[port.Process]
+		switch {
+		case n == nil:
+			errs = append(errs, fmt.Errorf("parameter %q: %s %q", p, errUnknownProcess, port.Process))
+		case !known(n, port.Port, true):
+			errs = append(errs, fmt.Errorf("parameter %q: %s %q", p, errUnknownPort, port))
+		case n.Ins[port.Port]:
+			errs = append(errs, fmt.Errorf("parameter %q: input %q is also connected", p, port))
+		case set[port]:
+			errs = append(errs, fmt.Errorf("parameter %q: input %q %s", p, port, errAlreadyAssigned))
+		}
+		set[port] = true
+	}
+
+	for _, name := range names {
+		n := w.nodes[name]
+		for _, out := range n.sortedOutNames() {
+			src := Port{Process: name, Port: out}
+			if !known(n, out, false) {
+				errs = append(errs, fmt.Errorf("connection from %q: %s", src, errUnknownPort))
+				continue
+			}
+			for _, ep := range n.Outs[out] {
+				tgt := Port{Process: ep.Node.Process, Port: ep.Port}
+				if !known(ep.Node, ep.Port, true) {
+					errs = append(errs, fmt.Errorf("connection from %q to %q: %s", src, tgt, errUnknownPort))
+					continue
+				}
+				st, tt := types[n.FuncName], types[ep.Node.FuncName]
+				if st == nil || st.Outs == nil || tt == nil || tt.Ins == nil {
+					continue
+				}
+				if from, to := st.Outs[out], tt.Ins[ep.Port]; !compatible(from, to) {
+					errs = append(errs, fmt.Errorf("connection from %q to %q: type %s not assignable to type %s", src, tgt, from, to))
+				}
+			}
+		}
+
+		t := types[n.FuncName]
+		if t == nil || t.Ins == nil {
+			continue
+		}
+		var ins []string
+		for in := range t.Ins {
+			ins = append(ins, in)
+		}
+		sort.Strings(ins)
+		for _, in := range ins {
+			port := Port{Process: name, Port: in}
+			if n.Ins[in] || set[port] {
+				continue
+			}
+			if opt.Required == nil || opt.Required(n.FuncName, in) {
+				errs = append(errs, fmt.Errorf("input %q has no value", port))
+			}
+		}
+	}
+
+	if _, err := w.schedule(); err != nil {
+		errs = append(errs, err)
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+
+	return errs.Pack()
+}
@@ -7,14 +7,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
-	api "github.com/antha-lang/antha/api/v1"
 	"github.com/antha-lang/antha/inject"
 )
 
-// TODO: deterministic node name/order
-
 var (
 	errCyclicWorkflow  = errors.New("cyclic workflow")
 	errUnknownPort     = errors.New("unknown port")
@@ -34,9 +33,11 @@ func (a Port) String() string {
 	return fmt.Sprintf("%s.%s", a.Process, a.Port)
 }
 
-// A Process is an instance of a component / element execution
+// A Process is an instance of a component / element execution or, if
+// Workflow is set, of a sub-workflow
 type Process struct {
 	Component string         `json:"component"`
+	Workflow  string         `json:"workflow,omitempty"` // Name of a workflow in Desc.Workflows
 	Metadata  screenPosition `json:"metadata"`
 }
 
@@ -52,9 +53,17 @@ type Connection struct {
 }
 
 // Desc is the description of a workflow.
+//
+// A workflow can be used as a process of another workflow. Connections to
+// and from such a process name its exported Inputs and Outputs, which map to
+// ports of the processes within it. Workflows defined in Workflows can be
+// used by processes of the description and of any workflow nested within it.
 type Desc struct {
 	Processes   map[string]Process `json:"Processes"`
 	Connections []Connection       `json:"connections"`
+	Workflows   map[string]*Desc   `json:"workflows,omitempty"`
+	Inputs      map[string]Port    `json:"inputs,omitempty"`
+	Outputs     map[string]Port    `json:"outputs,omitempty"`
 }
 
 type endpoint struct {
@@ -68,6 +77,8 @@ func (a endpoint) String() string {
 
 type node struct {
 	lock     sync.Mutex            // Lock on Params and Ins during Execute
+	order    int                   // Position in the serial execution order
+	stale    bool                  // Ran itself or had an upstream process run
 	Process  string                // Name of this instance
 	FuncName string                // Function that should be called
 	Params   inject.Value          // Parameters to this function
@@ -75,14 +86,18 @@ type node struct {
 	Ins      map[string]bool       // In edges
 }
 
-func (a *node) removeIn(port string) error {
+// removeIn removes an in edge and reports whether the node has no more in
+// edges. Both happen under the node lock so that exactly one of several
+// concurrently finishing parents observes the node becoming ready.
+func (a *node) removeIn(port string, stale bool) (bool, error) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 	if !a.Ins[port] {
-		return errAlreadyRemoved
+		return false, errAlreadyRemoved
 	}
 	delete(a.Ins, port)
-	return nil
+	a.stale = a.stale || stale
+	return len(a.Ins) == 0, nil
 }
 
 func (a *node) hasIns() bool {
@@ -101,21 +116,32 @@ func (a *node) setParam(port string, value interface{}) error {
 
 // Workflow is the state to execute a workflow
 type Workflow struct {
-	nodes   map[string]*node
-	Outputs map[Port]interface{} // Values generated that were not connected to another process
+	lock        sync.Mutex // Lock on nodes and Outputs during Run
+	nodes       map[string]*node
+	order       map[string]int // Serial execution order of processes after Run
+	maxParallel int
+	cache       Cache
+	inputs      map[Port]Port          // Exported inputs of sub-workflows to ports of nodes
+	outputs     map[Port]Port          // Exported outputs of sub-workflows to ports of nodes
+	runs        map[string]*processRun // Steps stage of each process that completed
+	Outputs     map[Port]interface{}   // Values generated that were not connected to another process
 }
 
-// FuncName gets the function to be called for the given process name
+// FuncName gets the function to be called for the given process name,
+// including processes that have already run
 func (a *Workflow) FuncName(process string) (string, error) {
-	n, ok := a.nodes[process]
-	if !ok {
-		return "", errUnknownProcess
+	if n, ok := a.nodes[process]; ok {
+		return n.FuncName, nil
 	}
-	return n.FuncName, nil
+	if r, ok := a.runs[process]; ok {
+		return r.FuncName, nil
+	}
+	return "", errUnknownProcess
 }
 
 // SetParam sets initial parameter values before executing
 func (a *Workflow) SetParam(port Port, value interface{}) error {
+	port = a.ResolveInput(port)
 	n := a.nodes[port.Process]
 	if n == nil {
 		return errUnknownPort
@@ -152,32 +178,45 @@ func updateOutParams(n *node, out inject.Value, unmatched map[Port]interface{})
 	return nil
 }
 
-func (a *Workflow) run(ctx context.Context, n *node) ([]*node, error) {
-	query := inject.NameQuery{
-		Repo:  n.FuncName,
-		Stage: api.ElementStage_STEPS,
+// sortedOutNames returns the output port names of a node in a stable order
+func (a *node) sortedOutNames() []string {
+	var names []string
+	for name := range a.Outs {
+		names = append(names, name)
 	}
-	out, err := inject.Call(ctx, query, n.Params)
+	sort.Strings(names)
+	return names
+}
 
+func (a *Workflow) run(ctx context.Context, n *node) ([]*node, error) {
+	out, err := a.call(withProcess(ctx, n), n)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := updateOutParams(n, out, a.Outputs); err != nil {
+	a.lock.Lock()
+	err = updateOutParams(n, out, a.Outputs)
+	a.runs[n.Process] = newProcessRun(n, out)
+	a.lock.Unlock()
+	if err != nil {
 		return nil, err
 	}
 
 	var roots []*node
-	for _, eps := range n.Outs {
-		for _, ep := range eps {
-			if err := ep.Node.removeIn(ep.Port); err != nil {
+	for _, name := range n.sortedOutNames() {
+		for _, ep := range n.Outs[name] {
+			if ready, err := ep.Node.removeIn(ep.Port, n.stale); err != nil {
 				return nil, fmt.Errorf("error removing in edge on %q: %s", ep, err)
-			} else if !ep.Node.hasIns() {
+			} else if ready {
 				roots = append(roots, ep.Node)
 			}
 		}
 	}
+
+	a.lock.Lock()
 	delete(a.nodes, n.Process)
+	a.lock.Unlock()
+
 	return roots, nil
 }
 
@@ -189,35 +228,111 @@ func makeRoots(nodes map[string]*node) ([]*node, error) {
 		}
 	}
 	if len(roots) == 0 && len(nodes) > 0 {
-		return nil, errCyclicWorkflow
+		return nil, cycleError(nodes, nil)
 	}
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].Process < roots[j].Process
+	})
 	return roots, nil
 }
 
-// Run a workflow
-func (a *Workflow) Run(ctx context.Context) error {
+// schedule returns the nodes in the order a serial execution runs them:
+// roots by process name, then breadth first following output ports by name.
+// This order is independent of how many processes actually run at once and
+// is recorded on each node so that results can be ordered reproducibly.
+func (a *Workflow) schedule() ([]*node, error) {
 	worklist, err := makeRoots(a.nodes)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	ins := make(map[*node]int)
+	for _, n := range a.nodes {
+		ins[n] = len(n.Ins)
+	}
+
+	var order []*node
 	for len(worklist) > 0 {
 		n := worklist[0]
 		worklist = worklist[1:]
 
-		if moreWork, err := a.run(ctx, n); err != nil {
-			return fmt.Errorf("cannot run process %q: %s", n.Process, err)
-		} else {
-			worklist = append(worklist, moreWork...)
+		n.order = len(order)
+		order = append(order, n)
+
+		for _, name := range n.sortedOutNames() {
+			for _, ep := range n.Outs[name] {
+				ins[ep.Node]--
+				if ins[ep.Node] == 0 {
+					worklist = append(worklist, ep.Node)
+				}
+			}
 		}
 	}
-	if len(a.nodes) > 0 { // by definition, len(worklist) == 0
-		return errCyclicWorkflow
+	if len(order) != len(a.nodes) {
+		return nil, cycleError(a.nodes, order)
+	}
+
+	a.order = make(map[string]int)
+	for _, n := range order {
+		a.order[n.Process] = n.order
+	}
+
+	return order, nil
+}
+
+// cycleError reports the processes that could not be scheduled
+func cycleError(nodes map[string]*node, scheduled []*node) error {
+	seen := make(map[*node]bool)
+	for _, n := range scheduled {
+		seen[n] = true
+	}
+	var names []string
+	for name, n := range nodes {
+		if !seen[n] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%s: processes %s depend on each other", errCyclicWorkflow, strings.Join(names, ", "))
+}
+
+// Run a workflow
+func (a *Workflow) Run(ctx context.Context) error {
+	order, err := a.schedule()
+	if err != nil {
+		return err
+	}
+
+	if a.maxParallel > 1 {
+		return a.runConcurrently(ctx, order)
+	}
+
+	for _, n := range order {
+		if _, err := a.run(ctx, n); err != nil {
+			return fmt.Errorf("cannot run process %q: %s", n.Process, err)
+		}
 	}
 
 	return nil
 }
 
+// OutputPorts returns the ports in Outputs, ordered by the position of their
+// process in the serial execution order and then by port name.
+func (a *Workflow) OutputPorts() []Port {
+	var ports []Port
+	for port := range a.Outputs {
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		oi, oj := a.order[ports[i].Process], a.order[ports[j].Process]
+		if oi != oj {
+			return oi < oj
+		}
+		return ports[i].Port < ports[j].Port
+	})
+	return ports
+}
+
 // AddNode adds a process to a workflow that executes funcName
 func (a *Workflow) AddNode(process, funcName string) error {
 	if a.nodes[process] != nil {
@@ -258,32 +373,36 @@ func (a *Workflow) AddEdge(src, tgt Port) error {
 // Opt are options for creating a new Workflow
 type Opt struct {
 	FromDesc *Desc
+	// Maximum number of processes to run at the same time. Values less than
+	// two run processes one at a time.
+	MaxParallel int
+	// If not nil, outputs of processes are stored to and restored from Cache
+	Cache Cache
 }
 
-// New creates a new Workflow
-func New(opt Opt) (*Workflow, error) {
-	w := &Workflow{
+func newWorkflow() *Workflow {
+	return &Workflow{
 		nodes:   make(map[string]*node),
+		inputs:  make(map[Port]Port),
+		outputs: make(map[Port]Port),
+		runs:    make(map[string]*processRun),
 		Outputs: make(map[Port]interface{}),
 	}
+}
 
-	var desc *Desc
-	if opt.FromDesc != nil {
-		desc = opt.FromDesc
-	} else {
-		desc = &Desc{}
-	}
+// New creates a new Workflow
+func New(opt Opt) (*Workflow, error) {
+	w := newWorkflow()
+	w.maxParallel = opt.MaxParallel
+	w.cache = opt.Cache
 
-	for name, process := range desc.Processes {
-		if err := w.AddNode(name, process.Component); err != nil {
-			return nil, err
-		}
+	if opt.FromDesc == nil {
+		return w, nil
 	}
 
-	for _, c := range desc.Connections {
-		if err := w.AddEdge(c.Src, c.Tgt); err != nil {
-			return nil, err
-		}
+	e := &expander{Workflow: w}
+	if _, _, err := e.expand("", opt.FromDesc, nil); err != nil {
+		return nil, err
 	}
 	return w, nil
 }
@@ -31,3 +31,65 @@ var condCopyEqualsJSON = `
     ]
 }
 `
+
+var selectCopyJSON = `
+{
+    "workflows": {
+        "Select": {
+            "processes": {
+                "Equals": { "component": "Equals" },
+                "Cond": { "component": "Cond" }
+            },
+            "connections": [
+                {
+                    "source": { "process": "Equals", "port": "Out" },
+                    "target": { "process": "Cond", "port": "Cond" }
+                }
+            ],
+            "inputs": {
+                "A": { "process": "Equals", "port": "A" },
+                "B": { "process": "Equals", "port": "B" },
+                "True": { "process": "Cond", "port": "True" },
+                "False": { "process": "Cond", "port": "False" }
+            },
+            "outputs": {
+                "Out": { "process": "Cond", "port": "Out" }
+            }
+        },
+        "SelectTwice": {
+            "processes": {
+                "First": { "workflow": "Select" },
+                "Second": { "workflow": "Select" }
+            },
+            "connections": [
+                {
+                    "source": { "process": "First", "port": "Out" },
+                    "target": { "process": "Second", "port": "A" }
+                }
+            ],
+            "inputs": {
+                "A": { "process": "First", "port": "A" },
+                "B": { "process": "First", "port": "B" },
+                "True": { "process": "First", "port": "True" },
+                "False": { "process": "First", "port": "False" },
+                "B2": { "process": "Second", "port": "B" },
+                "True2": { "process": "Second", "port": "True" },
+                "False2": { "process": "Second", "port": "False" }
+            },
+            "outputs": {
+                "Out": { "process": "Second", "port": "Out" }
+            }
+        }
+    },
+    "processes": {
+        "Select": { "workflow": "SelectTwice" },
+        "Copy": { "component": "Copy" }
+    },
+    "connections": [
+        {
+            "source": { "process": "Select", "port": "Out" },
+            "target": { "process": "Copy", "port": "In" }
+        }
+    ]
+}
+`
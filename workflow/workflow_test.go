@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	api "github.com/antha-lang/antha/api/v1"
 	"github.com/antha-lang/antha/inject"
+	"github.com/antha-lang/antha/utils"
 )
 
 func createContext() (context.Context, error) {
@@ -168,3 +171,324 @@ func TestDuplicateIns(t *testing.T) {
 		t.Errorf("expecting error setting in port")
 	}
 }
+
+func TestRunConcurrently(t *testing.T) {
+	const numCopies = 8
+
+	ctx := inject.NewContext(context.Background())
+
+	// Every Wait process blocks until all of them have started, so this only
+	// completes if they run at the same time
+	var started sync.WaitGroup
+	started.Add(numCopies)
+	if err := inject.Add(ctx, inject.Name{Repo: "Wait", Stage: api.ElementStage_STEPS}, &inject.FuncRunner{
+		RunFunc: func(_ context.Context, value inject.Value) (inject.Value, error) {
+			started.Done()
+			started.Wait()
+			return map[string]interface{}{"Out": value["In"]}, nil
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	var orders []int
+	if err := inject.Add(ctx, inject.Name{Repo: "Join", Stage: api.ElementStage_STEPS}, &inject.FuncRunner{
+		RunFunc: func(ctx context.Context, value inject.Value) (inject.Value, error) {
+			order, ok := ProcessOrder(ctx)
+			if !ok {
+				return nil, fmt.Errorf("no process order")
+			}
+			lock.Lock()
+			orders = append(orders, order)
+			lock.Unlock()
+			return map[string]interface{}{"Out": value["In"]}, nil
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(Opt{MaxParallel: numCopies})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < numCopies; i++ {
+		wait := fmt.Sprintf("Wait%d", i)
+		join := fmt.Sprintf("Join%d", i)
+		if err := w.AddNode(wait, "Wait"); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.AddNode(join, "Join"); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.AddEdge(Port{Process: wait, Port: "Out"}, Port{Process: join, Port: "In"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.SetParam(Port{Process: wait, Port: "In"}, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ports := w.OutputPorts()
+	if len(ports) != numCopies {
+		t.Fatalf("expecting %d outputs but got %d", numCopies, len(ports))
+	}
+	for i, port := range ports {
+		if e := (Port{Process: fmt.Sprintf("Join%d", i), Port: "Out"}); port != e {
+			t.Errorf("expecting output %d to be %q but got %q", i, e, port)
+		} else if out := w.Outputs[port]; out != i {
+			t.Errorf("expecting output %q to be %d but got %v", port, i, out)
+		}
+	}
+
+	// Wait processes sort first and so precede all Join processes
+	seen := make(map[int]bool)
+	for _, order := range orders {
+		if order < numCopies || order >= 2*numCopies || seen[order] {
+			t.Errorf("unexpected process order %d in %v", order, orders)
+		}
+		seen[order] = true
+	}
+}
+
+func TestRunConcurrentlyError(t *testing.T) {
+	ctx, err := createContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var desc *Desc
+	if err := json.Unmarshal([]byte(condCopyEqualsJSON), &desc); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(Opt{FromDesc: desc, MaxParallel: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing parameters for Equals
+	if err := w.Run(ctx); err == nil {
+		t.Errorf("expecting error running workflow")
+	} else if _, seen := w.Outputs[Port{Process: "Copy", Port: "Out"}]; seen {
+		t.Errorf("expecting no output after error")
+	}
+}
+
+func TestRunSubWorkflow(t *testing.T) {
+	var desc *Desc
+	if err := json.Unmarshal([]byte(selectCopyJSON), &desc); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(Opt{FromDesc: desc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := createContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := map[string]string{
+		"A":      "A",
+		"B":      "B",
+		"True":   "True",
+		"False":  "False",
+		"B2":     "False",
+		"True2":  "Same",
+		"False2": "Different",
+	}
+	for port, value := range params {
+		if err := w.SetParam(Port{Process: "Select", Port: port}, value); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if p, e := w.ResolveInput(Port{Process: "Select", Port: "B2"}), (Port{Process: "Select/Second/Equals", Port: "B"}); p != e {
+		t.Errorf("expecting input %q but got %q", e, p)
+	}
+	if c, err := w.FuncName("Select/First/Cond"); err != nil {
+		t.Error(err)
+	} else if c != "Cond" {
+		t.Errorf("expecting component %q but got %q", "Cond", c)
+	}
+
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, ok := w.Outputs[Port{Process: "Copy", Port: "Out"}].(string); !ok {
+		t.Errorf("cannot read parameter Out")
+	} else if out != "Same" {
+		t.Errorf("expecting output %q but got %q", "Same", out)
+	}
+}
+
+func TestSubWorkflowErrors(t *testing.T) {
+	sel := &Desc{
+		Processes: map[string]Process{
+			"Copy": {Component: "Copy"},
+		},
+		Inputs: map[string]Port{
+			"In": {Process: "Copy", Port: "In"},
+		},
+		Outputs: map[string]Port{
+			"Out": {Process: "Copy", Port: "Out"},
+		},
+	}
+
+	tests := map[string]*Desc{
+		"recursive": {
+			Workflows: map[string]*Desc{
+				"A": {
+					Processes: map[string]Process{"B": {Workflow: "B"}},
+				},
+				"B": {
+					Processes: map[string]Process{"A": {Workflow: "A"}},
+				},
+			},
+			Processes: map[string]Process{"Top": {Workflow: "A"}},
+		},
+		"cyclic across levels": {
+			Workflows: map[string]*Desc{"Copy": sel},
+			Processes: map[string]Process{
+				"Sub":  {Workflow: "Copy"},
+				"Copy": {Component: "Copy"},
+			},
+			Connections: []Connection{
+				{Src: Port{Process: "Sub", Port: "Out"}, Tgt: Port{Process: "Copy", Port: "In"}},
+				{Src: Port{Process: "Copy", Port: "Out"}, Tgt: Port{Process: "Sub", Port: "In"}},
+			},
+		},
+		"unknown workflow": {
+			Processes: map[string]Process{"Sub": {Workflow: "Missing"}},
+		},
+		"unknown exported port": {
+			Workflows: map[string]*Desc{"Copy": sel},
+			Processes: map[string]Process{
+				"Sub":  {Workflow: "Copy"},
+				"Copy": {Component: "Copy"},
+			},
+			Connections: []Connection{
+				{Src: Port{Process: "Sub", Port: "Missing"}, Tgt: Port{Process: "Copy", Port: "In"}},
+			},
+		},
+	}
+
+	for name, desc := range tests {
+		w, err := New(Opt{FromDesc: desc})
+		if err == nil {
+			_, err = w.schedule()
+		}
+		if err == nil {
+			t.Errorf("%s: expecting error", name)
+		}
+	}
+
+	w, err := New(Opt{FromDesc: tests["cyclic across levels"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.schedule(); err == nil || !strings.Contains(err.Error(), "Sub/Copy") {
+		t.Errorf("expecting error naming process %q but got %v", "Sub/Copy", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type strIn struct {
+		In string
+	}
+	type strOut struct {
+		Out string
+	}
+	type intIn struct {
+		In int
+	}
+
+	ctx := inject.NewContext(context.Background())
+	add := func(name string, in, out interface{}) {
+		if err := inject.Add(ctx, inject.Name{Repo: name, Stage: api.ElementStage_STEPS}, &inject.CheckedRunner{
+			RunFunc: func(context.Context, inject.Value) (inject.Value, error) {
+				return nil, fmt.Errorf("%s should not run", name)
+			},
+			In:  in,
+			Out: out,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("Str", &strIn{}, &strOut{})
+	add("Int", &intIn{}, &strOut{})
+
+	valid := &Desc{
+		Processes: map[string]Process{
+			"A": {Component: "Str"},
+			"B": {Component: "Str"},
+		},
+		Connections: []Connection{
+			{Src: Port{Process: "A", Port: "Out"}, Tgt: Port{Process: "B", Port: "In"}},
+		},
+	}
+	if err := Validate(ctx, ValidateOpt{
+		Desc:   valid,
+		Params: []Port{{Process: "A", Port: "In"}},
+	}); err != nil {
+		t.Errorf("expecting valid workflow but got %s", err)
+	}
+
+	invalid := &Desc{
+		Processes: map[string]Process{
+			"A": {Component: "Str"},
+			"B": {Component: "Int"},
+			"C": {Component: "Str"},
+			"D": {Component: "Str"},
+			"E": {Component: "Missing"},
+			"G": {Component: "Str"},
+		},
+		Connections: []Connection{
+			{Src: Port{Process: "A", Port: "Out"}, Tgt: Port{Process: "B", Port: "In"}},
+			{Src: Port{Process: "A", Port: "Wrong"}, Tgt: Port{Process: "G", Port: "In"}},
+			{Src: Port{Process: "C", Port: "Out"}, Tgt: Port{Process: "D", Port: "In"}},
+			{Src: Port{Process: "D", Port: "Out"}, Tgt: Port{Process: "C", Port: "In"}},
+			{Src: Port{Process: "A", Port: "Out"}, Tgt: Port{Process: "D", Port: "In"}},
+		},
+	}
+	err := Validate(ctx, ValidateOpt{
+		Desc: invalid,
+		Params: []Port{
+			{Process: "D", Port: "In"},
+			{Process: "F", Port: "In"},
+		},
+	})
+	errs, ok := err.(utils.ErrorSlice)
+	if !ok {
+		t.Fatalf("expecting errors but got %v", err)
+	}
+
+	expected := []string{
+		`"A.Wrong": unknown port`,                // unknown port
+		`"A.In" has no value`,                    // unconnected input
+		`"D.In" of process "D" already assigned`, // doubly assigned
+		`type string not assignable to type int`, // type mismatch
+		`processes C, D depend on each other`,    // cycle
+		`unknown component "Missing"`,            // unknown component
+		`parameter "D.In": input "D.In" is also`, // parameter and connection
+		`parameter "F.In": unknown process "F"`,  // unknown process
+	}
+	for _, e := range expected {
+		found := false
+		for _, err := range errs {
+			found = found || strings.Contains(err.Error(), e)
+		}
+		if !found {
+			t.Errorf("expecting error containing %q in:\n%s", e, err)
+		}
+	}
+}